@@ -2,43 +2,199 @@ package btc
 
 import (
 	"bytes"
-	"regexp"
+	"errors"
+	"strings"
 
 	"github.com/m0t0k1ch1/base58"
 )
 
+const (
+	base58ChecksumLength = 4
+)
+
+type AddressType int
+
+const (
+	AddressTypeUnknown AddressType = iota
+	AddressTypeP2pkh
+	AddressTypeP2sh
+	AddressTypeP2wpkh
+	AddressTypeP2wsh
+	AddressTypeP2tr
+	AddressTypeWitnessUnknown
+)
+
+var (
+	ErrInvalidAddressEncoding = errors.New("invalid address encoding")
+	ErrInvalidAddressLength   = errors.New("invalid address length")
+	ErrInvalidAddressChecksum = errors.New("invalid address checksum")
+	ErrInvalidAddressVersion  = errors.New("invalid address version")
+	ErrAddressNetworkMismatch = errors.New("address network mismatch")
+	ErrUnexpectedAddressType  = errors.New("unexpected address type")
+	ErrScriptHasNoAddress     = errors.New("script has no address")
+)
+
+var addressTypeNameMap = map[AddressType]string{
+	AddressTypeUnknown:        "unknown",
+	AddressTypeP2pkh:          "pubkeyhash",
+	AddressTypeP2sh:           "scripthash",
+	AddressTypeP2wpkh:         "witness_v0_keyhash",
+	AddressTypeP2wsh:          "witness_v0_scripthash",
+	AddressTypeP2tr:           "witness_v1_taproot",
+	AddressTypeWitnessUnknown: "witness_unknown",
+}
+
+func (typ AddressType) String() string {
+	return addressTypeNameMap[typ]
+}
+
 type Pkh []byte
 
 func (pkh Pkh) Bytes() []byte {
 	return []byte(pkh)
 }
 
-func (pkh Pkh) Address() (Address, error) {
+func (pkh Pkh) Address(params *Params) (Address, error) {
 	if len(pkh) != PkhLength {
 		return "", ErrInvalidPkhLength
 	}
 
-	b := pkh
+	return encodeBase58Address(params.PkhAddressVersion, pkh)
+}
+
+type ScriptHash []byte
+
+func (scriptHash ScriptHash) Bytes() []byte {
+	return []byte(scriptHash)
+}
 
-	if isTestnet() {
-		b = append([]byte{AddressVersionTest}, b...)
-	} else {
-		b = append([]byte{AddressVersionMain}, b...)
+func (scriptHash ScriptHash) Address(params *Params) (Address, error) {
+	if len(scriptHash) != ScriptHashLength {
+		return "", ErrInvalidScriptHashLength
 	}
 
-	doubleHashedBytes, err := Sha256Double(b)
+	return encodeBase58Address(params.ScriptHashAddressVersion, scriptHash)
+}
+
+// NewP2shP2wpkhAddress returns the P2SH-P2WPKH address of pubKey.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0141.mediawiki#p2wpkh-nested-in-bip16-p2sh
+func NewP2shP2wpkhAddress(pubKey *PublicKey, params *Params) (Address, error) {
+	redeemScript, err := NewP2shP2wpkhRedeemScript(pubKey)
+	if err != nil {
+		return "", err
+	}
+
+	scriptHash, err := redeemScript.ScriptHash()
+	if err != nil {
+		return "", err
+	}
+
+	return scriptHash.Address(params)
+}
+
+// NewP2shP2wshAddress returns the P2SH-P2WSH address of witnessScript.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0141.mediawiki#p2wsh-nested-in-bip16-p2sh
+func NewP2shP2wshAddress(witnessScript *Script, params *Params) (Address, error) {
+	redeemScript, err := NewP2shP2wshRedeemScript(witnessScript)
+	if err != nil {
+		return "", err
+	}
+
+	scriptHash, err := redeemScript.ScriptHash()
+	if err != nil {
+		return "", err
+	}
+
+	return scriptHash.Address(params)
+}
+
+// NewP2trAddress returns the P2TR address of internalKey committing to merkleRoot,
+// which is nil for outputs without script paths.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0341.mediawiki#constructing-and-spending-taproot-outputs
+func NewP2trAddress(internalKey *XOnlyPublicKey, merkleRoot []byte, params *Params) (Address, error) {
+	outputKey, err := TaprootOutputKey(internalKey, merkleRoot)
 	if err != nil {
 		return "", err
 	}
-	checksumBytes := doubleHashedBytes[0:4]
-	b = append(b, checksumBytes...)
 
-	address, err := base58.NewBitcoinBase58().EncodeToString(b)
+	return NewWitnessAddress(1, outputKey.Bytes(), params)
+}
+
+// WitnessAddress returns the P2WPKH address of pkh.
+func (pkh Pkh) WitnessAddress(params *Params) (Address, error) {
+	if len(pkh) != PkhLength {
+		return "", ErrInvalidPkhLength
+	}
+
+	return NewWitnessAddress(0, pkh, params)
+}
+
+// NewWitnessAddress returns the segwit address of a witness program.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0173.mediawiki#segwit-address-format
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0350.mediawiki#addresses-for-segregated-witness-outputs
+func NewWitnessAddress(version int, program []byte, params *Params) (Address, error) {
+	if err := validateWitnessProgram(version, program); err != nil {
+		return "", err
+	}
+
+	data, err := convertBits(program, 8, 5, true)
 	if err != nil {
-		return "", nil
+		return "", err
+	}
+
+	encoding := Bech32m
+	if version == 0 {
+		encoding = Bech32
+	}
+
+	s, err := Bech32Encode(params.Bech32Hrp, append([]byte{byte(version)}, data...), encoding)
+	if err != nil {
+		return "", err
+	}
+
+	return Address(s), nil
+}
+
+func decodeWitnessAddress(s string) (string, int, []byte, error) {
+	hrp, data, encoding, err := Bech32Decode(s)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	if len(data) < 1 {
+		return "", 0, nil, ErrInvalidWitnessProgram
+	}
+
+	version := int(data[0])
+	if version > WitnessVersionMax {
+		return "", 0, nil, ErrInvalidWitnessVersion
+	}
+	if (version == 0) != (encoding == Bech32) {
+		return "", 0, nil, ErrBech32InvalidChecksum
+	}
+
+	program, err := convertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	if err := validateWitnessProgram(version, program); err != nil {
+		return "", 0, nil, err
+	}
+
+	return hrp, version, program, nil
+}
+
+func validateWitnessProgram(version int, program []byte) error {
+	if version < WitnessVersionMin || version > WitnessVersionMax {
+		return ErrInvalidWitnessVersion
+	}
+	if len(program) < witnessProgramLengthMin || len(program) > witnessProgramLengthMax {
+		return ErrInvalidWitnessProgram
+	}
+	if version == 0 && len(program) != PkhLength && len(program) != WitnessScriptHashLength {
+		return ErrInvalidWitnessProgram
 	}
 
-	return Address(address), nil
+	return nil
 }
 
 type Address string
@@ -47,35 +203,207 @@ func (address Address) String() string {
 	return string(address)
 }
 
-func (address Address) Pkh() (Pkh, error) {
-	b, err := base58.NewBitcoinBase58().DecodeString(address.String())
+func (address Address) Pkh(params *Params) (Pkh, error) {
+	decoded, err := DecodeAddress(address.String(), params)
+	if err != nil {
+		return nil, err
+	}
+	if decoded.Type != AddressTypeP2pkh {
+		return nil, ErrUnexpectedAddressType
+	}
+
+	return Pkh(decoded.Data), nil
+}
+
+func (address Address) ScriptHash(params *Params) (ScriptHash, error) {
+	decoded, err := DecodeAddress(address.String(), params)
 	if err != nil {
 		return nil, err
 	}
+	if decoded.Type != AddressTypeP2sh {
+		return nil, ErrUnexpectedAddressType
+	}
+
+	return ScriptHash(decoded.Data), nil
+}
+
+// WitnessProgram returns the witness version and program of a segwit address for params.
+func (address Address) WitnessProgram(params *Params) (int, []byte, error) {
+	hrp, version, program, err := decodeWitnessAddress(address.String())
+	if err != nil {
+		return 0, nil, err
+	}
+	if hrp != params.Bech32Hrp {
+		return 0, nil, ErrBech32InvalidHrp
+	}
+
+	return version, program, nil
+}
+
+func (address Address) IsValid(params *Params) bool {
+	_, err := DecodeAddress(address.String(), params)
+	return err == nil
+}
 
-	return b[1 : len(b)-4], nil
+// DecodedAddress is an address validated against a network.
+type DecodedAddress struct {
+	Type           AddressType
+	WitnessVersion int
+	Data           []byte
 }
 
+// DecodeAddress decodes a base58 or bech32 address of the network defined by params.
 // ref. https://bitcointalk.org/index.php?topic=1026.0
-func (address Address) IsValid() (bool, error) {
-	s := address.String()
+func DecodeAddress(s string, params *Params) (*DecodedAddress, error) {
+	hrp, version, program, err := decodeWitnessAddress(s)
+	if err == nil {
+		if hrp != params.Bech32Hrp {
+			return nil, ErrAddressNetworkMismatch
+		}
+
+		typ := AddressTypeWitnessUnknown
+		switch {
+		case version == 0 && len(program) == PkhLength:
+			typ = AddressTypeP2wpkh
+		case version == 0 && len(program) == WitnessScriptHashLength:
+			typ = AddressTypeP2wsh
+		case version == 1 && len(program) == XOnlyPubKeyLength:
+			typ = AddressTypeP2tr
+		}
+
+		return &DecodedAddress{
+			Type:           typ,
+			WitnessVersion: version,
+			Data:           program,
+		}, nil
+	}
+	if hasBech32AddressPrefix(s) {
+		return nil, err
+	}
+
+	addrVersion, payload, err := decodeBase58Address(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var typ AddressType
+	switch addrVersion {
+	case params.PkhAddressVersion:
+		typ = AddressTypeP2pkh
+	case params.ScriptHashAddressVersion:
+		typ = AddressTypeP2sh
+	default:
+		for _, p := range networkParams {
+			if addrVersion == p.PkhAddressVersion || addrVersion == p.ScriptHashAddressVersion {
+				return nil, ErrAddressNetworkMismatch
+			}
+		}
+		return nil, ErrInvalidAddressVersion
+	}
+
+	return &DecodedAddress{
+		Type:           typ,
+		WitnessVersion: -1,
+		Data:           payload,
+	}, nil
+}
+
+// Script returns the scriptPubKey paying to the address.
+func (decoded *DecodedAddress) Script() (*Script, error) {
+	switch decoded.Type {
+	case AddressTypeP2pkh:
+		return NewP2pkhScript(Pkh(decoded.Data))
+	case AddressTypeP2sh:
+		return NewP2shScript(decoded.Data)
+	case AddressTypeP2wpkh, AddressTypeP2wsh, AddressTypeP2tr, AddressTypeWitnessUnknown:
+		return NewWitnessProgramScript(decoded.WitnessVersion, decoded.Data)
+	default:
+		return nil, ErrUnexpectedAddressType
+	}
+}
+
+// Address returns the address paying to the script, if it has one.
+func (script *Script) Address(params *Params) (Address, error) {
+	b, err := script.Bytes()
+	if err != nil {
+		return "", err
+	}
+
+	if isP2shScript(b) {
+		return ScriptHash(b[2 : 2+ScriptHashLength]).Address(params)
+	}
+	if version, program, ok := extractWitnessProgram(b); ok {
+		return NewWitnessAddress(version, program, params)
+	}
 
-	if ok := regexp.MustCompile(`^[a-zA-Z1-9]{27,35}$`).MatchString(s); !ok {
-		return false, nil
+	ops, err := parseScript(b)
+	if err != nil {
+		return "", err
+	}
+	if pkh := extractP2pkhPkh(ops); pkh != nil {
+		return pkh.Address(params)
 	}
 
+	return "", ErrScriptHasNoAddress
+}
+
+func hasBech32AddressPrefix(s string) bool {
+	s = strings.ToLower(s)
+	for _, p := range networkParams {
+		if strings.HasPrefix(s, p.Bech32Hrp+string(bech32Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func encodeBase58Address(version byte, payload []byte) (Address, error) {
+	s, err := encodeBase58Check(append([]byte{version}, payload...))
+	if err != nil {
+		return "", err
+	}
+
+	return Address(s), nil
+}
+
+func decodeBase58Address(s string) (byte, []byte, error) {
 	b, err := base58.NewBitcoinBase58().DecodeString(s)
 	if err != nil {
-		return false, err
+		return 0, nil, ErrInvalidAddressEncoding
+	}
+	if len(b) != 1+PkhLength+base58ChecksumLength {
+		return 0, nil, ErrInvalidAddressLength
 	}
 
-	doubleHashedBytes, err := Sha256Double(b[:len(b)-4])
+	ok, err := verifyBase58Checksum(b)
 	if err != nil {
-		return false, err
+		return 0, nil, err
+	}
+	if !ok {
+		return 0, nil, ErrInvalidAddressChecksum
+	}
+
+	return b[0], b[1 : len(b)-base58ChecksumLength], nil
+}
+
+func encodeBase58Check(b []byte) (string, error) {
+	doubleHashedBytes, err := Sha256Double(b)
+	if err != nil {
+		return "", err
 	}
-	if !bytes.Equal(b[len(b)-4:], doubleHashedBytes[:4]) {
-		return false, nil
+	checksumBytes := doubleHashedBytes[0:base58ChecksumLength]
+
+	return base58.NewBitcoinBase58().EncodeToString(concatBytes(b, checksumBytes))
+}
+
+func verifyBase58Checksum(b []byte) (bool, error) {
+	n := len(b) - base58ChecksumLength
+
+	doubleHashedBytes, err := Sha256Double(b[:n])
+	if err != nil {
+		return false, err
 	}
 
-	return true, nil
+	return bytes.Equal(b[n:], doubleHashedBytes[:base58ChecksumLength]), nil
 }
@@ -11,13 +11,20 @@ import (
 func TestPkhAddressConversion(t *testing.T) {
 	testCases := []struct {
 		pkh     string
+		params  *Params
 		address string
 	}{
 		{
 			// ref. https://en.bitcoin.it/wiki/Technical_background_of_version_1_Bitcoin_addresses
 			"010966776006953d5567439e5e39f86a0d273bee",
+			MainNetParams,
 			"16UwLL9Risc3QfPqBUvKofHmBQ7wMtjvM",
 		},
+		{
+			"cbc222711a230ecdd9a5aa65b61ed39c24db2b34",
+			TestNet3Params,
+			"mz6L2hYM8jPR5nhH6kEsc3DQFiSDA1Jqpa",
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.pkh, func(t *testing.T) {
@@ -25,12 +32,12 @@ func TestPkhAddressConversion(t *testing.T) {
 			require.NoError(t, err)
 
 			// address -> pkh
-			address, err := Pkh(b).Address()
+			address, err := Pkh(b).Address(tc.params)
 			require.NoError(t, err)
 			assert.Equal(t, address.String(), tc.address)
 
 			// pkh -> address
-			pkh, err := address.Pkh()
+			pkh, err := address.Pkh(tc.params)
 			require.NoError(t, err)
 			assert.Equal(t, hex.EncodeToString(pkh.Bytes()), tc.pkh)
 		})
@@ -40,23 +47,240 @@ func TestPkhAddressConversion(t *testing.T) {
 func TestAddressValidation(t *testing.T) {
 	testCases := []struct {
 		address Address
+		params  *Params
 		isValid bool
 	}{
 		{
 			Address("16UwLL9Risc3QfPqBUvKofHmBQ7wMtjvM"),
+			MainNetParams,
+			true,
+		},
+		{
+			Address("16UwLL9Risc3QfPqBUvKofHmBQ7wMtjvM"),
+			TestNet3Params,
+			false,
+		},
+		{
+			Address("bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"),
+			MainNetParams,
 			true,
 		},
 		{
 			Address("000000000000000000000000000000000"),
+			MainNetParams,
 			false,
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.address.String(), func(t *testing.T) {
-			ok, err := tc.address.IsValid()
+			assert.Equal(t, tc.address.IsValid(tc.params), tc.isValid)
+		})
+	}
+}
+
+func TestDecodeAddress(t *testing.T) {
+	testCases := []struct {
+		address string
+		params  *Params
+		typ     AddressType
+		script  string
+	}{
+		{
+			"16UwLL9Risc3QfPqBUvKofHmBQ7wMtjvM",
+			MainNetParams,
+			AddressTypeP2pkh,
+			"76a914010966776006953d5567439e5e39f86a0d273bee88ac",
+		},
+		{
+			"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy",
+			MainNetParams,
+			AddressTypeP2sh,
+			"a914b472a266d0bd89c13706a4132ccfb16f7c3b9fcb87",
+		},
+		{
+			"2MzQwSSnBHWHqSAqtTVQ6v47XtaisrJa1Vc",
+			TestNet3Params,
+			AddressTypeP2sh,
+			"a9144e9f39ca4688ff102128ea4ccda34105324305b087",
+		},
+		{
+			"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4",
+			MainNetParams,
+			AddressTypeP2wpkh,
+			"0014751e76e8199196d454941c45d1b3a323f1433bd6",
+		},
+		{
+			"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7",
+			TestNet3Params,
+			AddressTypeP2wsh,
+			"00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262",
+		},
+		{
+			"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0",
+			MainNetParams,
+			AddressTypeP2tr,
+			"512079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+		},
+		{
+			"bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs",
+			MainNetParams,
+			AddressTypeWitnessUnknown,
+			"5210751e76e8199196d454941c45d1b3a323",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.address, func(t *testing.T) {
+			decoded, err := DecodeAddress(tc.address, tc.params)
+			require.NoError(t, err)
+			assert.Equal(t, decoded.Type, tc.typ)
+
+			script, err := decoded.Script()
+			require.NoError(t, err)
+			assert.Equal(t, script.Hex, tc.script)
+		})
+	}
+}
+
+func TestDecodeAddressErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		address string
+		params  *Params
+		err     error
+	}{
+		{
+			"invalid encoding",
+			"000000000000000000000000000000000",
+			MainNetParams,
+			ErrInvalidAddressEncoding,
+		},
+		{
+			"invalid length",
+			"116L5yRNPTuciSgXGHqYwn9N6NekzyHT4U",
+			MainNetParams,
+			ErrInvalidAddressLength,
+		},
+		{
+			"invalid checksum",
+			"16UwLL9Risc3QfPqBUvKofHmBQ7wMtjvN",
+			MainNetParams,
+			ErrInvalidAddressChecksum,
+		},
+		{
+			"invalid version",
+			"LKDyUEtTR1HXamkiEphisSiBJu6o3ZPE34",
+			MainNetParams,
+			ErrInvalidAddressVersion,
+		},
+		{
+			"base58 network mismatch",
+			"mz6L2hYM8jPR5nhH6kEsc3DQFiSDA1Jqpa",
+			MainNetParams,
+			ErrAddressNetworkMismatch,
+		},
+		{
+			"bech32 network mismatch",
+			"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7",
+			MainNetParams,
+			ErrAddressNetworkMismatch,
+		},
+		{
+			"bech32 invalid checksum",
+			"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5",
+			MainNetParams,
+			ErrBech32InvalidChecksum,
+		},
+		{
+			"bech32 invalid witness program",
+			"BC1QR508D6QEJXTDG4Y5R3ZARVARYV98GJ9P",
+			MainNetParams,
+			ErrInvalidWitnessProgram,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := DecodeAddress(tc.address, tc.params)
+			assert.Equal(t, err, tc.err)
+		})
+	}
+}
+
+func TestAddressPkhErrors(t *testing.T) {
+	_, err := Address("3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy").Pkh(MainNetParams)
+	assert.Equal(t, err, ErrUnexpectedAddressType)
+}
+
+func TestScriptHashAddressConversion(t *testing.T) {
+	testCases := []struct {
+		scriptHash string
+		params     *Params
+		address    string
+	}{
+		{
+			"b472a266d0bd89c13706a4132ccfb16f7c3b9fcb",
+			MainNetParams,
+			"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy",
+		},
+		{
+			"4e9f39ca4688ff102128ea4ccda34105324305b0",
+			TestNet3Params,
+			"2MzQwSSnBHWHqSAqtTVQ6v47XtaisrJa1Vc",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.scriptHash, func(t *testing.T) {
+			b, err := hex.DecodeString(tc.scriptHash)
+			require.NoError(t, err)
+
+			// script hash -> address
+			address, err := ScriptHash(b).Address(tc.params)
 			require.NoError(t, err)
-			assert.Equal(t, ok, tc.isValid)
+			assert.Equal(t, address.String(), tc.address)
+
+			// address -> script hash
+			scriptHash, err := address.ScriptHash(tc.params)
+			require.NoError(t, err)
+			assert.Equal(t, hex.EncodeToString(scriptHash.Bytes()), tc.scriptHash)
 		})
 	}
 }
+
+func TestNestedSegwitAddress(t *testing.T) {
+	// the uncompressed form of the generator point must yield the same address
+	pubKey, err := NewPublicKeyFromHex("0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8")
+	require.NoError(t, err)
+
+	redeemScript, err := NewP2shP2wpkhRedeemScript(pubKey)
+	require.NoError(t, err)
+	assert.Equal(t, redeemScript.Hex, "0014751e76e8199196d454941c45d1b3a323f1433bd6")
+
+	address, err := NewP2shP2wpkhAddress(pubKey, MainNetParams)
+	require.NoError(t, err)
+	assert.Equal(t, address.String(), "3JvL6Ymt8MVWiCNHC7oWU6nLeHNJKLZGLN")
+
+	address, err = NewP2shP2wpkhAddress(pubKey, TestNet3Params)
+	require.NoError(t, err)
+	assert.Equal(t, address.String(), "2NAUYAHhujozruyzpsFRP63mbrdaU5wnEpN")
+
+	witnessScript, err := NewScriptFromHex("51210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f8179851ae")
+	require.NoError(t, err)
+
+	redeemScript, err = NewP2shP2wshRedeemScript(witnessScript)
+	require.NoError(t, err)
+	assert.Equal(t, redeemScript.Hex, "002028205333db922f66e8a941b4a32d66de5cea03d9cda46e3e6658935272b9b24f")
+
+	address, err = NewP2shP2wshAddress(witnessScript, MainNetParams)
+	require.NoError(t, err)
+	assert.Equal(t, address.String(), "344YToRR99ER5CRo975kXTUAnYcBrVxQYm")
+
+	address, err = NewP2shP2wshAddress(witnessScript, TestNet3Params)
+	require.NoError(t, err)
+	assert.Equal(t, address.String(), "2MuckXYMSkbjmGz4LpEhd9QTRztpMceVskG")
+
+	decoded, err := DecodeAddress(address.String(), TestNet3Params)
+	require.NoError(t, err)
+	assert.Equal(t, decoded.Type, AddressTypeP2sh)
+}
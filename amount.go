@@ -0,0 +1,261 @@
+package btc
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// AmountUnit is a unit of amounts, represented by the exponent of 10 of its value in satoshis.
+type AmountUnit int
+
+const (
+	AmountUnitMilliSatoshi AmountUnit = -3
+	AmountUnitSatoshi      AmountUnit = 0
+	AmountUnitBits         AmountUnit = 2
+	AmountUnitMicroBtc     AmountUnit = AmountUnitBits
+	AmountUnitMilliBtc     AmountUnit = 5
+	AmountUnitBtc          AmountUnit = 8
+)
+
+var (
+	ErrInvalidAmount     = errors.New("invalid amount")
+	ErrInvalidAmountUnit = errors.New("invalid amount unit")
+)
+
+// ParseAmountUnit returns the unit of name, e.g. "BTC", "mBTC", "µBTC", "bits", "sat" or "msat".
+func ParseAmountUnit(name string) (AmountUnit, error) {
+	switch name {
+	case "BTC":
+		return AmountUnitBtc, nil
+	case "mBTC":
+		return AmountUnitMilliBtc, nil
+	case "µBTC", "μBTC", "uBTC", "bits":
+		return AmountUnitMicroBtc, nil
+	case "sat", "sats":
+		return AmountUnitSatoshi, nil
+	case "msat":
+		return AmountUnitMilliSatoshi, nil
+	default:
+		return 0, ErrInvalidAmountUnit
+	}
+}
+
+func (unit AmountUnit) String() string {
+	switch unit {
+	case AmountUnitBtc:
+		return "BTC"
+	case AmountUnitMilliBtc:
+		return "mBTC"
+	case AmountUnitMicroBtc:
+		return "µBTC"
+	case AmountUnitSatoshi:
+		return "sat"
+	case AmountUnitMilliSatoshi:
+		return "msat"
+	default:
+		return "AmountUnit(" + strconv.Itoa(int(unit)) + ")"
+	}
+}
+
+// decimals returns the number of the decimal places of amounts in the unit.
+func (unit AmountUnit) decimals() int {
+	if unit < 0 {
+		return 0
+	}
+
+	return int(unit)
+}
+
+// ParseAmount parses a decimal amount in BTC, e.g. "0.29", exactly.
+func ParseAmount(s string) (Satoshi, error) {
+	return ParseAmountWithUnit(s, AmountUnitBtc)
+}
+
+// ParseAmountWithUnit parses a decimal amount in unit exactly.
+// An exponent is accepted as Bitcoin Core's ParseFixedPoint does, e.g. "1e-8".
+// Amounts not representable in satoshis and ones out of the money range are rejected.
+func ParseAmountWithUnit(s string, unit AmountUnit) (Satoshi, error) {
+	negative := strings.HasPrefix(s, "-")
+	if negative {
+		s = s[1:]
+	}
+
+	exp := 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.ParseInt(s[i+1:], 10, 16)
+		if err != nil {
+			return 0, ErrInvalidAmount
+		}
+		exp, s = int(e), s[:i]
+	}
+
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+	if len(intPart)+len(fracPart) == 0 || !isDigits(intPart) || !isDigits(fracPart) {
+		return 0, ErrInvalidAmount
+	}
+
+	if exp != 0 {
+		var err error
+		if intPart, fracPart, err = shiftDecimalPoint(intPart, fracPart, exp); err != nil {
+			return 0, err
+		}
+	}
+
+	decimals := unit.decimals()
+	if len(fracPart) > decimals {
+		if strings.TrimRight(fracPart[decimals:], "0") != "" {
+			return 0, ErrInvalidAmount
+		}
+		fracPart = fracPart[:decimals]
+	}
+
+	digits := strings.TrimLeft(intPart+fracPart+strings.Repeat("0", decimals-len(fracPart)), "0")
+	if len(digits) > len(strconv.FormatInt(MaxMoney.Int64(), 10))+decimals-int(unit) {
+		return 0, ErrAmountOutOfRange
+	}
+
+	var value int64
+	if digits != "" {
+		v, err := strconv.ParseInt(digits, 10, 64)
+		if err != nil {
+			return 0, ErrAmountOutOfRange
+		}
+		value = v
+	}
+
+	// amounts in units smaller than satoshis must be whole satoshis
+	if unit < 0 {
+		d := pow10(-int(unit))
+		if value%d != 0 {
+			return 0, ErrInvalidAmount
+		}
+		value /= d
+	}
+
+	if negative {
+		value = -value
+	}
+
+	satoshi := Satoshi(value)
+	if !satoshi.IsValid() {
+		return 0, ErrAmountOutOfRange
+	}
+
+	return satoshi, nil
+}
+
+// shiftDecimalPoint moves the decimal point between intPart and fracPart by exp places to the right.
+func shiftDecimalPoint(intPart, fracPart string, exp int) (string, string, error) {
+	digits := strings.TrimRight(intPart+fracPart, "0")
+	point := len(intPart) + exp
+
+	trimmed := strings.TrimLeft(digits, "0")
+	point -= len(digits) - len(trimmed)
+	digits = trimmed
+
+	if digits == "" {
+		return "0", "", nil
+	}
+
+	// no amount in any unit has its digits this far from the decimal point
+	if point > 40 {
+		return "", "", ErrAmountOutOfRange
+	}
+	if point-len(digits) < -40 {
+		return "", "", ErrInvalidAmount
+	}
+
+	switch {
+	case point <= 0:
+		return "", strings.Repeat("0", -point) + digits, nil
+	case point >= len(digits):
+		return digits + strings.Repeat("0", point-len(digits)), "", nil
+	default:
+		return digits[:point], digits[point:], nil
+	}
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func pow10(n int) int64 {
+	v := int64(1)
+	for i := 0; i < n; i++ {
+		v *= 10
+	}
+
+	return v
+}
+
+// String returns the amount in BTC with 8 decimal places as Bitcoin Core does, e.g. "0.29000000".
+// Note that %v and %s thus print BTC, while %d and JSON still give the integer number of satoshis.
+func (satoshi Satoshi) String() string {
+	return satoshi.Format(AmountUnitBtc)
+}
+
+// Format returns the amount in unit with all the decimal places the unit has.
+func (satoshi Satoshi) Format(unit AmountUnit) string {
+	sign := ""
+	value := satoshi.Int64()
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+
+	if unit <= 0 {
+		return sign + strconv.FormatInt(value, 10) + strings.Repeat("0", -int(unit))
+	}
+
+	d := pow10(int(unit))
+	frac := strconv.FormatInt(value%d, 10)
+
+	return sign + strconv.FormatInt(value/d, 10) + "." + strings.Repeat("0", int(unit)-len(frac)) + frac
+}
+
+// BtcAmount is an amount in satoshis encoded in JSON as a number of BTC with 8 decimal places
+// as Bitcoin Core does, e.g. 0.29000000, while Satoshi is encoded as an integer.
+type BtcAmount Satoshi
+
+func (amount BtcAmount) Satoshi() Satoshi {
+	return Satoshi(amount)
+}
+
+func (amount BtcAmount) String() string {
+	return Satoshi(amount).String()
+}
+
+func (amount BtcAmount) MarshalJSON() ([]byte, error) {
+	if !Satoshi(amount).IsValid() {
+		return nil, ErrAmountOutOfRange
+	}
+
+	return []byte(amount.String()), nil
+}
+
+// UnmarshalJSON parses the number exactly, including ones with an exponent, e.g. 1e-8,
+// rejecting ones with more than 8 decimal places.
+func (amount *BtcAmount) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+
+	satoshi, err := ParseAmount(string(b))
+	if err != nil {
+		return err
+	}
+
+	*amount = BtcAmount(satoshi)
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package btc
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBtcSatoshi(t *testing.T) {
+	assert.Equal(t, Btc(0.29).Satoshi(), Satoshi(29000000))
+	assert.Equal(t, Btc(0.00000001).Satoshi(), Satoshi(1))
+	assert.Equal(t, Btc(21000000).Satoshi(), MaxMoney)
+	assert.Equal(t, Satoshi(29000000).Btc(), Btc(0.29))
+}
+
+func TestParseAmount(t *testing.T) {
+	testCases := []struct {
+		s       string
+		unit    AmountUnit
+		satoshi Satoshi
+	}{
+		{"0.29", AmountUnitBtc, 29000000},
+		{"0.29000000", AmountUnitBtc, 29000000},
+		{"0.2900000000", AmountUnitBtc, 29000000},
+		{"1", AmountUnitBtc, 100000000},
+		{"1.", AmountUnitBtc, 100000000},
+		{".00000001", AmountUnitBtc, 1},
+		{"-0", AmountUnitBtc, 0},
+		{"21000000", AmountUnitBtc, MaxMoney},
+		{"0.5", AmountUnitMilliBtc, 50000},
+		{"1.23", AmountUnitBits, 123},
+		{"546", AmountUnitSatoshi, 546},
+		{"546000", AmountUnitMilliSatoshi, 546},
+		{"2100000000000000000", AmountUnitMilliSatoshi, MaxMoney},
+		{"1e-8", AmountUnitBtc, 1},
+		{"1E-8", AmountUnitBtc, 1},
+		{"2.9e-1", AmountUnitBtc, 29000000},
+		{"0.0029e+2", AmountUnitBtc, 29000000},
+		{"2.1e7", AmountUnitBtc, MaxMoney},
+		{"12.5e-7", AmountUnitBtc, 125},
+		{"5.46e2", AmountUnitSatoshi, 546},
+		{"0e-9999", AmountUnitBtc, 0},
+		{"-0e5", AmountUnitBtc, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.s+" "+tc.unit.String(), func(t *testing.T) {
+			satoshi, err := ParseAmountWithUnit(tc.s, tc.unit)
+			require.NoError(t, err)
+			assert.Equal(t, satoshi, tc.satoshi)
+		})
+	}
+
+	satoshi, err := ParseAmount("0.29")
+	require.NoError(t, err)
+	assert.Equal(t, satoshi, Satoshi(29000000))
+}
+
+func TestParseAmountErrors(t *testing.T) {
+	testCases := []struct {
+		s    string
+		unit AmountUnit
+		err  error
+	}{
+		{"", AmountUnitBtc, ErrInvalidAmount},
+		{".", AmountUnitBtc, ErrInvalidAmount},
+		{"-", AmountUnitBtc, ErrInvalidAmount},
+		{"+1", AmountUnitBtc, ErrInvalidAmount},
+		{"1e", AmountUnitBtc, ErrInvalidAmount},
+		{"e-8", AmountUnitBtc, ErrInvalidAmount},
+		{"1e-8.0", AmountUnitBtc, ErrInvalidAmount},
+		{"1e99999", AmountUnitBtc, ErrInvalidAmount},
+		{"1e-9", AmountUnitBtc, ErrInvalidAmount},
+		{"1e-100", AmountUnitBtc, ErrInvalidAmount},
+		{"2.1e7000", AmountUnitBtc, ErrAmountOutOfRange},
+		{"2.100000001e7", AmountUnitBtc, ErrAmountOutOfRange},
+		{"1.2.3", AmountUnitBtc, ErrInvalidAmount},
+		{" 1", AmountUnitBtc, ErrInvalidAmount},
+		{"0.000000001", AmountUnitBtc, ErrInvalidAmount},
+		{"0.5", AmountUnitSatoshi, ErrInvalidAmount},
+		{"1500", AmountUnitMilliSatoshi, ErrInvalidAmount},
+		{"-0.00000001", AmountUnitBtc, ErrAmountOutOfRange},
+		{"21000000.00000001", AmountUnitBtc, ErrAmountOutOfRange},
+		{"99999999999999999999", AmountUnitBtc, ErrAmountOutOfRange},
+		{"9999999999999999999", AmountUnitMilliSatoshi, ErrAmountOutOfRange},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.s+" "+tc.unit.String(), func(t *testing.T) {
+			_, err := ParseAmountWithUnit(tc.s, tc.unit)
+			assert.Equal(t, err, tc.err)
+		})
+	}
+}
+
+func TestParseAmountUnit(t *testing.T) {
+	for _, unit := range []AmountUnit{AmountUnitBtc, AmountUnitMilliBtc, AmountUnitMicroBtc, AmountUnitSatoshi, AmountUnitMilliSatoshi} {
+		parsed, err := ParseAmountUnit(unit.String())
+		require.NoError(t, err)
+		assert.Equal(t, parsed, unit)
+	}
+
+	unit, err := ParseAmountUnit("bits")
+	require.NoError(t, err)
+	assert.Equal(t, unit, AmountUnitBits)
+
+	_, err = ParseAmountUnit("btc")
+	assert.Equal(t, err, ErrInvalidAmountUnit)
+}
+
+func TestSatoshiFormat(t *testing.T) {
+	satoshi := Satoshi(29000001)
+
+	assert.Equal(t, satoshi.String(), "0.29000001")
+	assert.Equal(t, satoshi.Format(AmountUnitBtc), "0.29000001")
+	assert.Equal(t, satoshi.Format(AmountUnitMilliBtc), "290.00001")
+	assert.Equal(t, satoshi.Format(AmountUnitBits), "290000.01")
+	assert.Equal(t, satoshi.Format(AmountUnitSatoshi), "29000001")
+	assert.Equal(t, satoshi.Format(AmountUnitMilliSatoshi), "29000001000")
+	assert.Equal(t, Satoshi(0).String(), "0.00000000")
+	assert.Equal(t, Satoshi(-1).String(), "-0.00000001")
+	assert.Equal(t, MaxMoney.String(), "21000000.00000000")
+
+	for _, unit := range []AmountUnit{AmountUnitBtc, AmountUnitMilliBtc, AmountUnitBits, AmountUnitSatoshi, AmountUnitMilliSatoshi} {
+		parsed, err := ParseAmountWithUnit(satoshi.Format(unit), unit)
+		require.NoError(t, err)
+		assert.Equal(t, parsed, satoshi)
+	}
+}
+
+func TestAmountJSON(t *testing.T) {
+	type amounts struct {
+		Satoshi Satoshi   `json:"satoshi"`
+		Btc     BtcAmount `json:"btc"`
+	}
+
+	b, err := json.Marshal(&amounts{29000000, 29000000})
+	require.NoError(t, err)
+	assert.Equal(t, string(b), `{"satoshi":29000000,"btc":0.29000000}`)
+
+	var decoded amounts
+	require.NoError(t, json.Unmarshal([]byte(`{"satoshi":29000000,"btc":0.29}`), &decoded))
+	assert.Equal(t, decoded, amounts{29000000, 29000000})
+	require.NoError(t, json.Unmarshal([]byte(`{"satoshi":29000000,"btc":2.9e-1}`), &decoded))
+	assert.Equal(t, decoded, amounts{29000000, 29000000})
+
+	assert.Equal(t, json.Unmarshal([]byte(`{"btc":0.000000001}`), &decoded), ErrInvalidAmount)
+	assert.Equal(t, json.Unmarshal([]byte(`{"btc":21000001}`), &decoded), ErrAmountOutOfRange)
+
+	_, err = json.Marshal(BtcAmount(MaxMoney + 1))
+	assert.Error(t, err)
+}
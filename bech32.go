@@ -0,0 +1,251 @@
+package btc
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	Bech32 Bech32Encoding = iota + 1
+	Bech32m
+
+	bech32Charset        = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	bech32Separator      = '1'
+	bech32MaxLength      = 90
+	bech32ChecksumLength = 6
+
+	bech32Const  uint32 = 1
+	bech32mConst uint32 = 0x2bc830a3
+)
+
+var (
+	ErrBech32InvalidLength    = errors.New("invalid bech32 length")
+	ErrBech32InvalidChar      = errors.New("invalid bech32 character")
+	ErrBech32MixedCase        = errors.New("mixed case bech32 string")
+	ErrBech32InvalidSeparator = errors.New("invalid bech32 separator position")
+	ErrBech32InvalidChecksum  = errors.New("invalid bech32 checksum")
+	ErrBech32InvalidPadding   = errors.New("invalid bech32 padding")
+	ErrBech32InvalidHrp       = errors.New("invalid bech32 hrp")
+)
+
+// Bech32Encoding is the checksum variant of a bech32 string.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0173.mediawiki
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0350.mediawiki
+type Bech32Encoding int
+
+func (encoding Bech32Encoding) checksumConst() uint32 {
+	if encoding == Bech32m {
+		return bech32mConst
+	}
+
+	return bech32Const
+}
+
+func bech32Polymod(values []byte) uint32 {
+	gen := []uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+
+	return chk
+}
+
+func bech32HrpExpand(hrp string) []byte {
+	b := make([]byte, 0, 2*len(hrp)+1)
+	for i := 0; i < len(hrp); i++ {
+		b = append(b, hrp[i]>>5)
+	}
+	b = append(b, 0)
+	for i := 0; i < len(hrp); i++ {
+		b = append(b, hrp[i]&0x1f)
+	}
+
+	return b
+}
+
+func bech32Checksum(hrp string, data []byte, encoding Bech32Encoding) []byte {
+	values := concatBytes(bech32HrpExpand(hrp), data, make([]byte, bech32ChecksumLength))
+	mod := bech32Polymod(values) ^ encoding.checksumConst()
+
+	checksum := make([]byte, bech32ChecksumLength)
+	for i := range checksum {
+		checksum[i] = byte(mod>>uint(5*(5-i))) & 0x1f
+	}
+
+	return checksum
+}
+
+// bech32VerifyChecksum returns the encoding whose checksum data carries, or 0.
+func bech32VerifyChecksum(hrp string, data []byte) Bech32Encoding {
+	switch bech32Polymod(concatBytes(bech32HrpExpand(hrp), data)) {
+	case bech32Const:
+		return Bech32
+	case bech32mConst:
+		return Bech32m
+	default:
+		return 0
+	}
+}
+
+// Bech32Encode encodes 5-bit data with a checksum of the given encoding.
+func Bech32Encode(hrp string, data []byte, encoding Bech32Encoding) (string, error) {
+	if len(hrp) == 0 || len(hrp)+len(data)+1+bech32ChecksumLength > bech32MaxLength {
+		return "", ErrBech32InvalidLength
+	}
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return "", ErrBech32InvalidChar
+		}
+	}
+	hrp = strings.ToLower(hrp)
+
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteByte(bech32Separator)
+	for _, v := range concatBytes(data, bech32Checksum(hrp, data, encoding)) {
+		if v > 0x1f {
+			return "", ErrBech32InvalidChar
+		}
+		sb.WriteByte(bech32Charset[v])
+	}
+
+	return sb.String(), nil
+}
+
+// Bech32Decode returns the lowercase hrp, the 5-bit data without the checksum
+// and the checksum encoding of s.
+func Bech32Decode(s string) (string, []byte, Bech32Encoding, error) {
+	if len(s) > bech32MaxLength {
+		return "", nil, 0, ErrBech32InvalidLength
+	}
+
+	var hasLower, hasUpper bool
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 33 || c > 126 {
+			return "", nil, 0, ErrBech32InvalidChar
+		}
+		if 'a' <= c && c <= 'z' {
+			hasLower = true
+		}
+		if 'A' <= c && c <= 'Z' {
+			hasUpper = true
+		}
+	}
+	if hasLower && hasUpper {
+		return "", nil, 0, ErrBech32MixedCase
+	}
+	s = strings.ToLower(s)
+
+	pos := strings.LastIndexByte(s, bech32Separator)
+	if pos < 1 || pos+bech32ChecksumLength+1 > len(s) {
+		return "", nil, 0, ErrBech32InvalidSeparator
+	}
+
+	hrp := s[:pos]
+	data := make([]byte, len(s)-pos-1)
+	for i := range data {
+		v := strings.IndexByte(bech32Charset, s[pos+1+i])
+		if v < 0 {
+			return "", nil, 0, ErrBech32InvalidChar
+		}
+		data[i] = byte(v)
+	}
+
+	encoding := bech32VerifyChecksum(hrp, data)
+	if encoding == 0 {
+		return "", nil, 0, ErrBech32InvalidChecksum
+	}
+
+	return hrp, data[:len(data)-bech32ChecksumLength], encoding, nil
+}
+
+// LocateBech32Errors returns the likely positions of errors in the data part of s:
+// characters outside the bech32 charset, or otherwise the single character
+// whose substitution yields a valid checksum. It returns nil if no error can be located.
+func LocateBech32Errors(s string) []int {
+	if len(s) > bech32MaxLength {
+		return nil
+	}
+
+	lower := strings.ToLower(s)
+
+	pos := strings.LastIndexByte(lower, bech32Separator)
+	if pos < 1 || pos+bech32ChecksumLength+1 > len(lower) {
+		return nil
+	}
+
+	hrp := lower[:pos]
+	data := make([]byte, len(lower)-pos-1)
+
+	var positions []int
+	for i := range data {
+		v := strings.IndexByte(bech32Charset, lower[pos+1+i])
+		if v < 0 {
+			positions = append(positions, pos+1+i)
+			continue
+		}
+		data[i] = byte(v)
+	}
+	if positions != nil {
+		return positions
+	}
+
+	for i := range data {
+		orig := data[i]
+		for v := byte(0); v < 32; v++ {
+			if v == orig {
+				continue
+			}
+
+			data[i] = v
+			if bech32VerifyChecksum(hrp, data) != 0 {
+				positions = append(positions, pos+1+i)
+				break
+			}
+		}
+		data[i] = orig
+	}
+
+	return positions
+}
+
+// convertBits regroups data from fromBits-bit to toBits-bit groups.
+func convertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte, error) {
+	var acc uint32
+	var bits uint
+
+	maxV := uint32(1)<<toBits - 1
+
+	out := make([]byte, 0, len(data)*int(fromBits)/int(toBits)+1)
+	for _, v := range data {
+		if uint32(v)>>fromBits != 0 {
+			return nil, ErrBech32InvalidChar
+		}
+
+		acc = acc<<fromBits | uint32(v)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			out = append(out, byte(acc>>bits&maxV))
+		}
+	}
+
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(toBits-bits)&maxV))
+		}
+	} else if bits >= fromBits || acc<<(toBits-bits)&maxV != 0 {
+		return nil, ErrBech32InvalidPadding
+	}
+
+	return out, nil
+}
@@ -0,0 +1,262 @@
+package btc
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0173.mediawiki#test-vectors
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0350.mediawiki#test-vectors
+func TestBech32(t *testing.T) {
+	testCases := []struct {
+		s        string
+		encoding Bech32Encoding
+	}{
+		{"A12UEL5L", Bech32},
+		{"a12uel5l", Bech32},
+		{"an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs", Bech32},
+		{"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw", Bech32},
+		{"11qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqc8247j", Bech32},
+		{"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w", Bech32},
+		{"?1ezyfcl", Bech32},
+		{"A1LQFN3A", Bech32m},
+		{"a1lqfn3a", Bech32m},
+		{"an83characterlonghumanreadablepartthatcontainsthetheexcludedcharactersbioandnumber11sg7hg6", Bech32m},
+		{"abcdef1l7aum6echk45nj3s0wdvt2fg8x9yrzpqzd3ryx", Bech32m},
+		{"11llllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllludsr8", Bech32m},
+		{"split1checkupstagehandshakeupstreamerranterredcaperredlc445v", Bech32m},
+		{"?1v759aa", Bech32m},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.s, func(t *testing.T) {
+			hrp, data, encoding, err := Bech32Decode(tc.s)
+			require.NoError(t, err)
+			assert.Equal(t, encoding, tc.encoding)
+
+			s, err := Bech32Encode(hrp, data, encoding)
+			require.NoError(t, err)
+			assert.Equal(t, s, strings.ToLower(tc.s))
+		})
+	}
+}
+
+func TestBech32DecodeErrors(t *testing.T) {
+	testCases := []struct {
+		s   string
+		err error
+	}{
+		{"\x201nwldj5", ErrBech32InvalidChar},
+		{"\x7f1axkwrx", ErrBech32InvalidChar},
+		{"\x801eym55h", ErrBech32InvalidChar},
+		{"an84characterslonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1569pvx", ErrBech32InvalidLength},
+		{"pzry9x0s0muk", ErrBech32InvalidSeparator},
+		{"1pzry9x0s0muk", ErrBech32InvalidSeparator},
+		{"x1b4n0q5v", ErrBech32InvalidChar},
+		{"li1dgmt3", ErrBech32InvalidSeparator},
+		{"de1lg7wt\xff", ErrBech32InvalidChar},
+		{"A1G7SGD8", ErrBech32InvalidChecksum},
+		{"10a06t8", ErrBech32InvalidSeparator},
+		{"1qzzfhee", ErrBech32InvalidSeparator},
+		{"a12UEL5L", ErrBech32MixedCase},
+		{"split1checkupstagehandshakeupstreamerranterredcaperred2y9e2w", ErrBech32InvalidChecksum},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.s, func(t *testing.T) {
+			_, _, _, err := Bech32Decode(tc.s)
+			assert.Equal(t, err, tc.err)
+		})
+	}
+}
+
+func TestLocateBech32Errors(t *testing.T) {
+	testCases := []struct {
+		s         string
+		positions []int
+	}{
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", nil},
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5", []int{41}},
+		{"bc1qw508d6qejxtdg4y5r3zarvbry0c5xw7kv8f3t4", []int{26}},
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3b4", []int{40}},
+		{"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T5", []int{41}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.s, func(t *testing.T) {
+			assert.Equal(t, LocateBech32Errors(tc.s), tc.positions)
+		})
+	}
+}
+
+func TestWitnessAddress(t *testing.T) {
+	testCases := []struct {
+		address Address
+		params  *Params
+		script  string
+	}{
+		{
+			"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4",
+			MainNetParams,
+			"0014751e76e8199196d454941c45d1b3a323f1433bd6",
+		},
+		{
+			"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7",
+			TestNet3Params,
+			"00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262",
+		},
+		{
+			"bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y",
+			MainNetParams,
+			"5128751e76e8199196d454941c45d1b3a323f1433bd6751e76e8199196d454941c45d1b3a323f1433bd6",
+		},
+		{
+			"BC1SW50QGDZ25J",
+			MainNetParams,
+			"6002751e",
+		},
+		{
+			"bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs",
+			MainNetParams,
+			"5210751e76e8199196d454941c45d1b3a323",
+		},
+		{
+			"tb1qqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesrxh6hy",
+			TestNet3Params,
+			"0020000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433",
+		},
+		{
+			"tb1pqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesf3hn0c",
+			TestNet3Params,
+			"5120000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433",
+		},
+		{
+			"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0",
+			MainNetParams,
+			"512079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.address.String(), func(t *testing.T) {
+			assert.True(t, tc.address.IsValid(tc.params))
+
+			// address -> witness program
+			version, program, err := tc.address.WitnessProgram(tc.params)
+			require.NoError(t, err)
+
+			script, err := NewWitnessProgramScript(version, program)
+			require.NoError(t, err)
+			assert.Equal(t, script.Hex, tc.script)
+
+			// witness program -> address
+			address, err := NewWitnessAddress(version, program, tc.params)
+			require.NoError(t, err)
+			assert.Equal(t, address.String(), strings.ToLower(tc.address.String()))
+		})
+	}
+}
+
+func TestWitnessAddressErrors(t *testing.T) {
+	testCases := []struct {
+		address Address
+		params  *Params
+		err     error
+	}{
+		{
+			"tc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq5zuyut",
+			MainNetParams,
+			ErrBech32InvalidHrp,
+		},
+		{
+			"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd",
+			MainNetParams,
+			ErrBech32InvalidChecksum,
+		},
+		{
+			"tb1z0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqglt7rf",
+			TestNet3Params,
+			ErrBech32InvalidChecksum,
+		},
+		{
+			"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh",
+			MainNetParams,
+			ErrBech32InvalidChecksum,
+		},
+		{
+			"tb1q0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq24jc47",
+			TestNet3Params,
+			ErrBech32InvalidChecksum,
+		},
+		{
+			"bc1p38j9r5y49hruaue7wxjce0updqjuyyx0kh56v8s25huc6995vvpql3jow4",
+			MainNetParams,
+			ErrBech32InvalidChar,
+		},
+		{
+			"BC130XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ7ZWS8R",
+			MainNetParams,
+			ErrInvalidWitnessVersion,
+		},
+		{
+			"bc1pw5dgrnzv",
+			MainNetParams,
+			ErrInvalidWitnessProgram,
+		},
+		{
+			"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7v8n0nx0muaewav253zgeav",
+			MainNetParams,
+			ErrInvalidWitnessProgram,
+		},
+		{
+			"BC1QR508D6QEJXTDG4Y5R3ZARVARYV98GJ9P",
+			MainNetParams,
+			ErrInvalidWitnessProgram,
+		},
+		{
+			"tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq47Zagq",
+			TestNet3Params,
+			ErrBech32MixedCase,
+		},
+		{
+			"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7v07qwwzcrf",
+			MainNetParams,
+			ErrBech32InvalidPadding,
+		},
+		{
+			"tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vpggkg4j",
+			TestNet3Params,
+			ErrBech32InvalidPadding,
+		},
+		{
+			"bc1gmk9yu",
+			MainNetParams,
+			ErrInvalidWitnessProgram,
+		},
+		{
+			"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+			TestNet3Params,
+			ErrBech32InvalidHrp,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.address.String(), func(t *testing.T) {
+			_, _, err := tc.address.WitnessProgram(tc.params)
+			assert.Equal(t, err, tc.err)
+		})
+	}
+}
+
+func TestPkhWitnessAddress(t *testing.T) {
+	b, err := hex.DecodeString("751e76e8199196d454941c45d1b3a323f1433bd6")
+	require.NoError(t, err)
+
+	address, err := Pkh(b).WitnessAddress(MainNetParams)
+	require.NoError(t, err)
+	assert.Equal(t, address.String(), "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4")
+}
@@ -0,0 +1,191 @@
+package btc
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	MnemonicEntropyBitSizeMin = 128
+	MnemonicEntropyBitSizeMax = 256
+
+	mnemonicEntropyBitSizeStep = 32
+	mnemonicWordBitSize        = 11
+	mnemonicSeedIterations     = 2048
+	mnemonicSeedLength         = 64
+	mnemonicSaltPrefix         = "mnemonic"
+	wordlistSize               = 1 << mnemonicWordBitSize
+)
+
+var (
+	ErrInvalidEntropyLength    = errors.New("invalid entropy length")
+	ErrInvalidMnemonicLength   = errors.New("invalid mnemonic length")
+	ErrInvalidMnemonicWord     = errors.New("invalid mnemonic word")
+	ErrInvalidMnemonicChecksum = errors.New("invalid mnemonic checksum")
+)
+
+// Wordlist is a BIP39 wordlist of 2048 NFKD normalized words.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0039/bip-0039-wordlists.md
+type Wordlist struct {
+	words     []string
+	indexes   map[string]int
+	separator string
+}
+
+func newWordlist(s string, separator string) *Wordlist {
+	words := strings.Split(strings.TrimSpace(s), "\n")
+	if len(words) != wordlistSize {
+		panic("invalid wordlist size")
+	}
+
+	indexes := make(map[string]int, len(words))
+	for i, word := range words {
+		indexes[word] = i
+	}
+
+	return &Wordlist{
+		words:     words,
+		indexes:   indexes,
+		separator: separator,
+	}
+}
+
+func (wordlist *Wordlist) Word(index int) string {
+	return wordlist.words[index]
+}
+
+// Index returns the index of word, which is NFKD normalized before the lookup.
+func (wordlist *Wordlist) Index(word string) (int, bool) {
+	index, ok := wordlist.indexes[norm.NFKD.String(word)]
+	return index, ok
+}
+
+// NewEntropy returns random entropy of bitSize bits for a new mnemonic.
+func NewEntropy(bitSize int) ([]byte, error) {
+	if err := validateEntropyBitSize(bitSize); err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, bitSize/8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func validateEntropyBitSize(bitSize int) error {
+	if bitSize < MnemonicEntropyBitSizeMin || bitSize > MnemonicEntropyBitSizeMax || bitSize%mnemonicEntropyBitSizeStep != 0 {
+		return ErrInvalidEntropyLength
+	}
+
+	return nil
+}
+
+// Mnemonic is a BIP39 mnemonic sentence.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0039.mediawiki
+type Mnemonic string
+
+// NewMnemonic encodes entropy with its checksum into words of wordlist.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0039.mediawiki#generating-the-mnemonic
+func NewMnemonic(entropy []byte, wordlist *Wordlist) (Mnemonic, error) {
+	entBitSize := len(entropy) * 8
+	if err := validateEntropyBitSize(entBitSize); err != nil {
+		return "", err
+	}
+
+	h, err := Sha256(entropy)
+	if err != nil {
+		return "", err
+	}
+
+	b := concatBytes(entropy, h)
+	n := (entBitSize + entBitSize/32) / mnemonicWordBitSize
+
+	words := make([]string, n)
+	for i := range words {
+		index := 0
+		for j := 0; j < mnemonicWordBitSize; j++ {
+			index = index<<1 | bitAt(b, i*mnemonicWordBitSize+j)
+		}
+		words[i] = wordlist.Word(index)
+	}
+
+	return Mnemonic(strings.Join(words, wordlist.separator)), nil
+}
+
+func (mnemonic Mnemonic) String() string {
+	return string(mnemonic)
+}
+
+// Entropy decodes mnemonic with wordlist and validates its checksum.
+func (mnemonic Mnemonic) Entropy(wordlist *Wordlist) ([]byte, error) {
+	words := strings.Fields(mnemonic.String())
+
+	bitSize := len(words) * mnemonicWordBitSize
+	entBitSize := bitSize * 32 / 33
+	if bitSize%33 != 0 || validateEntropyBitSize(entBitSize) != nil {
+		return nil, ErrInvalidMnemonicLength
+	}
+
+	b := make([]byte, (bitSize+7)/8)
+	for i, word := range words {
+		index, ok := wordlist.Index(word)
+		if !ok {
+			return nil, ErrInvalidMnemonicWord
+		}
+		for j := 0; j < mnemonicWordBitSize; j++ {
+			if index>>uint(mnemonicWordBitSize-1-j)&1 == 1 {
+				pos := i*mnemonicWordBitSize + j
+				b[pos/8] |= 0x80 >> uint(pos%8)
+			}
+		}
+	}
+
+	entropy := b[:entBitSize/8]
+
+	h, err := Sha256(entropy)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < entBitSize/32; i++ {
+		if bitAt(h, i) != bitAt(b, entBitSize+i) {
+			return nil, ErrInvalidMnemonicChecksum
+		}
+	}
+
+	return entropy, nil
+}
+
+func (mnemonic Mnemonic) IsValid(wordlist *Wordlist) bool {
+	_, err := mnemonic.Entropy(wordlist)
+	return err == nil
+}
+
+// Seed derives the 64-byte seed with PBKDF2-HMAC-SHA512 from the NFKD normalized mnemonic and passphrase.
+// The mnemonic is not validated; see MasterExtendedKey.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0039.mediawiki#from-mnemonic-to-seed
+func (mnemonic Mnemonic) Seed(passphrase string) []byte {
+	password := norm.NFKD.String(mnemonic.String())
+	salt := norm.NFKD.String(mnemonicSaltPrefix + passphrase)
+
+	return pbkdf2.Key([]byte(password), []byte(salt), mnemonicSeedIterations, mnemonicSeedLength, sha512.New)
+}
+
+// MasterExtendedKey validates mnemonic with wordlist and returns the BIP32 master key of its seed.
+func (mnemonic Mnemonic) MasterExtendedKey(passphrase string, wordlist *Wordlist, params *Params) (*ExtendedKey, error) {
+	if _, err := mnemonic.Entropy(wordlist); err != nil {
+		return nil, err
+	}
+
+	return NewMasterExtendedKey(mnemonic.Seed(passphrase), params)
+}
+
+func bitAt(b []byte, i int) int {
+	return int(b[i/8]>>uint(7-i%8)) & 1
+}
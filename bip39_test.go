@@ -0,0 +1,202 @@
+package btc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestMnemonic(t *testing.T) {
+	testCases := []struct {
+		entropy    string
+		wordlist   *Wordlist
+		mnemonic   string
+		passphrase string
+		seed       string
+	}{
+		{
+			// ref. https://github.com/trezor/python-mnemonic/blob/master/vectors.json
+			"00000000000000000000000000000000",
+			WordlistEnglish,
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			"TREZOR",
+			"c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		},
+		{
+			"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			WordlistEnglish,
+			"legal winner thank year wave sausage worth useful legal winner thank yellow",
+			"TREZOR",
+			"2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+		},
+		{
+			"ffffffffffffffffffffffffffffffff",
+			WordlistEnglish,
+			"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+			"TREZOR",
+			"ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069",
+		},
+		{
+			"808080808080808080808080808080808080808080808080",
+			WordlistEnglish,
+			"letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic avoid letter always",
+			"TREZOR",
+			"107d7c02a5aa6f38c58083ff74f04c607c2d2c0ecc55501dadd72d025b751bc27fe913ffb796f841c49b1d33b610cf0e91d3aa239027f5e99fe4ce9e5088cd65",
+		},
+		{
+			"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			WordlistEnglish,
+			"legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth title",
+			"TREZOR",
+			"bc09fca1804f7e69da93c2f2028eb238c227f2e9dda30cd63699232578480a4021b146ad717fbb7e451ce9eb835f43620bf5c514db0f8add49f5d121449d3e87",
+		},
+		{
+			"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			WordlistJapanese,
+			"そつう　れきだい　ほんやく　わかす　りくつ　ばいか　ろせん　やちん　そつう　れきだい　ほんやく　わかめ",
+			"㍍ガバヴァぱばぐゞちぢ十人十色",
+			"aee025cbe6ca256862f889e48110a6a382365142f7d16f2b9545285b3af64e542143a577e9c144e101a6bdca18f8d97ec3366ebf5b088b1c1af9bc31346e60d9",
+		},
+		{
+			"8080808080808080808080808080808080808080",
+			WordlistSpanish,
+			"lino admitir bolero abrir álbum dejar acelga aprender lino admitir bolero abrir álbum dejar acabar",
+			"contraseña",
+			"27114ab38b4ae939c91ed345c39d798c8609e929ad19f7b4d91d31adcd7436a8a6be48e4485ff9b32ff85b56a717208cfc62bf2932102586b4c99a4bca925834",
+		},
+		{
+			"00000000000000000000000000000000",
+			WordlistFrench,
+			"abaisser abaisser abaisser abaisser abaisser abaisser abaisser abaisser abaisser abaisser abaisser abeille",
+			"",
+			"01051dcfa9e1695ca99d1bd2a3304d073c00d275ad5a389d0579a13f4f8e90e512f0c5839764179926bf51961d84c3bf3e20404298065998c60e43e4ec7985b6",
+		},
+		{
+			"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+			WordlistKorean,
+			"힘껏 힘껏 힘껏 힘껏 힘껏 힘껏 힘껏 힘껏 힘껏 힘껏 힘껏 힘껏 힘껏 힘껏 힘껏 힘껏 힘껏 힘껏 힘껏 힘껏 힘껏 힘껏 힘껏 허용",
+			"",
+			"5421e2f8b1889b2f4edd8d9fc14ba6d82908dce4b869d216fe411abfc0c270e789681a1815213aa1963f9d1d2fecd000800af0b21620bd986c36e1f7090fb50b",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.mnemonic, func(t *testing.T) {
+			entropy, err := hex.DecodeString(tc.entropy)
+			require.NoError(t, err)
+
+			// entropy -> mnemonic
+			mnemonic, err := NewMnemonic(entropy, tc.wordlist)
+			require.NoError(t, err)
+			assert.Equal(t, norm.NFC.String(mnemonic.String()), norm.NFC.String(tc.mnemonic))
+
+			// mnemonic -> entropy, accepting composed input as well
+			for _, m := range []Mnemonic{mnemonic, Mnemonic(norm.NFC.String(tc.mnemonic))} {
+				b, err := m.Entropy(tc.wordlist)
+				require.NoError(t, err)
+				assert.Equal(t, hex.EncodeToString(b), tc.entropy)
+
+				assert.Equal(t, hex.EncodeToString(m.Seed(tc.passphrase)), tc.seed)
+			}
+		})
+	}
+}
+
+func TestMnemonicMasterExtendedKey(t *testing.T) {
+	mnemonic := Mnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about")
+
+	key, err := mnemonic.MasterExtendedKey("TREZOR", WordlistEnglish, MainNetParams)
+	require.NoError(t, err)
+
+	s, err := key.Serialize()
+	require.NoError(t, err)
+	assert.Equal(t, s, "xprv9s21ZrQH143K3h3fDYiay8mocZ3afhfULfb5GX8kCBdno77K4HiA15Tg23wpbeF1pLfs1c5SPmYHrEpTuuRhxMwvKDwqdKiGJS9XFKzUsAF")
+
+	// the seed of TestHdAccount
+	assert.Equal(t, hex.EncodeToString(mnemonic.Seed("")), testHdSeed)
+}
+
+func TestMnemonicErrors(t *testing.T) {
+	testCases := []struct {
+		mnemonic Mnemonic
+		err      error
+	}{
+		{
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+			ErrInvalidMnemonicChecksum,
+		},
+		{
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+			ErrInvalidMnemonicLength,
+		},
+		{
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abou",
+			ErrInvalidMnemonicWord,
+		},
+		{
+			"",
+			ErrInvalidMnemonicLength,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.mnemonic.String(), func(t *testing.T) {
+			_, err := tc.mnemonic.Entropy(WordlistEnglish)
+			assert.Equal(t, err, tc.err)
+			assert.False(t, tc.mnemonic.IsValid(WordlistEnglish))
+		})
+	}
+
+	_, err := NewMnemonic(make([]byte, 15), WordlistEnglish)
+	assert.Equal(t, err, ErrInvalidEntropyLength)
+
+	_, err = NewEntropy(129)
+	assert.Equal(t, err, ErrInvalidEntropyLength)
+
+	entropy, err := NewEntropy(MnemonicEntropyBitSizeMax)
+	require.NoError(t, err)
+	assert.Len(t, entropy, 32)
+}
+
+func TestWordlists(t *testing.T) {
+	// the sha256 of the files of the wordlists
+	// ref. https://github.com/bitcoin/bips/tree/master/bip-0039
+	testCases := []struct {
+		wordlist *Wordlist
+		checksum string
+	}{
+		{WordlistEnglish, "2f5eed53a4727b4bf8880d8f3f199efc90e58503646d9ff8eff3a2ed3b24dbda"},
+		{WordlistJapanese, "2eed0aef492291e061633d7ad8117f1a2b03eb80a29d0e4e3117ac2528d05ffd"},
+		{WordlistKorean, "9e95f86c167de88f450f0aaf89e87f6624a57f973c67b516e338e8e8b8897f60"},
+		{WordlistSpanish, "46846a5a0139d1e3cb77293e521c2865f7bcdb82c44e8d0a06a2cd0ecba48c0b"},
+		{WordlistChineseSimplified, "5c5942792bd8340cb8b27cd592f1015edf56a8c5b26276ee18a482428e7c5726"},
+		{WordlistChineseTraditional, "417b26b3d8500a4ae3d59717d7011952db6fc2fb84b807f3f94ac734e89c1b5f"},
+		{WordlistFrench, "ebc3959ab7801a1df6bac4fa7d970652f1df76b683cd2f4003c941c63d517e59"},
+		{WordlistItalian, "d392c49fdb700a24cd1fceb237c1f65dcc128f6b34a8aacb58b59384b5c648c2"},
+		{WordlistCzech, "7e80e161c3e93d9554c2efb78d4e3cebf8fc727e9c52e03b83b94406bdcc95fc"},
+		{WordlistPortuguese, "2685e9c194c82ae67e10ba59d9ea5345a23dc093e92276fc5361f6667d79cd3f"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.checksum, func(t *testing.T) {
+			wordlist := tc.wordlist
+
+			checksum := sha256.Sum256([]byte(strings.Join(wordlist.words, "\n") + "\n"))
+			assert.Equal(t, hex.EncodeToString(checksum[:]), tc.checksum)
+
+			assert.Len(t, wordlist.indexes, wordlistSize)
+			for i, word := range wordlist.words {
+				assert.Equal(t, norm.NFKD.String(word), word)
+
+				index, ok := wordlist.Index(word)
+				assert.True(t, ok)
+				assert.Equal(t, index, i)
+			}
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package btc
 
+import "math/big"
+
 func reverseBytes(b []byte) []byte {
 	l := len(b)
 
@@ -11,3 +13,29 @@ func reverseBytes(b []byte) []byte {
 
 	return rb
 }
+
+func paddedBytes(n *big.Int, size int) []byte {
+	b := n.Bytes()
+	if len(b) >= size {
+		return b
+	}
+
+	pb := make([]byte, size)
+	copy(pb[size-len(b):], b)
+
+	return pb
+}
+
+func concatBytes(bs ...[]byte) []byte {
+	var l int
+	for _, b := range bs {
+		l += len(b)
+	}
+
+	cb := make([]byte, 0, l)
+	for _, b := range bs {
+		cb = append(cb, b...)
+	}
+
+	return cb
+}
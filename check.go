@@ -0,0 +1,107 @@
+package btc
+
+const (
+	MaxBlockWeight = 4000000
+
+	MinCoinBaseScriptSigSize = 2
+	MaxCoinBaseScriptSigSize = 100
+)
+
+// RejectReason is the reason Bitcoin Core rejects a tx for.
+type RejectReason string
+
+const (
+	RejectTxInsEmpty         RejectReason = "bad-txns-vin-empty"
+	RejectTxOutsEmpty        RejectReason = "bad-txns-vout-empty"
+	RejectOversize           RejectReason = "bad-txns-oversize"
+	RejectTxOutNegative      RejectReason = "bad-txns-vout-negative"
+	RejectTxOutTooLarge      RejectReason = "bad-txns-vout-toolarge"
+	RejectTxOutTotalTooLarge RejectReason = "bad-txns-txouttotal-toolarge"
+	RejectDuplicateTxIns     RejectReason = "bad-txns-inputs-duplicate"
+	RejectCoinBaseLength     RejectReason = "bad-cb-length"
+	RejectPrevOutNull        RejectReason = "bad-txns-prevout-null"
+)
+
+// RejectError is the error of a tx rejected for Reason.
+type RejectError struct {
+	Reason RejectReason
+}
+
+func newRejectError(reason RejectReason) error {
+	return &RejectError{reason}
+}
+
+func (err *RejectError) Error() string {
+	return string(err.Reason)
+}
+
+// isNullOutPoint reports whether the tx in spends no output, which only coinbase txs do.
+func (txIn *TxIn) isNullOutPoint() bool {
+	return txIn.Txid == CoinBaseTxid && txIn.Index == CoinBaseIndex
+}
+
+// CheckTransaction checks the tx without any context as Bitcoin Core does,
+// returning a RejectError if the tx is invalid under consensus rules.
+// ref. https://github.com/bitcoin/bitcoin/blob/master/src/consensus/tx_check.cpp
+func CheckTransaction(tx *Tx) error {
+	if len(tx.TxIns) == 0 {
+		return newRejectError(RejectTxInsEmpty)
+	}
+	if len(tx.TxOuts) == 0 {
+		return newRejectError(RejectTxOutsEmpty)
+	}
+
+	b, err := tx.StrippedBytes()
+	if err != nil {
+		return err
+	}
+	if len(b)*WitnessScaleFactor > MaxBlockWeight {
+		return newRejectError(RejectOversize)
+	}
+
+	var total Satoshi
+	for _, txOut := range tx.TxOuts {
+		if txOut.Amount < 0 {
+			return newRejectError(RejectTxOutNegative)
+		}
+		if txOut.Amount > MaxMoney {
+			return newRejectError(RejectTxOutTooLarge)
+		}
+
+		total += txOut.Amount
+		if !total.IsValid() {
+			return newRejectError(RejectTxOutTotalTooLarge)
+		}
+	}
+
+	type outPoint struct {
+		txid  string
+		index uint32
+	}
+	outPoints := make(map[outPoint]struct{}, len(tx.TxIns))
+	for _, txIn := range tx.TxIns {
+		op := outPoint{txIn.Txid, txIn.Index}
+		if _, ok := outPoints[op]; ok {
+			return newRejectError(RejectDuplicateTxIns)
+		}
+		outPoints[op] = struct{}{}
+	}
+
+	if tx.IsCoinbase() {
+		b, err := tx.TxIns[0].Script.Bytes()
+		if err != nil {
+			return err
+		}
+		if len(b) < MinCoinBaseScriptSigSize || len(b) > MaxCoinBaseScriptSigSize {
+			return newRejectError(RejectCoinBaseLength)
+		}
+	} else {
+		for _, txIn := range tx.TxIns {
+			if txIn.isNullOutPoint() {
+				return newRejectError(RejectPrevOutNull)
+			}
+		}
+	}
+
+	return nil
+}
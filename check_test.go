@@ -0,0 +1,140 @@
+package btc
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCheckTestTx(t *testing.T) *Tx {
+	tx, err := NewTxFromHex("020000000001036910051cf3ce36257a1d844e28959f368a35adc9520fb9679175f6cdf8c1f1d10000000000fffffffff8e9d0c1b2a3f4e5d6c7b8a9f0e1d2c3b4a5768695a4b3c2e1a9d8b7e4b3d8e50300000000fdffffffbc0a00000000000000000000000000000000000000000000000000000000000006000000000000000002a086010000000000160014a9b8c7d6e5f4a3b2c1d0e9f8a9b8c7d6e5f4a3b2a025260000000000225120b8c7d6e5f4a3b2c1d0e9f8a9b8c7d6e5f4a3b2c1d0e9f8a9b8c7d6e5f4a3b2c10140e907831f80848d1069a5371b402410364bdf1c5f8307b0084c55f1cbe2dca821525f66a4a85ea8b71e482a74f382d2ce5ebeee8fdb2172f477df4900d310536c0002473044022065fe1ea4e94a9b44fb62c2b874b63a947504273a60b99b8f7bbf77b4db9331b002205559d8ee93cf341d75866f9eb912af05904fb6eed7372a837308c4e37f3ab58f012103bae5f04799c40862358560e42e441c3080b997a3dec161dd40395e992362bfc920a10700")
+	require.NoError(t, err)
+
+	return tx
+}
+
+func newCheckTestCoinBaseTx(scriptSigHex string) *Tx {
+	tx := NewTx()
+	tx.AddTxIn(NewTxIn(CoinBaseTxid, CoinBaseIndex, &Script{Hex: scriptSigHex}))
+	tx.AddTxOut(NewTxOut(625000000, &Script{Hex: "0014" + strings.Repeat("11", 20)}))
+
+	return tx
+}
+
+func TestCheckTransaction(t *testing.T) {
+	require.NoError(t, CheckTransaction(newCheckTestTx(t)))
+	require.NoError(t, CheckTransaction(MainNetParams.GenesisBlock.Txes[0]))
+	require.NoError(t, CheckTransaction(newCheckTestCoinBaseTx("0101")))
+	require.NoError(t, CheckTransaction(newCheckTestCoinBaseTx(strings.Repeat("00", MaxCoinBaseScriptSigSize))))
+
+	testCases := []struct {
+		name   string
+		tx     func(t *testing.T) *Tx
+		reason RejectReason
+	}{
+		{
+			"no tx ins",
+			func(t *testing.T) *Tx {
+				tx := newCheckTestTx(t)
+				tx.TxIns = nil
+				return tx
+			},
+			RejectTxInsEmpty,
+		},
+		{
+			"no tx outs",
+			func(t *testing.T) *Tx {
+				tx := newCheckTestTx(t)
+				tx.TxOuts = nil
+				return tx
+			},
+			RejectTxOutsEmpty,
+		},
+		{
+			"oversize",
+			func(t *testing.T) *Tx {
+				tx := newCheckTestTx(t)
+				tx.TxOuts[0].Script = &Script{Hex: strings.Repeat("00", MaxBlockWeight/WitnessScaleFactor)}
+				return tx
+			},
+			RejectOversize,
+		},
+		{
+			"negative tx out",
+			func(t *testing.T) *Tx {
+				tx := newCheckTestTx(t)
+				tx.TxOuts[1].Amount = -1
+				return tx
+			},
+			RejectTxOutNegative,
+		},
+		{
+			"too large tx out",
+			func(t *testing.T) *Tx {
+				tx := newCheckTestTx(t)
+				tx.TxOuts[1].Amount = MaxMoney + 1
+				return tx
+			},
+			RejectTxOutTooLarge,
+		},
+		{
+			"too large total",
+			func(t *testing.T) *Tx {
+				tx := newCheckTestTx(t)
+				tx.TxOuts[1].Amount = MaxMoney
+				return tx
+			},
+			RejectTxOutTotalTooLarge,
+		},
+		{
+			"duplicate tx ins",
+			func(t *testing.T) *Tx {
+				tx := newCheckTestTx(t)
+				tx.TxIns[2].Txid = tx.TxIns[0].Txid
+				tx.TxIns[2].Index = tx.TxIns[0].Index
+				return tx
+			},
+			RejectDuplicateTxIns,
+		},
+		{
+			"too short coinbase",
+			func(t *testing.T) *Tx {
+				return newCheckTestCoinBaseTx("01")
+			},
+			RejectCoinBaseLength,
+		},
+		{
+			"too long coinbase",
+			func(t *testing.T) *Tx {
+				return newCheckTestCoinBaseTx(strings.Repeat("00", MaxCoinBaseScriptSigSize+1))
+			},
+			RejectCoinBaseLength,
+		},
+		{
+			"null prev out",
+			func(t *testing.T) *Tx {
+				tx := newCheckTestTx(t)
+				tx.TxIns[1].Txid = CoinBaseTxid
+				tx.TxIns[1].Index = CoinBaseIndex
+				return tx
+			},
+			RejectPrevOutNull,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckTransaction(tc.tx(t))
+			assert.Equal(t, err, &RejectError{tc.reason})
+			assert.Equal(t, err.Error(), string(tc.reason))
+		})
+	}
+
+	// a coinbase tx in with other ones
+	tx := newCheckTestCoinBaseTx("0101")
+	tx.AddTxIn(NewTxIn(fmt.Sprintf("%064x", 1), 0, &Script{}))
+	assert.Equal(t, CheckTransaction(tx), &RejectError{RejectPrevOutNull})
+}
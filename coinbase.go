@@ -0,0 +1,270 @@
+package btc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+)
+
+const (
+	WitnessReservedValueLength = 32
+
+	// the size of the witness commitment output script, which is OP_RETURN, the push of 36 bytes,
+	// the header and the commitment
+	witnessCommitmentScriptSize = 38
+)
+
+var (
+	// the header of the witness commitment following OP_RETURN and the push of 36 bytes
+	// ref. https://github.com/bitcoin/bips/blob/master/bip-0141.mediawiki#commitment-structure
+	witnessCommitmentHeader = []byte{0xaa, 0x21, 0xa9, 0xed}
+)
+
+var (
+	ErrNotCoinbaseTx            = errors.New("not coinbase tx")
+	ErrInvalidCoinbaseHeight    = errors.New("invalid coinbase height")
+	ErrInvalidExtraNonce        = errors.New("invalid extra nonce")
+	ErrInvalidWitnessRootLength = errors.New("invalid witness root length")
+)
+
+// IsCoinbase reports whether the tx is a coinbase tx, whose only tx in spends no output.
+func (tx *Tx) IsCoinbase() bool {
+	return len(tx.TxIns) == 1 && tx.TxIns[0].isNullOutPoint()
+}
+
+// coinbaseHeightBytes returns the beginning of the scriptSig of coinbase txs at height,
+// which pushes the height as Bitcoin Core does.
+func coinbaseHeightBytes(height uint32) ([]byte, error) {
+	w := newWriter()
+	if height <= 16 {
+		if err := w.writeOpCode(smallIntOpCode(int(height))); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := w.writePushedData(encodeScriptNum(int64(height))); err != nil {
+			return nil, err
+		}
+	}
+
+	return w.Bytes(), nil
+}
+
+// CoinbaseHeight returns the height of the block the coinbase tx is in, which the scriptSig begins with.
+// Coinbase txs in blocks before BIP34 activated may begin with anything else.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0034.mediawiki
+func (tx *Tx) CoinbaseHeight() (uint32, error) {
+	if !tx.IsCoinbase() {
+		return 0, ErrNotCoinbaseTx
+	}
+
+	b, err := tx.TxIns[0].Script.Bytes()
+	if err != nil {
+		return 0, err
+	}
+	if len(b) == 0 {
+		return 0, ErrInvalidCoinbaseHeight
+	}
+
+	var height uint32
+	switch op := OpCode(b[0]); {
+	case op.isSmallInt():
+		height = uint32(op.smallInt())
+	case 1 <= b[0] && b[0] <= 5 && len(b) > int(b[0]):
+		data := b[1 : 1+b[0]]
+		if data[len(data)-1]&0x80 != 0 {
+			return 0, ErrInvalidCoinbaseHeight
+		}
+
+		var n uint64
+		for i := len(data) - 1; i >= 0; i-- {
+			n = n<<8 | uint64(data[i])
+		}
+		if n > 0xffffffff {
+			return 0, ErrInvalidCoinbaseHeight
+		}
+		height = uint32(n)
+	default:
+		return 0, ErrInvalidCoinbaseHeight
+	}
+
+	// the height must be encoded as Bitcoin Core does, e.g. minimally
+	hb, err := coinbaseHeightBytes(height)
+	if err != nil {
+		return 0, err
+	}
+	if !bytes.HasPrefix(b, hb) {
+		return 0, ErrInvalidCoinbaseHeight
+	}
+
+	return height, nil
+}
+
+// WitnessCommitmentIndex returns the index of the tx out of the coinbase tx committing to the witnesses of the block,
+// which is the last one of the form, or -1 if there is none.
+// ref. https://github.com/bitcoin/bitcoin/blob/master/src/consensus/validation.h
+func (tx *Tx) WitnessCommitmentIndex() (int, error) {
+	idx := -1
+	for i, txOut := range tx.TxOuts {
+		b, err := txOut.Script.Bytes()
+		if err != nil {
+			return 0, err
+		}
+
+		if len(b) >= witnessCommitmentScriptSize &&
+			b[0] == OpReturn.Byte() &&
+			b[1] == 0x24 &&
+			bytes.Equal(b[2:6], witnessCommitmentHeader) {
+			idx = i
+		}
+	}
+
+	return idx, nil
+}
+
+// WitnessCommitment returns the commitment to the witnesses of the block, or nil if there is none.
+func (tx *Tx) WitnessCommitment() ([]byte, error) {
+	idx, err := tx.WitnessCommitmentIndex()
+	if err != nil || idx < 0 {
+		return nil, err
+	}
+
+	b, err := tx.TxOuts[idx].Script.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return b[6:witnessCommitmentScriptSize], nil
+}
+
+// WitnessMerkleRoot returns the merkle root of the wtxids of the block txs following the coinbase tx,
+// whose wtxid is regarded as 0.
+func WitnessMerkleRoot(txs []*Tx) ([]byte, error) {
+	hashes := make([][]byte, len(txs)+1)
+	hashes[0] = make([]byte, 32)
+	for i, tx := range txs {
+		wtxid, err := tx.Wtxid()
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := hex.DecodeString(wtxid)
+		if err != nil {
+			return nil, err
+		}
+
+		hashes[i+1] = reverseBytes(b)
+	}
+
+	for len(hashes) > 1 {
+		if len(hashes)%2 == 1 {
+			hashes = append(hashes, hashes[len(hashes)-1])
+		}
+
+		parents := make([][]byte, len(hashes)/2)
+		for i := range parents {
+			h, err := Sha256Double(concatBytes(hashes[2*i], hashes[2*i+1]))
+			if err != nil {
+				return nil, err
+			}
+			parents[i] = h
+		}
+		hashes = parents
+	}
+
+	return hashes[0], nil
+}
+
+// NewCoinbaseTx returns the coinbase tx of the block at height paying txOuts.
+// The scriptSig pushes the height and extraNonceSize zero bytes, which miners replace with SetExtraNonce.
+// If witnessRoot is not nil, the coinbase tx commits to it with the witness reserved value of zeros.
+func NewCoinbaseTx(height uint32, extraNonceSize int, txOuts []*TxOut, witnessRoot []byte) (*Tx, error) {
+	hb, err := coinbaseHeightBytes(height)
+	if err != nil {
+		return nil, err
+	}
+
+	w := newWriter()
+	if _, err := w.Write(hb); err != nil {
+		return nil, err
+	}
+	if err := w.writePushedData(make([]byte, extraNonceSize)); err != nil {
+		return nil, err
+	}
+	if w.Len() > MaxCoinBaseScriptSigSize {
+		return nil, ErrInvalidExtraNonce
+	}
+
+	tx := NewTx()
+	tx.Version = 2
+	tx.AddTxIn(NewTxIn(CoinBaseTxid, CoinBaseIndex, &Script{Hex: hex.EncodeToString(w.Bytes())}))
+	for _, txOut := range txOuts {
+		tx.AddTxOut(txOut)
+	}
+
+	if witnessRoot == nil {
+		return tx, nil
+	}
+	if len(witnessRoot) != 32 {
+		return nil, ErrInvalidWitnessRootLength
+	}
+
+	reservedValue := make([]byte, WitnessReservedValueLength)
+	commitment, err := Sha256Double(concatBytes(witnessRoot, reservedValue))
+	if err != nil {
+		return nil, err
+	}
+
+	w = newWriter()
+	if err := w.writeOpCode(OpReturn); err != nil {
+		return nil, err
+	}
+	if err := w.writePushedData(concatBytes(witnessCommitmentHeader, commitment)); err != nil {
+		return nil, err
+	}
+
+	tx.AddTxOut(NewTxOut(0, &Script{Hex: hex.EncodeToString(w.Bytes())}))
+	tx.TxIns[0].Witness = []string{hex.EncodeToString(reservedValue)}
+
+	return tx, nil
+}
+
+// SetExtraNonce replaces the extra nonce of the coinbase tx made by NewCoinbaseTx, whose size must not change.
+func (tx *Tx) SetExtraNonce(extraNonce []byte) error {
+	height, err := tx.CoinbaseHeight()
+	if err != nil {
+		return err
+	}
+
+	hb, err := coinbaseHeightBytes(height)
+	if err != nil {
+		return err
+	}
+
+	b, err := tx.TxIns[0].Script.Bytes()
+	if err != nil {
+		return err
+	}
+
+	// the push of the extra nonce follows the height, and anything may follow it
+	r := newReader(b[len(hb):])
+	op, err := r.readScriptOp()
+	if err != nil || !(op.op == Op0 || op.op.isPushData()) || len(op.data) != len(extraNonce) {
+		return ErrInvalidExtraNonce
+	}
+	rest := b[len(b)-r.Len():]
+
+	w := newWriter()
+	if _, err := w.Write(hb); err != nil {
+		return err
+	}
+	if err := w.writePushedData(extraNonce); err != nil {
+		return err
+	}
+	if _, err := w.Write(rest); err != nil {
+		return err
+	}
+
+	tx.TxIns[0].Script = &Script{Hex: hex.EncodeToString(w.Bytes())}
+
+	return nil
+}
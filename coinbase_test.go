@@ -0,0 +1,122 @@
+package btc
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxIsCoinbase(t *testing.T) {
+	assert.True(t, MainNetParams.GenesisBlock.Txes[0].IsCoinbase())
+	assert.False(t, newCheckTestTx(t).IsCoinbase())
+
+	// a null out point with other tx ins
+	tx := newCheckTestCoinBaseTx("0101")
+	tx.AddTxIn(NewTxIn(CoinBaseTxid, 0, &Script{}))
+	assert.False(t, tx.IsCoinbase())
+}
+
+func TestNewCoinbaseTx(t *testing.T) {
+	wtx := newCheckTestTx(t)
+
+	witnessRoot, err := WitnessMerkleRoot([]*Tx{wtx, wtx})
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(witnessRoot), "c10aa0250afece8c652f4fc1fd1a729d99dac92f8ff88d136749e0832b6381bc")
+
+	txOuts := []*TxOut{NewTxOut(625000000, &Script{Hex: "0014a9b8c7d6e5f4a3b2c1d0e9f8a9b8c7d6e5f4a3b2"})}
+
+	tx, err := NewCoinbaseTx(800000, 8, txOuts, witnessRoot)
+	require.NoError(t, err)
+	assert.True(t, tx.IsCoinbase())
+	assert.Equal(t, tx.TxIns[0].Script.Hex, "0300350c080000000000000000")
+	require.NoError(t, CheckTransaction(tx))
+
+	require.NoError(t, tx.SetExtraNonce([]byte{1, 2, 3, 4, 5, 6, 7, 8}))
+	assert.Equal(t, tx.SetExtraNonce([]byte{1, 2, 3, 4}), ErrInvalidExtraNonce)
+
+	txHex, err := tx.Hex()
+	require.NoError(t, err)
+	assert.Equal(t, txHex, "020000000001010000000000000000000000000000000000000000000000000000000000000000ffffffff0d0300350c080102030405060708ffffffff0240be402500000000160014a9b8c7d6e5f4a3b2c1d0e9f8a9b8c7d6e5f4a3b20000000000000000266a24aa21a9ed16d13bfcbcdaa53ae7480cf7a12733dff67d6daafad9b75bb7428ab134b0716b0120000000000000000000000000000000000000000000000000000000000000000000000000")
+
+	height, err := tx.CoinbaseHeight()
+	require.NoError(t, err)
+	assert.Equal(t, height, uint32(800000))
+
+	idx, err := tx.WitnessCommitmentIndex()
+	require.NoError(t, err)
+	assert.Equal(t, idx, 1)
+
+	commitment, err := tx.WitnessCommitment()
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(commitment), "16d13bfcbcdaa53ae7480cf7a12733dff67d6daafad9b75bb7428ab134b0716b")
+
+	// without the witness commitment
+	tx, err = NewCoinbaseTx(800000, 0, txOuts, nil)
+	require.NoError(t, err)
+	assert.Equal(t, tx.TxIns[0].Script.Hex, "0300350c00")
+	assert.False(t, tx.HasWitness())
+
+	idx, err = tx.WitnessCommitmentIndex()
+	require.NoError(t, err)
+	assert.Equal(t, idx, -1)
+
+	commitment, err = tx.WitnessCommitment()
+	require.NoError(t, err)
+	assert.Nil(t, commitment)
+
+	_, err = NewCoinbaseTx(800000, MaxCoinBaseScriptSigSize, txOuts, nil)
+	assert.Equal(t, err, ErrInvalidExtraNonce)
+
+	_, err = NewCoinbaseTx(800000, 8, txOuts, witnessRoot[1:])
+	assert.Equal(t, err, ErrInvalidWitnessRootLength)
+}
+
+func TestTxCoinbaseHeight(t *testing.T) {
+	testCases := []struct {
+		height       uint32
+		scriptSigHex string
+	}{
+		{0, "0000"},
+		{1, "5100"},
+		{16, "6000"},
+		{17, "011100"},
+		{127, "017f00"},
+		{128, "02800000"},
+		{227931, "035b7a0300"},
+		{800000, "0300350c00"},
+		{0x7fffffff, "04ffffff7f00"},
+		{0xffffffff, "05ffffffff0000"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scriptSigHex, func(t *testing.T) {
+			tx, err := NewCoinbaseTx(tc.height, 0, nil, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tx.TxIns[0].Script.Hex, tc.scriptSigHex)
+
+			height, err := tx.CoinbaseHeight()
+			require.NoError(t, err)
+			assert.Equal(t, height, tc.height)
+		})
+	}
+
+	for _, scriptSigHex := range []string{
+		"",
+		"0101",   // 1 not encoded as OP_1
+		"0181",   // negative
+		"020100", // not minimal
+		"03ffff", // truncated
+		"4f00",
+		"06ffffffffff00",
+	} {
+		t.Run(scriptSigHex, func(t *testing.T) {
+			_, err := newCheckTestCoinBaseTx(scriptSigHex).CoinbaseHeight()
+			assert.Equal(t, err, ErrInvalidCoinbaseHeight)
+		})
+	}
+
+	_, err := newCheckTestTx(t).CoinbaseHeight()
+	assert.Equal(t, err, ErrNotCoinbaseTx)
+}
@@ -0,0 +1,431 @@
+package btc
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+const (
+	// the fee rate Bitcoin Core expects to pay for spending outputs in the long term by default
+	DefaultLongTermFeeRate FeeRate = 10000
+
+	// the least change single random draw aims at
+	CoinSelectionChangeLower Satoshi = 50000
+
+	bnbMaxTries        = 100000
+	knapsackIterations = 1000
+)
+
+var (
+	ErrNoCoinSelection = errors.New("no coin selection")
+)
+
+// EffectiveValue returns the amount of the utxo minus the fee of spending it at rate.
+func (utxo *Utxo) EffectiveValue(rate FeeRate) (Satoshi, error) {
+	fee, err := utxo.spendingFee(rate)
+	if err != nil {
+		return 0, err
+	}
+
+	return utxo.Amount - fee, nil
+}
+
+func (utxo *Utxo) spendingFee(rate FeeRate) (Satoshi, error) {
+	weight, _, err := utxo.inputWeight()
+	if err != nil {
+		return 0, err
+	}
+
+	return rate.Fee(weightToVsize(weight)), nil
+}
+
+// CoinSelectionParams is the parameters coin selection algorithms work with.
+// ref. https://github.com/bitcoin/bitcoin/blob/master/src/wallet/coinselection.h
+type CoinSelectionParams struct {
+	// the fee rate of the tx
+	FeeRate FeeRate
+	// the fee rate at which the selected UTXOs would be spent otherwise,
+	// which makes spending more of them now cheaper or costlier than later
+	LongTermFeeRate FeeRate
+	// the virtual sizes of the change output and of the input spending it later
+	ChangeOutputSize int
+	ChangeSpendSize  int
+	// the change below which no change output is made, e.g. the dust threshold of the change output
+	MinViableChange Satoshi
+	// the change knapsack aims at, to avoid making small change outputs
+	MinChange Satoshi
+}
+
+// changeFee returns the fee of the change output.
+func (params *CoinSelectionParams) changeFee() Satoshi {
+	return params.FeeRate.Fee(params.ChangeOutputSize)
+}
+
+// costOfChange returns the fee of making the change output now and of spending it later.
+func (params *CoinSelectionParams) costOfChange() Satoshi {
+	return params.changeFee() + params.LongTermFeeRate.Fee(params.ChangeSpendSize)
+}
+
+// CoinSelection is UTXOs selected to cover a target.
+type CoinSelection struct {
+	Utxos []*Utxo
+	// the sum of the effective values of the UTXOs
+	Value Satoshi
+	// the amount of the change output, which is 0 if the selection is changeless
+	Change Satoshi
+	// the cost of the selection compared to spending the UTXOs at the long term fee rate
+	// without change, the lower the better
+	Waste Satoshi
+}
+
+// CoinSelector selects UTXOs whose effective values cover target,
+// which is the amounts paid plus the fee of the tx without inputs.
+type CoinSelector interface {
+	Select(utxos []*Utxo, target Satoshi, params *CoinSelectionParams) (*CoinSelection, error)
+}
+
+// SelectCoins returns the selection with the least waste among the ones of selectors,
+// preferring the one with more UTXOs on a tie as Bitcoin Core does.
+func SelectCoins(utxos []*Utxo, target Satoshi, params *CoinSelectionParams, selectors ...CoinSelector) (*CoinSelection, error) {
+	var best *CoinSelection
+	insufficient := true
+	for _, selector := range selectors {
+		selection, err := selector.Select(utxos, target, params)
+		if err == ErrInsufficientFunds || err == ErrNoCoinSelection {
+			insufficient = insufficient && err == ErrInsufficientFunds
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if best == nil || selection.Waste < best.Waste ||
+			(selection.Waste == best.Waste && len(selection.Utxos) > len(best.Utxos)) {
+			best = selection
+		}
+	}
+
+	if best == nil {
+		if insufficient {
+			return nil, ErrInsufficientFunds
+		}
+		return nil, ErrNoCoinSelection
+	}
+
+	return best, nil
+}
+
+type coinSelectionUtxo struct {
+	*Utxo
+	value       Satoshi
+	fee         Satoshi
+	longTermFee Satoshi
+}
+
+// newCoinSelectionUtxos returns the UTXOs worth spending at the fee rate
+// and the sum of their effective values.
+func newCoinSelectionUtxos(utxos []*Utxo, params *CoinSelectionParams) ([]*coinSelectionUtxo, Satoshi, error) {
+	var total Satoshi
+	csUtxos := make([]*coinSelectionUtxo, 0, len(utxos))
+	for _, utxo := range utxos {
+		fee, err := utxo.spendingFee(params.FeeRate)
+		if err != nil {
+			return nil, 0, err
+		}
+		if utxo.Amount <= fee {
+			continue
+		}
+
+		longTermFee, err := utxo.spendingFee(params.LongTermFeeRate)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		csUtxos = append(csUtxos, &coinSelectionUtxo{
+			Utxo:        utxo,
+			value:       utxo.Amount - fee,
+			fee:         fee,
+			longTermFee: longTermFee,
+		})
+		total += utxo.Amount - fee
+	}
+
+	return csUtxos, total, nil
+}
+
+// coinSelectionRand returns rng, or a source seeded with the current time if rng is nil.
+func coinSelectionRand(rng *rand.Rand) *rand.Rand {
+	if rng != nil {
+		return rng
+	}
+
+	return rand.New(rand.NewSource(time.Now().UnixNano()))
+}
+
+// sortCoinSelectionUtxos sorts UTXOs by their effective values in descending order.
+func sortCoinSelectionUtxos(utxos []*coinSelectionUtxo) {
+	sort.SliceStable(utxos, func(i, j int) bool {
+		return utxos[i].value > utxos[j].value
+	})
+}
+
+// newCoinSelection returns the selection of utxos for target, making change if it is viable.
+// ref. https://github.com/bitcoin/bitcoin/blob/master/src/wallet/coinselection.cpp
+func newCoinSelection(utxos []*coinSelectionUtxo, target Satoshi, params *CoinSelectionParams) *CoinSelection {
+	selection := &CoinSelection{
+		Utxos: make([]*Utxo, len(utxos)),
+	}
+
+	for i, utxo := range utxos {
+		selection.Utxos[i] = utxo.Utxo
+		selection.Value += utxo.value
+		selection.Waste += utxo.fee - utxo.longTermFee
+	}
+
+	excess := selection.Value - target
+	if change := excess - params.changeFee(); change >= params.MinViableChange && change > 0 {
+		selection.Change = change
+		selection.Waste += params.costOfChange()
+	} else {
+		selection.Waste += excess
+	}
+
+	return selection
+}
+
+// BnbSelector searches for a changeless selection with branch and bound,
+// whose effective values exceed target by no more than the cost of change.
+type BnbSelector struct{}
+
+func (selector *BnbSelector) Select(utxos []*Utxo, target Satoshi, params *CoinSelectionParams) (*CoinSelection, error) {
+	pool, available, err := newCoinSelectionUtxos(utxos, params)
+	if err != nil {
+		return nil, err
+	}
+	if available < target {
+		return nil, ErrInsufficientFunds
+	}
+
+	sortCoinSelectionUtxos(pool)
+
+	upper := target + params.costOfChange()
+	isFeeRateHigh := len(pool) > 0 && pool[0].fee > pool[0].longTermFee
+
+	var value, waste Satoshi
+	var selected, best []int
+	bestWaste := MaxMoney
+	for try, idx := 0, 0; try < bnbMaxTries; try, idx = try+1, idx+1 {
+		backtrack := false
+		if value+available < target || value > upper || (waste > bestWaste && isFeeRateHigh) {
+			backtrack = true
+		} else if value >= target {
+			if waste+value-target <= bestWaste {
+				best = append([]int{}, selected...)
+				bestWaste = waste + value - target
+			}
+			backtrack = true
+		}
+
+		if backtrack {
+			if len(selected) == 0 {
+				break
+			}
+
+			// put the omitted UTXOs back before omitting the last selected one
+			last := selected[len(selected)-1]
+			for idx--; idx > last; idx-- {
+				available += pool[idx].value
+			}
+
+			value -= pool[idx].value
+			waste -= pool[idx].fee - pool[idx].longTermFee
+			selected = selected[:len(selected)-1]
+		} else {
+			utxo := pool[idx]
+			available -= utxo.value
+
+			// skip the UTXO equivalent to the previous omitted one, which makes the same selections
+			if len(selected) == 0 || idx-1 == selected[len(selected)-1] ||
+				utxo.value != pool[idx-1].value || utxo.fee != pool[idx-1].fee {
+				selected = append(selected, idx)
+				value += utxo.value
+				waste += utxo.fee - utxo.longTermFee
+			}
+		}
+	}
+
+	if best == nil {
+		return nil, ErrNoCoinSelection
+	}
+
+	selection := make([]*coinSelectionUtxo, len(best))
+	for i, idx := range best {
+		selection[i] = pool[idx]
+	}
+
+	return newCoinSelection(selection, target, params), nil
+}
+
+// KnapsackSelector approximates the subset closest to target plus the change fee and MinChange
+// by random trials, falling back on the smallest UTXO larger than them.
+// Rand makes selections deterministic, which are random if it is nil.
+type KnapsackSelector struct {
+	Rand *rand.Rand
+}
+
+func NewKnapsackSelector(rng *rand.Rand) *KnapsackSelector {
+	return &KnapsackSelector{rng}
+}
+
+func (selector *KnapsackSelector) Select(utxos []*Utxo, target Satoshi, params *CoinSelectionParams) (*CoinSelection, error) {
+	pool, available, err := newCoinSelectionUtxos(utxos, params)
+	if err != nil {
+		return nil, err
+	}
+
+	selectionTarget := target + params.changeFee()
+	if available < selectionTarget {
+		return nil, ErrInsufficientFunds
+	}
+
+	rng := coinSelectionRand(selector.Rand)
+	rng.Shuffle(len(pool), func(i, j int) {
+		pool[i], pool[j] = pool[j], pool[i]
+	})
+
+	var applicable []*coinSelectionUtxo
+	var lowestLarger *coinSelectionUtxo
+	var totalLower Satoshi
+	for _, utxo := range pool {
+		if utxo.value == selectionTarget {
+			return newCoinSelection([]*coinSelectionUtxo{utxo}, target, params), nil
+		} else if utxo.value < selectionTarget+params.MinChange {
+			applicable = append(applicable, utxo)
+			totalLower += utxo.value
+		} else if lowestLarger == nil || utxo.value < lowestLarger.value {
+			lowestLarger = utxo
+		}
+	}
+
+	if totalLower == selectionTarget {
+		return newCoinSelection(applicable, target, params), nil
+	}
+	if totalLower < selectionTarget {
+		if lowestLarger == nil {
+			return nil, ErrInsufficientFunds
+		}
+		return newCoinSelection([]*coinSelectionUtxo{lowestLarger}, target, params), nil
+	}
+
+	sortCoinSelectionUtxos(applicable)
+
+	included, best := approximateBestSubset(rng, applicable, totalLower, selectionTarget)
+	if best != selectionTarget && totalLower >= selectionTarget+params.MinChange {
+		included, best = approximateBestSubset(rng, applicable, totalLower, selectionTarget+params.MinChange)
+	}
+
+	if lowestLarger != nil &&
+		((best != selectionTarget && best < selectionTarget+params.MinChange) || lowestLarger.value <= best) {
+		return newCoinSelection([]*coinSelectionUtxo{lowestLarger}, target, params), nil
+	}
+
+	var selection []*coinSelectionUtxo
+	for i, ok := range included {
+		if ok {
+			selection = append(selection, applicable[i])
+		}
+	}
+
+	return newCoinSelection(selection, target, params), nil
+}
+
+// approximateBestSubset returns the subset of utxos with the least sum reaching target found in random trials.
+func approximateBestSubset(rng *rand.Rand, utxos []*coinSelectionUtxo, total, target Satoshi) ([]bool, Satoshi) {
+	best := make([]bool, len(utxos))
+	for i := range best {
+		best[i] = true
+	}
+	bestTotal := total
+
+	for rep := 0; rep < knapsackIterations && bestTotal != target; rep++ {
+		included := make([]bool, len(utxos))
+		var sum Satoshi
+		reached := false
+		for pass := 0; pass < 2 && !reached; pass++ {
+			for i, utxo := range utxos {
+				// the first pass includes UTXOs at random, and the second one the rest of them
+				if (pass == 0 && rng.Intn(2) == 1) || (pass == 1 && !included[i]) {
+					sum += utxo.value
+					included[i] = true
+					if sum >= target {
+						reached = true
+						if sum < bestTotal {
+							bestTotal = sum
+							copy(best, included)
+						}
+						sum -= utxo.value
+						included[i] = false
+					}
+				}
+			}
+		}
+	}
+
+	return best, bestTotal
+}
+
+// SrdSelector draws UTXOs at random until they cover target plus the change fee and CoinSelectionChangeLower.
+// Rand makes selections deterministic, which are random if it is nil.
+type SrdSelector struct {
+	Rand *rand.Rand
+}
+
+func NewSrdSelector(rng *rand.Rand) *SrdSelector {
+	return &SrdSelector{rng}
+}
+
+func (selector *SrdSelector) Select(utxos []*Utxo, target Satoshi, params *CoinSelectionParams) (*CoinSelection, error) {
+	pool, _, err := newCoinSelectionUtxos(utxos, params)
+	if err != nil {
+		return nil, err
+	}
+
+	selectionTarget := target + params.changeFee() + CoinSelectionChangeLower
+
+	var value Satoshi
+	var selection []*coinSelectionUtxo
+	for _, i := range coinSelectionRand(selector.Rand).Perm(len(pool)) {
+		selection = append(selection, pool[i])
+		value += pool[i].value
+		if value >= selectionTarget {
+			return newCoinSelection(selection, target, params), nil
+		}
+	}
+
+	return nil, ErrInsufficientFunds
+}
+
+// LargestFirstSelector selects the UTXOs of the largest effective values until they cover target plus the change fee.
+type LargestFirstSelector struct{}
+
+func (selector *LargestFirstSelector) Select(utxos []*Utxo, target Satoshi, params *CoinSelectionParams) (*CoinSelection, error) {
+	pool, _, err := newCoinSelectionUtxos(utxos, params)
+	if err != nil {
+		return nil, err
+	}
+
+	sortCoinSelectionUtxos(pool)
+
+	selectionTarget := target + params.changeFee()
+
+	var value Satoshi
+	for i, utxo := range pool {
+		value += utxo.value
+		if value >= selectionTarget {
+			return newCoinSelection(pool[:i+1], target, params), nil
+		}
+	}
+
+	return nil, ErrInsufficientFunds
+}
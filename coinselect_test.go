@@ -0,0 +1,197 @@
+package btc
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// coinSelectionTestUtxos returns P2WPKH UTXOs, whose spending fee is 68 at 1 sat/vB.
+func coinSelectionTestUtxos(t *testing.T, values ...Satoshi) []*Utxo {
+	script, err := NewP2wpkhScript(Pkh(strings.Repeat("\x11", PkhLength)))
+	require.NoError(t, err)
+
+	utxos := make([]*Utxo, len(values))
+	for i, value := range values {
+		utxos[i] = NewUtxo(fmt.Sprintf("%064x", i+1), 0, &PrevOut{
+			TxOut: NewTxOut(int64(value+68), script),
+		})
+	}
+
+	return utxos
+}
+
+func coinSelectionTestParams() *CoinSelectionParams {
+	return &CoinSelectionParams{
+		FeeRate:          FeeRateSatPerVByte,
+		LongTermFeeRate:  DefaultLongTermFeeRate,
+		ChangeOutputSize: 31,
+		ChangeSpendSize:  68,
+		MinViableChange:  294,
+		MinChange:        CoinSelectionChangeLower,
+	}
+}
+
+func coinSelectionValues(t *testing.T, selection *CoinSelection) []Satoshi {
+	values := make([]Satoshi, len(selection.Utxos))
+	for i, utxo := range selection.Utxos {
+		value, err := utxo.EffectiveValue(FeeRateSatPerVByte)
+		require.NoError(t, err)
+		values[i] = value
+	}
+
+	return values
+}
+
+func TestUtxoEffectiveValue(t *testing.T) {
+	utxos := coinSelectionTestUtxos(t, 100000)
+
+	value, err := utxos[0].EffectiveValue(FeeRateSatPerVByte)
+	require.NoError(t, err)
+	assert.Equal(t, value, Satoshi(100000))
+
+	value, err = utxos[0].EffectiveValue(DefaultLongTermFeeRate)
+	require.NoError(t, err)
+	assert.Equal(t, value, Satoshi(100068-680))
+}
+
+func TestBnbSelector(t *testing.T) {
+	utxos := coinSelectionTestUtxos(t, 100000, 200000, 300000, 500000)
+	params := coinSelectionTestParams()
+	selector := &BnbSelector{}
+
+	// spending more UTXOs now wastes less below the long term fee rate
+	selection, err := selector.Select(utxos, 600000, params)
+	require.NoError(t, err)
+	assert.Equal(t, coinSelectionValues(t, selection), []Satoshi{300000, 200000, 100000})
+	assert.Equal(t, selection.Change, Satoshi(0))
+	assert.Equal(t, selection.Waste, Satoshi(3*(68-680)))
+
+	// the excess too small for change is wasted
+	selection, err = selector.Select(utxos, 599800, params)
+	require.NoError(t, err)
+	assert.Equal(t, coinSelectionValues(t, selection), []Satoshi{300000, 200000, 100000})
+	assert.Equal(t, selection.Change, Satoshi(0))
+	assert.Equal(t, selection.Waste, Satoshi(3*(68-680)+200))
+
+	// the excess within the cost of change still makes change if it is viable
+	selection, err = selector.Select(utxos, 599500, params)
+	require.NoError(t, err)
+	assert.Equal(t, selection.Change, Satoshi(500-31))
+	assert.Equal(t, selection.Waste, Satoshi(3*(68-680)+31+680))
+
+	_, err = selector.Select(utxos, 650000, params)
+	assert.Equal(t, err, ErrNoCoinSelection)
+
+	_, err = selector.Select(utxos, 1100001, params)
+	assert.Equal(t, err, ErrInsufficientFunds)
+}
+
+func TestLargestFirstSelector(t *testing.T) {
+	utxos := coinSelectionTestUtxos(t, 100000, 200000, 300000, 500000)
+	params := coinSelectionTestParams()
+	selector := &LargestFirstSelector{}
+
+	selection, err := selector.Select(utxos, 650000, params)
+	require.NoError(t, err)
+	assert.Equal(t, coinSelectionValues(t, selection), []Satoshi{500000, 300000})
+	assert.Equal(t, selection.Value, Satoshi(800000))
+	assert.Equal(t, selection.Change, Satoshi(150000-31))
+	assert.Equal(t, selection.Waste, Satoshi(2*(68-680)+31+680))
+
+	_, err = selector.Select(utxos, 1100000, params)
+	assert.Equal(t, err, ErrInsufficientFunds)
+}
+
+func TestKnapsackSelector(t *testing.T) {
+	utxos := coinSelectionTestUtxos(t, 100000, 200000, 300000, 500000, 1000000)
+	params := coinSelectionTestParams()
+
+	// a UTXO matching the target plus the change fee
+	selection, err := NewKnapsackSelector(rand.New(rand.NewSource(1))).Select(utxos, 300000-31, params)
+	require.NoError(t, err)
+	assert.Equal(t, coinSelectionValues(t, selection), []Satoshi{300000})
+
+	// the subset reaching the target plus the change fee and MinChange exactly
+	selection, err = NewKnapsackSelector(rand.New(rand.NewSource(1))).Select(utxos, 750000-31, params)
+	require.NoError(t, err)
+	assert.Equal(t, selection.Value, Satoshi(800000))
+	assert.Equal(t, selection.Change, Satoshi(50000))
+
+	// the least subset reaching the target plus the change fee
+	selection, err = NewKnapsackSelector(rand.New(rand.NewSource(1))).Select(utxos, 1100000, params)
+	require.NoError(t, err)
+	assert.Equal(t, selection.Value, Satoshi(1200000))
+
+	// the smallest UTXO larger than the target when the smaller ones do not reach it
+	selection, err = NewKnapsackSelector(rand.New(rand.NewSource(1))).Select(coinSelectionTestUtxos(t, 100000, 200000, 1000000), 400000, params)
+	require.NoError(t, err)
+	assert.Equal(t, coinSelectionValues(t, selection), []Satoshi{1000000})
+
+	_, err = NewKnapsackSelector(rand.New(rand.NewSource(1))).Select(utxos, 2100000, params)
+	assert.Equal(t, err, ErrInsufficientFunds)
+
+	// selections are random without Rand
+	for _, selector := range []*KnapsackSelector{NewKnapsackSelector(nil), {}} {
+		selection, err = selector.Select(utxos, 1100000, params)
+		require.NoError(t, err)
+		assert.True(t, selection.Value >= 1100000+31)
+	}
+}
+
+func TestSrdSelector(t *testing.T) {
+	utxos := coinSelectionTestUtxos(t, 100000, 200000, 300000, 500000, 1000000)
+	params := coinSelectionTestParams()
+
+	for seed := int64(0); seed < 10; seed++ {
+		selection, err := NewSrdSelector(rand.New(rand.NewSource(seed))).Select(utxos, 400000, params)
+		require.NoError(t, err)
+		assert.True(t, selection.Value >= 400000+31+CoinSelectionChangeLower)
+
+		// the same seed draws the same UTXOs
+		again, err := NewSrdSelector(rand.New(rand.NewSource(seed))).Select(utxos, 400000, params)
+		require.NoError(t, err)
+		assert.Equal(t, again, selection)
+	}
+
+	_, err := NewSrdSelector(rand.New(rand.NewSource(1))).Select(utxos, 2100000-31-CoinSelectionChangeLower+1, params)
+	assert.Equal(t, err, ErrInsufficientFunds)
+
+	// selections are random without Rand
+	for _, selector := range []*SrdSelector{NewSrdSelector(nil), {}} {
+		selection, err := selector.Select(utxos, 400000, params)
+		require.NoError(t, err)
+		assert.True(t, selection.Value >= 400000+31+CoinSelectionChangeLower)
+	}
+}
+
+func TestSelectCoins(t *testing.T) {
+	utxos := coinSelectionTestUtxos(t, 100000, 200000, 300000, 500000)
+	params := coinSelectionTestParams()
+	selectors := []CoinSelector{
+		&BnbSelector{},
+		NewKnapsackSelector(rand.New(rand.NewSource(1))),
+		NewSrdSelector(rand.New(rand.NewSource(1))),
+		&LargestFirstSelector{},
+	}
+
+	// the changeless selection wastes the least
+	selection, err := SelectCoins(utxos, 600000, params, selectors...)
+	require.NoError(t, err)
+	assert.Equal(t, coinSelectionValues(t, selection), []Satoshi{300000, 200000, 100000})
+	assert.Equal(t, selection.Change, Satoshi(0))
+
+	selection, err = SelectCoins(utxos, 650000, params, selectors[0], selectors[3])
+	require.NoError(t, err)
+	assert.Equal(t, coinSelectionValues(t, selection), []Satoshi{500000, 300000})
+
+	_, err = SelectCoins(utxos, 650000, params, selectors[0])
+	assert.Equal(t, err, ErrNoCoinSelection)
+
+	_, err = SelectCoins(utxos, 1100001, params, selectors...)
+	assert.Equal(t, err, ErrInsufficientFunds)
+}
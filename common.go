@@ -2,27 +2,35 @@ package btc
 
 import (
 	"errors"
-	"os"
+	"math"
 )
 
 const (
-	NetworkTypeEnvKey = "BTC_NETWORK_TYPE"
-	NetworkTypeMain   = "mainnet"
-	NetworkTypeTest   = "testnet"
-
 	SatoshiPerBtc = 100000000
 
+	// the amount no valid tx out can exceed
+	MaxMoney Satoshi = 21000000 * SatoshiPerBtc
+
 	TxVersion    int32  = 1
 	TxLockTime   uint32 = 0
 	TxInSequence uint32 = 4294967295
 
-	CoinBaseTxid = "0000000000000000000000000000000000000000000000000000000000000000"
+	// lock times below this are block heights, and the others are unix times
+	TxLockTimeThreshold uint32 = 500000000
+
+	TxWitnessMarker byte = 0x00
+	TxWitnessFlag   byte = 0x01
 
-	AddressVersionMain byte = 0x00
-	AddressVersionTest byte = 0x6f
+	// the weight of a non-witness byte
+	WitnessScaleFactor = 4
 
-	PkhLength    = 20 // 0x14
-	PubKeyLength = 65 // 0x41
+	CoinBaseTxid         = "0000000000000000000000000000000000000000000000000000000000000000"
+	CoinBaseIndex uint32 = 0xffffffff
+
+	PkhLength              = 20 // 0x14
+	PrivateKeyLength       = 32 // 0x20
+	CompressedPubKeyLength = 33 // 0x21
+	PubKeyLength           = 65 // 0x41
 )
 
 var (
@@ -35,8 +43,10 @@ func (btc Btc) Float64() float64 {
 	return float64(btc)
 }
 
+// Satoshi returns the amount rounded to the nearest satoshi, since btc * 1e8 is not exact in float64,
+// e.g. 0.29 * 1e8 is 28999999.999999996. Use ParseAmount to avoid float64 amounts.
 func (btc Btc) Satoshi() Satoshi {
-	return Satoshi(btc * SatoshiPerBtc)
+	return Satoshi(math.Round(float64(btc) * SatoshiPerBtc))
 }
 
 type Satoshi int64
@@ -45,18 +55,11 @@ func (satoshi Satoshi) Int64() int64 {
 	return int64(satoshi)
 }
 
-func (satoshi Satoshi) Btc() Btc {
-	return Btc(float64(satoshi) / SatoshiPerBtc)
+// IsValid reports whether the amount is in the money range.
+func (satoshi Satoshi) IsValid() bool {
+	return satoshi >= 0 && satoshi <= MaxMoney
 }
 
-func UseTestnet() error {
-	return os.Setenv(NetworkTypeEnvKey, NetworkTypeTest)
-}
-
-func isTestnet() bool {
-	if os.Getenv(NetworkTypeEnvKey) == NetworkTypeTest {
-		return true
-	}
-
-	return false
+func (satoshi Satoshi) Btc() Btc {
+	return Btc(float64(satoshi) / SatoshiPerBtc)
 }
@@ -0,0 +1,985 @@
+package btc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	descriptorInputCharset = "0123456789()[],'/*abcdefgh@:$%{}" +
+		"IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~" +
+		"ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+	descriptorChecksumLength    = 8
+	descriptorChecksumSeparator = "#"
+	descriptorOpeningBrackets   = "([{"
+	descriptorClosingBrackets   = ")]}"
+
+	maxP2shScriptSize = 520
+)
+
+var (
+	ErrInvalidDescriptor         = errors.New("invalid descriptor")
+	ErrInvalidDescriptorChar     = errors.New("invalid descriptor character")
+	ErrInvalidDescriptorChecksum = errors.New("invalid descriptor checksum")
+	ErrInvalidDescriptorContext  = errors.New("descriptor function not allowed in this context")
+	ErrInvalidDescriptorKey      = errors.New("invalid descriptor key")
+	ErrUncompressedDescriptorKey = errors.New("uncompressed key not allowed in this context")
+	ErrInvalidDescriptorIndex    = errors.New("invalid descriptor index")
+	ErrP2shScriptTooLarge        = errors.New("p2sh script too large")
+)
+
+var descriptorChecksumGenerator = []uint64{0xf5dee51989, 0xa9fdca3312, 0x1bab10e32d, 0x3706b1677a, 0x644d626ffd}
+
+// DescriptorChecksum returns the checksum of a descriptor without one.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0380.mediawiki#checksum
+func DescriptorChecksum(s string) (string, error) {
+	chk := uint64(1)
+	polymod := func(v uint64) {
+		top := chk >> 35
+		chk = (chk&0x7ffffffff)<<5 ^ v
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= descriptorChecksumGenerator[i]
+			}
+		}
+	}
+
+	var groups []uint64
+	for i := 0; i < len(s); i++ {
+		pos := strings.IndexByte(descriptorInputCharset, s[i])
+		if pos < 0 {
+			return "", ErrInvalidDescriptorChar
+		}
+
+		polymod(uint64(pos) & 31)
+		groups = append(groups, uint64(pos)>>5)
+		if len(groups) == 3 {
+			polymod(groups[0]*9 + groups[1]*3 + groups[2])
+			groups = groups[:0]
+		}
+	}
+	switch len(groups) {
+	case 1:
+		polymod(groups[0])
+	case 2:
+		polymod(groups[0]*3 + groups[1])
+	}
+	for i := 0; i < descriptorChecksumLength; i++ {
+		polymod(0)
+	}
+	chk ^= 1
+
+	b := make([]byte, descriptorChecksumLength)
+	for i := range b {
+		b[i] = bech32Charset[(chk>>uint(5*(descriptorChecksumLength-1-i)))&31]
+	}
+
+	return string(b), nil
+}
+
+// Descriptor is an output script descriptor.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0380.mediawiki
+type Descriptor struct {
+	node   descriptorNode
+	keys   []*descriptorKey
+	params *Params
+}
+
+// ParseDescriptor parses a descriptor for the network defined by params.
+// The checksum is optional, but it is verified if present.
+func ParseDescriptor(s string, params *Params) (*Descriptor, error) {
+	if i := strings.Index(s, descriptorChecksumSeparator); i >= 0 {
+		checksum, err := DescriptorChecksum(s[:i])
+		if err != nil {
+			return nil, err
+		}
+		if s[i+1:] != checksum {
+			return nil, ErrInvalidDescriptorChecksum
+		}
+		s = s[:i]
+	} else if _, err := DescriptorChecksum(s); err != nil {
+		return nil, err
+	}
+
+	p := &descriptorParser{params: params}
+
+	node, err := p.parseScript(s, descriptorContextTop)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Descriptor{
+		node:   node,
+		keys:   p.keys,
+		params: params,
+	}, nil
+}
+
+// String returns the descriptor with its checksum.
+func (desc *Descriptor) String() string {
+	s := desc.node.String()
+
+	// the parsed descriptor consists only of valid characters
+	checksum, _ := DescriptorChecksum(s)
+
+	return s + descriptorChecksumSeparator + checksum
+}
+
+// IsRange reports whether the descriptor contains a key ending with a wildcard.
+func (desc *Descriptor) IsRange() bool {
+	for _, key := range desc.keys {
+		if key.wildcard != descriptorWildcardNone {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Scripts returns the scriptPubKeys described at index, which is ignored by non-ranged descriptors.
+// Only combo() describes more than one script.
+func (desc *Descriptor) Scripts(index uint32) ([]*Script, error) {
+	if index >= HardenedKeyStart {
+		return nil, ErrInvalidDescriptorIndex
+	}
+
+	return desc.node.scripts(index)
+}
+
+// Addresses returns the addresses of the scripts described at index,
+// skipping scripts without an address, such as P2PK ones of combo().
+func (desc *Descriptor) Addresses(index uint32) ([]Address, error) {
+	scripts, err := desc.Scripts(index)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make([]Address, 0, len(scripts))
+	for _, script := range scripts {
+		address, err := script.Address(desc.params)
+		if err == ErrScriptHasNoAddress {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		addresses = append(addresses, address)
+	}
+	if len(addresses) == 0 {
+		return nil, ErrScriptHasNoAddress
+	}
+
+	return addresses, nil
+}
+
+type descriptorContext int
+
+const (
+	descriptorContextTop descriptorContext = iota
+	descriptorContextP2sh
+	descriptorContextP2wsh
+	descriptorContextP2tr
+)
+
+type descriptorParser struct {
+	params *Params
+	keys   []*descriptorKey
+}
+
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0381.mediawiki
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0382.mediawiki
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0383.mediawiki
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0384.mediawiki
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0385.mediawiki
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0386.mediawiki
+func (p *descriptorParser) parseScript(s string, ctx descriptorContext) (descriptorNode, error) {
+	name, args, err := splitDescriptorFunc(s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case "pk":
+		if len(args) != 1 {
+			return nil, ErrInvalidDescriptor
+		}
+		key, err := p.parseKey(args[0], ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &pkDescriptor{key, ctx == descriptorContextP2tr}, nil
+
+	case "pkh":
+		if ctx == descriptorContextP2tr {
+			return nil, ErrInvalidDescriptorContext
+		}
+		if len(args) != 1 {
+			return nil, ErrInvalidDescriptor
+		}
+		key, err := p.parseKey(args[0], ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &pkhDescriptor{key}, nil
+
+	case "wpkh":
+		if ctx != descriptorContextTop && ctx != descriptorContextP2sh {
+			return nil, ErrInvalidDescriptorContext
+		}
+		if len(args) != 1 {
+			return nil, ErrInvalidDescriptor
+		}
+		// the key of wpkh() must be compressed in any context
+		key, err := p.parseKey(args[0], descriptorContextP2wsh)
+		if err != nil {
+			return nil, err
+		}
+		return &wpkhDescriptor{key}, nil
+
+	case "combo":
+		if ctx != descriptorContextTop {
+			return nil, ErrInvalidDescriptorContext
+		}
+		if len(args) != 1 {
+			return nil, ErrInvalidDescriptor
+		}
+		key, err := p.parseKey(args[0], ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &comboDescriptor{key}, nil
+
+	case "multi", "sortedmulti":
+		if ctx == descriptorContextP2tr {
+			return nil, ErrInvalidDescriptorContext
+		}
+		if len(args) < 2 {
+			return nil, ErrInvalidDescriptor
+		}
+		m, err := strconv.Atoi(args[0])
+		if err != nil || m < 1 || m > len(args)-1 || len(args)-1 > MaxMultisigPubKeys {
+			return nil, ErrInvalidMultisig
+		}
+		// bare multisig is limited to the ones Bitcoin Core relays
+		if ctx == descriptorContextTop && len(args)-1 > maxStandardBareMultisigPubKeys {
+			return nil, ErrInvalidMultisig
+		}
+		keys := make([]*descriptorKey, len(args)-1)
+		// m, n and OP_CHECKMULTISIG
+		scriptSize := 3
+		for i, arg := range args[1:] {
+			if keys[i], err = p.parseKey(arg, ctx); err != nil {
+				return nil, err
+			}
+			scriptSize += keys[i].size() + 1
+		}
+		// redeem scripts are limited by the size of the data pushed in scriptSigs, e.g. to 15 compressed keys
+		if ctx == descriptorContextP2sh && scriptSize > maxP2shScriptSize {
+			return nil, ErrP2shScriptTooLarge
+		}
+		return &multiDescriptor{m, keys, name == "sortedmulti"}, nil
+
+	case "sh":
+		if ctx != descriptorContextTop {
+			return nil, ErrInvalidDescriptorContext
+		}
+		if len(args) != 1 {
+			return nil, ErrInvalidDescriptor
+		}
+		sub, err := p.parseScript(args[0], descriptorContextP2sh)
+		if err != nil {
+			return nil, err
+		}
+		return &shDescriptor{sub}, nil
+
+	case "wsh":
+		if ctx != descriptorContextTop && ctx != descriptorContextP2sh {
+			return nil, ErrInvalidDescriptorContext
+		}
+		if len(args) != 1 {
+			return nil, ErrInvalidDescriptor
+		}
+		sub, err := p.parseScript(args[0], descriptorContextP2wsh)
+		if err != nil {
+			return nil, err
+		}
+		return &wshDescriptor{sub}, nil
+
+	case "tr":
+		if ctx != descriptorContextTop {
+			return nil, ErrInvalidDescriptorContext
+		}
+		if len(args) != 1 && len(args) != 2 {
+			return nil, ErrInvalidDescriptor
+		}
+		key, err := p.parseKey(args[0], descriptorContextP2tr)
+		if err != nil {
+			return nil, err
+		}
+		var tree *tapTreeDescriptor
+		if len(args) == 2 {
+			if tree, err = p.parseTapTree(args[1]); err != nil {
+				return nil, err
+			}
+		}
+		return &trDescriptor{key, tree}, nil
+
+	case "addr":
+		if ctx != descriptorContextTop {
+			return nil, ErrInvalidDescriptorContext
+		}
+		if len(args) != 1 {
+			return nil, ErrInvalidDescriptor
+		}
+		decoded, err := DecodeAddress(args[0], p.params)
+		if err != nil {
+			return nil, err
+		}
+		script, err := decoded.Script()
+		if err != nil {
+			return nil, err
+		}
+		return &addrDescriptor{args[0], script}, nil
+
+	case "raw":
+		if ctx != descriptorContextTop {
+			return nil, ErrInvalidDescriptorContext
+		}
+		if len(args) != 1 {
+			return nil, ErrInvalidDescriptor
+		}
+		script, err := NewScriptFromHex(args[0])
+		if err != nil {
+			return nil, ErrInvalidDescriptor
+		}
+		return &rawDescriptor{script}, nil
+
+	default:
+		return nil, ErrInvalidDescriptor
+	}
+}
+
+// parseTapTree parses a TREE expression: a script or {TREE,TREE}.
+func (p *descriptorParser) parseTapTree(s string) (*tapTreeDescriptor, error) {
+	if !strings.HasPrefix(s, "{") {
+		leaf, err := p.parseScript(s, descriptorContextP2tr)
+		if err != nil {
+			return nil, err
+		}
+		return &tapTreeDescriptor{leaf: leaf}, nil
+	}
+	if !strings.HasSuffix(s, "}") {
+		return nil, ErrInvalidDescriptor
+	}
+
+	branches, err := splitDescriptorArgs(s[1 : len(s)-1])
+	if err != nil {
+		return nil, err
+	}
+	if len(branches) != 2 {
+		return nil, ErrInvalidDescriptor
+	}
+
+	left, err := p.parseTapTree(branches[0])
+	if err != nil {
+		return nil, err
+	}
+	right, err := p.parseTapTree(branches[1])
+	if err != nil {
+		return nil, err
+	}
+
+	return &tapTreeDescriptor{left: left, right: right}, nil
+}
+
+// splitDescriptorFunc splits "name(arg,...)" into its name and top-level arguments.
+func splitDescriptorFunc(s string) (string, []string, error) {
+	i := strings.IndexByte(s, '(')
+	if i < 1 || !strings.HasSuffix(s, ")") {
+		return "", nil, ErrInvalidDescriptor
+	}
+
+	args, err := splitDescriptorArgs(s[i+1 : len(s)-1])
+	if err != nil {
+		return "", nil, err
+	}
+
+	return s[:i], args, nil
+}
+
+// splitDescriptorArgs splits s at the commas outside any brackets.
+func splitDescriptorArgs(s string) ([]string, error) {
+	var args []string
+	var stack []byte
+
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if j := strings.IndexByte(descriptorClosingBrackets, c); j >= 0 {
+			if len(stack) == 0 || stack[len(stack)-1] != descriptorOpeningBrackets[j] {
+				return nil, ErrInvalidDescriptor
+			}
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		switch c {
+		case '(', '[', '{':
+			stack = append(stack, c)
+		case ',':
+			if len(stack) == 0 {
+				args = append(args, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if len(stack) != 0 {
+		return nil, ErrInvalidDescriptor
+	}
+	args = append(args, s[start:])
+
+	for _, arg := range args {
+		if len(arg) == 0 {
+			return nil, ErrInvalidDescriptor
+		}
+	}
+
+	return args, nil
+}
+
+type descriptorWildcard int
+
+const (
+	descriptorWildcardNone descriptorWildcard = iota
+	descriptorWildcardUnhardened
+	descriptorWildcardHardened
+)
+
+// descriptorKey is a KEY expression: an optional origin followed by a hex public key,
+// a WIF private key, or an extended key with a derivation path and an optional wildcard.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0380.mediawiki#key-expressions
+type descriptorKey struct {
+	s           string
+	fingerprint []byte
+	originPath  DerivationPath
+	pubKey      *PublicKey
+	extKey      *ExtendedKey
+	wildcard    descriptorWildcard
+}
+
+func (p *descriptorParser) parseKey(s string, ctx descriptorContext) (*descriptorKey, error) {
+	key := &descriptorKey{s: s}
+
+	if strings.HasPrefix(s, "[") {
+		i := strings.IndexByte(s, ']')
+		if i < 0 {
+			return nil, ErrInvalidDescriptorKey
+		}
+
+		elems := strings.SplitN(s[1:i], "/", 2)
+		fingerprint, err := hex.DecodeString(elems[0])
+		if err != nil || len(fingerprint) != hdFingerprintLength {
+			return nil, ErrInvalidDescriptorKey
+		}
+		key.fingerprint = fingerprint
+
+		if len(elems) == 2 {
+			if key.originPath, err = ParseDerivationPath(elems[1]); err != nil {
+				return nil, err
+			}
+		}
+
+		s = s[i+1:]
+	}
+
+	elems := strings.Split(s, "/")
+
+	if len(elems) == 1 {
+		if b, err := hex.DecodeString(s); err == nil {
+			if len(b) == XOnlyPubKeyLength && ctx == descriptorContextP2tr {
+				xOnly, err := NewXOnlyPublicKeyFromBytes(b)
+				if err != nil {
+					return nil, ErrInvalidDescriptorKey
+				}
+				key.pubKey = xOnly.PublicKey()
+			} else {
+				pubKey, err := NewPublicKeyFromBytes(b)
+				if err != nil {
+					return nil, ErrInvalidDescriptorKey
+				}
+				key.pubKey = pubKey
+			}
+		} else if privKey, compressed, err := Wif(s).Decode(p.params); err == nil {
+			key.pubKey = privKey.PublicKey()
+			if !compressed {
+				key.pubKey = key.pubKey.Uncompressed()
+			}
+		}
+
+		if key.pubKey != nil {
+			if !key.pubKey.IsCompressed() && ctx != descriptorContextTop && ctx != descriptorContextP2sh {
+				return nil, ErrUncompressedDescriptorKey
+			}
+
+			p.keys = append(p.keys, key)
+			return key, nil
+		}
+	}
+
+	extKey, err := NewExtendedKeyFromString(elems[0], p.params)
+	if err != nil {
+		return nil, ErrInvalidDescriptorKey
+	}
+
+	elems = elems[1:]
+	if n := len(elems); n > 0 {
+		switch elems[n-1] {
+		case "*":
+			key.wildcard = descriptorWildcardUnhardened
+		case "*'", "*h", "*H":
+			key.wildcard = descriptorWildcardHardened
+		}
+		if key.wildcard != descriptorWildcardNone {
+			elems = elems[:n-1]
+		}
+	}
+	if key.wildcard == descriptorWildcardHardened && !extKey.IsPrivate() {
+		return nil, ErrHardenedFromPublicKey
+	}
+
+	if len(elems) > 0 {
+		path, err := ParseDerivationPath(strings.Join(elems, "/"))
+		if err != nil {
+			return nil, err
+		}
+		if extKey, err = extKey.Derive(path); err != nil {
+			return nil, err
+		}
+	}
+	key.extKey = extKey
+
+	p.keys = append(p.keys, key)
+	return key, nil
+}
+
+func (key *descriptorKey) String() string {
+	return key.s
+}
+
+// size returns the size of the public keys the key derives.
+func (key *descriptorKey) size() int {
+	if key.pubKey != nil {
+		return len(key.pubKey.Bytes())
+	}
+
+	return CompressedPubKeyLength
+}
+
+func (key *descriptorKey) publicKey(index uint32) (*PublicKey, error) {
+	if key.extKey == nil {
+		return key.pubKey, nil
+	}
+
+	extKey := key.extKey
+	switch key.wildcard {
+	case descriptorWildcardUnhardened:
+		child, err := extKey.Child(index)
+		if err != nil {
+			return nil, err
+		}
+		extKey = child
+	case descriptorWildcardHardened:
+		child, err := extKey.Child(HardenedKeyStart + index)
+		if err != nil {
+			return nil, err
+		}
+		extKey = child
+	}
+
+	return extKey.PublicKey(), nil
+}
+
+type descriptorNode interface {
+	String() string
+	scripts(index uint32) ([]*Script, error)
+}
+
+// singleScript returns the only script described by node at index.
+func singleScript(node descriptorNode, index uint32) (*Script, error) {
+	scripts, err := node.scripts(index)
+	if err != nil {
+		return nil, err
+	}
+	if len(scripts) != 1 {
+		return nil, ErrInvalidDescriptor
+	}
+
+	return scripts[0], nil
+}
+
+func joinDescriptorArgs(name string, args ...string) string {
+	return name + "(" + strings.Join(args, ",") + ")"
+}
+
+type pkDescriptor struct {
+	key   *descriptorKey
+	xOnly bool
+}
+
+func (desc *pkDescriptor) String() string {
+	return joinDescriptorArgs("pk", desc.key.String())
+}
+
+func (desc *pkDescriptor) scripts(index uint32) ([]*Script, error) {
+	pubKey, err := desc.key.publicKey(index)
+	if err != nil {
+		return nil, err
+	}
+
+	if !desc.xOnly {
+		script, err := NewP2pkScript(pubKey)
+		if err != nil {
+			return nil, err
+		}
+		return []*Script{script}, nil
+	}
+
+	w := newWriter()
+	if err := w.writePushedData(pubKey.XOnly().Bytes()); err != nil {
+		return nil, err
+	}
+	if err := w.writeOpCode(OpCheckSig); err != nil {
+		return nil, err
+	}
+
+	script, err := NewScriptFromBytes(w.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	return []*Script{script}, nil
+}
+
+type pkhDescriptor struct {
+	key *descriptorKey
+}
+
+func (desc *pkhDescriptor) String() string {
+	return joinDescriptorArgs("pkh", desc.key.String())
+}
+
+func (desc *pkhDescriptor) scripts(index uint32) ([]*Script, error) {
+	pubKey, err := desc.key.publicKey(index)
+	if err != nil {
+		return nil, err
+	}
+
+	pkh, err := pubKey.Pkh()
+	if err != nil {
+		return nil, err
+	}
+
+	script, err := NewP2pkhScript(pkh)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*Script{script}, nil
+}
+
+type wpkhDescriptor struct {
+	key *descriptorKey
+}
+
+func (desc *wpkhDescriptor) String() string {
+	return joinDescriptorArgs("wpkh", desc.key.String())
+}
+
+func (desc *wpkhDescriptor) scripts(index uint32) ([]*Script, error) {
+	pubKey, err := desc.key.publicKey(index)
+	if err != nil {
+		return nil, err
+	}
+
+	pkh, err := pubKey.Pkh()
+	if err != nil {
+		return nil, err
+	}
+
+	script, err := NewP2wpkhScript(pkh)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*Script{script}, nil
+}
+
+type comboDescriptor struct {
+	key *descriptorKey
+}
+
+func (desc *comboDescriptor) String() string {
+	return joinDescriptorArgs("combo", desc.key.String())
+}
+
+// scripts returns the P2PK and P2PKH scripts of the key,
+// followed by the P2WPKH and P2SH-P2WPKH ones if it is compressed.
+func (desc *comboDescriptor) scripts(index uint32) ([]*Script, error) {
+	pubKey, err := desc.key.publicKey(index)
+	if err != nil {
+		return nil, err
+	}
+
+	p2pk, err := NewP2pkScript(pubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	pkh, err := pubKey.Pkh()
+	if err != nil {
+		return nil, err
+	}
+
+	p2pkh, err := NewP2pkhScript(pkh)
+	if err != nil {
+		return nil, err
+	}
+
+	if !pubKey.IsCompressed() {
+		return []*Script{p2pk, p2pkh}, nil
+	}
+
+	p2wpkh, err := NewP2wpkhScript(pkh)
+	if err != nil {
+		return nil, err
+	}
+
+	scriptHash, err := p2wpkh.ScriptHash()
+	if err != nil {
+		return nil, err
+	}
+
+	p2shP2wpkh, err := NewP2shScript(scriptHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*Script{p2pk, p2pkh, p2wpkh, p2shP2wpkh}, nil
+}
+
+type multiDescriptor struct {
+	m      int
+	keys   []*descriptorKey
+	sorted bool
+}
+
+func (desc *multiDescriptor) String() string {
+	name := "multi"
+	if desc.sorted {
+		name = "sortedmulti"
+	}
+
+	args := []string{strconv.Itoa(desc.m)}
+	for _, key := range desc.keys {
+		args = append(args, key.String())
+	}
+
+	return joinDescriptorArgs(name, args...)
+}
+
+func (desc *multiDescriptor) scripts(index uint32) ([]*Script, error) {
+	pubKeys := make([]*PublicKey, len(desc.keys))
+	for i, key := range desc.keys {
+		pubKey, err := key.publicKey(index)
+		if err != nil {
+			return nil, err
+		}
+		pubKeys[i] = pubKey
+	}
+
+	// ref. https://github.com/bitcoin/bips/blob/master/bip-0067.mediawiki
+	if desc.sorted {
+		sort.Slice(pubKeys, func(i, j int) bool {
+			return bytes.Compare(pubKeys[i].Bytes(), pubKeys[j].Bytes()) < 0
+		})
+	}
+
+	script, err := NewMultisigScript(desc.m, pubKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*Script{script}, nil
+}
+
+type shDescriptor struct {
+	sub descriptorNode
+}
+
+func (desc *shDescriptor) String() string {
+	return joinDescriptorArgs("sh", desc.sub.String())
+}
+
+func (desc *shDescriptor) scripts(index uint32) ([]*Script, error) {
+	redeemScript, err := singleScript(desc.sub, index)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := redeemScript.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxP2shScriptSize {
+		return nil, ErrP2shScriptTooLarge
+	}
+
+	scriptHash, err := redeemScript.ScriptHash()
+	if err != nil {
+		return nil, err
+	}
+
+	script, err := NewP2shScript(scriptHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*Script{script}, nil
+}
+
+type wshDescriptor struct {
+	sub descriptorNode
+}
+
+func (desc *wshDescriptor) String() string {
+	return joinDescriptorArgs("wsh", desc.sub.String())
+}
+
+func (desc *wshDescriptor) scripts(index uint32) ([]*Script, error) {
+	witnessScript, err := singleScript(desc.sub, index)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := witnessScript.WitnessScriptHash()
+	if err != nil {
+		return nil, err
+	}
+
+	script, err := NewP2wshScript(h)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*Script{script}, nil
+}
+
+type trDescriptor struct {
+	key  *descriptorKey
+	tree *tapTreeDescriptor
+}
+
+func (desc *trDescriptor) String() string {
+	if desc.tree == nil {
+		return joinDescriptorArgs("tr", desc.key.String())
+	}
+
+	return joinDescriptorArgs("tr", desc.key.String(), desc.tree.String())
+}
+
+func (desc *trDescriptor) scripts(index uint32) ([]*Script, error) {
+	pubKey, err := desc.key.publicKey(index)
+	if err != nil {
+		return nil, err
+	}
+
+	var merkleRoot []byte
+	if desc.tree != nil {
+		if merkleRoot, err = desc.tree.hash(index); err != nil {
+			return nil, err
+		}
+	}
+
+	outputKey, err := TaprootOutputKey(pubKey.XOnly(), merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	script, err := NewP2trScript(outputKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*Script{script}, nil
+}
+
+// tapTreeDescriptor is either a leaf script or a branch of two subtrees.
+type tapTreeDescriptor struct {
+	leaf        descriptorNode
+	left, right *tapTreeDescriptor
+}
+
+func (tree *tapTreeDescriptor) String() string {
+	if tree.leaf != nil {
+		return tree.leaf.String()
+	}
+
+	return "{" + tree.left.String() + "," + tree.right.String() + "}"
+}
+
+func (tree *tapTreeDescriptor) hash(index uint32) ([]byte, error) {
+	if tree.leaf != nil {
+		script, err := singleScript(tree.leaf, index)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := script.Bytes()
+		if err != nil {
+			return nil, err
+		}
+
+		return TapLeafHash(TapLeafVersionTapscript, b)
+	}
+
+	left, err := tree.left.hash(index)
+	if err != nil {
+		return nil, err
+	}
+	right, err := tree.right.hash(index)
+	if err != nil {
+		return nil, err
+	}
+
+	return TapBranchHash(left, right)
+}
+
+type addrDescriptor struct {
+	address string
+	script  *Script
+}
+
+func (desc *addrDescriptor) String() string {
+	return joinDescriptorArgs("addr", desc.address)
+}
+
+func (desc *addrDescriptor) scripts(index uint32) ([]*Script, error) {
+	return []*Script{desc.script}, nil
+}
+
+type rawDescriptor struct {
+	script *Script
+}
+
+func (desc *rawDescriptor) String() string {
+	return joinDescriptorArgs("raw", desc.script.Hex)
+}
+
+func (desc *rawDescriptor) scripts(index uint32) ([]*Script, error) {
+	return []*Script{desc.script}, nil
+}
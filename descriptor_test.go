@@ -0,0 +1,444 @@
+package btc
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// master key of testHdSeed
+const testHdMasterKey = "xprv9s21ZrQH143K3GJpoapnV8SFfukcVBSfeCficPSGfubmSFDxo1kuHnLisriDvSnRRuL2Qrg5ggqHKNVpxR86QEC8w35uxmGoggxtQTPvfUu"
+
+func TestDescriptorChecksum(t *testing.T) {
+	testCases := []struct {
+		s        string
+		checksum string
+	}{
+		// ref. https://github.com/bitcoin/bips/blob/master/bip-0380.mediawiki#test-vectors
+		{"raw(deadbeef)", "89f8spxm"},
+		// ref. https://github.com/bitcoin/bips/blob/master/bip-0385.mediawiki#test-vectors
+		{"addr(mkmZxiEcEd8ZqjQWVZuC6so5dFMKEFpN2j)", "02wpgw69"},
+		// ref. https://github.com/bitcoin/bitcoin/blob/master/doc/descriptors.md#checksums
+		{"pkh([d34db33f/44'/0'/0']xpub6ERApfZwUNrhLCkDtcHTcxd75RbzS1ed54G1LkBUHQVHQKqhMkhgbmJbZRkrgZw4koxb5JaHWkY4ALHY2grBGRjaDMzQLcgJvLJuZZvRcEL/1/*)", "ml40v0wf"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.s, func(t *testing.T) {
+			checksum, err := DescriptorChecksum(tc.s)
+			require.NoError(t, err)
+			assert.Equal(t, checksum, tc.checksum)
+		})
+	}
+}
+
+func TestDescriptor(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		params    *Params
+		isRange   bool
+		index     uint32
+		scripts   []string
+		addresses []Address
+	}{
+		{
+			"pk(0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798)",
+			MainNetParams,
+			false,
+			0,
+			[]string{"210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac"},
+			nil,
+		},
+		{
+			"pkh(02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5)",
+			MainNetParams,
+			false,
+			0,
+			[]string{"76a91406afd46bcdfd22ef94ac122aa11f241244a37ecc88ac"},
+			[]Address{"1cMh228HTCiwS8ZsaakH8A8wze1JR5ZsP"},
+		},
+		{
+			"pkh(L4rK1yDtCWekvXuE6oXD9jCYfFNV2cWRpVuPLBcCU2z8TrisoyY1)",
+			MainNetParams,
+			false,
+			0,
+			[]string{"76a9149a1c78a507689f6f54b847ad1cef1e614ee23f1e88ac"},
+			[]Address{"1F3sAm6ZtwLAUnj7d38pGFxtP3RVEvtsbV"},
+		},
+		{
+			"wpkh(02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9)",
+			MainNetParams,
+			false,
+			0,
+			[]string{"00147dd65592d0ab2fe0d0257d571abf032cd9db93dc"},
+			[]Address{"bc1q0ht9tyks4vh7p5p904t340cr9nvahy7u3re7zg"},
+		},
+		{
+			"sh(wpkh(02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9))",
+			MainNetParams,
+			false,
+			0,
+			[]string{"a91469ea5ff598a286f418ae77503ce85d83da4ae88e87"},
+			[]Address{"3BM3eLQZbwubG3XwwxJmd9qxwMJn7yUTSn"},
+		},
+		{
+			"combo(0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798)",
+			MainNetParams,
+			false,
+			0,
+			[]string{
+				"210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac",
+				"76a914751e76e8199196d454941c45d1b3a323f1433bd688ac",
+				"0014751e76e8199196d454941c45d1b3a323f1433bd6",
+				"a914bcfeb728b584253d5f3f70bcb780e9ef218a68f487",
+			},
+			[]Address{"1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAMH", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", "3JvL6Ymt8MVWiCNHC7oWU6nLeHNJKLZGLN"},
+		},
+		{
+			"combo(0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8)",
+			MainNetParams,
+			false,
+			0,
+			[]string{
+				"410479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8ac",
+				"76a91491b24bf9f5288532960ac687abb035127b1d28a588ac",
+			},
+			[]Address{"1EHNa6Q4Jz2uvNExL497mE43ikXhwF6kZm"},
+		},
+		{
+			"sh(multi(2,02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5,0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798))",
+			MainNetParams,
+			false,
+			0,
+			[]string{"a914c39c1e979cab943770ea6690fc10537c79326f4787"},
+			[]Address{"3KXJiKjSLBSvrJsLuffJd1Bg8UtXyGtaig"},
+		},
+		{
+			"sh(sortedmulti(2,02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5,0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798))",
+			MainNetParams,
+			false,
+			0,
+			[]string{"a91412fcac201d73f5b5dba0f1f22c40f02da17bb4a487"},
+			[]Address{"33RQmypKhD6f4tMquiR5a3C6dRT7eBpaiG"},
+		},
+		{
+			"wsh(multi(1,0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798,02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5,02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9))",
+			MainNetParams,
+			false,
+			0,
+			[]string{"00200baa63403867aa9ebc1740ae22be1c3c503f272c1eb172221fbb3d228bcaf9f9"},
+			[]Address{"bc1qpw4xxspcv74fa0qhgzhz90su83gr7fevr6chygslhv7j9z72l8usnlfr59"},
+		},
+		{
+			"sh(wsh(pkh(02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5)))",
+			MainNetParams,
+			false,
+			0,
+			[]string{"a9141def75e1dd672e63f5fd8490c197e08c360784e487"},
+			[]Address{"34RJP7UjSNxhfxNUdD97MKffPaD39u7Abp"},
+		},
+		{
+			"tr(79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798)",
+			MainNetParams,
+			false,
+			0,
+			[]string{"5120da4710964f7852695de2da025290e24af6d8c281de5a0b902b7135fd9fd74d21"},
+			[]Address{"bc1pmfr3p9j00pfxjh0zmgp99y8zftmd3s5pmedqhyptwy6lm87hf5sspknck9"},
+		},
+		{
+			"tr(c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5,{pk(0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798),{pk(f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9),pk(c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5)}})",
+			MainNetParams,
+			false,
+			0,
+			[]string{"512068a3c7f103df98f4ec5a48980a594d6be50093958d20f7dbd64f9916b6be2977"},
+			[]Address{"bc1pdz3u0ugrm7v0fmz6fzvq5k2dd0jspyu435s00k7kf7v3dd4799msfx6wp8"},
+		},
+		{
+			"addr(mkmZxiEcEd8ZqjQWVZuC6so5dFMKEFpN2j)",
+			TestNet3Params,
+			false,
+			0,
+			[]string{"76a914399c39ac90dac26965fb55fdb2035e6715fdac4e88ac"},
+			[]Address{"mkmZxiEcEd8ZqjQWVZuC6so5dFMKEFpN2j"},
+		},
+		{
+			"raw(deadbeef)",
+			MainNetParams,
+			false,
+			0,
+			[]string{"deadbeef"},
+			nil,
+		},
+		{
+			"pkh([73c5da0a/44'/0'/0']xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj/1/*)",
+			MainNetParams,
+			true,
+			5,
+			[]string{"76a91414258605f3a0e65ce7ddc0d06fb80ede5ed4f26188ac"},
+			[]Address{"12qXU1zQ8pQuRS6p1k9Wc8sSDyPhPtsZe2"},
+		},
+		{
+			"wpkh(" + testHdMasterKey + "/84h/0h/0h/0/*)",
+			MainNetParams,
+			true,
+			1,
+			[]string{"00149c90f934ea51fa0f6504177043e0908da6929983"},
+			[]Address{"bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g"},
+		},
+		{
+			"tr(" + testHdMasterKey + "/86'/0'/0'/0/*)",
+			MainNetParams,
+			true,
+			0,
+			[]string{"5120a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c"},
+			[]Address{"bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr"},
+		},
+		{
+			"pkh(" + testHdMasterKey + "/0'/*')",
+			MainNetParams,
+			true,
+			4,
+			[]string{"76a9145d510bf5bd96c95de5c8bcb085861fb7ec5144a888ac"},
+			[]Address{"19WQw4mVNFkjmrt6ti9A3XjydrTATMQfAv"},
+		},
+		{
+			"wsh(sortedmulti(1,[73c5da0a/48'/0'/0'/2']xpub6DkFAXWQ2dHxq2vatrt9qyA3bXYU4ToWQwCHbf5XB2mSTexcHZCeKS1VZYcPoBd5X8yVcbXFHJR9R8UCVpt82VX1VhR28mCyxUFL4r6KFrf/0/*,[73c5da0a/48'/0'/1'/2']xpub6DzhyrnFFYQ1HimDiM388xHnDiRPNdZJFBmmxge3Y1WWcHLtMJLfRuhRHqnQCPbTj3fGKTuKFLHzzwpJkp5Dtc3UtLKZKaVZe1yqMBXd6Vk/0/*))",
+			MainNetParams,
+			true,
+			1,
+			[]string{"00201bc00cc1755d97df4749bbb2774498935891a53662e779846c8650a2fddbeb51"},
+			[]Address{"bc1qr0qqest4tkta736fhwe8w3ycjdvfrffkvtnhnprvseg29lwmadgsvsh7ad"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			desc, err := ParseDescriptor(tc.desc, tc.params)
+			require.NoError(t, err)
+			assert.Equal(t, desc.IsRange(), tc.isRange)
+
+			checksum, err := DescriptorChecksum(tc.desc)
+			require.NoError(t, err)
+			assert.Equal(t, desc.String(), tc.desc+"#"+checksum)
+
+			// the checksum is verified if present
+			desc, err = ParseDescriptor(desc.String(), tc.params)
+			require.NoError(t, err)
+
+			scripts, err := desc.Scripts(tc.index)
+			require.NoError(t, err)
+
+			scriptHexes := make([]string, len(scripts))
+			for i, script := range scripts {
+				scriptHexes[i] = script.Hex
+			}
+			assert.Equal(t, scriptHexes, tc.scripts)
+
+			addresses, err := desc.Addresses(tc.index)
+			if tc.addresses == nil {
+				assert.Equal(t, err, ErrScriptHasNoAddress)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, addresses, tc.addresses)
+		})
+	}
+}
+
+func TestDescriptorErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		desc string
+		err  error
+	}{
+		{
+			"invalid checksum",
+			"raw(deadbeef)#89f8spxn",
+			ErrInvalidDescriptorChecksum,
+		},
+		{
+			"truncated checksum",
+			"raw(deadbeef)#89f8spx",
+			ErrInvalidDescriptorChecksum,
+		},
+		{
+			"invalid character",
+			"raw(deadbeef)é",
+			ErrInvalidDescriptorChar,
+		},
+		{
+			"unknown function",
+			"foo(deadbeef)",
+			ErrInvalidDescriptor,
+		},
+		{
+			"unbalanced brackets",
+			"sh(wpkh(02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9)",
+			ErrInvalidDescriptor,
+		},
+		{
+			"nested sh",
+			"sh(sh(pkh(02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5)))",
+			ErrInvalidDescriptorContext,
+		},
+		{
+			"wpkh in wsh",
+			"wsh(wpkh(02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9))",
+			ErrInvalidDescriptorContext,
+		},
+		{
+			"combo in sh",
+			"sh(combo(02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9))",
+			ErrInvalidDescriptorContext,
+		},
+		{
+			"tr in wsh",
+			"wsh(tr(79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798))",
+			ErrInvalidDescriptorContext,
+		},
+		{
+			"uncompressed key in wpkh",
+			"wpkh(0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8)",
+			ErrUncompressedDescriptorKey,
+		},
+		{
+			"uncompressed key in wsh",
+			"wsh(pk(0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8))",
+			ErrUncompressedDescriptorKey,
+		},
+		{
+			"x-only key outside tr",
+			"pk(79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798)",
+			ErrInvalidDescriptorKey,
+		},
+		{
+			"invalid fingerprint",
+			"pkh([73c5da/44'/0'/0']xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj/1/*)",
+			ErrInvalidDescriptorKey,
+		},
+		{
+			"hardened derivation from xpub",
+			"pkh(xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj/1'/*)",
+			ErrHardenedFromPublicKey,
+		},
+		{
+			"hardened wildcard from xpub",
+			"pkh(xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj/1/*')",
+			ErrHardenedFromPublicKey,
+		},
+		{
+			"invalid multisig threshold",
+			"multi(3,0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798,02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5)",
+			ErrInvalidMultisig,
+		},
+		{
+			"address network mismatch",
+			"addr(mkmZxiEcEd8ZqjQWVZuC6so5dFMKEFpN2j)",
+			ErrAddressNetworkMismatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseDescriptor(tc.desc, MainNetParams)
+			assert.Equal(t, err, tc.err)
+		})
+	}
+}
+
+func TestDescriptorMultisigLimits(t *testing.T) {
+	// 1G to 20G
+	pubKeys := []string{
+		"0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+		"02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5",
+		"02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9",
+		"02e493dbf1c10d80f3581e4904930b1404cc6c13900ee0758474fa94abe8c4cd13",
+		"022f8bde4d1a07209355b4a7250a5c5128e88b84bddc619ab7cba8d569b240efe4",
+		"03fff97bd5755eeea420453a14355235d382f6472f8568a18b2f057a1460297556",
+		"025cbdf0646e5db4eaa398f365f2ea7a0e3d419b7e0330e39ce92bddedcac4f9bc",
+		"022f01e5e15cca351daff3843fb70f3c2f0a1bdd05e5af888a67784ef3e10a2a01",
+		"03acd484e2f0c7f65309ad178a9f559abde09796974c57e714c35f110dfc27ccbe",
+		"03a0434d9e47f3c86235477c7b1ae6ae5d3442d49b1943c2b752a68e2a47e247c7",
+		"03774ae7f858a9411e5ef4246b70c65aac5649980be5c17891bbec17895da008cb",
+		"03d01115d548e7561b15c38f004d734633687cf4419620095bc5b0f47070afe85a",
+		"03f28773c2d975288bc7d1d205c3748651b075fbc6610e58cddeeddf8f19405aa8",
+		"03499fdf9e895e719cfd64e67f07d38e3226aa7b63678949e6e49b241a60e823e4",
+		"02d7924d4f7d43ea965a465ae3095ff41131e5946f3c85f79e44adbcf8e27e080e",
+		"03e60fce93b59e9ec53011aabc21c23e97b2a31369b87a5ae9c44ee89e2a6dec0a",
+		"03defdea4cdb677750a420fee807eacf21eb9898ae79b9768766e4faa04a2d4a34",
+		"025601570cb47f238d2b0286db4a990fa0f3ba28d1a319f5e7cf55c2a2444da7cc",
+		"022b4ea0a797a443d293ef5cff444f4979f06acfebd7e86d277475656138385b6c",
+		"024ce119c96e2fa357200b559b2f7dd5a5f02d5290aff74b03f3e471b273211c97",
+	}
+	multi := func(m, n int) string {
+		return fmt.Sprintf("multi(%d,%s)", m, strings.Join(pubKeys[:n], ","))
+	}
+
+	testCases := []struct {
+		name   string
+		desc   string
+		script string
+		err    error
+	}{
+		{
+			"2-of-17 in wsh",
+			"wsh(" + multi(2, 17) + ")",
+			"0020e89260e837c55781687e9a06c1e2a8e6ef7681120d7e133a6dae261dd2996781",
+			nil,
+		},
+		{
+			"20-of-20 in wsh",
+			"wsh(" + multi(20, 20) + ")",
+			"00205e396e66e050f50cb1434ab5bfcdb0194eb586e159e6c8bf3226c046407e76b0",
+			nil,
+		},
+		{
+			"21 keys in wsh",
+			"wsh(multi(1," + strings.Join(append(pubKeys, pubKeys[0]), ",") + "))",
+			"",
+			ErrInvalidMultisig,
+		},
+		{
+			"3 keys in bare multi",
+			multi(1, 3),
+			"51210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f817982102c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee52102f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f953ae",
+			nil,
+		},
+		{
+			"4 keys in bare multi",
+			multi(1, 4),
+			"",
+			ErrInvalidMultisig,
+		},
+		{
+			"15 compressed keys in sh",
+			"sh(" + multi(1, 15) + ")",
+			"a9140446c4e30a9334f3a4450220f4e4ede696f7a5d987",
+			nil,
+		},
+		{
+			"16 compressed keys in sh",
+			"sh(" + multi(1, 16) + ")",
+			"",
+			ErrP2shScriptTooLarge,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			desc, err := ParseDescriptor(tc.desc, MainNetParams)
+			if tc.err != nil {
+				assert.Equal(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+
+			scripts, err := desc.Scripts(0)
+			require.NoError(t, err)
+			require.Len(t, scripts, 1)
+			assert.Equal(t, scripts[0].Hex, tc.script)
+		})
+	}
+}
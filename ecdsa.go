@@ -0,0 +1,367 @@
+package btc
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/big"
+)
+
+const (
+	SigHashLength       = 32
+	CompactSigLength    = 65
+	compactSigMagic     = 27
+	compactSigCompMagic = 4
+
+	derSequenceTag byte = 0x30
+	derIntegerTag  byte = 0x02
+)
+
+var (
+	ErrInvalidSignature  = errors.New("invalid signature")
+	ErrInvalidHashLength = errors.New("invalid hash length")
+	ErrRecoveryFailed    = errors.New("public key recovery failed")
+)
+
+type Signature struct {
+	r *big.Int
+	s *big.Int
+}
+
+func NewSignatureFromHex(s string) (*Signature, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSignatureFromBytes(b)
+}
+
+// NewSignatureFromBytes parses a strict DER encoded signature without a sighash type.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0066.mediawiki
+func NewSignatureFromBytes(b []byte) (*Signature, error) {
+	if len(b) < 8 || len(b) > 72 {
+		return nil, ErrInvalidSignature
+	}
+	if b[0] != derSequenceTag || int(b[1]) != len(b)-2 {
+		return nil, ErrInvalidSignature
+	}
+
+	rLen := int(b[3])
+	if 5+rLen >= len(b) {
+		return nil, ErrInvalidSignature
+	}
+	sLen := int(b[5+rLen])
+	if rLen+sLen+6 != len(b) {
+		return nil, ErrInvalidSignature
+	}
+
+	rb := b[4 : 4+rLen]
+	if b[2] != derIntegerTag || !isValidDerInteger(rb) {
+		return nil, ErrInvalidSignature
+	}
+	sb := b[6+rLen:]
+	if b[4+rLen] != derIntegerTag || !isValidDerInteger(sb) {
+		return nil, ErrInvalidSignature
+	}
+
+	sig := &Signature{
+		r: new(big.Int).SetBytes(rb),
+		s: new(big.Int).SetBytes(sb),
+	}
+	if !sig.isInRange() {
+		return nil, ErrInvalidSignature
+	}
+
+	return sig, nil
+}
+
+func isValidDerInteger(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+	// negative
+	if b[0]&0x80 != 0 {
+		return false
+	}
+	// excessive padding
+	if len(b) > 1 && b[0] == 0x00 && b[1]&0x80 == 0 {
+		return false
+	}
+
+	return true
+}
+
+func (sig *Signature) R() *big.Int {
+	return new(big.Int).Set(sig.r)
+}
+
+func (sig *Signature) S() *big.Int {
+	return new(big.Int).Set(sig.s)
+}
+
+func (sig *Signature) isInRange() bool {
+	return sig.r.Sign() > 0 && sig.r.Cmp(secp256k1.N) < 0 &&
+		sig.s.Sign() > 0 && sig.s.Cmp(secp256k1.N) < 0
+}
+
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0062.mediawiki#low-s-values-in-signatures
+func (sig *Signature) IsLowS() bool {
+	return sig.s.Cmp(secp256k1.halfN) <= 0
+}
+
+func (sig *Signature) LowS() *Signature {
+	if sig.IsLowS() {
+		return sig
+	}
+
+	return &Signature{
+		r: sig.r,
+		s: new(big.Int).Sub(secp256k1.N, sig.s),
+	}
+}
+
+// Bytes returns the DER encoding of the signature.
+func (sig *Signature) Bytes() []byte {
+	rb := derInteger(sig.r)
+	sb := derInteger(sig.s)
+
+	b := make([]byte, 0, 6+len(rb)+len(sb))
+	b = append(b, derSequenceTag, byte(4+len(rb)+len(sb)))
+	b = append(b, derIntegerTag, byte(len(rb)))
+	b = append(b, rb...)
+	b = append(b, derIntegerTag, byte(len(sb)))
+
+	return append(b, sb...)
+}
+
+func derInteger(n *big.Int) []byte {
+	b := n.Bytes()
+	if len(b) == 0 || b[0]&0x80 != 0 {
+		b = append([]byte{0x00}, b...)
+	}
+
+	return b
+}
+
+func (sig *Signature) Hex() string {
+	return hex.EncodeToString(sig.Bytes())
+}
+
+// Sign returns a low-S ECDSA signature of hash with an RFC6979 deterministic nonce.
+func (privKey *PrivateKey) Sign(hash []byte) (*Signature, error) {
+	sig, _, err := privKey.sign(hash)
+
+	return sig, err
+}
+
+func (privKey *PrivateKey) sign(hash []byte) (*Signature, byte, error) {
+	if len(hash) != SigHashLength {
+		return nil, 0, ErrInvalidHashLength
+	}
+
+	n := secp256k1.N
+	z := hashToInt(hash)
+
+	nonces := newRfc6979(privKey.Bytes(), paddedBytes(z, 32))
+	for {
+		k := nonces.next()
+
+		rx, ry := secp256k1.ScalarBaseMult(k)
+
+		r := new(big.Int).Mod(rx, n)
+		if r.Sign() == 0 {
+			continue
+		}
+
+		s := new(big.Int).Mul(r, privKey.d)
+		s.Add(s, z)
+		s.Mul(s, new(big.Int).ModInverse(k, n))
+		s.Mod(s, n)
+		if s.Sign() == 0 {
+			continue
+		}
+
+		recID := byte(ry.Bit(0))
+		if rx.Cmp(n) >= 0 {
+			recID |= 0x02
+		}
+
+		sig := &Signature{r, s}
+		if !sig.IsLowS() {
+			sig = sig.LowS()
+			recID ^= 0x01
+		}
+
+		return sig, recID, nil
+	}
+}
+
+func (pubKey *PublicKey) Verify(hash []byte, sig *Signature) bool {
+	if len(hash) != SigHashLength || !sig.isInRange() {
+		return false
+	}
+
+	n := secp256k1.N
+	z := hashToInt(hash)
+
+	w := new(big.Int).ModInverse(sig.s, n)
+
+	u1 := new(big.Int).Mul(z, w)
+	u1.Mod(u1, n)
+	u2 := new(big.Int).Mul(sig.r, w)
+	u2.Mod(u2, n)
+
+	x1, y1 := secp256k1.ScalarBaseMult(u1)
+	x2, y2 := secp256k1.ScalarMult(pubKey.x, pubKey.y, u2)
+	x, _ := secp256k1.Add(x1, y1, x2, y2)
+	if x == nil {
+		return false
+	}
+
+	return x.Mod(x, n).Cmp(sig.r) == 0
+}
+
+// SignCompact returns a 65 byte recoverable signature of hash.
+// The first byte encodes the recovery id and whether the public key is compressed.
+func (privKey *PrivateKey) SignCompact(hash []byte, compressed bool) ([]byte, error) {
+	sig, recID, err := privKey.sign(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	header := compactSigMagic + recID
+	if compressed {
+		header += compactSigCompMagic
+	}
+
+	b := make([]byte, 0, CompactSigLength)
+	b = append(b, header)
+	b = append(b, paddedBytes(sig.r, 32)...)
+
+	return append(b, paddedBytes(sig.s, 32)...), nil
+}
+
+// RecoverCompact returns the public key that produced the compact signature of hash.
+// ref. https://www.secg.org/sec1-v2.pdf (4.1.6)
+func RecoverCompact(compactSig []byte, hash []byte) (*PublicKey, error) {
+	if len(hash) != SigHashLength {
+		return nil, ErrInvalidHashLength
+	}
+	if len(compactSig) != CompactSigLength {
+		return nil, ErrInvalidSignature
+	}
+
+	header := compactSig[0]
+	if header < compactSigMagic || header >= compactSigMagic+2*compactSigCompMagic {
+		return nil, ErrInvalidSignature
+	}
+	recID := (header - compactSigMagic) & 0x03
+	compressed := (header-compactSigMagic)&compactSigCompMagic != 0
+
+	sig := &Signature{
+		r: new(big.Int).SetBytes(compactSig[1:33]),
+		s: new(big.Int).SetBytes(compactSig[33:]),
+	}
+	if !sig.isInRange() {
+		return nil, ErrInvalidSignature
+	}
+
+	n := secp256k1.N
+
+	rx := new(big.Int).Set(sig.r)
+	if recID&0x02 != 0 {
+		rx.Add(rx, n)
+		if rx.Cmp(secp256k1.P) >= 0 {
+			return nil, ErrRecoveryFailed
+		}
+	}
+	ry := secp256k1.DecompressY(rx, recID&0x01 != 0)
+	if ry == nil {
+		return nil, ErrRecoveryFailed
+	}
+
+	// Q = r^-1 * (s*R - z*G)
+	rInv := new(big.Int).ModInverse(sig.r, n)
+
+	u1 := new(big.Int).Neg(hashToInt(hash))
+	u1.Mul(u1, rInv)
+	u1.Mod(u1, n)
+	u2 := new(big.Int).Mul(sig.s, rInv)
+	u2.Mod(u2, n)
+
+	x1, y1 := secp256k1.ScalarBaseMult(u1)
+	x2, y2 := secp256k1.ScalarMult(rx, ry, u2)
+	x, y := secp256k1.Add(x1, y1, x2, y2)
+	if x == nil {
+		return nil, ErrRecoveryFailed
+	}
+
+	pubKey := &PublicKey{
+		x:            x,
+		y:            y,
+		uncompressed: !compressed,
+	}
+	if !pubKey.Verify(hash, sig) {
+		return nil, ErrRecoveryFailed
+	}
+
+	return pubKey, nil
+}
+
+func hashToInt(hash []byte) *big.Int {
+	z := new(big.Int).SetBytes(hash)
+
+	return z.Mod(z, secp256k1.N)
+}
+
+// ref. https://tools.ietf.org/html/rfc6979#section-3.2
+type rfc6979 struct {
+	k []byte
+	v []byte
+
+	first bool
+}
+
+func newRfc6979(x, h1 []byte) *rfc6979 {
+	g := &rfc6979{
+		k:     make([]byte, 32),
+		v:     bytes.Repeat([]byte{0x01}, 32),
+		first: true,
+	}
+
+	g.k = g.hmac(g.k, g.v, []byte{0x00}, x, h1)
+	g.v = g.hmac(g.k, g.v)
+	g.k = g.hmac(g.k, g.v, []byte{0x01}, x, h1)
+	g.v = g.hmac(g.k, g.v)
+
+	return g
+}
+
+func (g *rfc6979) hmac(key []byte, data ...[]byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	for _, d := range data {
+		mac.Write(d)
+	}
+
+	return mac.Sum(nil)
+}
+
+func (g *rfc6979) next() *big.Int {
+	for {
+		if !g.first {
+			g.k = g.hmac(g.k, g.v, []byte{0x00})
+			g.v = g.hmac(g.k, g.v)
+		}
+		g.first = false
+
+		g.v = g.hmac(g.k, g.v)
+
+		k := new(big.Int).SetBytes(g.v)
+		if k.Sign() > 0 && k.Cmp(secp256k1.N) < 0 {
+			return k
+		}
+	}
+}
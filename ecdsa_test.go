@@ -0,0 +1,167 @@
+package btc
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSign(t *testing.T) {
+	testCases := []struct {
+		privKey    string
+		hash       string
+		sig        string
+		compactSig string
+	}{
+		{
+			"0000000000000000000000000000000000000000000000000000000000000001",
+			// sha256("Satoshi Nakamoto")
+			"a0dc65ffca799873cbea0ac274015b9526505daaaed385155425f7337704883e",
+			"3045022100934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d802202442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5",
+			"20934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d82442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5",
+		},
+		{
+			"fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140",
+			// sha256("Satoshi Nakamoto")
+			"a0dc65ffca799873cbea0ac274015b9526505daaaed385155425f7337704883e",
+			"3045022100fd567d121db66e382991534ada77a6bd3106f0a1098c231e47993447cd6af2d002206b39cd0eb1bc8603e159ef5c20a5c8ad685a45b06ce9bebed3f153d10d93bed5",
+			"1ffd567d121db66e382991534ada77a6bd3106f0a1098c231e47993447cd6af2d06b39cd0eb1bc8603e159ef5c20a5c8ad685a45b06ce9bebed3f153d10d93bed5",
+		},
+		{
+			"f8b8af8ce3c7cca5e300d33939540c10d45ce001b8f252bfbc57ba0342904181",
+			// sha256("Alan Turing")
+			"4ba38d48a60f1b29e9eb726eaff08b2e83d8d81e031666fee50e85900d7dc1ef",
+			"304402207063ae83e7f62bbb171798131b4a0564b956930092b33b07b395615d9ec7e15c022058dfcc1e00a35e1572f366ffe34ba0fc47db1e7189759b9fb233c5b05ab388ea",
+			"1f7063ae83e7f62bbb171798131b4a0564b956930092b33b07b395615d9ec7e15c58dfcc1e00a35e1572f366ffe34ba0fc47db1e7189759b9fb233c5b05ab388ea",
+		},
+		{
+			"e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35",
+			// sha256("All those moments will be lost in time, like tears in rain. Time to die...")
+			"7d1833f54854ac51659521afcd0ec6dca2ce2351429614bfa28a756b1b3c637f",
+			"3044022056a85e89071eaaf97806a6d6f2df4a2cbd4cb8600b56b14542f516708a64e8310220652adc250ccd6c7c653f1a7ba4e7f2cfd432f1a108f590e6b00e33b1dfd93d93",
+			"2056a85e89071eaaf97806a6d6f2df4a2cbd4cb8600b56b14542f516708a64e831652adc250ccd6c7c653f1a7ba4e7f2cfd432f1a108f590e6b00e33b1dfd93d93",
+		},
+		{
+			"18e14a7b6a307f426a94f8114701e7c8e774e7f9a47e2c2035db29a206321725",
+			// sha256("hello")
+			"2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+			"30440220620fe351f4226dfafeee8bf02523741168f923b4e8853dc3d6b69bf87f63745502205b29b49c3dcd8bc40a1ceae8e1e5cdd58e7589aa2ba41ef68b366a4e2810b824",
+			"1f620fe351f4226dfafeee8bf02523741168f923b4e8853dc3d6b69bf87f6374555b29b49c3dcd8bc40a1ceae8e1e5cdd58e7589aa2ba41ef68b366a4e2810b824",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.privKey, func(t *testing.T) {
+			privKey, err := NewPrivateKeyFromHex(tc.privKey)
+			require.NoError(t, err)
+			pubKey := privKey.PublicKey()
+
+			hash, err := hex.DecodeString(tc.hash)
+			require.NoError(t, err)
+
+			// DER
+			sig, err := privKey.Sign(hash)
+			require.NoError(t, err)
+			assert.True(t, sig.IsLowS())
+			assert.Equal(t, sig.Hex(), tc.sig)
+			assert.True(t, pubKey.Verify(hash, sig))
+
+			parsed, err := NewSignatureFromHex(tc.sig)
+			require.NoError(t, err)
+			assert.Equal(t, parsed.Hex(), tc.sig)
+			assert.True(t, pubKey.Verify(hash, parsed))
+
+			// compact
+			compactSig, err := privKey.SignCompact(hash, true)
+			require.NoError(t, err)
+			assert.Equal(t, hex.EncodeToString(compactSig), tc.compactSig)
+
+			recovered, err := RecoverCompact(compactSig, hash)
+			require.NoError(t, err)
+			assert.True(t, recovered.IsCompressed())
+			assert.True(t, recovered.IsEqual(pubKey))
+
+			compactSig, err = privKey.SignCompact(hash, false)
+			require.NoError(t, err)
+			recovered, err = RecoverCompact(compactSig, hash)
+			require.NoError(t, err)
+			assert.False(t, recovered.IsCompressed())
+			assert.True(t, recovered.IsEqual(pubKey))
+
+			// wrong hash
+			hash[0] ^= 0x01
+			assert.False(t, pubKey.Verify(hash, sig))
+		})
+	}
+}
+
+func TestSignatureLowS(t *testing.T) {
+	sig, err := NewSignatureFromHex("3045022100934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d802202442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5")
+	require.NoError(t, err)
+
+	highS := &Signature{
+		r: sig.R(),
+		s: sig.S().Sub(secp256k1.N, sig.S()),
+	}
+	assert.False(t, highS.IsLowS())
+	assert.Equal(t, highS.LowS().Hex(), sig.Hex())
+
+	hash, err := hex.DecodeString("a0dc65ffca799873cbea0ac274015b9526505daaaed385155425f7337704883e")
+	require.NoError(t, err)
+	privKey, err := NewPrivateKeyFromHex("0000000000000000000000000000000000000000000000000000000000000001")
+	require.NoError(t, err)
+	assert.True(t, privKey.PublicKey().Verify(hash, highS))
+}
+
+func TestInvalidSignatureEncoding(t *testing.T) {
+	testCases := []struct {
+		name string
+		sig  string
+	}{
+		{
+			"too short",
+			"300502010102",
+		},
+		{
+			"wrong sequence tag",
+			"3144022056a85e89071eaaf97806a6d6f2df4a2cbd4cb8600b56b14542f516708a64e8310220652adc250ccd6c7c653f1a7ba4e7f2cfd432f1a108f590e6b00e33b1dfd93d93",
+		},
+		{
+			"wrong sequence length",
+			"3045022056a85e89071eaaf97806a6d6f2df4a2cbd4cb8600b56b14542f516708a64e8310220652adc250ccd6c7c653f1a7ba4e7f2cfd432f1a108f590e6b00e33b1dfd93d93",
+		},
+		{
+			"wrong r tag",
+			"3044032056a85e89071eaaf97806a6d6f2df4a2cbd4cb8600b56b14542f516708a64e8310220652adc250ccd6c7c653f1a7ba4e7f2cfd432f1a108f590e6b00e33b1dfd93d93",
+		},
+		{
+			"negative r",
+			"30440220d6a85e89071eaaf97806a6d6f2df4a2cbd4cb8600b56b14542f516708a64e8310220652adc250ccd6c7c653f1a7ba4e7f2cfd432f1a108f590e6b00e33b1dfd93d93",
+		},
+		{
+			"excessively padded r",
+			"304502210056a85e89071eaaf97806a6d6f2df4a2cbd4cb8600b56b14542f516708a64e8310220652adc250ccd6c7c653f1a7ba4e7f2cfd432f1a108f590e6b00e33b1dfd93d93",
+		},
+		{
+			"zero length s",
+			"3024022056a85e89071eaaf97806a6d6f2df4a2cbd4cb8600b56b14542f516708a64e8310200",
+		},
+		{
+			"zero s",
+			"3025022056a85e89071eaaf97806a6d6f2df4a2cbd4cb8600b56b14542f516708a64e831020100",
+		},
+		{
+			"trailing garbage",
+			"3044022056a85e89071eaaf97806a6d6f2df4a2cbd4cb8600b56b14542f516708a64e8310220652adc250ccd6c7c653f1a7ba4e7f2cfd432f1a108f590e6b00e33b1dfd93d9301",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewSignatureFromHex(tc.sig)
+			assert.Equal(t, err, ErrInvalidSignature)
+		})
+	}
+}
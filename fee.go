@@ -0,0 +1,185 @@
+package btc
+
+import "errors"
+
+const (
+	FeeRateSatPerVByte FeeRate = 1000
+
+	// the fee rate Bitcoin Core determines dust outputs with by default
+	DustRelayFeeRate FeeRate = 3000
+)
+
+var (
+	ErrPrevTxOutNotFound = errors.New("prev tx out not found")
+	ErrAmountOutOfRange  = errors.New("amount out of range")
+	ErrNegativeFee       = errors.New("negative fee")
+)
+
+// FeeRate is a fee rate in satoshis per 1000 virtual bytes as Bitcoin Core's CFeeRate,
+// which represents fractional sat/vB rates exactly, e.g. 1.5 sat/vB is 1500.
+type FeeRate int64
+
+func (rate FeeRate) Int64() int64 {
+	return int64(rate)
+}
+
+func (rate FeeRate) SatPerVByte() float64 {
+	return float64(rate) / float64(FeeRateSatPerVByte)
+}
+
+// Fee returns the fee of vsize virtual bytes at the rate, rounded up.
+func (rate FeeRate) Fee(vsize int) Satoshi {
+	fee := rate.Int64() * int64(vsize)
+	if fee <= 0 {
+		return 0
+	}
+
+	return Satoshi((fee + FeeRateSatPerVByte.Int64() - 1) / FeeRateSatPerVByte.Int64())
+}
+
+// DustThreshold returns the amount below which the tx out costs more than it is worth to spend at rate,
+// which is 0 for unspendable outputs.
+// ref. https://github.com/bitcoin/bitcoin/blob/master/src/policy/policy.cpp
+func (txOut *TxOut) DustThreshold(rate FeeRate) (Satoshi, error) {
+	b, err := txOut.Script.Bytes()
+	if err != nil {
+		return 0, err
+	}
+	if (len(b) > 0 && OpCode(b[0]) == OpReturn) || len(b) > MaxScriptSize {
+		return 0, nil
+	}
+
+	size, err := txOut.Size()
+	if err != nil {
+		return 0, err
+	}
+
+	spendSize, err := txOut.spendSize()
+	if err != nil {
+		return 0, err
+	}
+
+	return rate.Fee(size + spendSize), nil
+}
+
+// spendSize returns the virtual size of the input spending the tx out with a signature and a public key,
+// which Bitcoin Core assumes for the dust threshold.
+func (txOut *TxOut) spendSize() (int, error) {
+	b, err := txOut.Script.Bytes()
+	if err != nil {
+		return 0, err
+	}
+
+	// the outpoint, sequence and scriptSig or witness
+	if _, _, ok := extractWitnessProgram(b); ok {
+		return 32 + 4 + 1 + 107/WitnessScaleFactor + 4, nil
+	}
+
+	return 32 + 4 + 1 + 107 + 4, nil
+}
+
+func (txOut *TxOut) IsDust(rate FeeRate) (bool, error) {
+	threshold, err := txOut.DustThreshold(rate)
+	if err != nil {
+		return false, err
+	}
+
+	return txOut.Amount < threshold, nil
+}
+
+// NewFeeRate returns the fee rate of paying fee for vsize virtual bytes, rounded down.
+func NewFeeRate(fee Satoshi, vsize int) FeeRate {
+	if vsize <= 0 {
+		return 0
+	}
+
+	return FeeRate(fee.Int64() * FeeRateSatPerVByte.Int64() / int64(vsize))
+}
+
+// SatPerKWeight returns the fee rate in satoshis per 1000 weight units.
+func (rate FeeRate) SatPerKWeight() float64 {
+	return float64(rate) / WitnessScaleFactor
+}
+
+// SetPrevTxOuts attaches the tx outs spent by the tx ins in order, with which the fee of the tx is computed.
+func (tx *Tx) SetPrevTxOuts(txOuts []*TxOut) error {
+	if len(txOuts) != len(tx.TxIns) {
+		return ErrPrevOutsMismatch
+	}
+
+	for i, txIn := range tx.TxIns {
+		txIn.PrevTxOut = txOuts[i]
+	}
+
+	return nil
+}
+
+// InputAmount returns the sum of the amounts of the tx outs spent by the tx.
+func (tx *Tx) InputAmount() (Satoshi, error) {
+	txOuts := make([]*TxOut, len(tx.TxIns))
+	for i, txIn := range tx.TxIns {
+		if txIn.PrevTxOut == nil {
+			return 0, ErrPrevTxOutNotFound
+		}
+
+		txOuts[i] = txIn.PrevTxOut
+	}
+
+	return sumAmounts(txOuts)
+}
+
+// OutputAmount returns the sum of the amounts of the tx outs of the tx.
+func (tx *Tx) OutputAmount() (Satoshi, error) {
+	return sumAmounts(tx.TxOuts)
+}
+
+// sumAmounts returns the sum of the amounts of txOuts, each of which as well as the sum must be in the money range.
+func sumAmounts(txOuts []*TxOut) (Satoshi, error) {
+	var sum Satoshi
+	for _, txOut := range txOuts {
+		if !txOut.Amount.IsValid() {
+			return 0, ErrAmountOutOfRange
+		}
+
+		sum += txOut.Amount
+		if !sum.IsValid() {
+			return 0, ErrAmountOutOfRange
+		}
+	}
+
+	return sum, nil
+}
+
+// Fee returns the input amount minus the output amount of the tx, whose tx ins need the tx outs they spend.
+func (tx *Tx) Fee() (Satoshi, error) {
+	in, err := tx.InputAmount()
+	if err != nil {
+		return 0, err
+	}
+
+	out, err := tx.OutputAmount()
+	if err != nil {
+		return 0, err
+	}
+
+	if in < out {
+		return 0, ErrNegativeFee
+	}
+
+	return in - out, nil
+}
+
+// FeeRate returns the fee rate the tx pays for its virtual size.
+func (tx *Tx) FeeRate() (FeeRate, error) {
+	fee, err := tx.Fee()
+	if err != nil {
+		return 0, err
+	}
+
+	vsize, err := tx.Vsize()
+	if err != nil {
+		return 0, err
+	}
+
+	return NewFeeRate(fee, vsize), nil
+}
@@ -0,0 +1,148 @@
+package btc
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeeRateFee(t *testing.T) {
+	testCases := []struct {
+		name  string
+		rate  FeeRate
+		vsize int
+		fee   Satoshi
+	}{
+		{"1 sat/vB", FeeRateSatPerVByte, 141, 141},
+		{"1.5 sat/vB rounded up", 1500, 141, 212},
+		{"0.1 sat/vB rounded up", 100, 141, 15},
+		{"zero", 0, 141, 0},
+		{"negative", -1000, 141, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.rate.Fee(tc.vsize), tc.fee)
+		})
+	}
+
+	assert.Equal(t, FeeRate(1500).SatPerVByte(), 1.5)
+}
+
+func TestTxOutDustThreshold(t *testing.T) {
+	testCases := []struct {
+		name      string
+		scriptHex string
+		threshold Satoshi
+	}{
+		{"p2pkh", "76a914" + strings.Repeat("11", 20) + "88ac", 546},
+		{"p2sh", "a914" + strings.Repeat("11", 20) + "87", 540},
+		{"p2wpkh", "0014" + strings.Repeat("11", 20), 294},
+		{"p2wsh", "0020" + strings.Repeat("11", 32), 330},
+		{"p2tr", "5120" + strings.Repeat("11", 32), 330},
+		{"null data", "6a0411111111", 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			script, err := NewScriptFromHex(tc.scriptHex)
+			require.NoError(t, err)
+
+			txOut := NewTxOut(int64(tc.threshold), script)
+
+			threshold, err := txOut.DustThreshold(DustRelayFeeRate)
+			require.NoError(t, err)
+			assert.Equal(t, threshold, tc.threshold)
+
+			isDust, err := txOut.IsDust(DustRelayFeeRate)
+			require.NoError(t, err)
+			assert.False(t, isDust)
+
+			if tc.threshold > 0 {
+				txOut.Amount--
+
+				isDust, err = txOut.IsDust(DustRelayFeeRate)
+				require.NoError(t, err)
+				assert.True(t, isDust)
+			}
+		})
+	}
+}
+
+func TestTxFee(t *testing.T) {
+	newTx := func(t *testing.T) *Tx {
+		// spends 3 tx outs to 100000 and 2500000 satoshis, whose vsize is 251
+		tx, err := NewTxFromHex("020000000001036910051cf3ce36257a1d844e28959f368a35adc9520fb9679175f6cdf8c1f1d10000000000fffffffff8e9d0c1b2a3f4e5d6c7b8a9f0e1d2c3b4a5768695a4b3c2e1a9d8b7e4b3d8e50300000000fdffffffbc0a00000000000000000000000000000000000000000000000000000000000006000000000000000002a086010000000000160014a9b8c7d6e5f4a3b2c1d0e9f8a9b8c7d6e5f4a3b2a025260000000000225120b8c7d6e5f4a3b2c1d0e9f8a9b8c7d6e5f4a3b2c1d0e9f8a9b8c7d6e5f4a3b2c10140e907831f80848d1069a5371b402410364bdf1c5f8307b0084c55f1cbe2dca821525f66a4a85ea8b71e482a74f382d2ce5ebeee8fdb2172f477df4900d310536c0002473044022065fe1ea4e94a9b44fb62c2b874b63a947504273a60b99b8f7bbf77b4db9331b002205559d8ee93cf341d75866f9eb912af05904fb6eed7372a837308c4e37f3ab58f012103bae5f04799c40862358560e42e441c3080b997a3dec161dd40395e992362bfc920a10700")
+		require.NoError(t, err)
+		return tx
+	}
+	prevTxOuts := func(amounts ...Satoshi) []*TxOut {
+		txOuts := make([]*TxOut, len(amounts))
+		for i, amount := range amounts {
+			txOuts[i] = NewTxOut(int64(amount), &Script{Hex: "0014" + strings.Repeat("11", 20)})
+		}
+		return txOuts
+	}
+
+	t.Run("success", func(t *testing.T) {
+		tx := newTx(t)
+		require.NoError(t, tx.SetPrevTxOuts(prevTxOuts(1000000, 1000000, 700000)))
+
+		in, err := tx.InputAmount()
+		require.NoError(t, err)
+		assert.Equal(t, in, Satoshi(2700000))
+
+		out, err := tx.OutputAmount()
+		require.NoError(t, err)
+		assert.Equal(t, out, Satoshi(2600000))
+
+		fee, err := tx.Fee()
+		require.NoError(t, err)
+		assert.Equal(t, fee, Satoshi(100000))
+
+		rate, err := tx.FeeRate()
+		require.NoError(t, err)
+		assert.Equal(t, rate, FeeRate(398406))
+		assert.Equal(t, rate.SatPerVByte(), 398.406)
+		assert.Equal(t, rate.SatPerKWeight(), 99601.5)
+
+		// the tx outs spent are not serialized
+		b, err := json.Marshal(tx)
+		require.NoError(t, err)
+		assert.False(t, strings.Contains(string(b), "prevTxOut"))
+	})
+
+	testCases := []struct {
+		name       string
+		prevTxOuts []*TxOut
+		err        error
+	}{
+		{"negative fee", prevTxOuts(1000000, 1000000, 599999), ErrNegativeFee},
+		{"negative amount", prevTxOuts(1000000, 1000000, -1), ErrAmountOutOfRange},
+		{"too large amount", prevTxOuts(1000000, 1000000, MaxMoney+1), ErrAmountOutOfRange},
+		{"too large sum", prevTxOuts(1000000, 1000000, MaxMoney), ErrAmountOutOfRange},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tx := newTx(t)
+			require.NoError(t, tx.SetPrevTxOuts(tc.prevTxOuts))
+
+			_, err := tx.Fee()
+			assert.Equal(t, err, tc.err)
+		})
+	}
+
+	tx := newTx(t)
+	assert.Equal(t, tx.SetPrevTxOuts(prevTxOuts(1000000)), ErrPrevOutsMismatch)
+
+	_, err := tx.Fee()
+	assert.Equal(t, err, ErrPrevTxOutNotFound)
+
+	tx.TxOuts[0].Amount = MaxMoney
+	_, err = tx.OutputAmount()
+	assert.Equal(t, err, ErrAmountOutOfRange)
+}
@@ -1,9 +1,15 @@
 module github.com/m0t0k1ch1/btc
 
-go 1.12
+go 1.18
 
 require (
 	github.com/m0t0k1ch1/base58 v0.1.0
 	github.com/stretchr/testify v1.3.0
-	golang.org/x/crypto v0.0.0-20180621125126-a49355c7e3f8
+	golang.org/x/crypto v0.0.0-20211117183948-ae814b36b871
+	golang.org/x/text v0.14.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
 )
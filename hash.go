@@ -37,3 +37,42 @@ func Sha256Double(b []byte) ([]byte, error) {
 
 	return h.Sum(nil), nil
 }
+
+func Sha256(b []byte) ([]byte, error) {
+	h := sha256.New()
+	if _, err := h.Write(b); err != nil {
+		return nil, err
+	}
+
+	return h.Sum(nil), nil
+}
+
+func Ripemd160(b []byte) ([]byte, error) {
+	h := ripemd160.New()
+	if _, err := h.Write(b); err != nil {
+		return nil, err
+	}
+
+	return h.Sum(nil), nil
+}
+
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0340.mediawiki#design
+func TaggedHash(tag string, b []byte) ([]byte, error) {
+	tagHash, err := Sha256([]byte(tag))
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha256.New()
+	if _, err := h.Write(tagHash); err != nil {
+		return nil, err
+	}
+	if _, err := h.Write(tagHash); err != nil {
+		return nil, err
+	}
+	if _, err := h.Write(b); err != nil {
+		return nil, err
+	}
+
+	return h.Sum(nil), nil
+}
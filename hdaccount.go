@@ -0,0 +1,206 @@
+package btc
+
+import "errors"
+
+const (
+	HdPurposeBip44 HdPurpose = 44
+	HdPurposeBip49 HdPurpose = 49
+	HdPurposeBip84 HdPurpose = 84
+	HdPurposeBip86 HdPurpose = 86
+
+	HdChainExternal uint32 = 0
+	HdChainInternal uint32 = 1
+)
+
+var (
+	ErrUnsupportedHdPurpose = errors.New("unsupported hd purpose")
+	ErrInvalidHdAccountKey  = errors.New("invalid hd account key")
+	ErrHdIndexOutOfRange    = errors.New("hd index out of range")
+)
+
+// HdPurpose is the purpose level of a BIP43 derivation path.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0043.mediawiki
+type HdPurpose uint32
+
+// Format returns the SLIP-132 format in which account keys of purpose are serialized.
+func (purpose HdPurpose) Format() (HdKeyFormat, error) {
+	switch purpose {
+	case HdPurposeBip44, HdPurposeBip86:
+		return HdKeyFormatStandard, nil
+	case HdPurposeBip49:
+		return HdKeyFormatP2shP2wpkh, nil
+	case HdPurposeBip84:
+		return HdKeyFormatP2wpkh, nil
+	default:
+		return 0, ErrUnsupportedHdPurpose
+	}
+}
+
+// AccountPath returns m/purpose'/coin_type'/account'.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0044.mediawiki#path-levels
+func (purpose HdPurpose) AccountPath(account uint32, params *Params) DerivationPath {
+	return DerivationPath{
+		HardenedKeyStart + uint32(purpose),
+		HardenedKeyStart + params.HdCoinType,
+		HardenedKeyStart + account,
+	}
+}
+
+// HdAccount is an account-level extended key of a single-key wallet,
+// from which receive and change addresses are derived.
+type HdAccount struct {
+	key     *ExtendedKey
+	purpose HdPurpose
+	params  *Params
+}
+
+// NewHdAccount derives the account key of purpose from the master key.
+func NewHdAccount(master *ExtendedKey, purpose HdPurpose, account uint32, params *Params) (*HdAccount, error) {
+	format, err := purpose.Format()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := master.Derive(purpose.AccountPath(account, params))
+	if err != nil {
+		return nil, err
+	}
+
+	key, err = key.WithFormat(format, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HdAccount{
+		key:     key,
+		purpose: purpose,
+		params:  params,
+	}, nil
+}
+
+// NewHdAccountFromString parses a serialized account key such as a zpub,
+// inferring the purpose from its SLIP-132 version bytes.
+// xpub and tpub are taken as BIP44; use NewHdAccountFromExtendedKey for BIP86.
+func NewHdAccountFromString(s string, params *Params) (*HdAccount, error) {
+	key, err := NewExtendedKeyFromString(s, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var purpose HdPurpose
+	switch key.Format() {
+	case HdKeyFormatStandard:
+		purpose = HdPurposeBip44
+	case HdKeyFormatP2shP2wpkh:
+		purpose = HdPurposeBip49
+	case HdKeyFormatP2wpkh:
+		purpose = HdPurposeBip84
+	default:
+		return nil, ErrUnsupportedHdPurpose
+	}
+
+	return NewHdAccountFromExtendedKey(key, purpose, params)
+}
+
+// NewHdAccountFromExtendedKey returns the account of purpose whose account-level key is key.
+func NewHdAccountFromExtendedKey(key *ExtendedKey, purpose HdPurpose, params *Params) (*HdAccount, error) {
+	format, err := purpose.Format()
+	if err != nil {
+		return nil, err
+	}
+	if format != key.Format() {
+		return nil, ErrInvalidHdAccountKey
+	}
+
+	return &HdAccount{
+		key:     key,
+		purpose: purpose,
+		params:  params,
+	}, nil
+}
+
+func (account *HdAccount) Purpose() HdPurpose {
+	return account.purpose
+}
+
+func (account *HdAccount) ExtendedKey() *ExtendedKey {
+	return account.key
+}
+
+func (account *HdAccount) ReceiveAddress(index uint32) (Address, error) {
+	return account.Address(HdChainExternal, index)
+}
+
+func (account *HdAccount) ChangeAddress(index uint32) (Address, error) {
+	return account.Address(HdChainInternal, index)
+}
+
+// Address returns the address at chain/index relative to the account.
+func (account *HdAccount) Address(chain uint32, index uint32) (Address, error) {
+	addresses, err := account.Addresses(chain, index, 1)
+	if err != nil {
+		return "", err
+	}
+
+	return addresses[0], nil
+}
+
+// Addresses returns count consecutive addresses from chain/start relative to the account.
+// The chain and the indexes must be below HardenedKeyStart, as addresses are never derived with hardened ones.
+func (account *HdAccount) Addresses(chain uint32, start uint32, count uint32) ([]Address, error) {
+	if chain >= HardenedKeyStart || uint64(start)+uint64(count) > uint64(HardenedKeyStart) {
+		return nil, ErrHdIndexOutOfRange
+	}
+
+	chainKey, err := account.key.Child(chain)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make([]Address, 0, count)
+	for i := uint32(0); i < count; i++ {
+		key, err := chainKey.Child(start + i)
+		if err != nil {
+			return nil, err
+		}
+
+		address, err := account.address(key.PublicKey())
+		if err != nil {
+			return nil, err
+		}
+
+		addresses = append(addresses, address)
+	}
+
+	return addresses, nil
+}
+
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0049.mediawiki
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0084.mediawiki
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0086.mediawiki
+func (account *HdAccount) address(pubKey *PublicKey) (Address, error) {
+	switch account.purpose {
+	case HdPurposeBip44:
+		pkh, err := pubKey.Pkh()
+		if err != nil {
+			return "", err
+		}
+		return pkh.Address(account.params)
+
+	case HdPurposeBip49:
+		return NewP2shP2wpkhAddress(pubKey, account.params)
+
+	case HdPurposeBip84:
+		pkh, err := pubKey.Pkh()
+		if err != nil {
+			return "", err
+		}
+		return pkh.WitnessAddress(account.params)
+
+	case HdPurposeBip86:
+		return NewP2trAddress(pubKey.XOnly(), nil, account.params)
+
+	default:
+		return "", ErrUnsupportedHdPurpose
+	}
+}
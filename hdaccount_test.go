@@ -0,0 +1,194 @@
+package btc
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seed of "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+const testHdSeed = "5eb00bbddcf069084889a8ab9155568165f5c453ccb85e70811aaed6f6da5fc19a5ac40b389cd370d086206dec8aa6c43daea6690f20ad3d8d48b2d2ce9e38e4"
+
+func TestHdAccount(t *testing.T) {
+	testCases := []struct {
+		purpose    HdPurpose
+		params     *Params
+		accountKey string
+		receive    []Address
+		change     []Address
+	}{
+		{
+			// ref. https://github.com/bitcoin/bips/blob/master/bip-0044.mediawiki
+			HdPurposeBip44,
+			MainNetParams,
+			"xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj",
+			[]Address{"1LqBGSKuX5yYUonjxT5qGfpUsXKYYWeabA", "1Ak8PffB2meyfYnbXZR9EGfLfFZVpzJvQP"},
+			[]Address{"1J3J6EvPrv8q6AC3VCjWV45Uf3nssNMRtH", "13vKxXzHXXd8HquAYdpkJoi9ULVXUgfpS5"},
+		},
+		{
+			// ref. https://github.com/bitcoin/bips/blob/master/bip-0049.mediawiki#test-vectors
+			HdPurposeBip49,
+			TestNet3Params,
+			"upub5EFU65HtV5TeiSHmZZm7FUffBGy8UKeqp7vw43jYbvZPpoVsgU93oac7Wk3u6moKegAEWtGNF8DehrnHtv21XXEMYRUocHqguyjknFHYfgY",
+			[]Address{"2Mww8dCYPUpKHofjgcXcBCEGmniw9CoaiD2", "2N55m54k8vr95ggehfUcNkdbUuQvaqG2GxK"},
+			[]Address{"2MvdUi5o3f2tnEFh9yGvta6FzptTZtkPJC8", "2NCtHHE9TjYrYnUWfZv79w9ktk1f2uPUzqu"},
+		},
+		{
+			HdPurposeBip49,
+			MainNetParams,
+			"ypub6Ww3ibxVfGzLrAH1PNcjyAWenMTbbAosGNB6VvmSEgytSER9azLDWCxoJwW7Ke7icmizBMXrzBx9979FfaHxHcrArf3zbeJJJUZPf663zsP",
+			[]Address{"37VucYSaXLCAsxYyAPfbSi9eh4iEcbShgf", "3LtMnn87fqUeHBUG414p9CWwnoV6E2pNKS"},
+			[]Address{"34K56kSjgUCUSD8GTtuF7c9Zzwokbs6uZ7", "3516F2wmK51jVRrggEJsTUBNWMSLLjzvJ2"},
+		},
+		{
+			// ref. https://github.com/bitcoin/bips/blob/master/bip-0084.mediawiki#test-vectors
+			HdPurposeBip84,
+			MainNetParams,
+			"zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs",
+			[]Address{"bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", "bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g"},
+			[]Address{"bc1q8c6fshw2dlwun7ekn9qwf37cu2rn755upcp6el", "bc1qggnasd834t54yulsep6fta8lpjekv4zj6gv5rf"},
+		},
+		{
+			HdPurposeBip84,
+			TestNet3Params,
+			"vpub5Y6cjg78GGuNLsaPhmYsiw4gYX3HoQiRBiSwDaBXKUafCt9bNwWQiitDk5VZ5BVxYnQdwoTyXSs2JHRPAgjAvtbBrf8ZhDYe2jWAqvZVnsc",
+			[]Address{"tb1q6rz28mcfaxtmd6v789l9rrlrusdprr9pqcpvkl", "tb1qd7spv5q28348xl4myc8zmh983w5jx32cjhkn97"},
+			[]Address{"tb1q9u62588spffmq4dzjxsr5l297znf3z6j5p2688", "tb1qkwgskuzmmwwvqajnyr7yp9hgvh5y45kg8wvdmd"},
+		},
+		{
+			// ref. https://github.com/bitcoin/bips/blob/master/bip-0086.mediawiki#test-vectors
+			HdPurposeBip86,
+			MainNetParams,
+			"xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ",
+			[]Address{"bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr", "bc1p4qhjn9zdvkux4e44uhx8tc55attvtyu358kutcqkudyccelu0was9fqzwh"},
+			[]Address{"bc1p3qkhfews2uk44qtvauqyr2ttdsw7svhkl9nkm9s9c3x4ax5h60wqwruhk7", "bc1ptdg60grjk9t3qqcqczp4tlyy3z47yrx9nhlrjsmw36q5a72lhdrs9f00nj"},
+		},
+	}
+
+	seed, err := hex.DecodeString(testHdSeed)
+	require.NoError(t, err)
+
+	for _, tc := range testCases {
+		t.Run(tc.accountKey, func(t *testing.T) {
+			master, err := NewMasterExtendedKey(seed, tc.params)
+			require.NoError(t, err)
+
+			account, err := NewHdAccount(master, tc.purpose, 0, tc.params)
+			require.NoError(t, err)
+
+			accountKey, err := account.ExtendedKey().Neuter().Serialize()
+			require.NoError(t, err)
+			assert.Equal(t, accountKey, tc.accountKey)
+
+			receive, err := account.Addresses(HdChainExternal, 0, uint32(len(tc.receive)))
+			require.NoError(t, err)
+			assert.Equal(t, receive, tc.receive)
+
+			change, err := account.ChangeAddress(1)
+			require.NoError(t, err)
+			assert.Equal(t, change, tc.change[1])
+
+			// the account parsed from its public key yields the same addresses
+			parsed, err := NewHdAccountFromString(tc.accountKey, tc.params)
+			if tc.purpose == HdPurposeBip86 {
+				require.NoError(t, err)
+				assert.Equal(t, parsed.Purpose(), HdPurposeBip44)
+
+				parsed, err = NewHdAccountFromExtendedKey(parsed.ExtendedKey(), HdPurposeBip86, tc.params)
+			}
+			require.NoError(t, err)
+			assert.Equal(t, parsed.Purpose(), tc.purpose)
+
+			changes, err := parsed.Addresses(HdChainInternal, 0, uint32(len(tc.change)))
+			require.NoError(t, err)
+			assert.Equal(t, changes, tc.change)
+
+			address, err := parsed.ReceiveAddress(0)
+			require.NoError(t, err)
+			assert.Equal(t, address, tc.receive[0])
+		})
+	}
+}
+
+func TestHdAccountAddressesRange(t *testing.T) {
+	seed, err := hex.DecodeString(testHdSeed)
+	require.NoError(t, err)
+
+	master, err := NewMasterExtendedKey(seed, MainNetParams)
+	require.NoError(t, err)
+
+	account, err := NewHdAccount(master, HdPurposeBip84, 0, MainNetParams)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name  string
+		chain uint32
+		start uint32
+		count uint32
+		err   error
+	}{
+		{"last unhardened indexes", HdChainExternal, HardenedKeyStart - 2, 2, nil},
+		{"no indexes from HardenedKeyStart", HdChainExternal, HardenedKeyStart, 0, nil},
+		{"past HardenedKeyStart", HdChainExternal, HardenedKeyStart - 1, 2, ErrHdIndexOutOfRange},
+		{"from HardenedKeyStart", HdChainExternal, HardenedKeyStart, 1, ErrHdIndexOutOfRange},
+		{"overflow", HdChainExternal, 0xffffffff, 2, ErrHdIndexOutOfRange},
+		{"hardened chain", HardenedKeyStart, 0, 1, ErrHdIndexOutOfRange},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			addresses, err := account.Addresses(tc.chain, tc.start, tc.count)
+			assert.Equal(t, err, tc.err)
+			if tc.err == nil {
+				assert.Len(t, addresses, int(tc.count))
+			}
+		})
+	}
+
+	_, err = account.ReceiveAddress(HardenedKeyStart)
+	assert.Equal(t, err, ErrHdIndexOutOfRange)
+}
+
+func TestExtendedKeyFormat(t *testing.T) {
+	xpub := "xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V"
+	zpub := "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs"
+
+	key, err := NewExtendedKeyFromString(xpub, MainNetParams)
+	require.NoError(t, err)
+	assert.Equal(t, key.Format(), HdKeyFormatStandard)
+
+	key, err = key.WithFormat(HdKeyFormatP2wpkh, MainNetParams)
+	require.NoError(t, err)
+
+	s, err := key.Serialize()
+	require.NoError(t, err)
+	assert.Equal(t, s, zpub)
+
+	key, err = NewExtendedKeyFromString(zpub, MainNetParams)
+	require.NoError(t, err)
+	assert.Equal(t, key.Format(), HdKeyFormatP2wpkh)
+
+	for _, format := range []HdKeyFormat{HdKeyFormatP2shP2wsh, HdKeyFormatP2wsh} {
+		k, err := key.WithFormat(format, MainNetParams)
+		require.NoError(t, err)
+
+		s, err := k.Serialize()
+		require.NoError(t, err)
+
+		parsed, err := NewExtendedKeyFromString(s, MainNetParams)
+		require.NoError(t, err)
+		assert.Equal(t, parsed.Format(), format)
+
+		// multisig formats have no single-key address derivation
+		_, err = NewHdAccountFromString(s, MainNetParams)
+		assert.Equal(t, err, ErrUnsupportedHdPurpose)
+	}
+
+	_, err = NewExtendedKeyFromString(zpub, TestNet3Params)
+	assert.Equal(t, err, ErrInvalidExtendedKeyVersion)
+
+	_, err = NewHdAccountFromExtendedKey(key, HdPurposeBip44, MainNetParams)
+	assert.Equal(t, err, ErrInvalidHdAccountKey)
+}
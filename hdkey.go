@@ -0,0 +1,433 @@
+package btc
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/m0t0k1ch1/base58"
+)
+
+const (
+	HardenedKeyStart uint32 = 0x80000000
+
+	HdSeedLengthMin = 16
+	HdSeedLengthMax = 64
+
+	hdMasterKeyHmacKey     = "Bitcoin seed"
+	hdFingerprintLength    = 4
+	hdMaxDepth             = 255
+	extendedKeyLength      = 78
+	derivationPathRoot     = "m"
+	derivationPathHardened = "'"
+)
+
+var (
+	ErrInvalidHdSeedLength       = errors.New("invalid hd seed length")
+	ErrInvalidExtendedKey        = errors.New("invalid extended key")
+	ErrInvalidExtendedKeyVersion = errors.New("invalid extended key version")
+	ErrInvalidChildKey           = errors.New("invalid child key")
+	ErrHardenedFromPublicKey     = errors.New("cannot derive a hardened key from a public key")
+	ErrNotPrivateExtendedKey     = errors.New("not a private extended key")
+	ErrMaxDepthExceeded          = errors.New("max depth exceeded")
+	ErrInvalidDerivationPath     = errors.New("invalid derivation path")
+	ErrInvalidHdKeyFormat        = errors.New("invalid hd key format")
+)
+
+// HdKeyFormat is the SLIP-132 format of an extended key, which tells the script type of its descendants.
+// ref. https://github.com/satoshilabs/slips/blob/master/slip-0132.md
+type HdKeyFormat int
+
+const (
+	HdKeyFormatStandard   HdKeyFormat = iota // xpub, tpub
+	HdKeyFormatP2shP2wpkh                    // ypub, upub
+	HdKeyFormatP2shP2wsh                     // Ypub, Upub
+	HdKeyFormatP2wpkh                        // zpub, vpub
+	HdKeyFormatP2wsh                         // Zpub, Vpub
+)
+
+var hdKeyFormats = []HdKeyFormat{
+	HdKeyFormatStandard,
+	HdKeyFormatP2shP2wpkh,
+	HdKeyFormatP2shP2wsh,
+	HdKeyFormatP2wpkh,
+	HdKeyFormatP2wsh,
+}
+
+// hdVersions is the pair of version bytes of a private extended key and its public counterpart.
+type hdVersions struct {
+	private [4]byte
+	public  [4]byte
+}
+
+func newHdVersions(format HdKeyFormat, params *Params) (hdVersions, error) {
+	switch format {
+	case HdKeyFormatStandard:
+		return hdVersions{params.HdPrivateKeyVersion, params.HdPublicKeyVersion}, nil
+	case HdKeyFormatP2shP2wpkh:
+		return hdVersions{params.HdP2shP2wpkhPrivateKeyVersion, params.HdP2shP2wpkhPublicKeyVersion}, nil
+	case HdKeyFormatP2shP2wsh:
+		return hdVersions{params.HdP2shP2wshPrivateKeyVersion, params.HdP2shP2wshPublicKeyVersion}, nil
+	case HdKeyFormatP2wpkh:
+		return hdVersions{params.HdP2wpkhPrivateKeyVersion, params.HdP2wpkhPublicKeyVersion}, nil
+	case HdKeyFormatP2wsh:
+		return hdVersions{params.HdP2wshPrivateKeyVersion, params.HdP2wshPublicKeyVersion}, nil
+	default:
+		return hdVersions{}, ErrInvalidHdKeyFormat
+	}
+}
+
+// ExtendedKey is a BIP32 extended private or public key.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0032.mediawiki
+type ExtendedKey struct {
+	format            HdKeyFormat
+	versions          hdVersions
+	depth             byte
+	parentFingerprint []byte
+	childIndex        uint32
+	chainCode         []byte
+	privKey           *PrivateKey
+	pubKey            *PublicKey
+}
+
+// NewMasterExtendedKey returns the master extended private key generated from seed.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0032.mediawiki#master-key-generation
+func NewMasterExtendedKey(seed []byte, params *Params) (*ExtendedKey, error) {
+	if len(seed) < HdSeedLengthMin || len(seed) > HdSeedLengthMax {
+		return nil, ErrInvalidHdSeedLength
+	}
+
+	il, ir := hmacSha512([]byte(hdMasterKeyHmacKey), seed)
+
+	privKey, err := NewPrivateKeyFromBytes(il)
+	if err != nil {
+		return nil, ErrInvalidChildKey
+	}
+
+	return &ExtendedKey{
+		format: HdKeyFormatStandard,
+		versions: hdVersions{
+			private: params.HdPrivateKeyVersion,
+			public:  params.HdPublicKeyVersion,
+		},
+		parentFingerprint: make([]byte, hdFingerprintLength),
+		chainCode:         ir,
+		privKey:           privKey,
+		pubKey:            privKey.PublicKey().Compressed(),
+	}, nil
+}
+
+// NewExtendedKeyFromString parses a base58check serialized extended key of the network defined by params.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0032.mediawiki#serialization-format
+func NewExtendedKeyFromString(s string, params *Params) (*ExtendedKey, error) {
+	b, err := base58.NewBitcoinBase58().DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidExtendedKey
+	}
+	if len(b) != extendedKeyLength+base58ChecksumLength {
+		return nil, ErrInvalidExtendedKey
+	}
+
+	ok, err := verifyBase58Checksum(b)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidExtendedKey
+	}
+
+	var version [4]byte
+	copy(version[:], b[0:4])
+
+	var format HdKeyFormat
+	var versions hdVersions
+	var isPrivate, found bool
+	for _, f := range hdKeyFormats {
+		vs, err := newHdVersions(f, params)
+		if err != nil {
+			return nil, err
+		}
+		if version == vs.private || version == vs.public {
+			format, versions, isPrivate, found = f, vs, version == vs.private, true
+			break
+		}
+	}
+	if !found {
+		return nil, ErrInvalidExtendedKeyVersion
+	}
+
+	key := &ExtendedKey{
+		format:            format,
+		versions:          versions,
+		depth:             b[4],
+		parentFingerprint: concatBytes(b[5:9]),
+		childIndex:        binary.BigEndian.Uint32(b[9:13]),
+		chainCode:         concatBytes(b[13:45]),
+	}
+	if key.depth == 0 && (!bytes.Equal(key.parentFingerprint, make([]byte, hdFingerprintLength)) || key.childIndex != 0) {
+		return nil, ErrInvalidExtendedKey
+	}
+
+	keyBytes := b[45:78]
+	if isPrivate {
+		if keyBytes[0] != 0x00 {
+			return nil, ErrInvalidExtendedKey
+		}
+
+		privKey, err := NewPrivateKeyFromBytes(keyBytes[1:])
+		if err != nil {
+			return nil, ErrInvalidExtendedKey
+		}
+
+		key.privKey = privKey
+		key.pubKey = privKey.PublicKey().Compressed()
+	} else {
+		if keyBytes[0] != pubKeyFormatCompressedEven && keyBytes[0] != pubKeyFormatCompressedOdd {
+			return nil, ErrInvalidExtendedKey
+		}
+
+		pubKey, err := NewPublicKeyFromBytes(keyBytes)
+		if err != nil {
+			return nil, ErrInvalidExtendedKey
+		}
+
+		key.pubKey = pubKey
+	}
+
+	return key, nil
+}
+
+func (key *ExtendedKey) Format() HdKeyFormat {
+	return key.format
+}
+
+// WithFormat returns a copy of key serialized with the SLIP-132 version bytes of format.
+func (key *ExtendedKey) WithFormat(format HdKeyFormat, params *Params) (*ExtendedKey, error) {
+	versions, err := newHdVersions(format, params)
+	if err != nil {
+		return nil, err
+	}
+
+	k := *key
+	k.format = format
+	k.versions = versions
+
+	return &k, nil
+}
+
+func (key *ExtendedKey) IsPrivate() bool {
+	return key.privKey != nil
+}
+
+func (key *ExtendedKey) Depth() byte {
+	return key.depth
+}
+
+func (key *ExtendedKey) ParentFingerprint() []byte {
+	return concatBytes(key.parentFingerprint)
+}
+
+func (key *ExtendedKey) ChildIndex() uint32 {
+	return key.childIndex
+}
+
+func (key *ExtendedKey) ChainCode() []byte {
+	return concatBytes(key.chainCode)
+}
+
+func (key *ExtendedKey) PrivateKey() (*PrivateKey, error) {
+	if !key.IsPrivate() {
+		return nil, ErrNotPrivateExtendedKey
+	}
+
+	return key.privKey, nil
+}
+
+// PublicKey returns the compressed public key of key.
+func (key *ExtendedKey) PublicKey() *PublicKey {
+	return key.pubKey
+}
+
+// Fingerprint returns the first 4 bytes of the HASH160 of the public key.
+func (key *ExtendedKey) Fingerprint() ([]byte, error) {
+	pkh, err := key.pubKey.Pkh()
+	if err != nil {
+		return nil, err
+	}
+
+	return pkh[:hdFingerprintLength], nil
+}
+
+// Neuter returns the extended public key of key.
+func (key *ExtendedKey) Neuter() *ExtendedKey {
+	return &ExtendedKey{
+		format:            key.format,
+		versions:          key.versions,
+		depth:             key.depth,
+		parentFingerprint: key.parentFingerprint,
+		childIndex:        key.childIndex,
+		chainCode:         key.chainCode,
+		pubKey:            key.pubKey,
+	}
+}
+
+// Child returns the child extended key at index; indexes from HardenedKeyStart are hardened.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0032.mediawiki#child-key-derivation-ckd-functions
+func (key *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	if key.depth == hdMaxDepth {
+		return nil, ErrMaxDepthExceeded
+	}
+
+	isHardened := index >= HardenedKeyStart
+	if isHardened && !key.IsPrivate() {
+		return nil, ErrHardenedFromPublicKey
+	}
+
+	indexBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(indexBytes, index)
+
+	var data []byte
+	if isHardened {
+		data = concatBytes([]byte{0x00}, key.privKey.Bytes(), indexBytes)
+	} else {
+		data = concatBytes(key.pubKey.CompressedBytes(), indexBytes)
+	}
+
+	il, ir := hmacSha512(key.chainCode, data)
+
+	fingerprint, err := key.Fingerprint()
+	if err != nil {
+		return nil, err
+	}
+
+	child := &ExtendedKey{
+		format:            key.format,
+		versions:          key.versions,
+		depth:             key.depth + 1,
+		parentFingerprint: fingerprint,
+		childIndex:        index,
+		chainCode:         ir,
+	}
+
+	// the tweak fails if IL >= n or the resulting key is invalid,
+	// in which case BIP32 asks the caller to proceed with the next index
+	if key.IsPrivate() {
+		privKey, err := key.privKey.TweakAdd(il)
+		if err != nil {
+			return nil, ErrInvalidChildKey
+		}
+
+		child.privKey = privKey
+		child.pubKey = privKey.PublicKey().Compressed()
+	} else {
+		pubKey, err := key.pubKey.TweakAdd(il)
+		if err != nil {
+			return nil, ErrInvalidChildKey
+		}
+
+		child.pubKey = pubKey
+	}
+
+	return child, nil
+}
+
+// Derive returns the descendant extended key at path relative to key.
+func (key *ExtendedKey) Derive(path DerivationPath) (*ExtendedKey, error) {
+	var err error
+
+	child := key
+	for _, index := range path {
+		child, err = child.Child(index)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return child, nil
+}
+
+// Serialize returns the base58check serialization of key.
+func (key *ExtendedKey) Serialize() (string, error) {
+	version := key.versions.public
+	keyBytes := key.pubKey.CompressedBytes()
+	if key.IsPrivate() {
+		version = key.versions.private
+		keyBytes = append([]byte{0x00}, key.privKey.Bytes()...)
+	}
+
+	indexBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(indexBytes, key.childIndex)
+
+	return encodeBase58Check(concatBytes(
+		version[:],
+		[]byte{key.depth},
+		key.parentFingerprint,
+		indexBytes,
+		key.chainCode,
+		keyBytes,
+	))
+}
+
+// DerivationPath is a sequence of child indexes.
+type DerivationPath []uint32
+
+// ParseDerivationPath parses a path like "m/84'/0'/0'/0/5"; "h" and "H" are accepted as hardened markers too.
+func ParseDerivationPath(s string) (DerivationPath, error) {
+	elems := strings.Split(s, "/")
+	if elems[0] == derivationPathRoot {
+		elems = elems[1:]
+	}
+
+	path := make(DerivationPath, 0, len(elems))
+	for _, elem := range elems {
+		var index uint32
+
+		switch {
+		case strings.HasSuffix(elem, derivationPathHardened), strings.HasSuffix(elem, "h"), strings.HasSuffix(elem, "H"):
+			elem = elem[:len(elem)-1]
+			index = HardenedKeyStart
+		}
+
+		// reject signs and other forms strconv would accept
+		if len(elem) == 0 || strings.IndexFunc(elem, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+			return nil, ErrInvalidDerivationPath
+		}
+
+		i, err := strconv.ParseUint(elem, 10, 32)
+		if err != nil || uint32(i) >= HardenedKeyStart {
+			return nil, ErrInvalidDerivationPath
+		}
+
+		path = append(path, index+uint32(i))
+	}
+
+	return path, nil
+}
+
+func (path DerivationPath) String() string {
+	var sb strings.Builder
+	sb.WriteString(derivationPathRoot)
+	for _, index := range path {
+		sb.WriteString("/")
+		if index >= HardenedKeyStart {
+			sb.WriteString(strconv.FormatUint(uint64(index-HardenedKeyStart), 10))
+			sb.WriteString(derivationPathHardened)
+		} else {
+			sb.WriteString(strconv.FormatUint(uint64(index), 10))
+		}
+	}
+
+	return sb.String()
+}
+
+func hmacSha512(key []byte, data []byte) ([]byte, []byte) {
+	mac := hmac.New(sha512.New, key)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	return sum[:32], sum[32:]
+}
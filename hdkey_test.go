@@ -0,0 +1,274 @@
+package btc
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0032.mediawiki#test-vectors
+func TestExtendedKey(t *testing.T) {
+	seed1 := "000102030405060708090a0b0c0d0e0f"
+	seed2 := "fffcf9f6f3f0edeae7e4e1dedbd8d5d2cfccc9c6c3c0bdbab7b4b1aeaba8a5a29f9c999693908d8a8784817e7b7875726f6c696663605d5a5754514e4b484542"
+	seed3 := "4b381541583be4423346c643850da4b320e46a87ae3d2a4e6da11eba819cd4acba45d239319ac14f863b8d5ab5a0d0c64d2e8a1e7d1457df2e5a3c51c73235be"
+
+	testCases := []struct {
+		seed   string
+		path   string
+		params *Params
+		xpub   string
+		xprv   string
+	}{
+		{
+			seed1,
+			"m",
+			MainNetParams,
+			"xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8",
+			"xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi",
+		},
+		{
+			seed1,
+			"m/0'",
+			MainNetParams,
+			"xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw",
+			"xprv9uHRZZhk6KAJC1avXpDAp4MDc3sQKNxDiPvvkX8Br5ngLNv1TxvUxt4cV1rGL5hj6KCesnDYUhd7oWgT11eZG7XnxHrnYeSvkzY7d2bhkJ7",
+		},
+		{
+			seed1,
+			"m/0'/1",
+			MainNetParams,
+			"xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ",
+			"xprv9wTYmMFdV23N2TdNG573QoEsfRrWKQgWeibmLntzniatZvR9BmLnvSxqu53Kw1UmYPxLgboyZQaXwTCg8MSY3H2EU4pWcQDnRnrVA1xe8fs",
+		},
+		{
+			seed1,
+			"m/0'/1/2'",
+			MainNetParams,
+			"xpub6D4BDPcP2GT577Vvch3R8wDkScZWzQzMMUm3PWbmWvVJrZwQY4VUNgqFJPMM3No2dFDFGTsxxpG5uJh7n7epu4trkrX7x7DogT5Uv6fcLW5",
+			"xprv9z4pot5VBttmtdRTWfWQmoH1taj2axGVzFqSb8C9xaxKymcFzXBDptWmT7FwuEzG3ryjH4ktypQSAewRiNMjANTtpgP4mLTj34bhnZX7UiM",
+		},
+		{
+			seed1,
+			"m/0'/1/2'/2",
+			MainNetParams,
+			"xpub6FHa3pjLCk84BayeJxFW2SP4XRrFd1JYnxeLeU8EqN3vDfZmbqBqaGJAyiLjTAwm6ZLRQUMv1ZACTj37sR62cfN7fe5JnJ7dh8zL4fiyLHV",
+			"xprvA2JDeKCSNNZky6uBCviVfJSKyQ1mDYahRjijr5idH2WwLsEd4Hsb2Tyh8RfQMuPh7f7RtyzTtdrbdqqsunu5Mm3wDvUAKRHSC34sJ7in334",
+		},
+		{
+			seed1,
+			"m/0'/1/2'/2/1000000000",
+			MainNetParams,
+			"xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy",
+			"xprvA41z7zogVVwxVSgdKUHDy1SKmdb533PjDz7J6N6mV6uS3ze1ai8FHa8kmHScGpWmj4WggLyQjgPie1rFSruoUihUZREPSL39UNdE3BBDu76",
+		},
+		{
+			seed2,
+			"m",
+			MainNetParams,
+			"xpub661MyMwAqRbcFW31YEwpkMuc5THy2PSt5bDMsktWQcFF8syAmRUapSCGu8ED9W6oDMSgv6Zz8idoc4a6mr8BDzTJY47LJhkJ8UB7WEGuduB",
+			"xprv9s21ZrQH143K31xYSDQpPDxsXRTUcvj2iNHm5NUtrGiGG5e2DtALGdso3pGz6ssrdK4PFmM8NSpSBHNqPqm55Qn3LqFtT2emdEXVYsCzC2U",
+		},
+		{
+			seed2,
+			"m/0",
+			MainNetParams,
+			"xpub69H7F5d8KSRgmmdJg2KhpAK8SR3DjMwAdkxj3ZuxV27CprR9LgpeyGmXUbC6wb7ERfvrnKZjXoUmmDznezpbZb7ap6r1D3tgFxHmwMkQTPH",
+			"xprv9vHkqa6EV4sPZHYqZznhT2NPtPCjKuDKGY38FBWLvgaDx45zo9WQRUT3dKYnjwih2yJD9mkrocEZXo1ex8G81dwSM1fwqWpWkeS3v86pgKt",
+		},
+		{
+			seed2,
+			"m/0/2147483647'",
+			MainNetParams,
+			"xpub6ASAVgeehLbnwdqV6UKMHVzgqAG8Gr6riv3Fxxpj8ksbH9ebxaEyBLZ85ySDhKiLDBrQSARLq1uNRts8RuJiHjaDMBU4Zn9h8LZNnBC5y4a",
+			"xprv9wSp6B7kry3Vj9m1zSnLvN3xH8RdsPP1Mh7fAaR7aRLcQMKTR2vidYEeEg2mUCTAwCd6vnxVrcjfy2kRgVsFawNzmjuHc2YmYRmagcEPdU9",
+		},
+		{
+			seed2,
+			"m/0/2147483647'/1",
+			MainNetParams,
+			"xpub6DF8uhdarytz3FWdA8TvFSvvAh8dP3283MY7p2V4SeE2wyWmG5mg5EwVvmdMVCQcoNJxGoWaU9DCWh89LojfZ537wTfunKau47EL2dhHKon",
+			"xprv9zFnWC6h2cLgpmSA46vutJzBcfJ8yaJGg8cX1e5StJh45BBciYTRXSd25UEPVuesF9yog62tGAQtHjXajPPdbRCHuWS6T8XA2ECKADdw4Ef",
+		},
+		{
+			seed2,
+			"m/0/2147483647'/1/2147483646'",
+			MainNetParams,
+			"xpub6ERApfZwUNrhLCkDtcHTcxd75RbzS1ed54G1LkBUHQVHQKqhMkhgbmJbZRkrgZw4koxb5JaHWkY4ALHY2grBGRjaDMzQLcgJvLJuZZvRcEL",
+			"xprvA1RpRA33e1JQ7ifknakTFpgNXPmW2YvmhqLQYMmrj4xJXXWYpDPS3xz7iAxn8L39njGVyuoseXzU6rcxFLJ8HFsTjSyQbLYnMpCqE2VbFWc",
+		},
+		{
+			seed2,
+			"m/0/2147483647'/1/2147483646'/2",
+			MainNetParams,
+			"xpub6FnCn6nSzZAw5Tw7cgR9bi15UV96gLZhjDstkXXxvCLsUXBGXPdSnLFbdpq8p9HmGsApME5hQTZ3emM2rnY5agb9rXpVGyy3bdW6EEgAtqt",
+			"xprvA2nrNbFZABcdryreWet9Ea4LvTJcGsqrMzxHx98MMrotbir7yrKCEXw7nadnHM8Dq38EGfSh6dqA9QWTyefMLEcBYJUuekgW4BYPJcr9E7j",
+		},
+		{
+			seed3,
+			"m",
+			MainNetParams,
+			"xpub661MyMwAqRbcEZVB4dScxMAdx6d4nFc9nvyvH3v4gJL378CSRZiYmhRoP7mBy6gSPSCYk6SzXPTf3ND1cZAceL7SfJ1Z3GC8vBgp2epUt13",
+			"xprv9s21ZrQH143K25QhxbucbDDuQ4naNntJRi4KUfWT7xo4EKsHt2QJDu7KXp1A3u7Bi1j8ph3EGsZ9Xvz9dGuVrtHHs7pXeTzjuxBrCmmhgC6",
+		},
+		{
+			seed3,
+			"m/0'",
+			MainNetParams,
+			"xpub68NZiKmJWnxxS6aaHmn81bvJeTESw724CRDs6HbuccFQN9Ku14VQrADWgqbhhTHBaohPX4CjNLf9fq9MYo6oDaPPLPxSb7gwQN3ih19Zm4Y",
+			"xprv9uPDJpEQgRQfDcW7BkF7eTya6RPxXeJCqCJGHuCJ4GiRVLzkTXBAJMu2qaMWPrS7AANYqdq6vcBcBUdJCVVFceUvJFjaPdGZ2y9WACViL4L",
+		},
+		{
+			seed1,
+			"m/0'/1/2'/2/1000000000",
+			TestNet3Params,
+			"tpubDHNy3kAG39ThyiwwsgoKY4iRenXDRtce8qdCFJZXPMCJg5dsCUHayp84raLTpvyiNA9sXPob5rgqkKvkN8S7MMyXbnEhGJMW64Cf4vFAoaF",
+			"tprv8kgvuL81tmn36Fv9z38j8f4K5m1HGZRjZY2QxnXDy5PuqbP6a5TzoKWCgTcGHBu66W3TgSbAu2yX6sPza5FkHmy564Sh6gmCPUNeUt4yj2x",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.xprv, func(t *testing.T) {
+			seed, err := hex.DecodeString(tc.seed)
+			require.NoError(t, err)
+
+			path, err := ParseDerivationPath(tc.path)
+			require.NoError(t, err)
+			assert.Equal(t, path.String(), tc.path)
+
+			master, err := NewMasterExtendedKey(seed, tc.params)
+			require.NoError(t, err)
+
+			key, err := master.Derive(path)
+			require.NoError(t, err)
+			assert.Equal(t, int(key.Depth()), len(path))
+
+			xprv, err := key.Serialize()
+			require.NoError(t, err)
+			assert.Equal(t, xprv, tc.xprv)
+
+			xpub, err := key.Neuter().Serialize()
+			require.NoError(t, err)
+			assert.Equal(t, xpub, tc.xpub)
+
+			// string -> extended key
+			parsed, err := NewExtendedKeyFromString(tc.xprv, tc.params)
+			require.NoError(t, err)
+			assert.True(t, parsed.IsPrivate())
+			s, err := parsed.Serialize()
+			require.NoError(t, err)
+			assert.Equal(t, s, tc.xprv)
+
+			parsed, err = NewExtendedKeyFromString(tc.xpub, tc.params)
+			require.NoError(t, err)
+			assert.False(t, parsed.IsPrivate())
+			s, err = parsed.Serialize()
+			require.NoError(t, err)
+			assert.Equal(t, s, tc.xpub)
+		})
+	}
+}
+
+func TestExtendedKeyPublicDerivation(t *testing.T) {
+	seed, err := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	require.NoError(t, err)
+
+	master, err := NewMasterExtendedKey(seed, MainNetParams)
+	require.NoError(t, err)
+
+	account, err := master.Derive(DerivationPath{HardenedKeyStart + 84, HardenedKeyStart, HardenedKeyStart})
+	require.NoError(t, err)
+
+	// CKDpub(N(k)) == N(CKDpriv(k)) for non-hardened indexes
+	privChild, err := account.Derive(DerivationPath{0, 5})
+	require.NoError(t, err)
+	pubChild, err := account.Neuter().Derive(DerivationPath{0, 5})
+	require.NoError(t, err)
+
+	assert.True(t, privChild.PublicKey().IsEqual(pubChild.PublicKey()))
+	assert.Equal(t, privChild.ChainCode(), pubChild.ChainCode())
+
+	fingerprint, err := account.Fingerprint()
+	require.NoError(t, err)
+	change, err := account.Child(0)
+	require.NoError(t, err)
+	assert.Equal(t, change.ParentFingerprint(), fingerprint)
+	assert.Equal(t, change.ChildIndex(), uint32(0))
+
+	_, err = account.Neuter().Child(HardenedKeyStart)
+	assert.Equal(t, err, ErrHardenedFromPublicKey)
+
+	_, err = account.Neuter().PrivateKey()
+	assert.Equal(t, err, ErrNotPrivateExtendedKey)
+}
+
+func TestExtendedKeyErrors(t *testing.T) {
+	_, err := NewMasterExtendedKey(make([]byte, HdSeedLengthMin-1), MainNetParams)
+	assert.Equal(t, err, ErrInvalidHdSeedLength)
+
+	_, err = NewMasterExtendedKey(make([]byte, HdSeedLengthMax+1), MainNetParams)
+	assert.Equal(t, err, ErrInvalidHdSeedLength)
+
+	testCases := []struct {
+		name   string
+		s      string
+		params *Params
+		err    error
+	}{
+		{
+			"network mismatch",
+			"xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi",
+			TestNet3Params,
+			ErrInvalidExtendedKeyVersion,
+		},
+		{
+			"invalid checksum",
+			"xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHj",
+			MainNetParams,
+			ErrInvalidExtendedKey,
+		},
+		{
+			"invalid length",
+			"16UwLL9Risc3QfPqBUvKofHmBQ7wMtjvM",
+			MainNetParams,
+			ErrInvalidExtendedKey,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewExtendedKeyFromString(tc.s, tc.params)
+			assert.Equal(t, err, tc.err)
+		})
+	}
+}
+
+func TestParseDerivationPath(t *testing.T) {
+	testCases := []struct {
+		s    string
+		path DerivationPath
+		err  error
+	}{
+		{"m", DerivationPath{}, nil},
+		{"m/84'/0'/0'/0/5", DerivationPath{HardenedKeyStart + 84, HardenedKeyStart, HardenedKeyStart, 0, 5}, nil},
+		{"m/84h/0H/0'", DerivationPath{HardenedKeyStart + 84, HardenedKeyStart, HardenedKeyStart}, nil},
+		{"0/1", DerivationPath{0, 1}, nil},
+		{"m/2147483647'", DerivationPath{0xffffffff}, nil},
+		{"", nil, ErrInvalidDerivationPath},
+		{"m/", nil, ErrInvalidDerivationPath},
+		{"m/-1", nil, ErrInvalidDerivationPath},
+		{"m/+1", nil, ErrInvalidDerivationPath},
+		{"m/2147483648", nil, ErrInvalidDerivationPath},
+		{"m/1''", nil, ErrInvalidDerivationPath},
+		{"m/0/m", nil, ErrInvalidDerivationPath},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.s, func(t *testing.T) {
+			path, err := ParseDerivationPath(tc.s)
+			assert.Equal(t, err, tc.err)
+			assert.Equal(t, path, tc.path)
+		})
+	}
+}
@@ -0,0 +1,289 @@
+package btc
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math/big"
+)
+
+const (
+	pubKeyFormatCompressedEven byte = 0x02
+	pubKeyFormatCompressedOdd  byte = 0x03
+	pubKeyFormatUncompressed   byte = 0x04
+)
+
+var (
+	ErrInvalidPrivateKey = errors.New("invalid private key")
+	ErrInvalidPubKey     = errors.New("invalid public key")
+	ErrInvalidTweak      = errors.New("invalid tweak")
+)
+
+type PrivateKey struct {
+	d *big.Int
+}
+
+func NewPrivateKey() (*PrivateKey, error) {
+	for {
+		b := make([]byte, PrivateKeyLength)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+
+		privKey, err := NewPrivateKeyFromBytes(b)
+		if err == ErrInvalidPrivateKey {
+			continue
+		}
+
+		return privKey, err
+	}
+}
+
+func NewPrivateKeyFromHex(s string) (*PrivateKey, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPrivateKeyFromBytes(b)
+}
+
+func NewPrivateKeyFromBytes(b []byte) (*PrivateKey, error) {
+	if len(b) != PrivateKeyLength {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	d := new(big.Int).SetBytes(b)
+	if d.Sign() == 0 || d.Cmp(secp256k1.N) >= 0 {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	return &PrivateKey{d}, nil
+}
+
+func (privKey *PrivateKey) Bytes() []byte {
+	return paddedBytes(privKey.d, PrivateKeyLength)
+}
+
+func (privKey *PrivateKey) Hex() string {
+	return hex.EncodeToString(privKey.Bytes())
+}
+
+func (privKey *PrivateKey) PublicKey() *PublicKey {
+	x, y := secp256k1.ScalarBaseMult(privKey.d)
+
+	return &PublicKey{
+		x:            x,
+		y:            y,
+		uncompressed: false,
+	}
+}
+
+func (privKey *PrivateKey) IsEqual(other *PrivateKey) bool {
+	return privKey.d.Cmp(other.d) == 0
+}
+
+// TweakAdd returns the private key (d + tweak) mod n.
+func (privKey *PrivateKey) TweakAdd(tweak []byte) (*PrivateKey, error) {
+	t, err := parseTweak(tweak)
+	if err != nil {
+		return nil, err
+	}
+
+	d := new(big.Int).Add(privKey.d, t)
+	d.Mod(d, secp256k1.N)
+	if d.Sign() == 0 {
+		return nil, ErrInvalidTweak
+	}
+
+	return &PrivateKey{d}, nil
+}
+
+// TweakMul returns the private key (d * tweak) mod n.
+func (privKey *PrivateKey) TweakMul(tweak []byte) (*PrivateKey, error) {
+	t, err := parseTweak(tweak)
+	if err != nil {
+		return nil, err
+	}
+	if t.Sign() == 0 {
+		return nil, ErrInvalidTweak
+	}
+
+	d := new(big.Int).Mul(privKey.d, t)
+	d.Mod(d, secp256k1.N)
+
+	return &PrivateKey{d}, nil
+}
+
+type PublicKey struct {
+	x            *big.Int
+	y            *big.Int
+	uncompressed bool
+}
+
+func NewPublicKeyFromHex(s string) (*PublicKey, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPublicKeyFromBytes(b)
+}
+
+// ref. https://www.secg.org/sec1-v2.pdf (2.3.4)
+func NewPublicKeyFromBytes(b []byte) (*PublicKey, error) {
+	switch len(b) {
+	case CompressedPubKeyLength:
+		if b[0] != pubKeyFormatCompressedEven && b[0] != pubKeyFormatCompressedOdd {
+			return nil, ErrInvalidPubKey
+		}
+
+		x := new(big.Int).SetBytes(b[1:])
+		y := secp256k1.DecompressY(x, b[0] == pubKeyFormatCompressedOdd)
+		if y == nil {
+			return nil, ErrInvalidPubKey
+		}
+
+		return &PublicKey{
+			x:            x,
+			y:            y,
+			uncompressed: false,
+		}, nil
+	case PubKeyLength:
+		if b[0] != pubKeyFormatUncompressed {
+			return nil, ErrInvalidPubKey
+		}
+
+		x := new(big.Int).SetBytes(b[1:33])
+		y := new(big.Int).SetBytes(b[33:])
+		if !secp256k1.IsOnCurve(x, y) {
+			return nil, ErrInvalidPubKey
+		}
+
+		return &PublicKey{
+			x:            x,
+			y:            y,
+			uncompressed: true,
+		}, nil
+	default:
+		return nil, ErrInvalidPubKey
+	}
+}
+
+func (pubKey *PublicKey) IsCompressed() bool {
+	return !pubKey.uncompressed
+}
+
+func (pubKey *PublicKey) Compressed() *PublicKey {
+	return &PublicKey{
+		x:            pubKey.x,
+		y:            pubKey.y,
+		uncompressed: false,
+	}
+}
+
+func (pubKey *PublicKey) Uncompressed() *PublicKey {
+	return &PublicKey{
+		x:            pubKey.x,
+		y:            pubKey.y,
+		uncompressed: true,
+	}
+}
+
+// Bytes returns the SEC1 serialization of the public key
+// in the format it was parsed or created with.
+func (pubKey *PublicKey) Bytes() []byte {
+	if pubKey.uncompressed {
+		return pubKey.UncompressedBytes()
+	}
+
+	return pubKey.CompressedBytes()
+}
+
+func (pubKey *PublicKey) CompressedBytes() []byte {
+	b := make([]byte, 0, CompressedPubKeyLength)
+	if pubKey.y.Bit(0) == 1 {
+		b = append(b, pubKeyFormatCompressedOdd)
+	} else {
+		b = append(b, pubKeyFormatCompressedEven)
+	}
+
+	return append(b, paddedBytes(pubKey.x, 32)...)
+}
+
+func (pubKey *PublicKey) UncompressedBytes() []byte {
+	b := make([]byte, 0, PubKeyLength)
+	b = append(b, pubKeyFormatUncompressed)
+	b = append(b, paddedBytes(pubKey.x, 32)...)
+
+	return append(b, paddedBytes(pubKey.y, 32)...)
+}
+
+func (pubKey *PublicKey) Hex() string {
+	return hex.EncodeToString(pubKey.Bytes())
+}
+
+func (pubKey *PublicKey) Pkh() (Pkh, error) {
+	b, err := Hash160(pubKey.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	return Pkh(b), nil
+}
+
+func (pubKey *PublicKey) IsEqual(other *PublicKey) bool {
+	return pubKey.x.Cmp(other.x) == 0 && pubKey.y.Cmp(other.y) == 0
+}
+
+// TweakAdd returns the public key P + tweak*G.
+func (pubKey *PublicKey) TweakAdd(tweak []byte) (*PublicKey, error) {
+	t, err := parseTweak(tweak)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, ty := secp256k1.ScalarBaseMult(t)
+	x, y := secp256k1.Add(pubKey.x, pubKey.y, tx, ty)
+	if x == nil {
+		return nil, ErrInvalidTweak
+	}
+
+	return &PublicKey{
+		x:            x,
+		y:            y,
+		uncompressed: pubKey.uncompressed,
+	}, nil
+}
+
+// TweakMul returns the public key tweak*P.
+func (pubKey *PublicKey) TweakMul(tweak []byte) (*PublicKey, error) {
+	t, err := parseTweak(tweak)
+	if err != nil {
+		return nil, err
+	}
+	if t.Sign() == 0 {
+		return nil, ErrInvalidTweak
+	}
+
+	x, y := secp256k1.ScalarMult(pubKey.x, pubKey.y, t)
+
+	return &PublicKey{
+		x:            x,
+		y:            y,
+		uncompressed: pubKey.uncompressed,
+	}, nil
+}
+
+func parseTweak(tweak []byte) (*big.Int, error) {
+	if len(tweak) != 32 {
+		return nil, ErrInvalidTweak
+	}
+
+	t := new(big.Int).SetBytes(tweak)
+	if t.Cmp(secp256k1.N) >= 0 {
+		return nil, ErrInvalidTweak
+	}
+
+	return t, nil
+}
@@ -0,0 +1,141 @@
+package btc
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyDerivation(t *testing.T) {
+	testCases := []struct {
+		privKey            string
+		compressedPubKey   string
+		uncompressedPubKey string
+	}{
+		{
+			"0000000000000000000000000000000000000000000000000000000000000001",
+			"0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+			"0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8",
+		},
+		{
+			"fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140",
+			"0379be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+			"0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798b7c52588d95c3b9aa25b0403f1eef75702e84bb7597aabe663b82f6f04ef2777",
+		},
+		{
+			// ref. https://en.bitcoin.it/wiki/Technical_background_of_version_1_Bitcoin_addresses
+			"18e14a7b6a307f426a94f8114701e7c8e774e7f9a47e2c2035db29a206321725",
+			"0250863ad64a87ae8a2fe83c1af1a8403cb53f53e486d8511dad8a04887e5b2352",
+			"0450863ad64a87ae8a2fe83c1af1a8403cb53f53e486d8511dad8a04887e5b23522cd470243453a299fa9e77237716103abc11a1df38855ed6f2ee187e9c582ba6",
+		},
+		{
+			"e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35",
+			"0339a36013301597daef41fbe593a02cc513d0b55527ec2df1050e2e8ff49c85c2",
+			"0439a36013301597daef41fbe593a02cc513d0b55527ec2df1050e2e8ff49c85c23cbe7ded0e7ce6a594896b8f62888fdbc5c8821305e2ea42bf01e37300116281",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.privKey, func(t *testing.T) {
+			privKey, err := NewPrivateKeyFromHex(tc.privKey)
+			require.NoError(t, err)
+			assert.Equal(t, privKey.Hex(), tc.privKey)
+
+			pubKey := privKey.PublicKey()
+			assert.True(t, pubKey.IsCompressed())
+			assert.Equal(t, pubKey.Hex(), tc.compressedPubKey)
+			assert.Equal(t, pubKey.Uncompressed().Hex(), tc.uncompressedPubKey)
+
+			// compressed
+			parsed, err := NewPublicKeyFromHex(tc.compressedPubKey)
+			require.NoError(t, err)
+			assert.True(t, parsed.IsCompressed())
+			assert.True(t, parsed.IsEqual(pubKey))
+
+			// uncompressed
+			parsed, err = NewPublicKeyFromHex(tc.uncompressedPubKey)
+			require.NoError(t, err)
+			assert.False(t, parsed.IsCompressed())
+			assert.True(t, parsed.IsEqual(pubKey))
+			assert.Equal(t, parsed.Hex(), tc.uncompressedPubKey)
+		})
+	}
+}
+
+func TestPubKeyPkh(t *testing.T) {
+	pubKey, err := NewPublicKeyFromHex("0450863ad64a87ae8a2fe83c1af1a8403cb53f53e486d8511dad8a04887e5b23522cd470243453a299fa9e77237716103abc11a1df38855ed6f2ee187e9c582ba6")
+	require.NoError(t, err)
+
+	pkh, err := pubKey.Pkh()
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(pkh), "010966776006953d5567439e5e39f86a0d273bee")
+
+	pkh, err = pubKey.Compressed().Pkh()
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(pkh), "f54a5851e9372b87810a8e60cdd2e7cfd80b6e31")
+}
+
+func TestInvalidKeys(t *testing.T) {
+	privKeys := []string{
+		"",
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141",
+		"18e14a7b6a307f426a94f8114701e7c8e774e7f9a47e2c2035db29a20632172501",
+	}
+	for _, s := range privKeys {
+		_, err := NewPrivateKeyFromHex(s)
+		assert.Equal(t, err, ErrInvalidPrivateKey, s)
+	}
+
+	pubKeys := []string{
+		"",
+		// invalid prefix
+		"0550863ad64a87ae8a2fe83c1af1a8403cb53f53e486d8511dad8a04887e5b2352",
+		// x is not on the curve
+		"020000000000000000000000000000000000000000000000000000000000000005",
+		// x >= p
+		"02fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc30",
+		// y does not match x
+		"0450863ad64a87ae8a2fe83c1af1a8403cb53f53e486d8511dad8a04887e5b23522cd470243453a299fa9e77237716103abc11a1df38855ed6f2ee187e9c582ba7",
+	}
+	for _, s := range pubKeys {
+		_, err := NewPublicKeyFromHex(s)
+		assert.Equal(t, err, ErrInvalidPubKey, s)
+	}
+}
+
+func TestKeyTweak(t *testing.T) {
+	privKey, err := NewPrivateKeyFromHex("e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35")
+	require.NoError(t, err)
+	pubKey := privKey.PublicKey()
+
+	tweak, err := hex.DecodeString("873dff81c02f525623fd1fe5167eac3a55a049de3d314bb42ee227ffed37d508")
+	require.NoError(t, err)
+
+	// add
+	tweakedPrivKey, err := privKey.TweakAdd(tweak)
+	require.NoError(t, err)
+	tweakedPubKey, err := pubKey.TweakAdd(tweak)
+	require.NoError(t, err)
+	assert.True(t, tweakedPrivKey.PublicKey().IsEqual(tweakedPubKey))
+
+	// mul
+	tweakedPrivKey, err = privKey.TweakMul(tweak)
+	require.NoError(t, err)
+	tweakedPubKey, err = pubKey.TweakMul(tweak)
+	require.NoError(t, err)
+	assert.True(t, tweakedPrivKey.PublicKey().IsEqual(tweakedPubKey))
+
+	// d + (n - d) = 0
+	_, err = privKey.TweakAdd(paddedBytes(new(big.Int).Sub(secp256k1.N, privKey.d), 32))
+	assert.Equal(t, err, ErrInvalidTweak)
+
+	_, err = pubKey.TweakMul(make([]byte, 32))
+	assert.Equal(t, err, ErrInvalidTweak)
+
+	_, err = pubKey.TweakAdd(secp256k1.N.Bytes())
+	assert.Equal(t, err, ErrInvalidTweak)
+}
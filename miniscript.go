@@ -0,0 +1,1524 @@
+package btc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	MiniscriptContextP2wsh MiniscriptContext = iota + 1
+	MiniscriptContextTapscript
+
+	miniscriptMaxMultiKeys        = 20
+	miniscriptMaxMultiAKeys       = 999
+	miniscriptTimelockMax         = 0x80000000
+	miniscriptLockTimeThreshold   = 500000000
+	miniscriptSequenceDisableFlag = 1 << 31
+	miniscriptSequenceTypeFlag    = 1 << 22
+	miniscriptSequenceMask        = 0x0000ffff
+	miniscriptHashPreimageSize    = 32
+
+	// ref. https://github.com/bitcoin/bitcoin/blob/master/src/script/miniscript.h
+	miniscriptTypeChars = "BVKWzonduefsmxghijk"
+)
+
+var (
+	ErrInvalidMiniscript               = errors.New("invalid miniscript")
+	ErrInvalidMiniscriptType           = errors.New("invalid miniscript type")
+	ErrInvalidMiniscriptKey            = errors.New("invalid miniscript key")
+	ErrInvalidMiniscriptContext        = errors.New("miniscript fragment not allowed in this context")
+	ErrMiniscriptUnsatisfiable         = errors.New("miniscript not satisfiable")
+	ErrMiniscriptMalleableSatisfaction = errors.New("no non-malleable miniscript satisfaction")
+)
+
+// MiniscriptContext is the script context a miniscript is written for.
+type MiniscriptContext int
+
+func (ctx MiniscriptContext) isTapscript() bool {
+	return ctx == MiniscriptContextTapscript
+}
+
+// sigSize and pubKeySize return the maximum sizes of a signature and a public key
+// pushed onto the stack, including the length prefix.
+func (ctx MiniscriptContext) sigSize() int {
+	if ctx.isTapscript() {
+		return 1 + 65
+	}
+
+	return 1 + 72
+}
+
+func (ctx MiniscriptContext) pubKeySize() int {
+	if ctx.isTapscript() {
+		return 1 + XOnlyPubKeyLength
+	}
+
+	return 1 + CompressedPubKeyLength
+}
+
+type miniscriptFragment int
+
+const (
+	miniscriptJust0 miniscriptFragment = iota
+	miniscriptJust1
+	miniscriptPkK
+	miniscriptPkH
+	miniscriptOlder
+	miniscriptAfter
+	miniscriptSha256
+	miniscriptHash256
+	miniscriptRipemd160
+	miniscriptHash160
+	miniscriptWrapA
+	miniscriptWrapS
+	miniscriptWrapC
+	miniscriptWrapD
+	miniscriptWrapV
+	miniscriptWrapJ
+	miniscriptWrapN
+	miniscriptAndV
+	miniscriptAndB
+	miniscriptOrB
+	miniscriptOrC
+	miniscriptOrD
+	miniscriptOrI
+	miniscriptAndOr
+	miniscriptThresh
+	miniscriptMulti
+	miniscriptMultiA
+)
+
+var miniscriptFragmentNameMap = map[miniscriptFragment]string{
+	miniscriptPkK:       "pk_k",
+	miniscriptPkH:       "pk_h",
+	miniscriptOlder:     "older",
+	miniscriptAfter:     "after",
+	miniscriptSha256:    "sha256",
+	miniscriptHash256:   "hash256",
+	miniscriptRipemd160: "ripemd160",
+	miniscriptHash160:   "hash160",
+	miniscriptAndV:      "and_v",
+	miniscriptAndB:      "and_b",
+	miniscriptOrB:       "or_b",
+	miniscriptOrC:       "or_c",
+	miniscriptOrD:       "or_d",
+	miniscriptOrI:       "or_i",
+	miniscriptAndOr:     "andor",
+	miniscriptThresh:    "thresh",
+	miniscriptMulti:     "multi",
+	miniscriptMultiA:    "multi_a",
+}
+
+var miniscriptWrapperMap = map[byte]miniscriptFragment{
+	'a': miniscriptWrapA,
+	's': miniscriptWrapS,
+	'c': miniscriptWrapC,
+	'd': miniscriptWrapD,
+	'v': miniscriptWrapV,
+	'j': miniscriptWrapJ,
+	'n': miniscriptWrapN,
+}
+
+// miniscriptType is a set of the basic types (B, V, K, W) and the type properties of an expression.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0379.mediawiki#type-system
+type miniscriptType uint32
+
+func mst(props string) miniscriptType {
+	var typ miniscriptType
+	for i := 0; i < len(props); i++ {
+		typ |= 1 << uint(strings.IndexByte(miniscriptTypeChars, props[i]))
+	}
+
+	return typ
+}
+
+func (typ miniscriptType) has(props string) bool {
+	t := mst(props)
+	return typ&t == t
+}
+
+func (typ miniscriptType) when(cond bool) miniscriptType {
+	if cond {
+		return typ
+	}
+
+	return 0
+}
+
+func (typ miniscriptType) isValid() bool {
+	return typ&mst("BVKW") != 0
+}
+
+func (typ miniscriptType) String() string {
+	var sb strings.Builder
+	for i := 0; i < len(miniscriptTypeChars); i++ {
+		if typ&(1<<uint(i)) != 0 {
+			sb.WriteByte(miniscriptTypeChars[i])
+		}
+	}
+
+	return sb.String()
+}
+
+// miniscriptTimelocksConflict reports whether satisfying both x and y would mix
+// height and time based timelocks of the same kind.
+func miniscriptTimelocksConflict(x, y miniscriptType) bool {
+	return (x.has("g") && y.has("h")) ||
+		(x.has("h") && y.has("g")) ||
+		(x.has("i") && y.has("j")) ||
+		(x.has("j") && y.has("i"))
+}
+
+// Miniscript is a miniscript expression for the P2WSH or tapscript context.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0379.mediawiki
+type Miniscript struct {
+	ctx      MiniscriptContext
+	fragment miniscriptFragment
+	k        uint32
+	keys     [][]byte
+	data     []byte
+	subs     []*Miniscript
+	typ      miniscriptType
+}
+
+func newMiniscript(ctx MiniscriptContext, fragment miniscriptFragment, k uint32, keys [][]byte, data []byte, subs ...*Miniscript) *Miniscript {
+	node := &Miniscript{
+		ctx:      ctx,
+		fragment: fragment,
+		k:        k,
+		keys:     keys,
+		data:     data,
+		subs:     subs,
+	}
+	node.typ = node.computeType()
+
+	return node
+}
+
+// ref. https://github.com/bitcoin/bitcoin/blob/master/src/script/miniscript.cpp
+func (node *Miniscript) computeType() miniscriptType {
+	var x, y, z miniscriptType
+	if len(node.subs) > 0 {
+		x = node.subs[0].typ
+	}
+	if len(node.subs) > 1 {
+		y = node.subs[1].typ
+	}
+	if len(node.subs) > 2 {
+		z = node.subs[2].typ
+	}
+
+	switch node.fragment {
+	case miniscriptPkK:
+		return mst("Konudemsxk")
+	case miniscriptPkH:
+		return mst("Knudemsxk")
+	case miniscriptOlder:
+		return mst("g").when(node.k&miniscriptSequenceTypeFlag != 0) |
+			mst("h").when(node.k&miniscriptSequenceTypeFlag == 0) |
+			mst("Bzfmxk")
+	case miniscriptAfter:
+		return mst("i").when(node.k >= miniscriptLockTimeThreshold) |
+			mst("j").when(node.k < miniscriptLockTimeThreshold) |
+			mst("Bzfmxk")
+	case miniscriptSha256, miniscriptHash256, miniscriptRipemd160, miniscriptHash160:
+		return mst("Bonudmk")
+	case miniscriptJust1:
+		return mst("Bzufmxk")
+	case miniscriptJust0:
+		return mst("Bzudemsxk")
+	case miniscriptWrapA:
+		return mst("W").when(x.has("B")) |
+			x&mst("ghijk") |
+			x&mst("udfems") |
+			mst("x")
+	case miniscriptWrapS:
+		return mst("W").when(x.has("Bo")) |
+			x&mst("ghijk") |
+			x&mst("udfemsx")
+	case miniscriptWrapC:
+		return mst("B").when(x.has("K")) |
+			x&mst("ghijk") |
+			x&mst("ondfem") |
+			mst("us")
+	case miniscriptWrapD:
+		// d: is u only in tapscript, where MINIMALIF is a consensus rule
+		return mst("B").when(x.has("Vz")) |
+			mst("o").when(x.has("z")) |
+			mst("e").when(x.has("f")) |
+			x&mst("ghijk") |
+			x&mst("ms") |
+			mst("u").when(node.ctx.isTapscript()) |
+			mst("ndx")
+	case miniscriptWrapV:
+		return mst("V").when(x.has("B")) |
+			x&mst("ghijk") |
+			x&mst("zonms") |
+			mst("fx")
+	case miniscriptWrapJ:
+		return mst("B").when(x.has("Bn")) |
+			mst("e").when(x.has("f")) |
+			x&mst("ghijk") |
+			x&mst("oums") |
+			mst("ndx")
+	case miniscriptWrapN:
+		return x&mst("ghijk") |
+			x&mst("Bzondfems") |
+			mst("ux")
+	case miniscriptAndV:
+		return (y & mst("KVB")).when(x.has("V")) |
+			x&mst("n") | (y & mst("n")).when(x.has("z")) |
+			((x | y) & mst("o")).when((x | y).has("z")) |
+			x&y&mst("dmz") |
+			(x|y)&mst("s") |
+			mst("f").when(y.has("f") || x.has("s")) |
+			y&mst("ux") |
+			(x|y)&mst("ghij") |
+			mst("k").when((x&y).has("k") && !miniscriptTimelocksConflict(x, y))
+	case miniscriptAndB:
+		return (x & mst("B")).when(y.has("W")) |
+			((x | y) & mst("o")).when((x | y).has("z")) |
+			x&mst("n") | (y & mst("n")).when(x.has("z")) |
+			(x & y & mst("e")).when((x & y).has("s")) |
+			x&y&mst("dzm") |
+			mst("f").when((x&y).has("f") || x.has("sf") || y.has("sf")) |
+			(x|y)&mst("s") |
+			mst("ux") |
+			(x|y)&mst("ghij") |
+			mst("k").when((x&y).has("k") && !miniscriptTimelocksConflict(x, y))
+	case miniscriptOrB:
+		return mst("B").when(x.has("Bd") && y.has("Wd")) |
+			((x | y) & mst("o")).when((x | y).has("z")) |
+			(x & y & mst("m")).when((x|y).has("s") && (x&y).has("e")) |
+			x&y&mst("zse") |
+			mst("dux") |
+			(x|y)&mst("ghij") |
+			x&y&mst("k")
+	case miniscriptOrD:
+		return (y & mst("B")).when(x.has("Bdu")) |
+			(x & mst("o")).when(y.has("z")) |
+			(x & y & mst("m")).when(x.has("e") && (x|y).has("s")) |
+			x&y&mst("zs") |
+			y&mst("ufde") |
+			mst("x") |
+			(x|y)&mst("ghij") |
+			x&y&mst("k")
+	case miniscriptOrC:
+		return (y & mst("V")).when(x.has("Bdu")) |
+			(x & mst("o")).when(y.has("z")) |
+			(x & y & mst("m")).when(x.has("e") && (x|y).has("s")) |
+			x&y&mst("zs") |
+			mst("fx") |
+			(x|y)&mst("ghij") |
+			x&y&mst("k")
+	case miniscriptOrI:
+		return x&y&mst("VBKufs") |
+			mst("o").when((x & y).has("z")) |
+			((x | y) & mst("e")).when((x | y).has("f")) |
+			(x & y & mst("m")).when((x | y).has("s")) |
+			(x|y)&mst("d") |
+			mst("x") |
+			(x|y)&mst("ghij") |
+			x&y&mst("k")
+	case miniscriptAndOr:
+		return (y & z & mst("BKV")).when(x.has("Bdu")) |
+			x&y&z&mst("z") |
+			((x | (y & z)) & mst("o")).when((x | (y & z)).has("z")) |
+			y&z&mst("u") |
+			(z & mst("f")).when(x.has("s") || y.has("f")) |
+			z&mst("d") |
+			(z & mst("e")).when(x.has("s") || y.has("f")) |
+			(x & y & z & mst("m")).when(x.has("e") && (x|y|z).has("s")) |
+			z&(x|y)&mst("s") |
+			mst("x") |
+			(x|y|z)&mst("ghij") |
+			mst("k").when((x&y&z).has("k") && !miniscriptTimelocksConflict(x, y))
+	case miniscriptMulti:
+		return mst("Bnudemsk")
+	case miniscriptMultiA:
+		return mst("Budemsk")
+	case miniscriptThresh:
+		allE, allM := true, true
+		args, numS := 0, 0
+		acc := mst("k")
+		for i, sub := range node.subs {
+			t := sub.typ
+			if (i == 0 && !t.has("Bdu")) || (i > 0 && !t.has("Wdu")) {
+				return 0
+			}
+			if !t.has("e") {
+				allE = false
+			}
+			if !t.has("m") {
+				allM = false
+			}
+			if t.has("s") {
+				numS++
+			}
+			switch {
+			case t.has("z"):
+			case t.has("o"):
+				args++
+			default:
+				args += 2
+			}
+			acc = (acc|t)&mst("ghij") |
+				mst("k").when((acc&t).has("k") && (node.k <= 1 || !miniscriptTimelocksConflict(acc, t)))
+		}
+		n := len(node.subs)
+		k := int(node.k)
+		return mst("Bdu") |
+			mst("z").when(args == 0) |
+			mst("o").when(args == 1) |
+			mst("e").when(allE && numS == n) |
+			mst("m").when(allE && allM && numS >= n-k) |
+			mst("s").when(numS >= n-k+1) |
+			acc
+	default:
+		return 0
+	}
+}
+
+// ParseMiniscript parses a miniscript expression of type B for ctx.
+// Keys are hex encoded compressed public keys for P2WSH and x-only ones for tapscript.
+func ParseMiniscript(s string, ctx MiniscriptContext) (*Miniscript, error) {
+	if ctx != MiniscriptContextP2wsh && ctx != MiniscriptContextTapscript {
+		return nil, ErrInvalidMiniscriptContext
+	}
+
+	node, err := parseMiniscript(s, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !node.typ.has("B") {
+		return nil, ErrInvalidMiniscriptType
+	}
+
+	return node, nil
+}
+
+func parseMiniscript(s string, ctx MiniscriptContext) (*Miniscript, error) {
+	open := strings.IndexByte(s, '(')
+	if colon := strings.IndexByte(s, ':'); colon >= 0 && (open < 0 || colon < open) {
+		wrappers := s[:colon]
+		if len(wrappers) == 0 {
+			return nil, ErrInvalidMiniscript
+		}
+
+		node, err := parseMiniscript(s[colon+1:], ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := len(wrappers) - 1; i >= 0; i-- {
+			switch c := wrappers[i]; c {
+			case 't':
+				node = newMiniscript(ctx, miniscriptAndV, 0, nil, nil, node, newMiniscript(ctx, miniscriptJust1, 0, nil, nil))
+			case 'l':
+				node = newMiniscript(ctx, miniscriptOrI, 0, nil, nil, newMiniscript(ctx, miniscriptJust0, 0, nil, nil), node)
+			case 'u':
+				node = newMiniscript(ctx, miniscriptOrI, 0, nil, nil, node, newMiniscript(ctx, miniscriptJust0, 0, nil, nil))
+			default:
+				fragment, ok := miniscriptWrapperMap[c]
+				if !ok {
+					return nil, ErrInvalidMiniscript
+				}
+				node = newMiniscript(ctx, fragment, 0, nil, nil, node)
+			}
+			if !node.typ.isValid() {
+				return nil, ErrInvalidMiniscriptType
+			}
+		}
+
+		return node, nil
+	}
+
+	switch s {
+	case "0":
+		return newMiniscript(ctx, miniscriptJust0, 0, nil, nil), nil
+	case "1":
+		return newMiniscript(ctx, miniscriptJust1, 0, nil, nil), nil
+	}
+
+	name, args, err := splitDescriptorFunc(s)
+	if err != nil {
+		return nil, ErrInvalidMiniscript
+	}
+
+	var node *Miniscript
+	switch name {
+	case "pk_k", "pk_h", "pk", "pkh":
+		if len(args) != 1 {
+			return nil, ErrInvalidMiniscript
+		}
+		key, err := parseMiniscriptKey(args[0], ctx)
+		if err != nil {
+			return nil, err
+		}
+		switch name {
+		case "pk_k", "pk":
+			node = newMiniscript(ctx, miniscriptPkK, 0, [][]byte{key}, nil)
+		default:
+			node = newMiniscript(ctx, miniscriptPkH, 0, [][]byte{key}, nil)
+		}
+		if name == "pk" || name == "pkh" {
+			node = newMiniscript(ctx, miniscriptWrapC, 0, nil, nil, node)
+		}
+
+	case "older", "after":
+		if len(args) != 1 {
+			return nil, ErrInvalidMiniscript
+		}
+		k, err := parseMiniscriptUint(args[0])
+		if err != nil || k < 1 || k >= miniscriptTimelockMax {
+			return nil, ErrInvalidMiniscript
+		}
+		fragment := miniscriptOlder
+		if name == "after" {
+			fragment = miniscriptAfter
+		}
+		node = newMiniscript(ctx, fragment, k, nil, nil)
+
+	case "sha256", "hash256", "ripemd160", "hash160":
+		if len(args) != 1 {
+			return nil, ErrInvalidMiniscript
+		}
+		fragment := map[string]miniscriptFragment{
+			"sha256":    miniscriptSha256,
+			"hash256":   miniscriptHash256,
+			"ripemd160": miniscriptRipemd160,
+			"hash160":   miniscriptHash160,
+		}[name]
+		data, err := hex.DecodeString(args[0])
+		if err != nil || len(data) != miniscriptHashLength(fragment) {
+			return nil, ErrInvalidMiniscript
+		}
+		node = newMiniscript(ctx, fragment, 0, nil, data)
+
+	case "and_v", "and_b", "or_b", "or_c", "or_d", "or_i", "and_n", "andor":
+		subs := make([]*Miniscript, len(args))
+		for i, arg := range args {
+			if subs[i], err = parseMiniscript(arg, ctx); err != nil {
+				return nil, err
+			}
+		}
+		switch name {
+		case "andor":
+			if len(subs) != 3 {
+				return nil, ErrInvalidMiniscript
+			}
+			node = newMiniscript(ctx, miniscriptAndOr, 0, nil, nil, subs...)
+		case "and_n":
+			if len(subs) != 2 {
+				return nil, ErrInvalidMiniscript
+			}
+			node = newMiniscript(ctx, miniscriptAndOr, 0, nil, nil, subs[0], subs[1], newMiniscript(ctx, miniscriptJust0, 0, nil, nil))
+		default:
+			if len(subs) != 2 {
+				return nil, ErrInvalidMiniscript
+			}
+			fragment := map[string]miniscriptFragment{
+				"and_v": miniscriptAndV,
+				"and_b": miniscriptAndB,
+				"or_b":  miniscriptOrB,
+				"or_c":  miniscriptOrC,
+				"or_d":  miniscriptOrD,
+				"or_i":  miniscriptOrI,
+			}[name]
+			node = newMiniscript(ctx, fragment, 0, nil, nil, subs...)
+		}
+
+	case "thresh", "multi", "multi_a":
+		if len(args) < 2 {
+			return nil, ErrInvalidMiniscript
+		}
+		k, err := parseMiniscriptUint(args[0])
+		if err != nil || k < 1 || int(k) > len(args)-1 {
+			return nil, ErrInvalidMiniscript
+		}
+		switch name {
+		case "thresh":
+			subs := make([]*Miniscript, len(args)-1)
+			for i, arg := range args[1:] {
+				if subs[i], err = parseMiniscript(arg, ctx); err != nil {
+					return nil, err
+				}
+			}
+			node = newMiniscript(ctx, miniscriptThresh, k, nil, nil, subs...)
+		default:
+			fragment, maxKeys := miniscriptMulti, miniscriptMaxMultiKeys
+			if name == "multi_a" {
+				fragment, maxKeys = miniscriptMultiA, miniscriptMaxMultiAKeys
+			}
+			if (fragment == miniscriptMultiA) != ctx.isTapscript() {
+				return nil, ErrInvalidMiniscriptContext
+			}
+			if len(args)-1 > maxKeys {
+				return nil, ErrInvalidMiniscript
+			}
+			keys := make([][]byte, len(args)-1)
+			for i, arg := range args[1:] {
+				if keys[i], err = parseMiniscriptKey(arg, ctx); err != nil {
+					return nil, err
+				}
+			}
+			node = newMiniscript(ctx, fragment, k, keys, nil)
+		}
+
+	default:
+		return nil, ErrInvalidMiniscript
+	}
+
+	if !node.typ.isValid() {
+		return nil, ErrInvalidMiniscriptType
+	}
+
+	return node, nil
+}
+
+func parseMiniscriptUint(s string) (uint32, error) {
+	if len(s) == 0 || strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return 0, ErrInvalidMiniscript
+	}
+
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, ErrInvalidMiniscript
+	}
+
+	return uint32(n), nil
+}
+
+func parseMiniscriptKey(s string, ctx MiniscriptContext) ([]byte, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidMiniscriptKey
+	}
+
+	if ctx.isTapscript() {
+		if _, err := NewXOnlyPublicKeyFromBytes(b); err != nil {
+			return nil, ErrInvalidMiniscriptKey
+		}
+		return b, nil
+	}
+
+	pubKey, err := NewPublicKeyFromBytes(b)
+	if err != nil || !pubKey.IsCompressed() {
+		return nil, ErrInvalidMiniscriptKey
+	}
+
+	return b, nil
+}
+
+func miniscriptHashLength(fragment miniscriptFragment) int {
+	if fragment == miniscriptRipemd160 || fragment == miniscriptHash160 {
+		return ScriptHashLength
+	}
+
+	return 32
+}
+
+// String returns the canonical text of the miniscript, using the pk, pkh, and_n, t:, l: and u: aliases.
+func (node *Miniscript) String() string {
+	return node.toString(false)
+}
+
+// toString prefixes the expression with ":" if it is the operand of a wrapper
+// and is not a wrapper itself.
+func (node *Miniscript) toString(wrapped bool) string {
+	prefix := ""
+	if wrapped {
+		prefix = ":"
+	}
+
+	switch node.fragment {
+	case miniscriptWrapC:
+		switch node.subs[0].fragment {
+		case miniscriptPkK:
+			return prefix + "pk(" + hex.EncodeToString(node.subs[0].keys[0]) + ")"
+		case miniscriptPkH:
+			return prefix + "pkh(" + hex.EncodeToString(node.subs[0].keys[0]) + ")"
+		}
+		return "c" + node.subs[0].toString(true)
+	case miniscriptWrapA, miniscriptWrapS, miniscriptWrapD, miniscriptWrapV, miniscriptWrapJ, miniscriptWrapN:
+		for c, fragment := range miniscriptWrapperMap {
+			if fragment == node.fragment {
+				return string(c) + node.subs[0].toString(true)
+			}
+		}
+	case miniscriptAndV:
+		if node.subs[1].fragment == miniscriptJust1 {
+			return "t" + node.subs[0].toString(true)
+		}
+	case miniscriptOrI:
+		if node.subs[0].fragment == miniscriptJust0 {
+			return "l" + node.subs[1].toString(true)
+		}
+		if node.subs[1].fragment == miniscriptJust0 {
+			return "u" + node.subs[0].toString(true)
+		}
+	}
+
+	switch node.fragment {
+	case miniscriptJust0:
+		return prefix + "0"
+	case miniscriptJust1:
+		return prefix + "1"
+	case miniscriptPkK, miniscriptPkH:
+		return prefix + miniscriptFragmentNameMap[node.fragment] + "(" + hex.EncodeToString(node.keys[0]) + ")"
+	case miniscriptOlder, miniscriptAfter:
+		return prefix + miniscriptFragmentNameMap[node.fragment] + "(" + strconv.FormatUint(uint64(node.k), 10) + ")"
+	case miniscriptSha256, miniscriptHash256, miniscriptRipemd160, miniscriptHash160:
+		return prefix + miniscriptFragmentNameMap[node.fragment] + "(" + hex.EncodeToString(node.data) + ")"
+	case miniscriptMulti, miniscriptMultiA:
+		args := []string{strconv.FormatUint(uint64(node.k), 10)}
+		for _, key := range node.keys {
+			args = append(args, hex.EncodeToString(key))
+		}
+		return prefix + joinDescriptorArgs(miniscriptFragmentNameMap[node.fragment], args...)
+	}
+
+	var args []string
+	if node.fragment == miniscriptThresh {
+		args = append(args, strconv.FormatUint(uint64(node.k), 10))
+	}
+	for _, sub := range node.subs {
+		args = append(args, sub.toString(false))
+	}
+
+	if node.fragment == miniscriptAndOr && node.subs[2].fragment == miniscriptJust0 {
+		return prefix + joinDescriptorArgs("and_n", args[:2]...)
+	}
+
+	return prefix + joinDescriptorArgs(miniscriptFragmentNameMap[node.fragment], args...)
+}
+
+// Type returns the basic type and the type properties of the miniscript, such as "Bonduesmk".
+func (node *Miniscript) Type() string {
+	return node.typ.String()
+}
+
+// IsNonMalleable reports whether the miniscript always has a non-malleable satisfaction.
+func (node *Miniscript) IsNonMalleable() bool {
+	return node.typ.has("m")
+}
+
+// NeedsSignature reports whether every satisfaction of the miniscript requires a signature.
+func (node *Miniscript) NeedsSignature() bool {
+	return node.typ.has("s")
+}
+
+// HasTimelockMix reports whether the miniscript has a branch requiring
+// both a height based and a time based timelock of the same kind.
+func (node *Miniscript) HasTimelockMix() bool {
+	return !node.typ.has("k")
+}
+
+// IsSane reports whether the miniscript is of type B, non-malleable, needs a signature,
+// has no timelock mix and has no duplicate keys. In P2WSH, the script size, the executed ops
+// and the witness stack items must also be within the limits Bitcoin Core relays.
+func (node *Miniscript) IsSane() bool {
+	if !node.typ.has("B") || !node.IsNonMalleable() || !node.NeedsSignature() || node.HasTimelockMix() {
+		return false
+	}
+
+	seen := make(map[string]bool)
+	for _, key := range node.PublicKeys() {
+		if seen[string(key)] {
+			return false
+		}
+		seen[string(key)] = true
+	}
+
+	if node.ctx == MiniscriptContextP2wsh {
+		return node.isWithinP2wshLimits()
+	}
+
+	return true
+}
+
+func (node *Miniscript) isWithinP2wshLimits() bool {
+	ops, err := node.scriptOps()
+	if err != nil {
+		return false
+	}
+
+	script, err := node.Script()
+	if err != nil {
+		return false
+	}
+	b, err := script.Bytes()
+	if err != nil || len(b) > MaxStandardP2wshScriptSize {
+		return false
+	}
+
+	// every non-push op counts whether executed or not, as do the keys of executed CHECKMULTISIGs
+	opsCount := 0
+	for _, op := range ops {
+		if !op.op.isPushData() && op.op > Op16 {
+			opsCount++
+		}
+	}
+	if sat, _ := node.multisigKeysExecuted(); sat.valid {
+		opsCount += sat.n
+	}
+	if opsCount > MaxOpsPerScript {
+		return false
+	}
+
+	items, err := node.MaxWitnessItems()
+
+	return err == nil && items <= MaxStandardP2wshStackItems
+}
+
+// PublicKeys returns the serialized keys of the miniscript in the order they appear.
+func (node *Miniscript) PublicKeys() [][]byte {
+	keys := append([][]byte{}, node.keys...)
+	for _, sub := range node.subs {
+		keys = append(keys, sub.PublicKeys()...)
+	}
+
+	return keys
+}
+
+// Script returns the script the miniscript encodes.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0379.mediawiki#translation-table
+func (node *Miniscript) Script() (*Script, error) {
+	ops, err := node.scriptOps()
+	if err != nil {
+		return nil, err
+	}
+
+	w := newWriter()
+	for _, op := range ops {
+		if op.op.isPushData() {
+			if err := w.writePushedData(op.data); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := w.writeOpCode(op.op); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewScriptFromBytes(w.Bytes())
+}
+
+var miniscriptVerifyOpCodeMap = map[OpCode]OpCode{
+	OpEqual:         OpEqualVerify,
+	OpCheckSig:      OpCheckSigVerify,
+	OpCheckMultiSig: OpCheckMultiSigVerify,
+	OpNumEqual:      OpNumEqualVerify,
+}
+
+func miniscriptOps(ops ...OpCode) []*scriptOp {
+	sops := make([]*scriptOp, len(ops))
+	for i, op := range ops {
+		sops[i] = &scriptOp{op: op}
+	}
+
+	return sops
+}
+
+func miniscriptPush(data []byte) *scriptOp {
+	return &scriptOp{op: OpCode(len(data)), data: data}
+}
+
+func miniscriptPushNum(n uint32) *scriptOp {
+	if n <= 16 {
+		return &scriptOp{op: smallIntOpCode(int(n))}
+	}
+
+	return miniscriptPush(encodeScriptNum(int64(n)))
+}
+
+func (node *Miniscript) scriptOps() ([]*scriptOp, error) {
+	subOps := make([][]*scriptOp, len(node.subs))
+	for i, sub := range node.subs {
+		ops, err := sub.scriptOps()
+		if err != nil {
+			return nil, err
+		}
+		subOps[i] = ops
+	}
+
+	var ops []*scriptOp
+	add := func(more ...*scriptOp) {
+		ops = append(ops, more...)
+	}
+
+	switch node.fragment {
+	case miniscriptJust0:
+		add(miniscriptOps(Op0)...)
+	case miniscriptJust1:
+		add(miniscriptOps(Op1)...)
+	case miniscriptPkK:
+		add(miniscriptPush(node.keys[0]))
+	case miniscriptPkH:
+		h, err := Hash160(node.keys[0])
+		if err != nil {
+			return nil, err
+		}
+		add(miniscriptOps(OpDup, OpHash160)...)
+		add(miniscriptPush(h))
+		add(miniscriptOps(OpEqualVerify)...)
+	case miniscriptOlder:
+		add(miniscriptPushNum(node.k))
+		add(miniscriptOps(OpCheckSequenceVerify)...)
+	case miniscriptAfter:
+		add(miniscriptPushNum(node.k))
+		add(miniscriptOps(OpCheckLockTimeVerify)...)
+	case miniscriptSha256, miniscriptHash256, miniscriptRipemd160, miniscriptHash160:
+		op := map[miniscriptFragment]OpCode{
+			miniscriptSha256:    OpSha256,
+			miniscriptHash256:   OpHash256,
+			miniscriptRipemd160: OpRipemd160,
+			miniscriptHash160:   OpHash160,
+		}[node.fragment]
+		add(miniscriptOps(OpSize)...)
+		add(miniscriptPushNum(miniscriptHashPreimageSize))
+		add(miniscriptOps(OpEqualVerify, op)...)
+		add(miniscriptPush(node.data))
+		add(miniscriptOps(OpEqual)...)
+	case miniscriptWrapA:
+		add(miniscriptOps(OpToAltStack)...)
+		add(subOps[0]...)
+		add(miniscriptOps(OpFromAltStack)...)
+	case miniscriptWrapS:
+		add(miniscriptOps(OpSwap)...)
+		add(subOps[0]...)
+	case miniscriptWrapC:
+		add(subOps[0]...)
+		add(miniscriptOps(OpCheckSig)...)
+	case miniscriptWrapD:
+		add(miniscriptOps(OpDup, OpIf)...)
+		add(subOps[0]...)
+		add(miniscriptOps(OpEndIf)...)
+	case miniscriptWrapV:
+		add(subOps[0]...)
+		last := ops[len(ops)-1]
+		if op, ok := miniscriptVerifyOpCodeMap[last.op]; ok && last.data == nil {
+			ops[len(ops)-1] = &scriptOp{op: op}
+		} else {
+			add(miniscriptOps(OpVerify)...)
+		}
+	case miniscriptWrapJ:
+		add(miniscriptOps(OpSize, Op0NotEqual, OpIf)...)
+		add(subOps[0]...)
+		add(miniscriptOps(OpEndIf)...)
+	case miniscriptWrapN:
+		add(subOps[0]...)
+		add(miniscriptOps(Op0NotEqual)...)
+	case miniscriptAndV:
+		add(subOps[0]...)
+		add(subOps[1]...)
+	case miniscriptAndB:
+		add(subOps[0]...)
+		add(subOps[1]...)
+		add(miniscriptOps(OpBoolAnd)...)
+	case miniscriptOrB:
+		add(subOps[0]...)
+		add(subOps[1]...)
+		add(miniscriptOps(OpBoolOr)...)
+	case miniscriptOrC:
+		add(subOps[0]...)
+		add(miniscriptOps(OpNotIf)...)
+		add(subOps[1]...)
+		add(miniscriptOps(OpEndIf)...)
+	case miniscriptOrD:
+		add(subOps[0]...)
+		add(miniscriptOps(OpIfDup, OpNotIf)...)
+		add(subOps[1]...)
+		add(miniscriptOps(OpEndIf)...)
+	case miniscriptOrI:
+		add(miniscriptOps(OpIf)...)
+		add(subOps[0]...)
+		add(miniscriptOps(OpElse)...)
+		add(subOps[1]...)
+		add(miniscriptOps(OpEndIf)...)
+	case miniscriptAndOr:
+		add(subOps[0]...)
+		add(miniscriptOps(OpNotIf)...)
+		add(subOps[2]...)
+		add(miniscriptOps(OpElse)...)
+		add(subOps[1]...)
+		add(miniscriptOps(OpEndIf)...)
+	case miniscriptThresh:
+		for i, sub := range subOps {
+			add(sub...)
+			if i > 0 {
+				add(miniscriptOps(OpAdd)...)
+			}
+		}
+		add(miniscriptPushNum(node.k))
+		add(miniscriptOps(OpEqual)...)
+	case miniscriptMulti:
+		add(miniscriptPushNum(node.k))
+		for _, key := range node.keys {
+			add(miniscriptPush(key))
+		}
+		add(miniscriptPushNum(uint32(len(node.keys))))
+		add(miniscriptOps(OpCheckMultiSig)...)
+	case miniscriptMultiA:
+		for i, key := range node.keys {
+			add(miniscriptPush(key))
+			if i == 0 {
+				add(miniscriptOps(OpCheckSig)...)
+			} else {
+				add(miniscriptOps(OpCheckSigAdd)...)
+			}
+		}
+		add(miniscriptPushNum(node.k))
+		add(miniscriptOps(OpNumEqual)...)
+	default:
+		return nil, ErrInvalidMiniscript
+	}
+
+	return ops, nil
+}
+
+// miniscriptSize is an optional upper bound; the zero value means "impossible".
+type miniscriptSize struct {
+	valid bool
+	n     int
+}
+
+func newMiniscriptSize(n int) miniscriptSize {
+	return miniscriptSize{true, n}
+}
+
+func (a miniscriptSize) add(b miniscriptSize) miniscriptSize {
+	if !a.valid || !b.valid {
+		return miniscriptSize{}
+	}
+
+	return newMiniscriptSize(a.n + b.n)
+}
+
+func (a miniscriptSize) or(b miniscriptSize) miniscriptSize {
+	if !a.valid {
+		return b
+	}
+	if !b.valid || a.n >= b.n {
+		return a
+	}
+
+	return b
+}
+
+// miniscriptElementSizes are the sizes of the witness elements a satisfaction consists of.
+type miniscriptElementSizes struct {
+	sig, pubKey, preimage, one, zero int
+}
+
+// witnessSizes returns the upper bounds of the satisfaction and the dissatisfaction
+// measured with sizes.
+// ref. https://github.com/bitcoin/bitcoin/blob/master/src/script/miniscript.h
+func (node *Miniscript) witnessSizes(sizes miniscriptElementSizes) (miniscriptSize, miniscriptSize) {
+	sats := make([]miniscriptSize, len(node.subs))
+	dsats := make([]miniscriptSize, len(node.subs))
+	for i, sub := range node.subs {
+		sats[i], dsats[i] = sub.witnessSizes(sizes)
+	}
+
+	none := miniscriptSize{}
+	size := newMiniscriptSize
+	k := int(node.k)
+
+	switch node.fragment {
+	case miniscriptJust0:
+		return none, size(0)
+	case miniscriptJust1, miniscriptOlder, miniscriptAfter:
+		return size(0), none
+	case miniscriptPkK:
+		return size(sizes.sig), size(sizes.zero)
+	case miniscriptPkH:
+		return size(sizes.sig + sizes.pubKey), size(sizes.zero + sizes.pubKey)
+	case miniscriptSha256, miniscriptHash256, miniscriptRipemd160, miniscriptHash160:
+		return size(sizes.preimage), none
+	case miniscriptAndOr:
+		return sats[0].add(sats[1]).or(dsats[0].add(sats[2])), dsats[0].add(dsats[2])
+	case miniscriptAndV:
+		return sats[0].add(sats[1]), none
+	case miniscriptAndB:
+		return sats[0].add(sats[1]), dsats[0].add(dsats[1])
+	case miniscriptOrB:
+		return dsats[0].add(sats[1]).or(sats[0].add(dsats[1])), dsats[0].add(dsats[1])
+	case miniscriptOrC:
+		return sats[0].or(dsats[0].add(sats[1])), none
+	case miniscriptOrD:
+		return sats[0].or(dsats[0].add(sats[1])), dsats[0].add(dsats[1])
+	case miniscriptOrI:
+		return sats[0].add(size(sizes.one)).or(sats[1].add(size(sizes.zero))),
+			dsats[0].add(size(sizes.one)).or(dsats[1].add(size(sizes.zero)))
+	case miniscriptMulti:
+		return size(k*sizes.sig + sizes.zero), size((k + 1) * sizes.zero)
+	case miniscriptMultiA:
+		n := len(node.keys)
+		return size(k*sizes.sig + (n-k)*sizes.zero), size(n * sizes.zero)
+	case miniscriptWrapA, miniscriptWrapS, miniscriptWrapC, miniscriptWrapN:
+		return sats[0], dsats[0]
+	case miniscriptWrapD:
+		return size(sizes.one).add(sats[0]), size(sizes.zero)
+	case miniscriptWrapV:
+		return sats[0], none
+	case miniscriptWrapJ:
+		return sats[0], size(sizes.zero)
+	case miniscriptThresh:
+		return miniscriptThreshSizes(sats, dsats, k)
+	default:
+		return none, none
+	}
+}
+
+// multisigKeysExecuted returns the upper bounds of the public keys the executed CHECKMULTISIGs
+// of the satisfaction and the dissatisfaction have, which count towards the ops limit.
+func (node *Miniscript) multisigKeysExecuted() (miniscriptSize, miniscriptSize) {
+	sats := make([]miniscriptSize, len(node.subs))
+	dsats := make([]miniscriptSize, len(node.subs))
+	for i, sub := range node.subs {
+		sats[i], dsats[i] = sub.multisigKeysExecuted()
+	}
+
+	none := miniscriptSize{}
+	size := newMiniscriptSize
+
+	switch node.fragment {
+	case miniscriptJust0:
+		return none, size(0)
+	case miniscriptJust1, miniscriptOlder, miniscriptAfter,
+		miniscriptSha256, miniscriptHash256, miniscriptRipemd160, miniscriptHash160:
+		return size(0), none
+	case miniscriptPkK, miniscriptPkH, miniscriptMultiA:
+		return size(0), size(0)
+	case miniscriptMulti:
+		return size(len(node.keys)), size(len(node.keys))
+	case miniscriptAndOr:
+		return sats[0].add(sats[1]).or(dsats[0].add(sats[2])), dsats[0].add(dsats[2])
+	case miniscriptAndV:
+		return sats[0].add(sats[1]), none
+	case miniscriptAndB:
+		return sats[0].add(sats[1]), dsats[0].add(dsats[1])
+	case miniscriptOrB:
+		return dsats[0].add(sats[1]).or(sats[0].add(dsats[1])), dsats[0].add(dsats[1])
+	case miniscriptOrC:
+		return sats[0].or(dsats[0].add(sats[1])), none
+	case miniscriptOrD:
+		return sats[0].or(dsats[0].add(sats[1])), dsats[0].add(dsats[1])
+	case miniscriptOrI:
+		return sats[0].or(sats[1]), dsats[0].or(dsats[1])
+	case miniscriptWrapA, miniscriptWrapS, miniscriptWrapC, miniscriptWrapN:
+		return sats[0], dsats[0]
+	case miniscriptWrapD, miniscriptWrapJ:
+		return sats[0], size(0)
+	case miniscriptWrapV:
+		return sats[0], none
+	case miniscriptThresh:
+		return miniscriptThreshSizes(sats, dsats, int(node.k))
+	default:
+		return none, none
+	}
+}
+
+// miniscriptThreshSizes returns the upper bounds of the satisfaction and the dissatisfaction of thresh
+// from the ones of its subexpressions.
+func miniscriptThreshSizes(sats, dsats []miniscriptSize, k int) (miniscriptSize, miniscriptSize) {
+	// best[j] is the largest size satisfying j of the subexpressions seen so far
+	best := []miniscriptSize{newMiniscriptSize(0)}
+	for i := range sats {
+		next := []miniscriptSize{best[0].add(dsats[i])}
+		for j := 1; j < len(best); j++ {
+			next = append(next, best[j].add(dsats[i]).or(best[j-1].add(sats[i])))
+		}
+		next = append(next, best[len(best)-1].add(sats[i]))
+		best = next
+	}
+
+	return best[k], best[0]
+}
+
+// MaxWitnessSize returns the maximum size in bytes of the witness stack elements,
+// including their length prefixes, of a satisfaction. The script itself is not included.
+func (node *Miniscript) MaxWitnessSize() (int, error) {
+	sat, _ := node.witnessSizes(miniscriptElementSizes{
+		sig:      node.ctx.sigSize(),
+		pubKey:   node.ctx.pubKeySize(),
+		preimage: 1 + miniscriptHashPreimageSize,
+		one:      2,
+		zero:     1,
+	})
+	if !sat.valid {
+		return 0, ErrMiniscriptUnsatisfiable
+	}
+
+	return sat.n, nil
+}
+
+// MaxWitnessItems returns the maximum number of witness stack elements of a satisfaction.
+func (node *Miniscript) MaxWitnessItems() (int, error) {
+	sat, _ := node.witnessSizes(miniscriptElementSizes{
+		sig:      1,
+		pubKey:   1,
+		preimage: 1,
+		one:      1,
+		zero:     1,
+	})
+	if !sat.valid {
+		return 0, ErrMiniscriptUnsatisfiable
+	}
+
+	return sat.n, nil
+}
+
+// MaxSatisfactionWeight returns the maximum weight of the witness spending the script:
+// the satisfaction followed by the script. The control block of a tapscript spend is not included.
+func (node *Miniscript) MaxSatisfactionWeight() (int, error) {
+	size, err := node.MaxWitnessSize()
+	if err != nil {
+		return 0, err
+	}
+
+	items, err := node.MaxWitnessItems()
+	if err != nil {
+		return 0, err
+	}
+
+	script, err := node.Script()
+	if err != nil {
+		return 0, err
+	}
+
+	b, err := script.Bytes()
+	if err != nil {
+		return 0, err
+	}
+
+	return compactSizeLength(uint64(items+1)) + size + compactSizeLength(uint64(len(b))) + len(b), nil
+}
+
+func compactSizeLength(n uint64) int {
+	switch {
+	case n < 0xfd:
+		return 1
+	case n <= 0xffff:
+		return 3
+	case n <= 0xffffffff:
+		return 5
+	default:
+		return 9
+	}
+}
+
+// MiniscriptSatisfier provides the signatures, preimages and timelocks available to satisfy a miniscript.
+type MiniscriptSatisfier interface {
+	// Signature returns a signature for pubKey as serialized in the script.
+	Signature(pubKey []byte) ([]byte, bool)
+	// Preimage returns a 32-byte preimage of hash.
+	Preimage(hash []byte) ([]byte, bool)
+	// CheckOlder reports whether a relative timelock of sequence is satisfied.
+	CheckOlder(sequence uint32) bool
+	// CheckAfter reports whether an absolute timelock of lockTime is satisfied.
+	CheckAfter(lockTime uint32) bool
+}
+
+// MiniscriptAssets is a MiniscriptSatisfier backed by known signatures and preimages,
+// and the sequence and lock time of the spending transaction.
+type MiniscriptAssets struct {
+	Signatures map[string][]byte // keyed by hex encoded public key
+	Preimages  [][]byte
+	Sequence   uint32
+	LockTime   uint32
+}
+
+func (assets *MiniscriptAssets) Signature(pubKey []byte) ([]byte, bool) {
+	sig, ok := assets.Signatures[hex.EncodeToString(pubKey)]
+	return sig, ok
+}
+
+func (assets *MiniscriptAssets) Preimage(hash []byte) ([]byte, bool) {
+	for _, preimage := range assets.Preimages {
+		for _, hashFunc := range []func([]byte) ([]byte, error){Sha256, Sha256Double, Ripemd160, Hash160} {
+			h, err := hashFunc(preimage)
+			if err == nil && bytes.Equal(h, hash) {
+				return preimage, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// CheckOlder follows BIP68 and BIP112.
+func (assets *MiniscriptAssets) CheckOlder(sequence uint32) bool {
+	if assets.Sequence&miniscriptSequenceDisableFlag != 0 {
+		return false
+	}
+	if assets.Sequence&miniscriptSequenceTypeFlag != sequence&miniscriptSequenceTypeFlag {
+		return false
+	}
+
+	return assets.Sequence&miniscriptSequenceMask >= sequence&miniscriptSequenceMask
+}
+
+// CheckAfter follows BIP65.
+func (assets *MiniscriptAssets) CheckAfter(lockTime uint32) bool {
+	if (assets.LockTime < miniscriptLockTimeThreshold) != (lockTime < miniscriptLockTimeThreshold) {
+		return false
+	}
+
+	return assets.LockTime >= lockTime
+}
+
+// miniscriptStack is a candidate witness stack, bottom first, with the properties
+// needed to choose a non-malleable satisfaction.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0379.mediawiki#satisfaction
+type miniscriptStack struct {
+	available bool
+	hasSig    bool
+	malleable bool
+	nonCanon  bool
+	size      int
+	items     [][]byte
+}
+
+func newMiniscriptStack(items ...[]byte) miniscriptStack {
+	stack := miniscriptStack{available: true, items: items}
+	for _, item := range items {
+		stack.size += 1 + len(item)
+	}
+
+	return stack
+}
+
+func (a miniscriptStack) withSig() miniscriptStack {
+	a.hasSig = true
+	return a
+}
+
+func (a miniscriptStack) asMalleable() miniscriptStack {
+	a.malleable = true
+	return a
+}
+
+func (a miniscriptStack) asNonCanon() miniscriptStack {
+	a.nonCanon = true
+	return a
+}
+
+// add returns a with b on top of it.
+func (a miniscriptStack) add(b miniscriptStack) miniscriptStack {
+	if !a.available || !b.available {
+		return miniscriptStack{}
+	}
+
+	return miniscriptStack{
+		available: true,
+		hasSig:    a.hasSig || b.hasSig,
+		malleable: a.malleable || b.malleable,
+		nonCanon:  a.nonCanon || b.nonCanon,
+		size:      a.size + b.size,
+		items:     append(append([][]byte{}, a.items...), b.items...),
+	}
+}
+
+// or chooses between two alternatives so that a third party without
+// the signing keys cannot swap one for the other.
+func (a miniscriptStack) or(b miniscriptStack) miniscriptStack {
+	if !a.available {
+		return b
+	}
+	if !b.available {
+		return a
+	}
+
+	// a solution without signature can be substituted for one with
+	if !a.hasSig && b.hasSig {
+		return a
+	}
+	if !b.hasSig && a.hasSig {
+		return b
+	}
+	if !a.hasSig && !b.hasSig {
+		a.malleable = true
+		b.malleable = true
+	} else {
+		if b.malleable && !a.malleable {
+			return a
+		}
+		if a.malleable && !b.malleable {
+			return b
+		}
+	}
+
+	// honest signers never produce non-canonical stacks if they can avoid it
+	if b.nonCanon && !a.nonCanon {
+		return a
+	}
+	if a.nonCanon && !b.nonCanon {
+		return b
+	}
+
+	if a.size <= b.size {
+		return a
+	}
+
+	return b
+}
+
+// Satisfy returns the smallest non-malleable witness stack satisfying the miniscript,
+// bottom first and without the script itself.
+func (node *Miniscript) Satisfy(satisfier MiniscriptSatisfier) ([][]byte, error) {
+	sat, _ := node.satisfy(satisfier)
+	if !sat.available {
+		return nil, ErrMiniscriptUnsatisfiable
+	}
+	if sat.malleable || !sat.hasSig {
+		return nil, ErrMiniscriptMalleableSatisfaction
+	}
+
+	return sat.items, nil
+}
+
+// satisfy returns the best satisfaction and dissatisfaction of the expression.
+// ref. https://github.com/bitcoin/bitcoin/blob/master/src/script/miniscript.h
+func (node *Miniscript) satisfy(satisfier MiniscriptSatisfier) (miniscriptStack, miniscriptStack) {
+	sats := make([]miniscriptStack, len(node.subs))
+	dsats := make([]miniscriptStack, len(node.subs))
+	for i, sub := range node.subs {
+		sats[i], dsats[i] = sub.satisfy(satisfier)
+	}
+
+	invalid := miniscriptStack{}
+	empty := newMiniscriptStack()
+	zero := newMiniscriptStack([]byte{})
+	one := newMiniscriptStack([]byte{0x01})
+
+	sign := func(key []byte) miniscriptStack {
+		sig, ok := satisfier.Signature(key)
+		if !ok {
+			return invalid
+		}
+		return newMiniscriptStack(sig).withSig()
+	}
+
+	switch node.fragment {
+	case miniscriptJust0:
+		return invalid, empty
+	case miniscriptJust1:
+		return empty, invalid
+	case miniscriptPkK:
+		return sign(node.keys[0]), zero
+	case miniscriptPkH:
+		key := newMiniscriptStack(node.keys[0])
+		return sign(node.keys[0]).add(key), zero.add(key)
+	case miniscriptOlder:
+		if satisfier.CheckOlder(node.k) {
+			return empty, invalid
+		}
+		return invalid, invalid
+	case miniscriptAfter:
+		if satisfier.CheckAfter(node.k) {
+			return empty, invalid
+		}
+		return invalid, invalid
+	case miniscriptSha256, miniscriptHash256, miniscriptRipemd160, miniscriptHash160:
+		dsat := newMiniscriptStack(make([]byte, miniscriptHashPreimageSize)).asMalleable()
+		preimage, ok := satisfier.Preimage(node.data)
+		if !ok || len(preimage) != miniscriptHashPreimageSize {
+			return invalid, dsat
+		}
+		return newMiniscriptStack(preimage), dsat
+	case miniscriptAndV:
+		return sats[1].add(sats[0]), dsats[1].add(sats[0]).asNonCanon()
+	case miniscriptAndB:
+		return sats[1].add(sats[0]),
+			dsats[1].add(dsats[0]).
+				or(sats[1].add(dsats[0]).asMalleable().asNonCanon()).
+				or(dsats[1].add(sats[0]).asMalleable().asNonCanon())
+	case miniscriptOrB:
+		return dsats[1].add(sats[0]).
+				or(sats[1].add(dsats[0])).
+				or(sats[1].add(sats[0]).asMalleable().asNonCanon()),
+			dsats[1].add(dsats[0])
+	case miniscriptOrC:
+		return sats[0].or(sats[1].add(dsats[0])), invalid
+	case miniscriptOrD:
+		return sats[0].or(sats[1].add(dsats[0])), dsats[1].add(dsats[0])
+	case miniscriptAndOr:
+		return sats[1].add(sats[0]).or(sats[2].add(dsats[0])),
+			dsats[1].add(sats[0]).asNonCanon().or(dsats[2].add(dsats[0]))
+	case miniscriptOrI:
+		return sats[0].add(one).or(sats[1].add(zero)), dsats[0].add(one).or(dsats[1].add(zero))
+	case miniscriptWrapA, miniscriptWrapS, miniscriptWrapC, miniscriptWrapN:
+		return sats[0], dsats[0]
+	case miniscriptWrapD:
+		return sats[0].add(one), zero
+	case miniscriptWrapV:
+		return sats[0], invalid
+	case miniscriptWrapJ:
+		// a dissatisfaction with a nonzero top element may exist
+		dsat := zero
+		if dsats[0].available && !dsats[0].hasSig {
+			dsat = dsat.asMalleable()
+		}
+		return sats[0], dsat
+	case miniscriptMulti:
+		// the extra element is consumed by the CHECKMULTISIG bug
+		best := []miniscriptStack{zero}
+		for _, key := range node.keys {
+			sat := sign(key)
+			next := []miniscriptStack{best[0]}
+			for j := 1; j < len(best); j++ {
+				next = append(next, best[j].or(best[j-1].add(sat)))
+			}
+			next = append(next, best[len(best)-1].add(sat))
+			best = next
+		}
+		dsat := zero
+		for i := uint32(0); i < node.k; i++ {
+			dsat = dsat.add(zero)
+		}
+		return best[node.k], dsat
+	case miniscriptMultiA:
+		// the signature for the first key is at the top of the stack
+		best := []miniscriptStack{empty}
+		for i := range node.keys {
+			sat := sign(node.keys[len(node.keys)-1-i])
+			next := []miniscriptStack{best[0].add(zero)}
+			for j := 1; j < len(best); j++ {
+				next = append(next, best[j].add(zero).or(best[j-1].add(sat)))
+			}
+			next = append(next, best[len(best)-1].add(sat))
+			best = next
+		}
+		return best[node.k], best[0]
+	case miniscriptThresh:
+		// best[j] satisfies j of the last subexpressions seen so far
+		best := []miniscriptStack{empty}
+		for i := range node.subs {
+			sat, dsat := sats[len(sats)-1-i], dsats[len(dsats)-1-i]
+			next := []miniscriptStack{best[0].add(dsat)}
+			for j := 1; j < len(best); j++ {
+				next = append(next, best[j].add(dsat).or(best[j-1].add(sat)))
+			}
+			next = append(next, best[len(best)-1].add(sat))
+			best = next
+		}
+		dsat := invalid
+		for i, stack := range best {
+			if i != 0 && i != int(node.k) {
+				stack = stack.asMalleable().asNonCanon()
+			}
+			if i != int(node.k) {
+				dsat = dsat.or(stack)
+			}
+		}
+		return best[node.k], dsat
+	default:
+		return invalid, invalid
+	}
+}
@@ -0,0 +1,491 @@
+package btc
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// public keys of the private keys 1, 2 and 3
+var testMiniscriptKeyReplacer = strings.NewReplacer(
+	"K1", "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+	"K2", "02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5",
+	"K3", "02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9",
+	"X1", "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+	"X2", "c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5",
+	"X3", "f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9",
+)
+
+func testMiniscriptPrivateKey(t *testing.T, i byte) *PrivateKey {
+	b := make([]byte, 32)
+	b[31] = i
+
+	privKey, err := NewPrivateKeyFromBytes(b)
+	require.NoError(t, err)
+
+	return privKey
+}
+
+func TestMiniscript(t *testing.T) {
+	testCases := []struct {
+		s              string
+		ctx            MiniscriptContext
+		typ            string
+		script         string
+		maxWitnessSize int
+		maxWeight      int
+		isSane         bool
+	}{
+		{
+			"pk(K1)",
+			MiniscriptContextP2wsh,
+			"Bonduesmk",
+			"210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac",
+			73,
+			110,
+			true,
+		},
+		{
+			"pkh(K1)",
+			MiniscriptContextP2wsh,
+			"Bnduesmk",
+			"76a914751e76e8199196d454941c45d1b3a323f1433bd688ac",
+			107,
+			134,
+			true,
+		},
+		{
+			"or_d(pk(K1),and_v(v:pkh(K2),older(1008)))",
+			MiniscriptContextP2wsh,
+			"Bfsmxhk",
+			"210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac736476a91406afd46bcdfd22ef94ac122aa11f241244a37ecc88ad02f003b268",
+			108,
+			177,
+			true,
+		},
+		{
+			"and_v(v:pk(K1),or_d(pk(K2),and_v(v:pk(K3),after(500))))",
+			MiniscriptContextP2wsh,
+			"Bnfsmxjk",
+			"210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ad2102c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5ac73642102f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9ad02f401b168",
+			147,
+			261,
+			true,
+		},
+		{
+			"multi(2,K1,K2,K3)",
+			MiniscriptContextP2wsh,
+			"Bnduesmk",
+			"52210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f817982102c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee52102f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f953ae",
+			147,
+			254,
+			true,
+		},
+		{
+			"thresh(2,pk(K1),s:pk(K2),sln:older(10))",
+			MiniscriptContextP2wsh,
+			"Bdusmhk",
+			"210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac7c2102c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5ac937c6300675ab29268935287",
+			148,
+			233,
+			true,
+		},
+		{
+			"andor(pk(K1),sha256(1111111111111111111111111111111111111111111111111111111111111111),and_n(pk(K2),older(5)))",
+			MiniscriptContextP2wsh,
+			"Bdesmxhk",
+			"210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac642102c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5ac64006755b2686782012088a82011111111111111111111111111111111111111111111111111111111111111118768",
+			106,
+			226,
+			true,
+		},
+		{
+			"or_b(pk(K1),a:pk(K2))",
+			MiniscriptContextP2wsh,
+			"Bduesmxk",
+			"210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac6b2102c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5ac6c9b",
+			74,
+			149,
+			true,
+		},
+		{
+			"t:or_c(pk(K1),v:pk(K2))",
+			MiniscriptContextP2wsh,
+			"Bufsmxk",
+			"210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac642102c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5ad6851",
+			74,
+			149,
+			true,
+		},
+		{
+			// ref. https://github.com/bitcoin/bitcoin/blob/master/src/test/miniscript_tests.cpp
+			"lltvln:after(1231488000)",
+			MiniscriptContextP2wsh,
+			"Bdumxik",
+			"6300676300676300670400046749b1926869516868",
+			3,
+			26,
+			false,
+		},
+		{
+			"multi_a(2,X1,X2,X3)",
+			MiniscriptContextTapscript,
+			"Bduesmk",
+			"2079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac20c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5ba20f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9ba529c",
+			133,
+			239,
+			true,
+		},
+		{
+			"or_d(pk(X1),and_v(v:pk(X2),older(144)))",
+			MiniscriptContextTapscript,
+			"Bfsmxhk",
+			"2079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac736420c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5ad029000b268",
+			67,
+			144,
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.s, func(t *testing.T) {
+			s := testMiniscriptKeyReplacer.Replace(tc.s)
+
+			ms, err := ParseMiniscript(s, tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, ms.String(), s)
+			assert.Equal(t, ms.Type(), tc.typ)
+			assert.Equal(t, ms.IsSane(), tc.isSane)
+
+			script, err := ms.Script()
+			require.NoError(t, err)
+			assert.Equal(t, script.Hex, tc.script)
+
+			maxWitnessSize, err := ms.MaxWitnessSize()
+			require.NoError(t, err)
+			assert.Equal(t, maxWitnessSize, tc.maxWitnessSize)
+
+			maxWeight, err := ms.MaxSatisfactionWeight()
+			require.NoError(t, err)
+			assert.Equal(t, maxWeight, tc.maxWeight)
+		})
+	}
+}
+
+func TestMiniscriptAliases(t *testing.T) {
+	testCases := []struct {
+		in  string
+		out string
+	}{
+		{"c:pk_k(K1)", "pk(K1)"},
+		{"c:pk_h(K1)", "pkh(K1)"},
+		{"andor(pk(K1),pk(K2),0)", "and_n(pk(K1),pk(K2))"},
+		{"and_v(v:pk(K1),1)", "tv:pk(K1)"},
+		{"or_i(0,pk(K1))", "l:pk(K1)"},
+		{"or_i(pk(K1),0)", "u:pk(K1)"},
+		{"d:v:older(1)", "dv:older(1)"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			ms, err := ParseMiniscript(testMiniscriptKeyReplacer.Replace(tc.in), MiniscriptContextP2wsh)
+			require.NoError(t, err)
+			assert.Equal(t, ms.String(), testMiniscriptKeyReplacer.Replace(tc.out))
+		})
+	}
+}
+
+func TestMiniscriptResourceLimits(t *testing.T) {
+	keys := make([]string, 120)
+	for i := range keys {
+		keys[i] = testMiniscriptPrivateKey(t, byte(i+1)).PublicKey().Hex()
+	}
+
+	// andV chains the subexpressions with and_v, verifying all but the last one
+	andV := func(subs ...string) string {
+		s := subs[len(subs)-1]
+		for i := len(subs) - 2; i >= 0; i-- {
+			s = "and_v(v:" + subs[i] + "," + s + ")"
+		}
+		return s
+	}
+	olders := func(n int) []string {
+		subs := make([]string, n)
+		for i := range subs {
+			subs[i] = "older(1)"
+		}
+		return subs
+	}
+	multis := func(k, count int) []string {
+		subs := make([]string, count)
+		for i := range subs {
+			subs[i] = fmt.Sprintf("multi(%d,%s)", k, strings.Join(keys[20*i:20*(i+1)], ","))
+		}
+		return subs
+	}
+
+	testCases := []struct {
+		name   string
+		s      string
+		ctx    MiniscriptContext
+		isSane bool
+	}{
+		{
+			// 100 * 2 + 1 = 201 ops
+			"201 ops",
+			andV(append(olders(100), "pk(K1)")...),
+			MiniscriptContextP2wsh,
+			true,
+		},
+		{
+			// 101 * 2 + 1 = 203 ops
+			"203 ops",
+			andV(append(olders(101), "pk(K1)")...),
+			MiniscriptContextP2wsh,
+			false,
+		},
+		{
+			"203 ops in tapscript",
+			andV(append(olders(101), "pk(X1)")...),
+			MiniscriptContextTapscript,
+			true,
+		},
+		{
+			// 1 + 20 + 89 * 2 + 1 = 200 ops
+			"200 ops with multi",
+			andV(append(append(multis(1, 1), olders(89)...), "pk("+keys[20]+")")...),
+			MiniscriptContextP2wsh,
+			true,
+		},
+		{
+			// 1 + 20 + 91 * 2 + 1 = 204 ops, of which only 184 are non-push ones
+			"204 ops with multi",
+			andV(append(append(multis(1, 1), olders(91)...), "pk("+keys[20]+")")...),
+			MiniscriptContextP2wsh,
+			false,
+		},
+		{
+			// 4 * 21 = 84 stack items
+			"84 stack items",
+			andV(multis(20, 4)...),
+			MiniscriptContextP2wsh,
+			true,
+		},
+		{
+			// 5 * 21 = 105 stack items
+			"105 stack items",
+			andV(multis(20, 5)...),
+			MiniscriptContextP2wsh,
+			false,
+		},
+		{
+			// 5 * (3 + 20 * 34) = 3415 bytes
+			"3415-byte script",
+			andV(multis(1, 5)...),
+			MiniscriptContextP2wsh,
+			true,
+		},
+		{
+			// 6 * (3 + 20 * 34) = 4098 bytes
+			"4098-byte script",
+			andV(multis(1, 6)...),
+			MiniscriptContextP2wsh,
+			false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ms, err := ParseMiniscript(testMiniscriptKeyReplacer.Replace(tc.s), tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, ms.IsSane(), tc.isSane)
+		})
+	}
+}
+
+func TestMiniscriptErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		s    string
+		ctx  MiniscriptContext
+		err  error
+	}{
+		{"unknown fragment", "foo(K1)", MiniscriptContextP2wsh, ErrInvalidMiniscript},
+		{"unknown wrapper", "x:pk(K1)", MiniscriptContextP2wsh, ErrInvalidMiniscript},
+		{"top level not B", "v:pk(K1)", MiniscriptContextP2wsh, ErrInvalidMiniscriptType},
+		{"invalid subexpression type", "and_v(pk(K1),pk(K2))", MiniscriptContextP2wsh, ErrInvalidMiniscriptType},
+		{"x-only key in p2wsh", "pk(X1)", MiniscriptContextP2wsh, ErrInvalidMiniscriptKey},
+		{"compressed key in tapscript", "pk(K1)", MiniscriptContextTapscript, ErrInvalidMiniscriptKey},
+		{"multi in tapscript", "multi(1,X1,X2)", MiniscriptContextTapscript, ErrInvalidMiniscriptContext},
+		{"multi_a in p2wsh", "multi_a(1,K1,K2)", MiniscriptContextP2wsh, ErrInvalidMiniscriptContext},
+		{"zero timelock", "older(0)", MiniscriptContextP2wsh, ErrInvalidMiniscript},
+		{"timelock out of range", "after(2147483648)", MiniscriptContextP2wsh, ErrInvalidMiniscript},
+		{"threshold too large", "multi(3,K1,K2)", MiniscriptContextP2wsh, ErrInvalidMiniscript},
+		{"invalid hash length", "sha256(1111)", MiniscriptContextP2wsh, ErrInvalidMiniscript},
+		{"invalid context", "pk(K1)", MiniscriptContext(0), ErrInvalidMiniscriptContext},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseMiniscript(testMiniscriptKeyReplacer.Replace(tc.s), tc.ctx)
+			assert.Equal(t, err, tc.err)
+		})
+	}
+}
+
+func TestMiniscriptSatisfy(t *testing.T) {
+	hash := make([]byte, 32)
+
+	sigs := make(map[string][]byte)
+	for i := byte(1); i <= 3; i++ {
+		privKey := testMiniscriptPrivateKey(t, i)
+
+		sig, err := privKey.Sign(hash)
+		require.NoError(t, err)
+
+		sigs[privKey.PublicKey().Hex()] = append(sig.Bytes(), byte(SigHashAll))
+	}
+	sig1 := sigs[testMiniscriptKeyReplacer.Replace("K1")]
+	sig2 := sigs[testMiniscriptKeyReplacer.Replace("K2")]
+	sig3 := sigs[testMiniscriptKeyReplacer.Replace("K3")]
+
+	pubKey2, err := hex.DecodeString(testMiniscriptKeyReplacer.Replace("K2"))
+	require.NoError(t, err)
+
+	preimage := make([]byte, 32)
+	preimage[0] = 0x01
+	preimageHash, err := Sha256(preimage)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name    string
+		s       string
+		signers []string
+		assets  MiniscriptAssets
+		witness [][]byte
+	}{
+		{
+			"primary path",
+			"or_d(pk(K1),and_v(v:pkh(K2),older(1008)))",
+			[]string{"K1", "K2"},
+			MiniscriptAssets{},
+			[][]byte{sig1},
+		},
+		{
+			"recovery path",
+			"or_d(pk(K1),and_v(v:pkh(K2),older(1008)))",
+			[]string{"K2"},
+			MiniscriptAssets{Sequence: 1008},
+			[][]byte{sig2, pubKey2, {}},
+		},
+		{
+			"multisig",
+			"multi(2,K1,K2,K3)",
+			[]string{"K1", "K3"},
+			MiniscriptAssets{},
+			[][]byte{{}, sig1, sig3},
+		},
+		{
+			"threshold with timelock",
+			"thresh(2,pk(K1),s:pk(K2),sln:older(10))",
+			[]string{"K2"},
+			MiniscriptAssets{Sequence: 10},
+			[][]byte{{}, sig2, {}},
+		},
+		{
+			"hashlock",
+			"andor(pk(K1),sha256(H),and_n(pk(K2),older(5)))",
+			[]string{"K1"},
+			MiniscriptAssets{Preimages: [][]byte{preimage}},
+			[][]byte{preimage, sig1},
+		},
+		{
+			"absolute timelock",
+			"and_v(v:pk(K1),or_d(pk(K2),and_v(v:pk(K3),after(500))))",
+			[]string{"K1", "K3"},
+			MiniscriptAssets{LockTime: 600},
+			[][]byte{sig3, {}, sig1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := strings.Replace(testMiniscriptKeyReplacer.Replace(tc.s), "H", hex.EncodeToString(preimageHash), -1)
+
+			ms, err := ParseMiniscript(s, MiniscriptContextP2wsh)
+			require.NoError(t, err)
+
+			assets := tc.assets
+			assets.Signatures = make(map[string][]byte)
+			for _, signer := range tc.signers {
+				pubKey := testMiniscriptKeyReplacer.Replace(signer)
+				assets.Signatures[pubKey] = sigs[pubKey]
+			}
+
+			witness, err := ms.Satisfy(&assets)
+			require.NoError(t, err)
+			assert.Equal(t, witness, tc.witness)
+		})
+	}
+}
+
+func TestMiniscriptSatisfyErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		s      string
+		assets MiniscriptAssets
+		err    error
+	}{
+		{
+			"missing signature",
+			"pk(K1)",
+			MiniscriptAssets{},
+			ErrMiniscriptUnsatisfiable,
+		},
+		{
+			"relative timelock not reached",
+			"or_d(pk(K1),and_v(v:pk(K2),older(1008)))",
+			MiniscriptAssets{Signatures: map[string][]byte{"K2": {0x01}}, Sequence: 1007},
+			ErrMiniscriptUnsatisfiable,
+		},
+		{
+			"relative timelock of a different type",
+			"or_d(pk(K1),and_v(v:pk(K2),older(1008)))",
+			MiniscriptAssets{Signatures: map[string][]byte{"K2": {0x01}}, Sequence: miniscriptSequenceTypeFlag | 1008},
+			ErrMiniscriptUnsatisfiable,
+		},
+		{
+			"absolute timelock of a different type",
+			"and_v(v:pk(K1),after(500))",
+			MiniscriptAssets{Signatures: map[string][]byte{"K1": {0x01}}, LockTime: miniscriptLockTimeThreshold},
+			ErrMiniscriptUnsatisfiable,
+		},
+		{
+			"no signature",
+			"after(500)",
+			MiniscriptAssets{LockTime: 500},
+			ErrMiniscriptMalleableSatisfaction,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ms, err := ParseMiniscript(testMiniscriptKeyReplacer.Replace(tc.s), MiniscriptContextP2wsh)
+			require.NoError(t, err)
+
+			assets := tc.assets
+			sigs := make(map[string][]byte)
+			for signer, sig := range assets.Signatures {
+				sigs[testMiniscriptKeyReplacer.Replace(signer)] = sig
+			}
+			assets.Signatures = sigs
+
+			_, err = ms.Satisfy(&assets)
+			assert.Equal(t, err, tc.err)
+		})
+	}
+}
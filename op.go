@@ -1,41 +1,249 @@
 package btc
 
 const (
-	Op0           OpCode = 0x00
-	OpFalse       OpCode = 0x00
-	OpDataLenMin  OpCode = 0x01
-	OpDataLenMax  OpCode = 0x4b
-	OpPushdata1   OpCode = 0x4c
-	OpPushdata2   OpCode = 0x4d
-	OpPushdata4   OpCode = 0x4e
-	Op1           OpCode = 0x51
-	OpTrue        OpCode = 0x51
-	OpReturn      OpCode = 0x6a
-	OpDrop        OpCode = 0x75
-	OpDup         OpCode = 0x76
-	OpEqualVerify OpCode = 0x88
-	OpHash160     OpCode = 0xa9
-	OpCheckSig    OpCode = 0xac
+	Op0                   OpCode = 0x00
+	OpFalse               OpCode = 0x00
+	OpDataLenMin          OpCode = 0x01
+	OpDataLenMax          OpCode = 0x4b
+	OpPushdata1           OpCode = 0x4c
+	OpPushdata2           OpCode = 0x4d
+	OpPushdata4           OpCode = 0x4e
+	Op1Negate             OpCode = 0x4f
+	OpReserved            OpCode = 0x50
+	Op1                   OpCode = 0x51
+	OpTrue                OpCode = 0x51
+	Op2                   OpCode = 0x52
+	Op3                   OpCode = 0x53
+	Op4                   OpCode = 0x54
+	Op5                   OpCode = 0x55
+	Op6                   OpCode = 0x56
+	Op7                   OpCode = 0x57
+	Op8                   OpCode = 0x58
+	Op9                   OpCode = 0x59
+	Op10                  OpCode = 0x5a
+	Op11                  OpCode = 0x5b
+	Op12                  OpCode = 0x5c
+	Op13                  OpCode = 0x5d
+	Op14                  OpCode = 0x5e
+	Op15                  OpCode = 0x5f
+	Op16                  OpCode = 0x60
+	OpNop                 OpCode = 0x61
+	OpVer                 OpCode = 0x62
+	OpIf                  OpCode = 0x63
+	OpNotIf               OpCode = 0x64
+	OpVerIf               OpCode = 0x65
+	OpVerNotIf            OpCode = 0x66
+	OpElse                OpCode = 0x67
+	OpEndIf               OpCode = 0x68
+	OpVerify              OpCode = 0x69
+	OpReturn              OpCode = 0x6a
+	OpToAltStack          OpCode = 0x6b
+	OpFromAltStack        OpCode = 0x6c
+	Op2Drop               OpCode = 0x6d
+	Op2Dup                OpCode = 0x6e
+	Op3Dup                OpCode = 0x6f
+	Op2Over               OpCode = 0x70
+	Op2Rot                OpCode = 0x71
+	Op2Swap               OpCode = 0x72
+	OpIfDup               OpCode = 0x73
+	OpDepth               OpCode = 0x74
+	OpDrop                OpCode = 0x75
+	OpDup                 OpCode = 0x76
+	OpNip                 OpCode = 0x77
+	OpOver                OpCode = 0x78
+	OpPick                OpCode = 0x79
+	OpRoll                OpCode = 0x7a
+	OpRot                 OpCode = 0x7b
+	OpSwap                OpCode = 0x7c
+	OpTuck                OpCode = 0x7d
+	OpCat                 OpCode = 0x7e
+	OpSubstr              OpCode = 0x7f
+	OpLeft                OpCode = 0x80
+	OpRight               OpCode = 0x81
+	OpSize                OpCode = 0x82
+	OpInvert              OpCode = 0x83
+	OpAnd                 OpCode = 0x84
+	OpOr                  OpCode = 0x85
+	OpXor                 OpCode = 0x86
+	OpEqual               OpCode = 0x87
+	OpEqualVerify         OpCode = 0x88
+	OpReserved1           OpCode = 0x89
+	OpReserved2           OpCode = 0x8a
+	Op1Add                OpCode = 0x8b
+	Op1Sub                OpCode = 0x8c
+	Op2Mul                OpCode = 0x8d
+	Op2Div                OpCode = 0x8e
+	OpNegate              OpCode = 0x8f
+	OpAbs                 OpCode = 0x90
+	OpNot                 OpCode = 0x91
+	Op0NotEqual           OpCode = 0x92
+	OpAdd                 OpCode = 0x93
+	OpSub                 OpCode = 0x94
+	OpMul                 OpCode = 0x95
+	OpDiv                 OpCode = 0x96
+	OpMod                 OpCode = 0x97
+	OpLShift              OpCode = 0x98
+	OpRShift              OpCode = 0x99
+	OpBoolAnd             OpCode = 0x9a
+	OpBoolOr              OpCode = 0x9b
+	OpNumEqual            OpCode = 0x9c
+	OpNumEqualVerify      OpCode = 0x9d
+	OpNumNotEqual         OpCode = 0x9e
+	OpLessThan            OpCode = 0x9f
+	OpGreaterThan         OpCode = 0xa0
+	OpLessThanOrEqual     OpCode = 0xa1
+	OpGreaterThanOrEqual  OpCode = 0xa2
+	OpMin                 OpCode = 0xa3
+	OpMax                 OpCode = 0xa4
+	OpWithin              OpCode = 0xa5
+	OpRipemd160           OpCode = 0xa6
+	OpSha1                OpCode = 0xa7
+	OpSha256              OpCode = 0xa8
+	OpHash160             OpCode = 0xa9
+	OpHash256             OpCode = 0xaa
+	OpCodeSeparator       OpCode = 0xab
+	OpCheckSig            OpCode = 0xac
+	OpCheckSigVerify      OpCode = 0xad
+	OpCheckMultiSig       OpCode = 0xae
+	OpCheckMultiSigVerify OpCode = 0xaf
+	OpNop1                OpCode = 0xb0
+	OpCheckLockTimeVerify OpCode = 0xb1
+	OpCheckSequenceVerify OpCode = 0xb2
+	OpNop4                OpCode = 0xb3
+	OpNop5                OpCode = 0xb4
+	OpNop6                OpCode = 0xb5
+	OpNop7                OpCode = 0xb6
+	OpNop8                OpCode = 0xb7
+	OpNop9                OpCode = 0xb8
+	OpNop10               OpCode = 0xb9
+	OpCheckSigAdd         OpCode = 0xba
+	OpInvalidOpCode       OpCode = 0xff
 )
 
 var opCodeNameMap = map[OpCode]string{
-	Op0:           "OP_0",
-	Op1:           "OP_1",
-	OpPushdata1:   "OP_PUSHDATA1",
-	OpPushdata2:   "OP_PUSHDATA2",
-	OpPushdata4:   "OP_PUSHDATA4",
-	OpReturn:      "OP_RETURN",
-	OpDrop:        "OP_DROP",
-	OpDup:         "OP_DUP",
-	OpEqualVerify: "OP_EQUALVERIFY",
-	OpHash160:     "OP_HASH160",
-	OpCheckSig:    "OP_CHECKSIG",
+	Op0:                   "OP_0",
+	OpPushdata1:           "OP_PUSHDATA1",
+	OpPushdata2:           "OP_PUSHDATA2",
+	OpPushdata4:           "OP_PUSHDATA4",
+	Op1Negate:             "OP_1NEGATE",
+	OpReserved:            "OP_RESERVED",
+	Op1:                   "OP_1",
+	Op2:                   "OP_2",
+	Op3:                   "OP_3",
+	Op4:                   "OP_4",
+	Op5:                   "OP_5",
+	Op6:                   "OP_6",
+	Op7:                   "OP_7",
+	Op8:                   "OP_8",
+	Op9:                   "OP_9",
+	Op10:                  "OP_10",
+	Op11:                  "OP_11",
+	Op12:                  "OP_12",
+	Op13:                  "OP_13",
+	Op14:                  "OP_14",
+	Op15:                  "OP_15",
+	Op16:                  "OP_16",
+	OpNop:                 "OP_NOP",
+	OpVer:                 "OP_VER",
+	OpIf:                  "OP_IF",
+	OpNotIf:               "OP_NOTIF",
+	OpVerIf:               "OP_VERIF",
+	OpVerNotIf:            "OP_VERNOTIF",
+	OpElse:                "OP_ELSE",
+	OpEndIf:               "OP_ENDIF",
+	OpVerify:              "OP_VERIFY",
+	OpReturn:              "OP_RETURN",
+	OpToAltStack:          "OP_TOALTSTACK",
+	OpFromAltStack:        "OP_FROMALTSTACK",
+	Op2Drop:               "OP_2DROP",
+	Op2Dup:                "OP_2DUP",
+	Op3Dup:                "OP_3DUP",
+	Op2Over:               "OP_2OVER",
+	Op2Rot:                "OP_2ROT",
+	Op2Swap:               "OP_2SWAP",
+	OpIfDup:               "OP_IFDUP",
+	OpDepth:               "OP_DEPTH",
+	OpDrop:                "OP_DROP",
+	OpDup:                 "OP_DUP",
+	OpNip:                 "OP_NIP",
+	OpOver:                "OP_OVER",
+	OpPick:                "OP_PICK",
+	OpRoll:                "OP_ROLL",
+	OpRot:                 "OP_ROT",
+	OpSwap:                "OP_SWAP",
+	OpTuck:                "OP_TUCK",
+	OpCat:                 "OP_CAT",
+	OpSubstr:              "OP_SUBSTR",
+	OpLeft:                "OP_LEFT",
+	OpRight:               "OP_RIGHT",
+	OpSize:                "OP_SIZE",
+	OpInvert:              "OP_INVERT",
+	OpAnd:                 "OP_AND",
+	OpOr:                  "OP_OR",
+	OpXor:                 "OP_XOR",
+	OpEqual:               "OP_EQUAL",
+	OpEqualVerify:         "OP_EQUALVERIFY",
+	OpReserved1:           "OP_RESERVED1",
+	OpReserved2:           "OP_RESERVED2",
+	Op1Add:                "OP_1ADD",
+	Op1Sub:                "OP_1SUB",
+	Op2Mul:                "OP_2MUL",
+	Op2Div:                "OP_2DIV",
+	OpNegate:              "OP_NEGATE",
+	OpAbs:                 "OP_ABS",
+	OpNot:                 "OP_NOT",
+	Op0NotEqual:           "OP_0NOTEQUAL",
+	OpAdd:                 "OP_ADD",
+	OpSub:                 "OP_SUB",
+	OpMul:                 "OP_MUL",
+	OpDiv:                 "OP_DIV",
+	OpMod:                 "OP_MOD",
+	OpLShift:              "OP_LSHIFT",
+	OpRShift:              "OP_RSHIFT",
+	OpBoolAnd:             "OP_BOOLAND",
+	OpBoolOr:              "OP_BOOLOR",
+	OpNumEqual:            "OP_NUMEQUAL",
+	OpNumEqualVerify:      "OP_NUMEQUALVERIFY",
+	OpNumNotEqual:         "OP_NUMNOTEQUAL",
+	OpLessThan:            "OP_LESSTHAN",
+	OpGreaterThan:         "OP_GREATERTHAN",
+	OpLessThanOrEqual:     "OP_LESSTHANOREQUAL",
+	OpGreaterThanOrEqual:  "OP_GREATERTHANOREQUAL",
+	OpMin:                 "OP_MIN",
+	OpMax:                 "OP_MAX",
+	OpWithin:              "OP_WITHIN",
+	OpRipemd160:           "OP_RIPEMD160",
+	OpSha1:                "OP_SHA1",
+	OpSha256:              "OP_SHA256",
+	OpHash160:             "OP_HASH160",
+	OpHash256:             "OP_HASH256",
+	OpCodeSeparator:       "OP_CODESEPARATOR",
+	OpCheckSig:            "OP_CHECKSIG",
+	OpCheckSigVerify:      "OP_CHECKSIGVERIFY",
+	OpCheckMultiSig:       "OP_CHECKMULTISIG",
+	OpCheckMultiSigVerify: "OP_CHECKMULTISIGVERIFY",
+	OpNop1:                "OP_NOP1",
+	OpCheckLockTimeVerify: "OP_CHECKLOCKTIMEVERIFY",
+	OpCheckSequenceVerify: "OP_CHECKSEQUENCEVERIFY",
+	OpNop4:                "OP_NOP4",
+	OpNop5:                "OP_NOP5",
+	OpNop6:                "OP_NOP6",
+	OpNop7:                "OP_NOP7",
+	OpNop8:                "OP_NOP8",
+	OpNop9:                "OP_NOP9",
+	OpNop10:               "OP_NOP10",
+	OpCheckSigAdd:         "OP_CHECKSIGADD",
+	OpInvalidOpCode:       "OP_INVALIDOPCODE",
 }
 
 type OpCode byte
 
 func (op OpCode) Name() string {
-	return opCodeNameMap[op]
+	if name, ok := opCodeNameMap[op]; ok {
+		return name
+	}
+
+	return "OP_UNKNOWN"
 }
 
 func (op OpCode) Byte() byte {
@@ -52,3 +260,23 @@ func (op OpCode) isPushData() bool {
 		op == OpPushdata2 ||
 		op == OpPushdata4
 }
+
+func (op OpCode) isSmallInt() bool {
+	return op == Op0 || (Op1 <= op && op <= Op16)
+}
+
+func (op OpCode) smallInt() int {
+	if op == Op0 {
+		return 0
+	}
+
+	return int(op-Op1) + 1
+}
+
+func smallIntOpCode(n int) OpCode {
+	if n == 0 {
+		return Op0
+	}
+
+	return Op1 + OpCode(n-1)
+}
@@ -0,0 +1,269 @@
+package btc
+
+import "encoding/hex"
+
+const (
+	genesisMsgMain     = "The Times 03/Jan/2009 Chancellor on brink of second bailout for banks"
+	genesisMsgTestNet4 = "03/May/2024 000000000000000000001ebd58c244970b3aa9d783bb001011fbe8ea8e98e00e"
+
+	genesisScriptMain     = "4104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac"
+	genesisScriptTestNet4 = "21000000000000000000000000000000000000000000000000000000000000000000ac"
+
+	genesisPrevBlockhash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+	genesisReward Satoshi = 50 * SatoshiPerBtc
+)
+
+// Params defines a Bitcoin network.
+// ref. https://github.com/bitcoin/bitcoin/blob/master/src/kernel/chainparams.cpp
+type Params struct {
+	Name        string
+	Net         uint32
+	DefaultPort string
+
+	GenesisBlock     *Block
+	GenesisBlockhash string
+
+	PkhAddressVersion        byte
+	ScriptHashAddressVersion byte
+	PrivateKeyVersion        byte
+	Bech32Hrp                string
+
+	HdPrivateKeyVersion [4]byte
+	HdPublicKeyVersion  [4]byte
+	HdCoinType          uint32
+
+	// ref. https://github.com/satoshilabs/slips/blob/master/slip-0132.md
+	HdP2shP2wpkhPrivateKeyVersion [4]byte
+	HdP2shP2wpkhPublicKeyVersion  [4]byte
+	HdP2shP2wshPrivateKeyVersion  [4]byte
+	HdP2shP2wshPublicKeyVersion   [4]byte
+	HdP2wpkhPrivateKeyVersion     [4]byte
+	HdP2wpkhPublicKeyVersion      [4]byte
+	HdP2wshPrivateKeyVersion      [4]byte
+	HdP2wshPublicKeyVersion       [4]byte
+
+	Bip34Height  int32
+	Bip65Height  int32
+	Bip66Height  int32
+	CsvHeight    int32
+	SegwitHeight int32
+}
+
+var MainNetParams = &Params{
+	Name:        "mainnet",
+	Net:         0xd9b4bef9,
+	DefaultPort: "8333",
+
+	GenesisBlock:     mustGenesisBlock(genesisMsgMain, genesisScriptMain, 1231006505, 0x1d00ffff, 2083236893),
+	GenesisBlockhash: "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f",
+
+	PkhAddressVersion:        0x00,
+	ScriptHashAddressVersion: 0x05,
+	PrivateKeyVersion:        0x80,
+	Bech32Hrp:                "bc",
+
+	HdPrivateKeyVersion: [4]byte{0x04, 0x88, 0xad, 0xe4},
+	HdPublicKeyVersion:  [4]byte{0x04, 0x88, 0xb2, 0x1e},
+	HdCoinType:          0,
+
+	HdP2shP2wpkhPrivateKeyVersion: [4]byte{0x04, 0x9d, 0x78, 0x78},
+	HdP2shP2wpkhPublicKeyVersion:  [4]byte{0x04, 0x9d, 0x7c, 0xb2},
+	HdP2shP2wshPrivateKeyVersion:  [4]byte{0x02, 0x95, 0xb0, 0x05},
+	HdP2shP2wshPublicKeyVersion:   [4]byte{0x02, 0x95, 0xb4, 0x3f},
+	HdP2wpkhPrivateKeyVersion:     [4]byte{0x04, 0xb2, 0x43, 0x0c},
+	HdP2wpkhPublicKeyVersion:      [4]byte{0x04, 0xb2, 0x47, 0x46},
+	HdP2wshPrivateKeyVersion:      [4]byte{0x02, 0xaa, 0x7a, 0x99},
+	HdP2wshPublicKeyVersion:       [4]byte{0x02, 0xaa, 0x7e, 0xd3},
+
+	Bip34Height:  227931,
+	Bip65Height:  388381,
+	Bip66Height:  363725,
+	CsvHeight:    419328,
+	SegwitHeight: 481824,
+}
+
+var TestNet3Params = &Params{
+	Name:        "testnet3",
+	Net:         0x0709110b,
+	DefaultPort: "18333",
+
+	GenesisBlock:     mustGenesisBlock(genesisMsgMain, genesisScriptMain, 1296688602, 0x1d00ffff, 414098458),
+	GenesisBlockhash: "000000000933ea01ad0ee984209779baaec3ced90fa3f408719526f8d77f4943",
+
+	PkhAddressVersion:        0x6f,
+	ScriptHashAddressVersion: 0xc4,
+	PrivateKeyVersion:        0xef,
+	Bech32Hrp:                "tb",
+
+	HdPrivateKeyVersion: [4]byte{0x04, 0x35, 0x83, 0x94},
+	HdPublicKeyVersion:  [4]byte{0x04, 0x35, 0x87, 0xcf},
+	HdCoinType:          1,
+
+	HdP2shP2wpkhPrivateKeyVersion: [4]byte{0x04, 0x4a, 0x4e, 0x28},
+	HdP2shP2wpkhPublicKeyVersion:  [4]byte{0x04, 0x4a, 0x52, 0x62},
+	HdP2shP2wshPrivateKeyVersion:  [4]byte{0x02, 0x42, 0x85, 0xb5},
+	HdP2shP2wshPublicKeyVersion:   [4]byte{0x02, 0x42, 0x89, 0xef},
+	HdP2wpkhPrivateKeyVersion:     [4]byte{0x04, 0x5f, 0x18, 0xbc},
+	HdP2wpkhPublicKeyVersion:      [4]byte{0x04, 0x5f, 0x1c, 0xf6},
+	HdP2wshPrivateKeyVersion:      [4]byte{0x02, 0x57, 0x50, 0x48},
+	HdP2wshPublicKeyVersion:       [4]byte{0x02, 0x57, 0x54, 0x83},
+
+	Bip34Height:  21111,
+	Bip65Height:  581885,
+	Bip66Height:  330776,
+	CsvHeight:    770112,
+	SegwitHeight: 834624,
+}
+
+var TestNet4Params = &Params{
+	Name:        "testnet4",
+	Net:         0x283f161c,
+	DefaultPort: "48333",
+
+	GenesisBlock:     mustGenesisBlock(genesisMsgTestNet4, genesisScriptTestNet4, 1714777860, 0x1d00ffff, 393743547),
+	GenesisBlockhash: "00000000da84f2bafbbc53dee25a72ae507ff4914b867c565be350b0da8bf043",
+
+	PkhAddressVersion:        0x6f,
+	ScriptHashAddressVersion: 0xc4,
+	PrivateKeyVersion:        0xef,
+	Bech32Hrp:                "tb",
+
+	HdPrivateKeyVersion: [4]byte{0x04, 0x35, 0x83, 0x94},
+	HdPublicKeyVersion:  [4]byte{0x04, 0x35, 0x87, 0xcf},
+	HdCoinType:          1,
+
+	HdP2shP2wpkhPrivateKeyVersion: [4]byte{0x04, 0x4a, 0x4e, 0x28},
+	HdP2shP2wpkhPublicKeyVersion:  [4]byte{0x04, 0x4a, 0x52, 0x62},
+	HdP2shP2wshPrivateKeyVersion:  [4]byte{0x02, 0x42, 0x85, 0xb5},
+	HdP2shP2wshPublicKeyVersion:   [4]byte{0x02, 0x42, 0x89, 0xef},
+	HdP2wpkhPrivateKeyVersion:     [4]byte{0x04, 0x5f, 0x18, 0xbc},
+	HdP2wpkhPublicKeyVersion:      [4]byte{0x04, 0x5f, 0x1c, 0xf6},
+	HdP2wshPrivateKeyVersion:      [4]byte{0x02, 0x57, 0x50, 0x48},
+	HdP2wshPublicKeyVersion:       [4]byte{0x02, 0x57, 0x54, 0x83},
+
+	Bip34Height:  1,
+	Bip65Height:  1,
+	Bip66Height:  1,
+	CsvHeight:    1,
+	SegwitHeight: 1,
+}
+
+// SigNetParams defines the default signet.
+var SigNetParams = &Params{
+	Name:        "signet",
+	Net:         0x40cf030a,
+	DefaultPort: "38333",
+
+	GenesisBlock:     mustGenesisBlock(genesisMsgMain, genesisScriptMain, 1598918400, 0x1e0377ae, 52613770),
+	GenesisBlockhash: "00000008819873e925422c1ff0f99f7cc9bbb232af63a077a480a3633bee1ef6",
+
+	PkhAddressVersion:        0x6f,
+	ScriptHashAddressVersion: 0xc4,
+	PrivateKeyVersion:        0xef,
+	Bech32Hrp:                "tb",
+
+	HdPrivateKeyVersion: [4]byte{0x04, 0x35, 0x83, 0x94},
+	HdPublicKeyVersion:  [4]byte{0x04, 0x35, 0x87, 0xcf},
+	HdCoinType:          1,
+
+	HdP2shP2wpkhPrivateKeyVersion: [4]byte{0x04, 0x4a, 0x4e, 0x28},
+	HdP2shP2wpkhPublicKeyVersion:  [4]byte{0x04, 0x4a, 0x52, 0x62},
+	HdP2shP2wshPrivateKeyVersion:  [4]byte{0x02, 0x42, 0x85, 0xb5},
+	HdP2shP2wshPublicKeyVersion:   [4]byte{0x02, 0x42, 0x89, 0xef},
+	HdP2wpkhPrivateKeyVersion:     [4]byte{0x04, 0x5f, 0x18, 0xbc},
+	HdP2wpkhPublicKeyVersion:      [4]byte{0x04, 0x5f, 0x1c, 0xf6},
+	HdP2wshPrivateKeyVersion:      [4]byte{0x02, 0x57, 0x50, 0x48},
+	HdP2wshPublicKeyVersion:       [4]byte{0x02, 0x57, 0x54, 0x83},
+
+	Bip34Height:  1,
+	Bip65Height:  1,
+	Bip66Height:  1,
+	CsvHeight:    1,
+	SegwitHeight: 1,
+}
+
+var RegTestParams = &Params{
+	Name:        "regtest",
+	Net:         0xdab5bffa,
+	DefaultPort: "18444",
+
+	GenesisBlock:     mustGenesisBlock(genesisMsgMain, genesisScriptMain, 1296688602, 0x207fffff, 2),
+	GenesisBlockhash: "0f9188f13cb7b2c71f2a335e3a4fc328bf5beb436012afca590b1a11466e2206",
+
+	PkhAddressVersion:        0x6f,
+	ScriptHashAddressVersion: 0xc4,
+	PrivateKeyVersion:        0xef,
+	Bech32Hrp:                "bcrt",
+
+	HdPrivateKeyVersion: [4]byte{0x04, 0x35, 0x83, 0x94},
+	HdPublicKeyVersion:  [4]byte{0x04, 0x35, 0x87, 0xcf},
+	HdCoinType:          1,
+
+	HdP2shP2wpkhPrivateKeyVersion: [4]byte{0x04, 0x4a, 0x4e, 0x28},
+	HdP2shP2wpkhPublicKeyVersion:  [4]byte{0x04, 0x4a, 0x52, 0x62},
+	HdP2shP2wshPrivateKeyVersion:  [4]byte{0x02, 0x42, 0x85, 0xb5},
+	HdP2shP2wshPublicKeyVersion:   [4]byte{0x02, 0x42, 0x89, 0xef},
+	HdP2wpkhPrivateKeyVersion:     [4]byte{0x04, 0x5f, 0x18, 0xbc},
+	HdP2wpkhPublicKeyVersion:      [4]byte{0x04, 0x5f, 0x1c, 0xf6},
+	HdP2wshPrivateKeyVersion:      [4]byte{0x02, 0x57, 0x50, 0x48},
+	HdP2wshPublicKeyVersion:       [4]byte{0x02, 0x57, 0x54, 0x83},
+
+	Bip34Height:  1,
+	Bip65Height:  1,
+	Bip66Height:  1,
+	CsvHeight:    1,
+	SegwitHeight: 0,
+}
+
+var networkParams = []*Params{
+	MainNetParams,
+	TestNet3Params,
+	TestNet4Params,
+	SigNetParams,
+	RegTestParams,
+}
+
+// ref. https://github.com/bitcoin/bitcoin/blob/master/src/kernel/chainparams.cpp (CreateGenesisBlock)
+func mustGenesisBlock(msg string, scriptHex string, timestamp uint32, bits uint32, nonce uint32) *Block {
+	w := newWriter()
+	for _, data := range [][]byte{{0xff, 0xff, 0x00, 0x1d}, {0x04}, []byte(msg)} {
+		if err := w.writePushedData(data); err != nil {
+			panic(err)
+		}
+	}
+
+	script, err := NewScriptFromHex(scriptHex)
+	if err != nil {
+		panic(err)
+	}
+
+	tx := NewTx()
+	tx.AddTxIn(&TxIn{
+		Txid:     CoinBaseTxid,
+		Index:    CoinBaseIndex,
+		Script:   &Script{Hex: hex.EncodeToString(w.Bytes())},
+		Sequence: TxInSequence,
+	})
+	tx.AddTxOut(&TxOut{
+		Amount: genesisReward,
+		Script: script,
+	})
+
+	txid, err := tx.Txid()
+	if err != nil {
+		panic(err)
+	}
+
+	return &Block{
+		BlockHeader: &BlockHeader{
+			Version:       1,
+			PrevBlockhash: genesisPrevBlockhash,
+			MerkleRoot:    txid,
+			Timestamp:     timestamp,
+			Bits:          bits,
+			Nonce:         nonce,
+		},
+		Txes: []*Tx{tx},
+	}
+}
@@ -0,0 +1,52 @@
+package btc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParamsGenesisBlock(t *testing.T) {
+	testCases := []struct {
+		params     *Params
+		merkleRoot string
+	}{
+		{
+			MainNetParams,
+			"4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b",
+		},
+		{
+			TestNet3Params,
+			"4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b",
+		},
+		{
+			TestNet4Params,
+			"7aa0a7ae1e223414cb807e40cd57e667b718e42aaf9306db9102fe28912b7b4e",
+		},
+		{
+			SigNetParams,
+			"4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b",
+		},
+		{
+			RegTestParams,
+			"4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.params.Name, func(t *testing.T) {
+			block := tc.params.GenesisBlock
+			assert.Equal(t, block.MerkleRoot, tc.merkleRoot)
+
+			blockhash, err := block.Blockhash()
+			require.NoError(t, err)
+			assert.Equal(t, blockhash, tc.params.GenesisBlockhash)
+		})
+	}
+
+	// ref. https://en.bitcoin.it/wiki/Genesis_block
+	blockHex, err := MainNetParams.GenesisBlock.Hex()
+	require.NoError(t, err)
+	assert.Equal(t, blockHex, "0100000000000000000000000000000000000000000000000000000000000000000000003ba3edfd7a7b12b27ac72c3e67768f617fc81bc3888a51323a9fb8aa4b1e5e4a29ab5f49ffff001d1dac2b7c0101000000010000000000000000000000000000000000000000000000000000000000000000ffffffff4d04ffff001d0104455468652054696d65732030332f4a616e2f32303039204368616e63656c6c6f72206f6e206272696e6b206f66207365636f6e64206261696c6f757420666f722062616e6b73ffffffff0100f2052a01000000434104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac00000000")
+}
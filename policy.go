@@ -0,0 +1,558 @@
+package btc
+
+import (
+	"encoding/hex"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	policyWeightSeparator = "@"
+
+	// the properties a candidate is selected by when composing expressions
+	policyCandidateProps = "BVKWzondufesk"
+
+	// wrappers are applied repeatedly until no cheaper candidate appears
+	policyMaxWrapperRounds = 4
+)
+
+var (
+	ErrInvalidPolicy      = errors.New("invalid policy")
+	ErrUncompilablePolicy = errors.New("policy cannot be compiled to a sane miniscript")
+)
+
+type policyKind int
+
+const (
+	policyPk policyKind = iota
+	policyAfter
+	policyOlder
+	policySha256
+	policyHash256
+	policyRipemd160
+	policyHash160
+	policyAnd
+	policyOr
+	policyThresh
+)
+
+var policyHashFragmentMap = map[policyKind]miniscriptFragment{
+	policySha256:    miniscriptSha256,
+	policyHash256:   miniscriptHash256,
+	policyRipemd160: miniscriptRipemd160,
+	policyHash160:   miniscriptHash160,
+}
+
+// Policy is a spending policy in the policy language, which is compiled to a miniscript.
+// ref. https://bitcoin.sipa.be/miniscript/
+type Policy struct {
+	kind    policyKind
+	key     string
+	k       uint32
+	data    []byte
+	subs    []*Policy
+	weights []uint32
+}
+
+// ParsePolicy parses a policy such as "or(99@pk(A),1@and(pk(B),older(1008)))".
+// Keys are hex encoded and validated against the context when the policy is compiled.
+func ParsePolicy(s string) (*Policy, error) {
+	name, args, err := splitDescriptorFunc(s)
+	if err != nil {
+		return nil, ErrInvalidPolicy
+	}
+
+	switch name {
+	case "pk":
+		if len(args) != 1 {
+			return nil, ErrInvalidPolicy
+		}
+		if _, err := hex.DecodeString(args[0]); err != nil || len(args[0]) == 0 {
+			return nil, ErrInvalidPolicy
+		}
+		return &Policy{kind: policyPk, key: strings.ToLower(args[0])}, nil
+
+	case "after", "older":
+		if len(args) != 1 {
+			return nil, ErrInvalidPolicy
+		}
+		k, err := parseMiniscriptUint(args[0])
+		if err != nil || k < 1 || k >= miniscriptTimelockMax {
+			return nil, ErrInvalidPolicy
+		}
+		kind := policyAfter
+		if name == "older" {
+			kind = policyOlder
+		}
+		return &Policy{kind: kind, k: k}, nil
+
+	case "sha256", "hash256", "ripemd160", "hash160":
+		if len(args) != 1 {
+			return nil, ErrInvalidPolicy
+		}
+		kind := map[string]policyKind{
+			"sha256":    policySha256,
+			"hash256":   policyHash256,
+			"ripemd160": policyRipemd160,
+			"hash160":   policyHash160,
+		}[name]
+		data, err := hex.DecodeString(args[0])
+		if err != nil || len(data) != miniscriptHashLength(policyHashFragmentMap[kind]) {
+			return nil, ErrInvalidPolicy
+		}
+		return &Policy{kind: kind, data: data}, nil
+
+	case "and", "or":
+		if len(args) != 2 {
+			return nil, ErrInvalidPolicy
+		}
+		policy := &Policy{kind: policyAnd}
+		if name == "or" {
+			policy.kind = policyOr
+		}
+		for _, arg := range args {
+			weight := uint32(1)
+			if i := strings.Index(arg, policyWeightSeparator); i >= 0 && i < strings.IndexByte(arg, '(') {
+				if policy.kind != policyOr {
+					return nil, ErrInvalidPolicy
+				}
+				w, err := parseMiniscriptUint(arg[:i])
+				if err != nil || w == 0 {
+					return nil, ErrInvalidPolicy
+				}
+				weight, arg = w, arg[i+1:]
+			}
+			sub, err := ParsePolicy(arg)
+			if err != nil {
+				return nil, err
+			}
+			policy.subs = append(policy.subs, sub)
+			policy.weights = append(policy.weights, weight)
+		}
+		return policy, nil
+
+	case "thresh":
+		if len(args) < 2 {
+			return nil, ErrInvalidPolicy
+		}
+		k, err := parseMiniscriptUint(args[0])
+		if err != nil || k < 1 || int(k) > len(args)-1 {
+			return nil, ErrInvalidPolicy
+		}
+		policy := &Policy{kind: policyThresh, k: k}
+		for _, arg := range args[1:] {
+			sub, err := ParsePolicy(arg)
+			if err != nil {
+				return nil, err
+			}
+			policy.subs = append(policy.subs, sub)
+		}
+		return policy, nil
+
+	default:
+		return nil, ErrInvalidPolicy
+	}
+}
+
+func (policy *Policy) String() string {
+	switch policy.kind {
+	case policyPk:
+		return "pk(" + policy.key + ")"
+	case policyAfter:
+		return "after(" + strconv.FormatUint(uint64(policy.k), 10) + ")"
+	case policyOlder:
+		return "older(" + strconv.FormatUint(uint64(policy.k), 10) + ")"
+	case policySha256, policyHash256, policyRipemd160, policyHash160:
+		return miniscriptFragmentNameMap[policyHashFragmentMap[policy.kind]] + "(" + hex.EncodeToString(policy.data) + ")"
+	case policyAnd, policyOr:
+		weighted := policy.kind == policyOr && (policy.weights[0] != 1 || policy.weights[1] != 1)
+		args := make([]string, len(policy.subs))
+		for i, sub := range policy.subs {
+			args[i] = sub.String()
+			if weighted {
+				args[i] = strconv.FormatUint(uint64(policy.weights[i]), 10) + policyWeightSeparator + args[i]
+			}
+		}
+		name := "and"
+		if policy.kind == policyOr {
+			name = "or"
+		}
+		return joinDescriptorArgs(name, args...)
+	default:
+		args := []string{strconv.FormatUint(uint64(policy.k), 10)}
+		for _, sub := range policy.subs {
+			args = append(args, sub.String())
+		}
+		return joinDescriptorArgs("thresh", args...)
+	}
+}
+
+// policyCandidate is a miniscript compiled from a policy with its script size
+// and the expected sizes of its satisfaction and dissatisfaction.
+type policyCandidate struct {
+	node       *Miniscript
+	scriptSize int
+	sat        float64
+	dsat       float64
+}
+
+func (cand *policyCandidate) cost(psat, pdsat float64) float64 {
+	cost := float64(cand.scriptSize) + psat*cand.sat
+	if pdsat > 0 && !math.IsInf(cand.dsat, 1) {
+		cost += pdsat * cand.dsat
+	}
+
+	return cost
+}
+
+// policyCandidates holds the cheapest candidate for each combination of the type properties
+// that matter when composing expressions.
+type policyCandidates struct {
+	psat, pdsat float64
+	m           map[miniscriptType]*policyCandidate
+}
+
+func newPolicyCandidates(psat, pdsat float64) *policyCandidates {
+	return &policyCandidates{
+		psat:  psat,
+		pdsat: pdsat,
+		m:     make(map[miniscriptType]*policyCandidate),
+	}
+}
+
+// add keeps node if it is non-malleable and cheaper than the known candidate of the same type.
+func (cands *policyCandidates) add(node *Miniscript, sat, dsat float64) (bool, error) {
+	if !node.typ.isValid() || !node.typ.has("m") {
+		return false, nil
+	}
+
+	script, err := node.Script()
+	if err != nil {
+		return false, err
+	}
+	b, err := script.Bytes()
+	if err != nil {
+		return false, err
+	}
+
+	cand := &policyCandidate{
+		node:       node,
+		scriptSize: len(b),
+		sat:        sat,
+		dsat:       dsat,
+	}
+
+	key := node.typ & mst(policyCandidateProps)
+	if known, ok := cands.m[key]; ok && !cands.less(cand, known) {
+		return false, nil
+	}
+	cands.m[key] = cand
+
+	return true, nil
+}
+
+// less orders candidates by cost, breaking ties deterministically.
+func (cands *policyCandidates) less(a, b *policyCandidate) bool {
+	aCost, bCost := a.cost(cands.psat, cands.pdsat), b.cost(cands.psat, cands.pdsat)
+	if aCost != bCost {
+		return aCost < bCost
+	}
+	if a.scriptSize != b.scriptSize {
+		return a.scriptSize < b.scriptSize
+	}
+
+	return a.node.String() < b.node.String()
+}
+
+func (cands *policyCandidates) list() []*policyCandidate {
+	list := make([]*policyCandidate, 0, len(cands.m))
+	for _, cand := range cands.m {
+		list = append(list, cand)
+	}
+
+	return list
+}
+
+// best returns the cheapest candidate having props.
+func (cands *policyCandidates) best(props string) *policyCandidate {
+	var best *policyCandidate
+	for _, cand := range cands.m {
+		if !cand.node.typ.has(props) {
+			continue
+		}
+		if best == nil || cands.less(cand, best) {
+			best = cand
+		}
+	}
+
+	return best
+}
+
+// Compile compiles the policy to the miniscript with the lowest expected spending cost for ctx,
+// counting the script size and the weighted sizes of the satisfactions.
+// ref. https://bitcoin.sipa.be/miniscript/
+func (policy *Policy) Compile(ctx MiniscriptContext) (*Miniscript, error) {
+	if ctx != MiniscriptContextP2wsh && ctx != MiniscriptContextTapscript {
+		return nil, ErrInvalidMiniscriptContext
+	}
+
+	cands, err := policy.compile(ctx, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	best := cands.best("Bmsk")
+	if best == nil || !best.node.IsSane() {
+		return nil, ErrUncompilablePolicy
+	}
+
+	return best.node, nil
+}
+
+// compile returns the candidates of the policy, which is satisfied with probability psat
+// and dissatisfied with probability pdsat.
+func (policy *Policy) compile(ctx MiniscriptContext, psat, pdsat float64) (*policyCandidates, error) {
+	cands := newPolicyCandidates(psat, pdsat)
+	inf := math.Inf(1)
+	sigSize, pubKeySize := float64(ctx.sigSize()), float64(ctx.pubKeySize())
+
+	switch policy.kind {
+	case policyPk:
+		key, err := parseMiniscriptKey(policy.key, ctx)
+		if err != nil {
+			return nil, err
+		}
+		keys := [][]byte{key}
+		if _, err := cands.add(newMiniscript(ctx, miniscriptPkK, 0, keys, nil), sigSize, 1); err != nil {
+			return nil, err
+		}
+		if _, err := cands.add(newMiniscript(ctx, miniscriptPkH, 0, keys, nil), sigSize+pubKeySize, 1+pubKeySize); err != nil {
+			return nil, err
+		}
+
+	case policyAfter, policyOlder:
+		fragment := miniscriptAfter
+		if policy.kind == policyOlder {
+			fragment = miniscriptOlder
+		}
+		if _, err := cands.add(newMiniscript(ctx, fragment, policy.k, nil, nil), 0, inf); err != nil {
+			return nil, err
+		}
+
+	case policySha256, policyHash256, policyRipemd160, policyHash160:
+		node := newMiniscript(ctx, policyHashFragmentMap[policy.kind], 0, nil, policy.data)
+		if _, err := cands.add(node, 1+miniscriptHashPreimageSize, 1+miniscriptHashPreimageSize); err != nil {
+			return nil, err
+		}
+
+	case policyAnd:
+		if err := compileAndPolicy(ctx, cands, policy.subs[0], policy.subs[1]); err != nil {
+			return nil, err
+		}
+
+	case policyOr:
+		p := float64(policy.weights[0]) / float64(policy.weights[0]+policy.weights[1])
+		if err := compileOrPolicy(ctx, cands, policy.subs[0], policy.subs[1], p); err != nil {
+			return nil, err
+		}
+
+	case policyThresh:
+		if err := compileThreshPolicy(ctx, cands, policy); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cands.wrap(ctx); err != nil {
+		return nil, err
+	}
+
+	return cands, nil
+}
+
+func compileAndPolicy(ctx MiniscriptContext, cands *policyCandidates, x, y *Policy) error {
+	xCands, err := x.compile(ctx, cands.psat, cands.pdsat)
+	if err != nil {
+		return err
+	}
+	yCands, err := y.compile(ctx, cands.psat, cands.pdsat)
+	if err != nil {
+		return err
+	}
+
+	just0 := newMiniscript(ctx, miniscriptJust0, 0, nil, nil)
+	for _, pair := range [][2]*policyCandidates{{xCands, yCands}, {yCands, xCands}} {
+		for _, l := range pair[0].list() {
+			for _, r := range pair[1].list() {
+				sat := l.sat + r.sat
+				if _, err := cands.add(newMiniscript(ctx, miniscriptAndV, 0, nil, nil, l.node, r.node), sat, math.Inf(1)); err != nil {
+					return err
+				}
+				if _, err := cands.add(newMiniscript(ctx, miniscriptAndB, 0, nil, nil, l.node, r.node), sat, l.dsat+r.dsat); err != nil {
+					return err
+				}
+				if _, err := cands.add(newMiniscript(ctx, miniscriptAndOr, 0, nil, nil, l.node, r.node, just0), sat, l.dsat); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// compileOrPolicy compiles or(x, y) where x is taken with probability p.
+func compileOrPolicy(ctx MiniscriptContext, cands *policyCandidates, x, y *Policy, p float64) error {
+	q := 1 - p
+
+	xCands, err := x.compile(ctx, p*cands.psat, q*cands.psat+cands.pdsat)
+	if err != nil {
+		return err
+	}
+	yCands, err := y.compile(ctx, q*cands.psat, p*cands.psat+cands.pdsat)
+	if err != nil {
+		return err
+	}
+
+	type orPair struct {
+		l, r *policyCandidates
+		p    float64
+	}
+
+	for _, pair := range []orPair{{xCands, yCands, p}, {yCands, xCands, q}} {
+		p, q := pair.p, 1-pair.p
+		for _, l := range pair.l.list() {
+			for _, r := range pair.r.list() {
+				nodes := []struct {
+					fragment  miniscriptFragment
+					sat, dsat float64
+				}{
+					{miniscriptOrB, p*(l.sat+r.dsat) + q*(l.dsat+r.sat), l.dsat + r.dsat},
+					{miniscriptOrD, p*l.sat + q*(l.dsat+r.sat), l.dsat + r.dsat},
+					{miniscriptOrC, p*l.sat + q*(l.dsat+r.sat), math.Inf(1)},
+					{miniscriptOrI, p*(l.sat+2) + q*(r.sat+1), math.Min(l.dsat+2, r.dsat+1)},
+				}
+				for _, n := range nodes {
+					if _, err := cands.add(newMiniscript(ctx, n.fragment, 0, nil, nil, l.node, r.node), n.sat, n.dsat); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func compileThreshPolicy(ctx MiniscriptContext, cands *policyCandidates, policy *Policy) error {
+	n, k := len(policy.subs), int(policy.k)
+
+	// thresh(n,X,Y) and thresh(1,X,Y) are the same as and(X,Y) and or(X,Y)
+	if n == 2 && k == 2 {
+		if err := compileAndPolicy(ctx, cands, policy.subs[0], policy.subs[1]); err != nil {
+			return err
+		}
+	}
+	if n == 2 && k == 1 {
+		if err := compileOrPolicy(ctx, cands, policy.subs[0], policy.subs[1], 0.5); err != nil {
+			return err
+		}
+	}
+
+	// a threshold of keys only is a multisig
+	var keys [][]byte
+	for _, sub := range policy.subs {
+		if sub.kind != policyPk {
+			keys = nil
+			break
+		}
+		key, err := parseMiniscriptKey(sub.key, ctx)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+	if keys != nil {
+		sigSize := float64(ctx.sigSize())
+		if ctx.isTapscript() && n <= miniscriptMaxMultiAKeys {
+			if _, err := cands.add(newMiniscript(ctx, miniscriptMultiA, policy.k, keys, nil), float64(k)*sigSize+float64(n-k), float64(n)); err != nil {
+				return err
+			}
+		}
+		if !ctx.isTapscript() && n <= miniscriptMaxMultiKeys {
+			if _, err := cands.add(newMiniscript(ctx, miniscriptMulti, policy.k, keys, nil), float64(k)*sigSize+1, float64(k+1)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// each subexpression is satisfied with probability k/n
+	pk := float64(k) / float64(n)
+	psat := cands.psat * pk
+	pdsat := cands.psat*(1-pk) + cands.pdsat
+
+	subs := make([]*Miniscript, n)
+	var sat, dsat float64
+	for i, sub := range policy.subs {
+		subCands, err := sub.compile(ctx, psat, pdsat)
+		if err != nil {
+			return err
+		}
+		props := "Wdu"
+		if i == 0 {
+			props = "Bdu"
+		}
+		best := subCands.best(props)
+		if best == nil {
+			return nil
+		}
+		subs[i] = best.node
+		sat += pk*best.sat + (1-pk)*best.dsat
+		dsat += best.dsat
+	}
+
+	_, err := cands.add(newMiniscript(ctx, miniscriptThresh, policy.k, nil, nil, subs...), sat, dsat)
+	return err
+}
+
+// wrap adds the candidates obtained by wrapping the known ones.
+func (cands *policyCandidates) wrap(ctx MiniscriptContext) error {
+	inf := math.Inf(1)
+	just0 := newMiniscript(ctx, miniscriptJust0, 0, nil, nil)
+	just1 := newMiniscript(ctx, miniscriptJust1, 0, nil, nil)
+
+	for i := 0; i < policyMaxWrapperRounds; i++ {
+		changed := false
+		for _, cand := range cands.list() {
+			x := cand.node
+			wrapped := []struct {
+				node      *Miniscript
+				sat, dsat float64
+			}{
+				{newMiniscript(ctx, miniscriptWrapA, 0, nil, nil, x), cand.sat, cand.dsat},
+				{newMiniscript(ctx, miniscriptWrapS, 0, nil, nil, x), cand.sat, cand.dsat},
+				{newMiniscript(ctx, miniscriptWrapC, 0, nil, nil, x), cand.sat, cand.dsat},
+				{newMiniscript(ctx, miniscriptWrapD, 0, nil, nil, x), cand.sat + 2, 1},
+				{newMiniscript(ctx, miniscriptWrapV, 0, nil, nil, x), cand.sat, inf},
+				{newMiniscript(ctx, miniscriptWrapJ, 0, nil, nil, x), cand.sat, 1},
+				{newMiniscript(ctx, miniscriptWrapN, 0, nil, nil, x), cand.sat, cand.dsat},
+				{newMiniscript(ctx, miniscriptOrI, 0, nil, nil, just0, x), cand.sat + 1, 2},
+				{newMiniscript(ctx, miniscriptOrI, 0, nil, nil, x, just0), cand.sat + 2, 1},
+				{newMiniscript(ctx, miniscriptAndV, 0, nil, nil, x, just1), cand.sat, inf},
+			}
+			for _, w := range wrapped {
+				added, err := cands.add(w.node, w.sat, w.dsat)
+				if err != nil {
+					return err
+				}
+				changed = changed || added
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package btc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyCompile(t *testing.T) {
+	testCases := []struct {
+		policy string
+		ctx    MiniscriptContext
+		ms     string
+	}{
+		{
+			"pk(K1)",
+			MiniscriptContextP2wsh,
+			"pk(K1)",
+		},
+		{
+			"or(pk(K1),pk(K2))",
+			MiniscriptContextP2wsh,
+			"or_b(pk(K1),s:pk(K2))",
+		},
+		{
+			"or(99@pk(K1),1@and(pk(K2),older(1008)))",
+			MiniscriptContextP2wsh,
+			"or_d(pk(K1),and_v(v:pkh(K2),older(1008)))",
+		},
+		{
+			"thresh(2,pk(K1),pk(K2),pk(K3))",
+			MiniscriptContextP2wsh,
+			"multi(2,K1,K2,K3)",
+		},
+		{
+			"thresh(2,pk(X1),pk(X2),pk(X3))",
+			MiniscriptContextTapscript,
+			"multi_a(2,X1,X2,X3)",
+		},
+		{
+			"or(thresh(2,pk(K1),pk(K2)),and(pk(K3),older(4032)))",
+			MiniscriptContextP2wsh,
+			"c:or_i(and_v(v:older(4032),pk_k(K3)),and_v(v:pk(K1),pk_k(K2)))",
+		},
+		{
+			"thresh(3,pk(K1),pk(K2),pk(K3),older(12960))",
+			MiniscriptContextP2wsh,
+			"thresh(3,pk(K1),s:pk(K2),s:pk(K3),sln:older(12960))",
+		},
+		{
+			"and(pk(K1),sha256(1111111111111111111111111111111111111111111111111111111111111111))",
+			MiniscriptContextP2wsh,
+			"and_v(v:pk(K1),sha256(1111111111111111111111111111111111111111111111111111111111111111))",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.policy, func(t *testing.T) {
+			policy, err := ParsePolicy(testMiniscriptKeyReplacer.Replace(tc.policy))
+			require.NoError(t, err)
+			assert.Equal(t, policy.String(), testMiniscriptKeyReplacer.Replace(tc.policy))
+
+			ms, err := policy.Compile(tc.ctx)
+			require.NoError(t, err)
+			assert.Equal(t, ms.String(), testMiniscriptKeyReplacer.Replace(tc.ms))
+			assert.True(t, ms.IsSane())
+		})
+	}
+}
+
+func TestPolicyErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		policy string
+		err    error
+	}{
+		{"unknown fragment", "multi(1,K1)", ErrInvalidPolicy},
+		{"zero timelock", "after(0)", ErrInvalidPolicy},
+		{"weighted and", "and(1@pk(K1),pk(K2))", ErrInvalidPolicy},
+		{"zero weight", "or(0@pk(K1),pk(K2))", ErrInvalidPolicy},
+		{"threshold too large", "thresh(3,pk(K1),pk(K2))", ErrInvalidPolicy},
+		{"invalid hash length", "hash160(1111)", ErrInvalidPolicy},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParsePolicy(testMiniscriptKeyReplacer.Replace(tc.policy))
+			assert.Equal(t, err, tc.err)
+		})
+	}
+}
+
+func TestPolicyCompileErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		policy string
+		ctx    MiniscriptContext
+		err    error
+	}{
+		{"no signature required", "or(pk(K1),after(500))", MiniscriptContextP2wsh, ErrUncompilablePolicy},
+		{"timelock mix", "and(pk(K1),and(after(500),after(500000000)))", MiniscriptContextP2wsh, ErrUncompilablePolicy},
+		{"x-only key in p2wsh", "pk(X1)", MiniscriptContextP2wsh, ErrInvalidMiniscriptKey},
+		{"compressed key in tapscript", "pk(K1)", MiniscriptContextTapscript, ErrInvalidMiniscriptKey},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := ParsePolicy(testMiniscriptKeyReplacer.Replace(tc.policy))
+			require.NoError(t, err)
+
+			_, err = policy.Compile(tc.ctx)
+			assert.Equal(t, err, tc.err)
+		})
+	}
+}
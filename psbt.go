@@ -0,0 +1,2209 @@
+package btc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"sort"
+)
+
+const (
+	PsbtMagic = "psbt\xff"
+
+	PsbtVersion0 uint32 = 0
+	PsbtVersion2 uint32 = 2
+
+	PsbtGlobalUnsignedTx       byte = 0x00
+	PsbtGlobalXpub             byte = 0x01
+	PsbtGlobalTxVersion        byte = 0x02
+	PsbtGlobalFallbackLockTime byte = 0x03
+	PsbtGlobalInputCount       byte = 0x04
+	PsbtGlobalOutputCount      byte = 0x05
+	PsbtGlobalTxModifiable     byte = 0x06
+	PsbtGlobalVersion          byte = 0xfb
+
+	PsbtInNonWitnessUtxo     byte = 0x00
+	PsbtInWitnessUtxo        byte = 0x01
+	PsbtInPartialSig         byte = 0x02
+	PsbtInSigHashType        byte = 0x03
+	PsbtInRedeemScript       byte = 0x04
+	PsbtInWitnessScript      byte = 0x05
+	PsbtInBip32Derivation    byte = 0x06
+	PsbtInFinalScriptSig     byte = 0x07
+	PsbtInFinalScriptWitness byte = 0x08
+
+	PsbtInPreviousTxid           byte = 0x0e
+	PsbtInOutputIndex            byte = 0x0f
+	PsbtInSequence               byte = 0x10
+	PsbtInRequiredTimeLockTime   byte = 0x11
+	PsbtInRequiredHeightLockTime byte = 0x12
+	PsbtInTapKeySig              byte = 0x13
+	PsbtInTapScriptSig           byte = 0x14
+	PsbtInTapLeafScript          byte = 0x15
+	PsbtInTapBip32Derivation     byte = 0x16
+	PsbtInTapInternalKey         byte = 0x17
+	PsbtInTapMerkleRoot          byte = 0x18
+
+	PsbtOutRedeemScript       byte = 0x00
+	PsbtOutWitnessScript      byte = 0x01
+	PsbtOutBip32Derivation    byte = 0x02
+	PsbtOutAmount             byte = 0x03
+	PsbtOutScript             byte = 0x04
+	PsbtOutTapInternalKey     byte = 0x05
+	PsbtOutTapTree            byte = 0x06
+	PsbtOutTapBip32Derivation byte = 0x07
+
+	// flags of PsbtGlobalTxModifiable
+	PsbtTxModifiableInputs        byte = 0x01
+	PsbtTxModifiableOutputs       byte = 0x02
+	PsbtTxModifiableSigHashSingle byte = 0x04
+
+	psbtSeparator byte = 0x00
+)
+
+var (
+	ErrInvalidPsbtMagic       = errors.New("invalid psbt magic")
+	ErrInvalidPsbt            = errors.New("invalid psbt")
+	ErrDuplicatePsbtKey       = errors.New("duplicate psbt key")
+	ErrUnsupportedPsbtVersion = errors.New("unsupported psbt version")
+	ErrPsbtTxNotUnsigned      = errors.New("psbt tx is not unsigned")
+	ErrPsbtUtxoNotFound       = errors.New("psbt utxo not found")
+	ErrPsbtUtxoMismatch       = errors.New("psbt utxo does not match tx in")
+	ErrPsbtTxMismatch         = errors.New("psbts have different txs")
+	ErrPsbtNotFinalized       = errors.New("psbt is not finalized")
+	ErrPsbtLockTimeConflict   = errors.New("psbt inputs require conflicting lock times")
+)
+
+type psbtKeyValue struct {
+	keyType byte
+	keyData []byte
+	value   []byte
+}
+
+func (kv *psbtKeyValue) unknown() *PsbtUnknown {
+	return &PsbtUnknown{
+		Key:   append([]byte{kv.keyType}, kv.keyData...),
+		Value: kv.value,
+	}
+}
+
+// sortPsbtKeyValues sorts key-value pairs of the same type by their key data,
+// which makes the serialization independent of the order the pairs were added in.
+func sortPsbtKeyValues(kvs []*psbtKeyValue) {
+	sort.SliceStable(kvs, func(i, j int) bool {
+		return bytes.Compare(kvs[i].keyData, kvs[j].keyData) < 0
+	})
+}
+
+// PsbtUnknown is a key-value pair of an unknown type, which is kept as is.
+type PsbtUnknown struct {
+	Key   []byte
+	Value []byte
+}
+
+func newPsbtUnknownKeyValues(unknowns []*PsbtUnknown) []*psbtKeyValue {
+	kvs := make([]*psbtKeyValue, len(unknowns))
+	for i, u := range unknowns {
+		kvs[i] = &psbtKeyValue{
+			keyType: u.Key[0],
+			keyData: u.Key[1:],
+			value:   u.Value,
+		}
+	}
+	sortPsbtKeyValues(kvs)
+
+	return kvs
+}
+
+func mergePsbtUnknowns(unknowns, others []*PsbtUnknown) []*PsbtUnknown {
+	for _, other := range others {
+		found := false
+		for _, u := range unknowns {
+			if bytes.Equal(u.Key, other.Key) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			unknowns = append(unknowns, other)
+		}
+	}
+
+	return unknowns
+}
+
+// PsbtXpub is an extended public key with the master key fingerprint and path it is derived with.
+type PsbtXpub struct {
+	Xpub        []byte
+	Fingerprint []byte
+	Path        DerivationPath
+}
+
+// PsbtBip32Derivation tells the master key fingerprint and path a public key is derived with.
+type PsbtBip32Derivation struct {
+	PubKey      *PublicKey
+	Fingerprint []byte
+	Path        DerivationPath
+}
+
+func newPsbtBip32Derivation(keyData, value []byte) (*PsbtBip32Derivation, error) {
+	pubKey, err := NewPublicKeyFromBytes(keyData)
+	if err != nil {
+		return nil, ErrInvalidPsbt
+	}
+
+	fingerprint, path, err := parsePsbtKeyOrigin(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PsbtBip32Derivation{
+		PubKey:      pubKey,
+		Fingerprint: fingerprint,
+		Path:        path,
+	}, nil
+}
+
+func newPsbtBip32DerivationKeyValues(keyType byte, derivations []*PsbtBip32Derivation) []*psbtKeyValue {
+	kvs := make([]*psbtKeyValue, len(derivations))
+	for i, d := range derivations {
+		kvs[i] = &psbtKeyValue{
+			keyType: keyType,
+			keyData: d.PubKey.Bytes(),
+			value:   psbtKeyOriginBytes(d.Fingerprint, d.Path),
+		}
+	}
+	sortPsbtKeyValues(kvs)
+
+	return kvs
+}
+
+func mergePsbtBip32Derivations(derivations, others []*PsbtBip32Derivation) []*PsbtBip32Derivation {
+	for _, other := range others {
+		found := false
+		for _, d := range derivations {
+			if bytes.Equal(d.PubKey.Bytes(), other.PubKey.Bytes()) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			derivations = append(derivations, other)
+		}
+	}
+
+	return derivations
+}
+
+// parsePsbtKeyOrigin parses a master key fingerprint followed by little endian child indexes.
+func parsePsbtKeyOrigin(b []byte) ([]byte, DerivationPath, error) {
+	if len(b) < hdFingerprintLength || len(b)%4 != 0 {
+		return nil, nil, ErrInvalidPsbt
+	}
+
+	path := make(DerivationPath, 0, (len(b)-hdFingerprintLength)/4)
+	for i := hdFingerprintLength; i < len(b); i += 4 {
+		path = append(path, binary.LittleEndian.Uint32(b[i:i+4]))
+	}
+
+	return append([]byte{}, b[:hdFingerprintLength]...), path, nil
+}
+
+func psbtKeyOriginBytes(fingerprint []byte, path DerivationPath) []byte {
+	b := append([]byte{}, fingerprint...)
+	for _, index := range path {
+		indexBytes := make([]byte, 4)
+		binary.LittleEndian.PutUint32(indexBytes, index)
+		b = append(b, indexBytes...)
+	}
+
+	return b
+}
+
+// PsbtTapBip32Derivation tells the master key fingerprint and path an x-only public key is derived with,
+// and the leaves it is used in.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0371.mediawiki#specification
+type PsbtTapBip32Derivation struct {
+	PubKey      *XOnlyPublicKey
+	LeafHashes  [][]byte
+	Fingerprint []byte
+	Path        DerivationPath
+}
+
+func newPsbtTapBip32Derivation(keyData, value []byte) (*PsbtTapBip32Derivation, error) {
+	pubKey, err := NewXOnlyPublicKeyFromBytes(keyData)
+	if err != nil {
+		return nil, ErrInvalidPsbt
+	}
+
+	r := newReader(value)
+	cnt, err := r.readVarInt()
+	if err != nil || cnt > uint(r.Len())/32 {
+		return nil, ErrInvalidPsbt
+	}
+
+	leafHashes := make([][]byte, cnt)
+	for i := range leafHashes {
+		if leafHashes[i], err = r.readBytes(32); err != nil {
+			return nil, ErrInvalidPsbt
+		}
+	}
+
+	fingerprint, path, err := parsePsbtKeyOrigin(r.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	return &PsbtTapBip32Derivation{
+		PubKey:      pubKey,
+		LeafHashes:  leafHashes,
+		Fingerprint: fingerprint,
+		Path:        path,
+	}, nil
+}
+
+func newPsbtTapBip32DerivationKeyValues(keyType byte, derivations []*PsbtTapBip32Derivation) ([]*psbtKeyValue, error) {
+	kvs := make([]*psbtKeyValue, len(derivations))
+	for i, d := range derivations {
+		w := newWriter()
+		if err := w.writeVarInt(uint(len(d.LeafHashes))); err != nil {
+			return nil, err
+		}
+		for _, h := range d.LeafHashes {
+			if _, err := w.Write(h); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := w.Write(psbtKeyOriginBytes(d.Fingerprint, d.Path)); err != nil {
+			return nil, err
+		}
+
+		kvs[i] = &psbtKeyValue{
+			keyType: keyType,
+			keyData: d.PubKey.Bytes(),
+			value:   w.Bytes(),
+		}
+	}
+	sortPsbtKeyValues(kvs)
+
+	return kvs, nil
+}
+
+func mergePsbtTapBip32Derivations(derivations, others []*PsbtTapBip32Derivation) []*PsbtTapBip32Derivation {
+	for _, other := range others {
+		found := false
+		for _, d := range derivations {
+			if d.PubKey.IsEqual(other.PubKey) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			derivations = append(derivations, other)
+		}
+	}
+
+	return derivations
+}
+
+// isValidPsbtTapSig reports whether b is a schnorr signature optionally followed by a non-default sighash type.
+func isValidPsbtTapSig(b []byte) bool {
+	switch len(b) {
+	case SchnorrSigLength:
+		return true
+	case SchnorrSigLength + 1:
+		hashType := SigHashType(b[SchnorrSigLength])
+		return hashType != SigHashDefault && hashType.isValidTaproot()
+	default:
+		return false
+	}
+}
+
+// PsbtTapScriptSig is a signature of an x-only public key for a script path spend of the leaf of LeafHash.
+type PsbtTapScriptSig struct {
+	PubKey    *XOnlyPublicKey
+	LeafHash  []byte
+	Signature []byte
+}
+
+// PsbtTapLeafScript is a leaf with the control block for spending it.
+type PsbtTapLeafScript struct {
+	ControlBlock *ControlBlock
+	Leaf         *TapLeaf
+}
+
+// PsbtTapTreeLeaf is a leaf of the tap tree of an output with its depth,
+// listed in depth-first search order from left to right.
+type PsbtTapTreeLeaf struct {
+	Depth byte
+	Leaf  *TapLeaf
+}
+
+// NewPsbtTapTreeLeaves returns the leaves of tree with their depths.
+func NewPsbtTapTreeLeaves(tree *TapTree) []*PsbtTapTreeLeaf {
+	return newPsbtTapTreeLeaves(tree, 0)
+}
+
+func newPsbtTapTreeLeaves(tree *TapTree, depth byte) []*PsbtTapTreeLeaf {
+	if tree.leaf != nil {
+		return []*PsbtTapTreeLeaf{
+			{
+				Depth: depth,
+				Leaf:  tree.leaf,
+			},
+		}
+	}
+
+	return append(newPsbtTapTreeLeaves(tree.left, depth+1), newPsbtTapTreeLeaves(tree.right, depth+1)...)
+}
+
+// NewTapTreeFromPsbtLeaves rebuilds the tree whose leaves and depths are leaves.
+func NewTapTreeFromPsbtLeaves(leaves []*PsbtTapTreeLeaf) (*TapTree, error) {
+	if len(leaves) == 0 {
+		return nil, ErrEmptyTapTree
+	}
+
+	type node struct {
+		tree  *TapTree
+		depth int
+	}
+
+	// subtrees waiting for their right siblings
+	var stack []*node
+	for _, leaf := range leaves {
+		if leaf.Depth > TapControlBlockMaxDepth {
+			return nil, ErrTapTreeTooDeep
+		}
+
+		tree, err := NewTapTreeLeaf(leaf.Leaf)
+		if err != nil {
+			return nil, err
+		}
+
+		n := &node{tree, int(leaf.Depth)}
+		for len(stack) > 0 && stack[len(stack)-1].depth == n.depth {
+			if n.depth == 0 {
+				return nil, ErrInvalidPsbt
+			}
+
+			left := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			if n.tree, err = NewTapTreeBranch(left.tree, n.tree); err != nil {
+				return nil, err
+			}
+			n.depth--
+		}
+		stack = append(stack, n)
+	}
+
+	if len(stack) != 1 || stack[0].depth != 0 {
+		return nil, ErrInvalidPsbt
+	}
+
+	return stack[0].tree, nil
+}
+
+func parsePsbtTapTree(b []byte) ([]*PsbtTapTreeLeaf, error) {
+	r := newReader(b)
+
+	var leaves []*PsbtTapTreeLeaf
+	for r.Len() > 0 {
+		depth, err := r.ReadByte()
+		if err != nil {
+			return nil, ErrInvalidPsbt
+		}
+		version, err := r.ReadByte()
+		if err != nil {
+			return nil, ErrInvalidPsbt
+		}
+		sb, err := r.readVarBytes()
+		if err != nil {
+			return nil, ErrInvalidPsbt
+		}
+		script, err := parsePsbtScript(sb)
+		if err != nil {
+			return nil, err
+		}
+
+		leaves = append(leaves, &PsbtTapTreeLeaf{
+			Depth: depth,
+			Leaf: &TapLeaf{
+				Version: version,
+				Script:  script,
+			},
+		})
+	}
+
+	if _, err := NewTapTreeFromPsbtLeaves(leaves); err != nil {
+		return nil, ErrInvalidPsbt
+	}
+
+	return leaves, nil
+}
+
+func psbtTapTreeBytes(leaves []*PsbtTapTreeLeaf) ([]byte, error) {
+	w := newWriter()
+	for _, leaf := range leaves {
+		if err := w.WriteByte(leaf.Depth); err != nil {
+			return nil, err
+		}
+		if err := w.WriteByte(leaf.Leaf.Version); err != nil {
+			return nil, err
+		}
+		if err := w.writeScript(leaf.Leaf.Script); err != nil {
+			return nil, err
+		}
+	}
+
+	return w.Bytes(), nil
+}
+
+func parsePsbtTx(b []byte, stripped bool) (*Tx, error) {
+	r := newReader(b)
+
+	tx, err := r.readTxWithWitness(!stripped)
+	if err != nil || r.Len() != 0 {
+		return nil, ErrInvalidPsbt
+	}
+
+	return tx, nil
+}
+
+func parsePsbtTxOut(b []byte) (*TxOut, error) {
+	r := newReader(b)
+
+	txOut, err := r.readTxOut()
+	if err != nil || r.Len() != 0 {
+		return nil, ErrInvalidPsbt
+	}
+
+	return txOut, nil
+}
+
+func parsePsbtScript(b []byte) (*Script, error) {
+	script, err := NewScriptFromBytes(b)
+	if err != nil {
+		return nil, ErrInvalidPsbt
+	}
+
+	return script, nil
+}
+
+// PsbtPartialSig is a signature of an input, which ends with its sighash type.
+type PsbtPartialSig struct {
+	PubKey    *PublicKey
+	Signature []byte
+}
+
+type psbtPartialSigSorter struct {
+	kvs  []*psbtKeyValue
+	pkhs []Pkh
+}
+
+func (sorter psbtPartialSigSorter) Len() int {
+	return len(sorter.kvs)
+}
+
+func (sorter psbtPartialSigSorter) Less(i, j int) bool {
+	return bytes.Compare(sorter.pkhs[i], sorter.pkhs[j]) < 0
+}
+
+func (sorter psbtPartialSigSorter) Swap(i, j int) {
+	sorter.kvs[i], sorter.kvs[j] = sorter.kvs[j], sorter.kvs[i]
+	sorter.pkhs[i], sorter.pkhs[j] = sorter.pkhs[j], sorter.pkhs[i]
+}
+
+func parsePsbtUint32(b []byte) (uint32, error) {
+	if len(b) != 4 {
+		return 0, ErrInvalidPsbt
+	}
+
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+func psbtUint32Bytes(n uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, n)
+
+	return b
+}
+
+// isPsbtVersion2InputType reports whether keyType is an input type only version 2 PSBTs have.
+// Pairs of the types with key data are unknowns in version 0 as they were before version 2.
+func isPsbtVersion2InputType(keyType byte) bool {
+	return keyType >= PsbtInPreviousTxid && keyType <= PsbtInRequiredHeightLockTime
+}
+
+// PsbtInput is the input map of a PSBT.
+// RequiredTimeLockTime and RequiredHeightLockTime are used by version 2 PSBTs,
+// in which zero means that the input does not require a lock time of the type.
+type PsbtInput struct {
+	NonWitnessUtxo         *Tx
+	WitnessUtxo            *TxOut
+	PartialSigs            []*PsbtPartialSig
+	SigHashType            SigHashType
+	RedeemScript           *Script
+	WitnessScript          *Script
+	Bip32Derivations       []*PsbtBip32Derivation
+	FinalScriptSig         *Script
+	FinalScriptWitness     []string
+	RequiredTimeLockTime   uint32
+	RequiredHeightLockTime uint32
+	TapKeySig              []byte
+	TapScriptSigs          []*PsbtTapScriptSig
+	TapLeafScripts         []*PsbtTapLeafScript
+	TapBip32Derivations    []*PsbtTapBip32Derivation
+	TapInternalKey         *XOnlyPublicKey
+	TapMerkleRoot          []byte
+	Unknowns               []*PsbtUnknown
+}
+
+// newPsbtInput parses the input map of a PSBT of version.
+// For version 2, it also returns the tx in described by the map.
+func newPsbtInput(kvs []*psbtKeyValue, version uint32) (*PsbtInput, *TxIn, error) {
+	in := &PsbtInput{}
+	txIn := &TxIn{
+		Script:   &Script{},
+		Sequence: TxInSequence,
+	}
+
+	var hasTxid, hasIndex bool
+
+	for _, kv := range kvs {
+		var err error
+
+		if isPsbtVersion2InputType(kv.keyType) {
+			if len(kv.keyData) != 0 && version != PsbtVersion2 {
+				in.Unknowns = append(in.Unknowns, kv.unknown())
+				continue
+			}
+			if len(kv.keyData) != 0 || version != PsbtVersion2 {
+				return nil, nil, ErrInvalidPsbt
+			}
+		}
+
+		switch kv.keyType {
+		case PsbtInNonWitnessUtxo:
+			if len(kv.keyData) != 0 {
+				return nil, nil, ErrInvalidPsbt
+			}
+			in.NonWitnessUtxo, err = parsePsbtTx(kv.value, false)
+		case PsbtInWitnessUtxo:
+			if len(kv.keyData) != 0 {
+				return nil, nil, ErrInvalidPsbt
+			}
+			in.WitnessUtxo, err = parsePsbtTxOut(kv.value)
+		case PsbtInPartialSig:
+			pubKey, err := NewPublicKeyFromBytes(kv.keyData)
+			if err != nil || len(kv.value) == 0 {
+				return nil, nil, ErrInvalidPsbt
+			}
+			in.PartialSigs = append(in.PartialSigs, &PsbtPartialSig{
+				PubKey:    pubKey,
+				Signature: kv.value,
+			})
+		case PsbtInSigHashType:
+			if len(kv.keyData) != 0 {
+				return nil, nil, ErrInvalidPsbt
+			}
+			var hashType uint32
+			hashType, err = parsePsbtUint32(kv.value)
+			in.SigHashType = SigHashType(hashType)
+		case PsbtInRedeemScript:
+			if len(kv.keyData) != 0 {
+				return nil, nil, ErrInvalidPsbt
+			}
+			in.RedeemScript, err = parsePsbtScript(kv.value)
+		case PsbtInWitnessScript:
+			if len(kv.keyData) != 0 {
+				return nil, nil, ErrInvalidPsbt
+			}
+			in.WitnessScript, err = parsePsbtScript(kv.value)
+		case PsbtInBip32Derivation:
+			d, err := newPsbtBip32Derivation(kv.keyData, kv.value)
+			if err != nil {
+				return nil, nil, err
+			}
+			in.Bip32Derivations = append(in.Bip32Derivations, d)
+		case PsbtInFinalScriptSig:
+			if len(kv.keyData) != 0 {
+				return nil, nil, ErrInvalidPsbt
+			}
+			in.FinalScriptSig, err = parsePsbtScript(kv.value)
+		case PsbtInFinalScriptWitness:
+			if len(kv.keyData) != 0 {
+				return nil, nil, ErrInvalidPsbt
+			}
+			r := newReader(kv.value)
+			if in.FinalScriptWitness, err = r.readWitness(); err != nil || r.Len() != 0 {
+				return nil, nil, ErrInvalidPsbt
+			}
+		case PsbtInPreviousTxid:
+			if len(kv.value) != 32 {
+				return nil, nil, ErrInvalidPsbt
+			}
+			txIn.Txid = hex.EncodeToString(reverseBytes(kv.value))
+			hasTxid = true
+		case PsbtInOutputIndex:
+			txIn.Index, err = parsePsbtUint32(kv.value)
+			hasIndex = true
+		case PsbtInSequence:
+			txIn.Sequence, err = parsePsbtUint32(kv.value)
+		case PsbtInRequiredTimeLockTime:
+			in.RequiredTimeLockTime, err = parsePsbtUint32(kv.value)
+			if err == nil && in.RequiredTimeLockTime < TxLockTimeThreshold {
+				err = ErrInvalidPsbt
+			}
+		case PsbtInRequiredHeightLockTime:
+			in.RequiredHeightLockTime, err = parsePsbtUint32(kv.value)
+			if err == nil && (in.RequiredHeightLockTime == 0 || in.RequiredHeightLockTime >= TxLockTimeThreshold) {
+				err = ErrInvalidPsbt
+			}
+		case PsbtInTapKeySig:
+			if len(kv.keyData) != 0 || !isValidPsbtTapSig(kv.value) {
+				return nil, nil, ErrInvalidPsbt
+			}
+			in.TapKeySig = kv.value
+		case PsbtInTapScriptSig:
+			if len(kv.keyData) != 64 || !isValidPsbtTapSig(kv.value) {
+				return nil, nil, ErrInvalidPsbt
+			}
+			pubKey, err := NewXOnlyPublicKeyFromBytes(kv.keyData[:32])
+			if err != nil {
+				return nil, nil, ErrInvalidPsbt
+			}
+			in.TapScriptSigs = append(in.TapScriptSigs, &PsbtTapScriptSig{
+				PubKey:    pubKey,
+				LeafHash:  kv.keyData[32:],
+				Signature: kv.value,
+			})
+		case PsbtInTapLeafScript:
+			cb, err := NewControlBlockFromBytes(kv.keyData)
+			if err != nil || len(kv.value) == 0 {
+				return nil, nil, ErrInvalidPsbt
+			}
+			leafVersion := kv.value[len(kv.value)-1]
+			if leafVersion != cb.LeafVersion {
+				return nil, nil, ErrInvalidPsbt
+			}
+			script, err := parsePsbtScript(kv.value[:len(kv.value)-1])
+			if err != nil {
+				return nil, nil, err
+			}
+			in.TapLeafScripts = append(in.TapLeafScripts, &PsbtTapLeafScript{
+				ControlBlock: cb,
+				Leaf: &TapLeaf{
+					Version: leafVersion,
+					Script:  script,
+				},
+			})
+		case PsbtInTapBip32Derivation:
+			d, err := newPsbtTapBip32Derivation(kv.keyData, kv.value)
+			if err != nil {
+				return nil, nil, err
+			}
+			in.TapBip32Derivations = append(in.TapBip32Derivations, d)
+		case PsbtInTapInternalKey:
+			if len(kv.keyData) != 0 {
+				return nil, nil, ErrInvalidPsbt
+			}
+			in.TapInternalKey, err = NewXOnlyPublicKeyFromBytes(kv.value)
+		case PsbtInTapMerkleRoot:
+			if len(kv.keyData) != 0 || len(kv.value) != 32 {
+				return nil, nil, ErrInvalidPsbt
+			}
+			in.TapMerkleRoot = kv.value
+		default:
+			in.Unknowns = append(in.Unknowns, kv.unknown())
+		}
+		if err != nil {
+			return nil, nil, ErrInvalidPsbt
+		}
+	}
+
+	if version != PsbtVersion2 {
+		return in, nil, nil
+	}
+	if !hasTxid || !hasIndex {
+		return nil, nil, ErrInvalidPsbt
+	}
+
+	return in, txIn, nil
+}
+
+// keyValues returns the key-value pairs of the input,
+// which include the fields of txIn if it is not nil (version 2).
+func (in *PsbtInput) keyValues(txIn *TxIn) ([]*psbtKeyValue, error) {
+	kvs := []*psbtKeyValue{}
+
+	if in.NonWitnessUtxo != nil {
+		b, err := in.NonWitnessUtxo.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		kvs = append(kvs, &psbtKeyValue{
+			keyType: PsbtInNonWitnessUtxo,
+			value:   b,
+		})
+	}
+
+	if in.WitnessUtxo != nil {
+		w := newWriter()
+		if err := w.writeTxOut(in.WitnessUtxo); err != nil {
+			return nil, err
+		}
+		kvs = append(kvs, &psbtKeyValue{
+			keyType: PsbtInWitnessUtxo,
+			value:   w.Bytes(),
+		})
+	}
+
+	// partial signatures are ordered by the hashes of their public keys as Bitcoin Core does
+	sigs := make([]*psbtKeyValue, len(in.PartialSigs))
+	pkhs := make([]Pkh, len(in.PartialSigs))
+	for i, ps := range in.PartialSigs {
+		pkh, err := ps.PubKey.Pkh()
+		if err != nil {
+			return nil, err
+		}
+		sigs[i] = &psbtKeyValue{
+			keyType: PsbtInPartialSig,
+			keyData: ps.PubKey.Bytes(),
+			value:   ps.Signature,
+		}
+		pkhs[i] = pkh
+	}
+	sort.Sort(psbtPartialSigSorter{sigs, pkhs})
+	kvs = append(kvs, sigs...)
+
+	if in.SigHashType != SigHashDefault {
+		kvs = append(kvs, &psbtKeyValue{
+			keyType: PsbtInSigHashType,
+			value:   psbtUint32Bytes(in.SigHashType.Uint32()),
+		})
+	}
+
+	for _, s := range []struct {
+		keyType byte
+		script  *Script
+	}{
+		{PsbtInRedeemScript, in.RedeemScript},
+		{PsbtInWitnessScript, in.WitnessScript},
+	} {
+		if s.script == nil {
+			continue
+		}
+		b, err := s.script.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		kvs = append(kvs, &psbtKeyValue{
+			keyType: s.keyType,
+			value:   b,
+		})
+	}
+
+	kvs = append(kvs, newPsbtBip32DerivationKeyValues(PsbtInBip32Derivation, in.Bip32Derivations)...)
+
+	if in.FinalScriptSig != nil {
+		b, err := in.FinalScriptSig.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		kvs = append(kvs, &psbtKeyValue{
+			keyType: PsbtInFinalScriptSig,
+			value:   b,
+		})
+	}
+
+	if in.FinalScriptWitness != nil {
+		w := newWriter()
+		if err := w.writeWitness(in.FinalScriptWitness); err != nil {
+			return nil, err
+		}
+		kvs = append(kvs, &psbtKeyValue{
+			keyType: PsbtInFinalScriptWitness,
+			value:   w.Bytes(),
+		})
+	}
+
+	if txIn != nil {
+		txid, err := hex.DecodeString(txIn.Txid)
+		if err != nil {
+			return nil, err
+		}
+		kvs = append(kvs,
+			&psbtKeyValue{
+				keyType: PsbtInPreviousTxid,
+				value:   reverseBytes(txid),
+			},
+			&psbtKeyValue{
+				keyType: PsbtInOutputIndex,
+				value:   psbtUint32Bytes(txIn.Index),
+			},
+		)
+		if txIn.Sequence != TxInSequence {
+			kvs = append(kvs, &psbtKeyValue{
+				keyType: PsbtInSequence,
+				value:   psbtUint32Bytes(txIn.Sequence),
+			})
+		}
+		if in.RequiredTimeLockTime != 0 {
+			kvs = append(kvs, &psbtKeyValue{
+				keyType: PsbtInRequiredTimeLockTime,
+				value:   psbtUint32Bytes(in.RequiredTimeLockTime),
+			})
+		}
+		if in.RequiredHeightLockTime != 0 {
+			kvs = append(kvs, &psbtKeyValue{
+				keyType: PsbtInRequiredHeightLockTime,
+				value:   psbtUint32Bytes(in.RequiredHeightLockTime),
+			})
+		}
+	}
+
+	if in.TapKeySig != nil {
+		kvs = append(kvs, &psbtKeyValue{
+			keyType: PsbtInTapKeySig,
+			value:   in.TapKeySig,
+		})
+	}
+
+	scriptSigs := make([]*psbtKeyValue, len(in.TapScriptSigs))
+	for i, ss := range in.TapScriptSigs {
+		scriptSigs[i] = &psbtKeyValue{
+			keyType: PsbtInTapScriptSig,
+			keyData: concatBytes(ss.PubKey.Bytes(), ss.LeafHash),
+			value:   ss.Signature,
+		}
+	}
+	sortPsbtKeyValues(scriptSigs)
+	kvs = append(kvs, scriptSigs...)
+
+	leafScripts := make([]*psbtKeyValue, len(in.TapLeafScripts))
+	for i, ls := range in.TapLeafScripts {
+		b, err := ls.Leaf.Script.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		leafScripts[i] = &psbtKeyValue{
+			keyType: PsbtInTapLeafScript,
+			keyData: ls.ControlBlock.Bytes(),
+			value:   append(b, ls.Leaf.Version),
+		}
+	}
+	sortPsbtKeyValues(leafScripts)
+	kvs = append(kvs, leafScripts...)
+
+	derivations, err := newPsbtTapBip32DerivationKeyValues(PsbtInTapBip32Derivation, in.TapBip32Derivations)
+	if err != nil {
+		return nil, err
+	}
+	kvs = append(kvs, derivations...)
+
+	if in.TapInternalKey != nil {
+		kvs = append(kvs, &psbtKeyValue{
+			keyType: PsbtInTapInternalKey,
+			value:   in.TapInternalKey.Bytes(),
+		})
+	}
+
+	if in.TapMerkleRoot != nil {
+		kvs = append(kvs, &psbtKeyValue{
+			keyType: PsbtInTapMerkleRoot,
+			value:   in.TapMerkleRoot,
+		})
+	}
+
+	return append(kvs, newPsbtUnknownKeyValues(in.Unknowns)...), nil
+}
+
+func (in *PsbtInput) IsFinalized() bool {
+	return in.FinalScriptSig != nil || in.FinalScriptWitness != nil
+}
+
+func (in *PsbtInput) partialSig(pubKey []byte) []byte {
+	for _, ps := range in.PartialSigs {
+		if bytes.Equal(ps.PubKey.Bytes(), pubKey) {
+			return ps.Signature
+		}
+	}
+
+	return nil
+}
+
+// addPartialSig adds sig unless the input already has a signature of pubKey.
+func (in *PsbtInput) addPartialSig(pubKey *PublicKey, sig []byte) {
+	if in.partialSig(pubKey.Bytes()) != nil {
+		return
+	}
+
+	in.PartialSigs = append(in.PartialSigs, &PsbtPartialSig{
+		PubKey:    pubKey,
+		Signature: sig,
+	})
+}
+
+func (in *PsbtInput) merge(other *PsbtInput) {
+	if in.NonWitnessUtxo == nil {
+		in.NonWitnessUtxo = other.NonWitnessUtxo
+	}
+	if in.WitnessUtxo == nil {
+		in.WitnessUtxo = other.WitnessUtxo
+	}
+	for _, ps := range other.PartialSigs {
+		in.addPartialSig(ps.PubKey, ps.Signature)
+	}
+	if in.SigHashType == SigHashDefault {
+		in.SigHashType = other.SigHashType
+	}
+	if in.RedeemScript == nil {
+		in.RedeemScript = other.RedeemScript
+	}
+	if in.WitnessScript == nil {
+		in.WitnessScript = other.WitnessScript
+	}
+	in.Bip32Derivations = mergePsbtBip32Derivations(in.Bip32Derivations, other.Bip32Derivations)
+	if in.FinalScriptSig == nil {
+		in.FinalScriptSig = other.FinalScriptSig
+	}
+	if in.FinalScriptWitness == nil {
+		in.FinalScriptWitness = other.FinalScriptWitness
+	}
+	if in.RequiredTimeLockTime == 0 {
+		in.RequiredTimeLockTime = other.RequiredTimeLockTime
+	}
+	if in.RequiredHeightLockTime == 0 {
+		in.RequiredHeightLockTime = other.RequiredHeightLockTime
+	}
+	if in.TapKeySig == nil {
+		in.TapKeySig = other.TapKeySig
+	}
+	for _, otherSig := range other.TapScriptSigs {
+		found := false
+		for _, ss := range in.TapScriptSigs {
+			if ss.PubKey.IsEqual(otherSig.PubKey) && bytes.Equal(ss.LeafHash, otherSig.LeafHash) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			in.TapScriptSigs = append(in.TapScriptSigs, otherSig)
+		}
+	}
+	for _, otherScript := range other.TapLeafScripts {
+		found := false
+		for _, ls := range in.TapLeafScripts {
+			if bytes.Equal(ls.ControlBlock.Bytes(), otherScript.ControlBlock.Bytes()) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			in.TapLeafScripts = append(in.TapLeafScripts, otherScript)
+		}
+	}
+	in.TapBip32Derivations = mergePsbtTapBip32Derivations(in.TapBip32Derivations, other.TapBip32Derivations)
+	if in.TapInternalKey == nil {
+		in.TapInternalKey = other.TapInternalKey
+	}
+	if in.TapMerkleRoot == nil {
+		in.TapMerkleRoot = other.TapMerkleRoot
+	}
+	in.Unknowns = mergePsbtUnknowns(in.Unknowns, other.Unknowns)
+}
+
+// solve returns the stack items satisfying a P2PK, P2PKH or multisig script with the partial signatures.
+func (in *PsbtInput) solve(script []byte) ([][]byte, error) {
+	ops, err := parseScript(script)
+	if err != nil {
+		return nil, err
+	}
+
+	if pubKey := extractP2pkPubKey(ops); pubKey != nil {
+		sig := in.partialSig(pubKey)
+		if sig == nil {
+			return nil, ErrNotEnoughSignatures
+		}
+
+		return [][]byte{sig}, nil
+	}
+
+	if pkh := extractP2pkhPkh(ops); pkh != nil {
+		for _, ps := range in.PartialSigs {
+			h, err := ps.PubKey.Pkh()
+			if err != nil {
+				return nil, err
+			}
+			if bytes.Equal(h, pkh) {
+				return [][]byte{ps.Signature, ps.PubKey.Bytes()}, nil
+			}
+		}
+
+		return nil, ErrNotEnoughSignatures
+	}
+
+	if m, pubKeys, ok := extractMultisig(ops); ok {
+		// CHECKMULTISIG pops one extra item
+		items := [][]byte{{}}
+
+		for _, pubKey := range pubKeys {
+			if len(items) == m+1 {
+				break
+			}
+			if sig := in.partialSig(pubKey); sig != nil {
+				items = append(items, sig)
+			}
+		}
+
+		if len(items) < m+1 {
+			return nil, ErrNotEnoughSignatures
+		}
+
+		return items, nil
+	}
+
+	return nil, ErrUnsupportedScript
+}
+
+// PsbtOutput is the output map of a PSBT.
+type PsbtOutput struct {
+	RedeemScript        *Script
+	WitnessScript       *Script
+	Bip32Derivations    []*PsbtBip32Derivation
+	TapInternalKey      *XOnlyPublicKey
+	TapTree             []*PsbtTapTreeLeaf
+	TapBip32Derivations []*PsbtTapBip32Derivation
+	Unknowns            []*PsbtUnknown
+}
+
+// newPsbtOutput parses the output map of a PSBT of version.
+// For version 2, it also returns the tx out described by the map.
+func newPsbtOutput(kvs []*psbtKeyValue, version uint32) (*PsbtOutput, *TxOut, error) {
+	out := &PsbtOutput{}
+	txOut := &TxOut{}
+
+	var hasAmount bool
+
+	for _, kv := range kvs {
+		var err error
+
+		switch kv.keyType {
+		case PsbtOutRedeemScript:
+			if len(kv.keyData) != 0 {
+				return nil, nil, ErrInvalidPsbt
+			}
+			out.RedeemScript, err = parsePsbtScript(kv.value)
+		case PsbtOutWitnessScript:
+			if len(kv.keyData) != 0 {
+				return nil, nil, ErrInvalidPsbt
+			}
+			out.WitnessScript, err = parsePsbtScript(kv.value)
+		case PsbtOutBip32Derivation:
+			d, err := newPsbtBip32Derivation(kv.keyData, kv.value)
+			if err != nil {
+				return nil, nil, err
+			}
+			out.Bip32Derivations = append(out.Bip32Derivations, d)
+		case PsbtOutAmount:
+			if len(kv.keyData) != 0 && version != PsbtVersion2 {
+				out.Unknowns = append(out.Unknowns, kv.unknown())
+				continue
+			}
+			if version != PsbtVersion2 || len(kv.keyData) != 0 || len(kv.value) != 8 {
+				return nil, nil, ErrInvalidPsbt
+			}
+			txOut.Amount = Satoshi(binary.LittleEndian.Uint64(kv.value))
+			hasAmount = true
+		case PsbtOutScript:
+			if len(kv.keyData) != 0 && version != PsbtVersion2 {
+				out.Unknowns = append(out.Unknowns, kv.unknown())
+				continue
+			}
+			if version != PsbtVersion2 || len(kv.keyData) != 0 {
+				return nil, nil, ErrInvalidPsbt
+			}
+			txOut.Script, err = parsePsbtScript(kv.value)
+		case PsbtOutTapInternalKey:
+			if len(kv.keyData) != 0 {
+				return nil, nil, ErrInvalidPsbt
+			}
+			if out.TapInternalKey, err = NewXOnlyPublicKeyFromBytes(kv.value); err != nil {
+				return nil, nil, ErrInvalidPsbt
+			}
+		case PsbtOutTapTree:
+			if len(kv.keyData) != 0 {
+				return nil, nil, ErrInvalidPsbt
+			}
+			out.TapTree, err = parsePsbtTapTree(kv.value)
+		case PsbtOutTapBip32Derivation:
+			d, err := newPsbtTapBip32Derivation(kv.keyData, kv.value)
+			if err != nil {
+				return nil, nil, err
+			}
+			out.TapBip32Derivations = append(out.TapBip32Derivations, d)
+		default:
+			out.Unknowns = append(out.Unknowns, kv.unknown())
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if version != PsbtVersion2 {
+		return out, nil, nil
+	}
+	if !hasAmount || txOut.Script == nil {
+		return nil, nil, ErrInvalidPsbt
+	}
+
+	return out, txOut, nil
+}
+
+// keyValues returns the key-value pairs of the output,
+// which include the fields of txOut if it is not nil (version 2).
+func (out *PsbtOutput) keyValues(txOut *TxOut) ([]*psbtKeyValue, error) {
+	kvs := []*psbtKeyValue{}
+
+	for _, s := range []struct {
+		keyType byte
+		script  *Script
+	}{
+		{PsbtOutRedeemScript, out.RedeemScript},
+		{PsbtOutWitnessScript, out.WitnessScript},
+	} {
+		if s.script == nil {
+			continue
+		}
+		b, err := s.script.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		kvs = append(kvs, &psbtKeyValue{
+			keyType: s.keyType,
+			value:   b,
+		})
+	}
+
+	kvs = append(kvs, newPsbtBip32DerivationKeyValues(PsbtOutBip32Derivation, out.Bip32Derivations)...)
+
+	if txOut != nil {
+		amount := make([]byte, 8)
+		binary.LittleEndian.PutUint64(amount, uint64(txOut.Amount))
+
+		b, err := txOut.Script.Bytes()
+		if err != nil {
+			return nil, err
+		}
+
+		kvs = append(kvs,
+			&psbtKeyValue{
+				keyType: PsbtOutAmount,
+				value:   amount,
+			},
+			&psbtKeyValue{
+				keyType: PsbtOutScript,
+				value:   b,
+			},
+		)
+	}
+
+	if out.TapInternalKey != nil {
+		kvs = append(kvs, &psbtKeyValue{
+			keyType: PsbtOutTapInternalKey,
+			value:   out.TapInternalKey.Bytes(),
+		})
+	}
+
+	if out.TapTree != nil {
+		b, err := psbtTapTreeBytes(out.TapTree)
+		if err != nil {
+			return nil, err
+		}
+		kvs = append(kvs, &psbtKeyValue{
+			keyType: PsbtOutTapTree,
+			value:   b,
+		})
+	}
+
+	derivations, err := newPsbtTapBip32DerivationKeyValues(PsbtOutTapBip32Derivation, out.TapBip32Derivations)
+	if err != nil {
+		return nil, err
+	}
+	kvs = append(kvs, derivations...)
+
+	return append(kvs, newPsbtUnknownKeyValues(out.Unknowns)...), nil
+}
+
+func (out *PsbtOutput) merge(other *PsbtOutput) {
+	if out.RedeemScript == nil {
+		out.RedeemScript = other.RedeemScript
+	}
+	if out.WitnessScript == nil {
+		out.WitnessScript = other.WitnessScript
+	}
+	out.Bip32Derivations = mergePsbtBip32Derivations(out.Bip32Derivations, other.Bip32Derivations)
+	if out.TapInternalKey == nil {
+		out.TapInternalKey = other.TapInternalKey
+	}
+	if out.TapTree == nil {
+		out.TapTree = other.TapTree
+	}
+	out.TapBip32Derivations = mergePsbtTapBip32Derivations(out.TapBip32Derivations, other.TapBip32Derivations)
+	out.Unknowns = mergePsbtUnknowns(out.Unknowns, other.Unknowns)
+}
+
+// Psbt is a partially signed bitcoin transaction.
+// For version 2, Tx is built from the global, input and output maps and its lock time is the fallback one,
+// which is overridden by the lock times required by the inputs (see LockTime).
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0174.mediawiki
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0370.mediawiki
+type Psbt struct {
+	Tx           *Tx
+	Version      uint32
+	TxModifiable byte
+	Xpubs        []*PsbtXpub
+	Inputs       []*PsbtInput
+	Outputs      []*PsbtOutput
+	Unknowns     []*PsbtUnknown
+}
+
+// NewPsbt returns a PSBT of tx, whose scriptSigs and witnesses must be empty (Creator).
+func NewPsbt(tx *Tx) (*Psbt, error) {
+	if err := checkPsbtUnsignedTx(tx); err != nil {
+		return nil, err
+	}
+
+	psbt := &Psbt{
+		Tx:      tx,
+		Inputs:  make([]*PsbtInput, len(tx.TxIns)),
+		Outputs: make([]*PsbtOutput, len(tx.TxOuts)),
+	}
+	for i := range psbt.Inputs {
+		psbt.Inputs[i] = &PsbtInput{}
+	}
+	for i := range psbt.Outputs {
+		psbt.Outputs[i] = &PsbtOutput{}
+	}
+
+	return psbt, nil
+}
+
+func checkPsbtUnsignedTx(tx *Tx) error {
+	for _, txIn := range tx.TxIns {
+		if (txIn.Script != nil && txIn.Script.Hex != "") || txIn.HasWitness() {
+			return ErrPsbtTxNotUnsigned
+		}
+	}
+
+	return nil
+}
+
+func NewPsbtFromBase64(s string) (*Psbt, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPsbtFromBytes(b)
+}
+
+func NewPsbtFromBytes(b []byte) (*Psbt, error) {
+	if !bytes.HasPrefix(b, []byte(PsbtMagic)) {
+		return nil, ErrInvalidPsbtMagic
+	}
+
+	r := newReader(b[len(PsbtMagic):])
+
+	globals, err := r.readPsbtMap()
+	if err != nil {
+		return nil, err
+	}
+
+	psbt := &Psbt{}
+
+	// the global pairs of the types of version 2, which are parsed once the version is known
+	var v2Globals []*psbtKeyValue
+
+	for _, kv := range globals {
+		switch kv.keyType {
+		case PsbtGlobalUnsignedTx:
+			if len(kv.keyData) != 0 {
+				return nil, ErrInvalidPsbt
+			}
+			if psbt.Tx, err = parsePsbtTx(kv.value, true); err != nil {
+				return nil, err
+			}
+		case PsbtGlobalXpub:
+			if len(kv.keyData) != extendedKeyLength {
+				return nil, ErrInvalidPsbt
+			}
+			fingerprint, path, err := parsePsbtKeyOrigin(kv.value)
+			if err != nil {
+				return nil, err
+			}
+			psbt.Xpubs = append(psbt.Xpubs, &PsbtXpub{
+				Xpub:        kv.keyData,
+				Fingerprint: fingerprint,
+				Path:        path,
+			})
+		case PsbtGlobalTxVersion, PsbtGlobalFallbackLockTime,
+			PsbtGlobalInputCount, PsbtGlobalOutputCount, PsbtGlobalTxModifiable:
+			v2Globals = append(v2Globals, kv)
+		case PsbtGlobalVersion:
+			if len(kv.keyData) != 0 {
+				return nil, ErrInvalidPsbt
+			}
+			if psbt.Version, err = parsePsbtUint32(kv.value); err != nil {
+				return nil, err
+			}
+		default:
+			psbt.Unknowns = append(psbt.Unknowns, kv.unknown())
+		}
+	}
+
+	var inputCount, outputCount uint
+
+	switch psbt.Version {
+	case PsbtVersion0:
+		for _, kv := range v2Globals {
+			if len(kv.keyData) == 0 {
+				return nil, ErrInvalidPsbt
+			}
+			psbt.Unknowns = append(psbt.Unknowns, kv.unknown())
+		}
+		if psbt.Tx == nil {
+			return nil, ErrInvalidPsbt
+		}
+		if err := checkPsbtUnsignedTx(psbt.Tx); err != nil {
+			return nil, err
+		}
+		inputCount, outputCount = uint(len(psbt.Tx.TxIns)), uint(len(psbt.Tx.TxOuts))
+	case PsbtVersion2:
+		if psbt.Tx != nil {
+			return nil, ErrInvalidPsbt
+		}
+		if inputCount, outputCount, err = psbt.parseVersion2Globals(v2Globals); err != nil {
+			return nil, err
+		}
+		// every map takes at least its separator
+		if inputCount+outputCount > uint(r.Len()) {
+			return nil, ErrInvalidPsbt
+		}
+	default:
+		return nil, ErrUnsupportedPsbtVersion
+	}
+
+	psbt.Inputs = make([]*PsbtInput, inputCount)
+	for i := range psbt.Inputs {
+		kvs, err := r.readPsbtMap()
+		if err != nil {
+			return nil, err
+		}
+
+		in, txIn, err := newPsbtInput(kvs, psbt.Version)
+		if err != nil {
+			return nil, err
+		}
+		if txIn != nil {
+			psbt.Tx.TxIns = append(psbt.Tx.TxIns, txIn)
+		}
+		if in.NonWitnessUtxo != nil {
+			if err := checkPsbtNonWitnessUtxo(psbt.Tx.TxIns[i], in.NonWitnessUtxo); err != nil {
+				return nil, err
+			}
+		}
+
+		psbt.Inputs[i] = in
+	}
+
+	psbt.Outputs = make([]*PsbtOutput, outputCount)
+	for i := range psbt.Outputs {
+		kvs, err := r.readPsbtMap()
+		if err != nil {
+			return nil, err
+		}
+
+		out, txOut, err := newPsbtOutput(kvs, psbt.Version)
+		if err != nil {
+			return nil, err
+		}
+		if txOut != nil {
+			psbt.Tx.TxOuts = append(psbt.Tx.TxOuts, txOut)
+		}
+
+		psbt.Outputs[i] = out
+	}
+
+	if r.Len() != 0 {
+		return nil, ErrInvalidPsbt
+	}
+
+	if _, err := psbt.LockTime(); err != nil {
+		return nil, err
+	}
+
+	return psbt, nil
+}
+
+// parseVersion2Globals sets the tx without tx ins and tx outs described by the global fields of version 2,
+// and returns the numbers of the inputs and outputs.
+func (psbt *Psbt) parseVersion2Globals(kvs []*psbtKeyValue) (uint, uint, error) {
+	psbt.Tx = &Tx{}
+
+	var inputCount, outputCount uint
+	var hasTxVersion, hasInputCount, hasOutputCount bool
+
+	parseCount := func(b []byte) (uint, error) {
+		r := newReader(b)
+		n, err := r.readVarInt()
+		if err != nil || r.Len() != 0 {
+			return 0, ErrInvalidPsbt
+		}
+
+		return n, nil
+	}
+
+	for _, kv := range kvs {
+		var err error
+
+		if len(kv.keyData) != 0 {
+			return 0, 0, ErrInvalidPsbt
+		}
+
+		switch kv.keyType {
+		case PsbtGlobalTxVersion:
+			var version uint32
+			version, err = parsePsbtUint32(kv.value)
+			psbt.Tx.Version = int32(version)
+			hasTxVersion = true
+		case PsbtGlobalFallbackLockTime:
+			psbt.Tx.LockTime, err = parsePsbtUint32(kv.value)
+		case PsbtGlobalInputCount:
+			inputCount, err = parseCount(kv.value)
+			hasInputCount = true
+		case PsbtGlobalOutputCount:
+			outputCount, err = parseCount(kv.value)
+			hasOutputCount = true
+		case PsbtGlobalTxModifiable:
+			if len(kv.value) != 1 {
+				return 0, 0, ErrInvalidPsbt
+			}
+			psbt.TxModifiable = kv.value[0]
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if !hasTxVersion || psbt.Tx.Version < 2 || !hasInputCount || !hasOutputCount {
+		return 0, 0, ErrInvalidPsbt
+	}
+
+	return inputCount, outputCount, nil
+}
+
+func checkPsbtNonWitnessUtxo(txIn *TxIn, utxo *Tx) error {
+	txid, err := utxo.Txid()
+	if err != nil {
+		return err
+	}
+	if txid != txIn.Txid || int(txIn.Index) >= len(utxo.TxOuts) {
+		return ErrPsbtUtxoMismatch
+	}
+
+	return nil
+}
+
+func (psbt *Psbt) Bytes() ([]byte, error) {
+	w := newWriter()
+	if _, err := w.WriteString(PsbtMagic); err != nil {
+		return nil, err
+	}
+
+	var globals []*psbtKeyValue
+
+	switch psbt.Version {
+	case PsbtVersion0:
+		txBytes, err := psbt.Tx.StrippedBytes()
+		if err != nil {
+			return nil, err
+		}
+		globals = append(globals, &psbtKeyValue{
+			keyType: PsbtGlobalUnsignedTx,
+			value:   txBytes,
+		})
+	case PsbtVersion2:
+		if psbt.Tx.Version < 2 {
+			return nil, ErrInvalidPsbt
+		}
+	default:
+		return nil, ErrUnsupportedPsbtVersion
+	}
+
+	xpubs := make([]*psbtKeyValue, len(psbt.Xpubs))
+	for i, xpub := range psbt.Xpubs {
+		xpubs[i] = &psbtKeyValue{
+			keyType: PsbtGlobalXpub,
+			keyData: xpub.Xpub,
+			value:   psbtKeyOriginBytes(xpub.Fingerprint, xpub.Path),
+		}
+	}
+	sortPsbtKeyValues(xpubs)
+	globals = append(globals, xpubs...)
+
+	if psbt.Version == PsbtVersion2 {
+		countBytes := func(n int) ([]byte, error) {
+			w := newWriter()
+			if err := w.writeVarInt(uint(n)); err != nil {
+				return nil, err
+			}
+
+			return w.Bytes(), nil
+		}
+
+		inputCount, err := countBytes(len(psbt.Tx.TxIns))
+		if err != nil {
+			return nil, err
+		}
+		outputCount, err := countBytes(len(psbt.Tx.TxOuts))
+		if err != nil {
+			return nil, err
+		}
+
+		globals = append(globals, &psbtKeyValue{
+			keyType: PsbtGlobalTxVersion,
+			value:   psbtUint32Bytes(uint32(psbt.Tx.Version)),
+		})
+		if psbt.Tx.LockTime != 0 {
+			globals = append(globals, &psbtKeyValue{
+				keyType: PsbtGlobalFallbackLockTime,
+				value:   psbtUint32Bytes(psbt.Tx.LockTime),
+			})
+		}
+		globals = append(globals,
+			&psbtKeyValue{
+				keyType: PsbtGlobalInputCount,
+				value:   inputCount,
+			},
+			&psbtKeyValue{
+				keyType: PsbtGlobalOutputCount,
+				value:   outputCount,
+			},
+		)
+		if psbt.TxModifiable != 0 {
+			globals = append(globals, &psbtKeyValue{
+				keyType: PsbtGlobalTxModifiable,
+				value:   []byte{psbt.TxModifiable},
+			})
+		}
+	}
+
+	if psbt.Version != PsbtVersion0 {
+		globals = append(globals, &psbtKeyValue{
+			keyType: PsbtGlobalVersion,
+			value:   psbtUint32Bytes(psbt.Version),
+		})
+	}
+
+	globals = append(globals, newPsbtUnknownKeyValues(psbt.Unknowns)...)
+	if err := w.writePsbtMap(globals); err != nil {
+		return nil, err
+	}
+
+	for i, in := range psbt.Inputs {
+		var txIn *TxIn
+		if psbt.Version == PsbtVersion2 {
+			txIn = psbt.Tx.TxIns[i]
+		}
+
+		kvs, err := in.keyValues(txIn)
+		if err != nil {
+			return nil, err
+		}
+		if err := w.writePsbtMap(kvs); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, out := range psbt.Outputs {
+		var txOut *TxOut
+		if psbt.Version == PsbtVersion2 {
+			txOut = psbt.Tx.TxOuts[i]
+		}
+
+		kvs, err := out.keyValues(txOut)
+		if err != nil {
+			return nil, err
+		}
+		if err := w.writePsbtMap(kvs); err != nil {
+			return nil, err
+		}
+	}
+
+	return w.Bytes(), nil
+}
+
+func (psbt *Psbt) Base64() (string, error) {
+	b, err := psbt.Bytes()
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// LockTime returns the lock time of the tx.
+// For version 2, it is the largest lock time of the type required by the inputs, preferring block heights,
+// or the fallback lock time if no input requires one.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0370.mediawiki#determining-lock-time
+func (psbt *Psbt) LockTime() (uint32, error) {
+	if psbt.Version != PsbtVersion2 {
+		return psbt.Tx.LockTime, nil
+	}
+
+	var timeLockTime, heightLockTime uint32
+	required, allowsTime, allowsHeight := false, true, true
+
+	for _, in := range psbt.Inputs {
+		if in.RequiredTimeLockTime == 0 && in.RequiredHeightLockTime == 0 {
+			continue
+		}
+		required = true
+
+		if in.RequiredTimeLockTime == 0 {
+			allowsTime = false
+		} else if in.RequiredTimeLockTime > timeLockTime {
+			timeLockTime = in.RequiredTimeLockTime
+		}
+
+		if in.RequiredHeightLockTime == 0 {
+			allowsHeight = false
+		} else if in.RequiredHeightLockTime > heightLockTime {
+			heightLockTime = in.RequiredHeightLockTime
+		}
+	}
+
+	switch {
+	case !required:
+		return psbt.Tx.LockTime, nil
+	case allowsHeight:
+		return heightLockTime, nil
+	case allowsTime:
+		return timeLockTime, nil
+	default:
+		return 0, ErrPsbtLockTimeConflict
+	}
+}
+
+// unsignedTx returns the tx the inputs are signed for, whose lock time is the one LockTime returns.
+func (psbt *Psbt) unsignedTx() (*Tx, error) {
+	lockTime, err := psbt.LockTime()
+	if err != nil {
+		return nil, err
+	}
+	if lockTime == psbt.Tx.LockTime {
+		return psbt.Tx, nil
+	}
+
+	tx := *psbt.Tx
+	tx.LockTime = lockTime
+
+	return &tx, nil
+}
+
+func (psbt *Psbt) input(idx int) (*PsbtInput, error) {
+	if idx < 0 || idx >= len(psbt.Inputs) {
+		return nil, ErrTxInIndexOutOfRange
+	}
+
+	return psbt.Inputs[idx], nil
+}
+
+// utxo returns the output spent by the input at idx.
+func (psbt *Psbt) utxo(idx int) (*TxOut, error) {
+	in := psbt.Inputs[idx]
+
+	if in.WitnessUtxo != nil {
+		return in.WitnessUtxo, nil
+	}
+	if in.NonWitnessUtxo != nil {
+		txIn := psbt.Tx.TxIns[idx]
+		if int(txIn.Index) >= len(in.NonWitnessUtxo.TxOuts) {
+			return nil, ErrPsbtUtxoMismatch
+		}
+
+		return in.NonWitnessUtxo.TxOuts[txIn.Index], nil
+	}
+
+	return nil, ErrPsbtUtxoNotFound
+}
+
+// signer returns a Signer of the input at idx, whose sighash type is the one requested by the input.
+// The outputs spent by the other inputs are added if known, which taproot signatures commit to.
+func (psbt *Psbt) signer(idx int, keys KeySource) (*Signer, error) {
+	tx, err := psbt.unsignedTx()
+	if err != nil {
+		return nil, err
+	}
+
+	prevOuts := make([]*PrevOut, len(psbt.Inputs))
+	for i, in := range psbt.Inputs {
+		utxo, err := psbt.utxo(i)
+		if err != nil {
+			if i == idx {
+				return nil, err
+			}
+			continue
+		}
+
+		prevOuts[i] = &PrevOut{
+			TxOut:          utxo,
+			RedeemScript:   in.RedeemScript,
+			WitnessScript:  in.WitnessScript,
+			TapInternalKey: in.TapInternalKey,
+			TapMerkleRoot:  in.TapMerkleRoot,
+		}
+	}
+
+	signer, err := NewSigner(tx, prevOuts, keys)
+	if err != nil {
+		return nil, err
+	}
+	signer.SetHashType(psbt.Inputs[idx].SigHashType)
+
+	return signer, nil
+}
+
+// UpdateInput adds the output spent by the input at idx and its scripts (Updater).
+// Only outputs spent with witnesses are added as witness UTXOs;
+// the others need the whole previous tx, which is added with SetNonWitnessUtxo.
+func (psbt *Psbt) UpdateInput(idx int, prevOut *PrevOut) error {
+	in, err := psbt.input(idx)
+	if err != nil {
+		return err
+	}
+
+	b, err := prevOut.Script.Bytes()
+	if err != nil {
+		return err
+	}
+	if classifyScript(b) == ScriptTypeP2sh && prevOut.RedeemScript != nil {
+		if b, err = prevOut.RedeemScript.Bytes(); err != nil {
+			return err
+		}
+	}
+	if _, _, ok := extractWitnessProgram(b); ok {
+		in.WitnessUtxo = prevOut.TxOut
+	}
+
+	if prevOut.RedeemScript != nil {
+		in.RedeemScript = prevOut.RedeemScript
+	}
+	if prevOut.WitnessScript != nil {
+		in.WitnessScript = prevOut.WitnessScript
+	}
+	if prevOut.TapInternalKey != nil {
+		in.TapInternalKey = prevOut.TapInternalKey
+	}
+	if prevOut.TapMerkleRoot != nil {
+		in.TapMerkleRoot = prevOut.TapMerkleRoot
+	}
+
+	return nil
+}
+
+// SetNonWitnessUtxo adds the previous tx of the input at idx (Updater).
+func (psbt *Psbt) SetNonWitnessUtxo(idx int, tx *Tx) error {
+	in, err := psbt.input(idx)
+	if err != nil {
+		return err
+	}
+
+	if err := checkPsbtNonWitnessUtxo(psbt.Tx.TxIns[idx], tx); err != nil {
+		return err
+	}
+
+	in.NonWitnessUtxo = tx
+
+	return nil
+}
+
+// Sign adds partial signatures of keys to every input they can sign (Signer).
+// Inputs spending unsupported scripts, not involving keys or whose UTXOs are not known yet are left as they are.
+func (psbt *Psbt) Sign(keys KeySource) error {
+	for i := range psbt.Inputs {
+		err := psbt.SignInput(i, keys)
+		if err == ErrPrivateKeyNotFound || err == ErrUnsupportedScript || err == ErrTapInternalKeyNotFound || err == ErrPsbtUtxoNotFound {
+			continue
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SignInput adds a partial signature of every key in keys involved in the input at idx,
+// or the key path signature for P2TR, which needs the outputs spent by all the inputs.
+// It supports the scripts Finalize does.
+func (psbt *Psbt) SignInput(idx int, keys KeySource) error {
+	in, err := psbt.input(idx)
+	if err != nil {
+		return err
+	}
+	if in.IsFinalized() {
+		return nil
+	}
+
+	signer, err := psbt.signer(idx, keys)
+	if err != nil {
+		return err
+	}
+
+	b, err := signer.prevOuts[idx].Script.Bytes()
+	if err != nil {
+		return err
+	}
+	if classifyScript(b) == ScriptTypeP2tr {
+		for _, prevOut := range signer.prevOuts {
+			if prevOut == nil {
+				return ErrPsbtUtxoNotFound
+			}
+		}
+
+		items, err := signer.signTaproot(idx, b)
+		if err != nil {
+			return err
+		}
+		in.TapKeySig = items[0]
+
+		return nil
+	}
+
+	script, hasher, err := psbt.signingScript(signer, idx)
+	if err != nil {
+		return err
+	}
+
+	ops, err := parseScript(script)
+	if err != nil {
+		return err
+	}
+
+	var pkhs []Pkh
+	if pubKey := extractP2pkPubKey(ops); pubKey != nil {
+		pkh, err := Hash160(pubKey)
+		if err != nil {
+			return err
+		}
+		pkhs = append(pkhs, pkh)
+	} else if pkh := extractP2pkhPkh(ops); pkh != nil {
+		pkhs = append(pkhs, pkh)
+	} else if _, pubKeys, ok := extractMultisig(ops); ok {
+		for _, pubKey := range pubKeys {
+			pkh, err := Hash160(pubKey)
+			if err != nil {
+				return err
+			}
+			pkhs = append(pkhs, pkh)
+		}
+	} else {
+		return ErrUnsupportedScript
+	}
+
+	signed := false
+	for _, pkh := range pkhs {
+		privKey, err := keys.PrivateKeyByPkh(pkh)
+		if err == ErrPrivateKeyNotFound {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		pubKey, err := matchPkh(privKey.PublicKey(), pkh)
+		if err != nil {
+			return err
+		}
+
+		sig, err := signer.ecdsaSign(privKey, hasher)
+		if err != nil {
+			return err
+		}
+
+		in.addPartialSig(pubKey, sig)
+		signed = true
+	}
+	if !signed {
+		return ErrPrivateKeyNotFound
+	}
+
+	return nil
+}
+
+// signingScript returns the P2PK, P2PKH or multisig script the signatures of the input at idx are checked against.
+func (psbt *Psbt) signingScript(signer *Signer, idx int) ([]byte, sigHasher, error) {
+	prevOut := signer.prevOuts[idx]
+
+	b, err := prevOut.Script.Bytes()
+	if err != nil {
+		return nil, nil, err
+	}
+	if classifyScript(b) == ScriptTypeP2sh {
+		if b, err = signer.redeemScript(prevOut, b); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	switch classifyScript(b) {
+	case ScriptTypeP2pk, ScriptTypeP2pkh, ScriptTypeMultisig:
+		return b, signer.legacySigner(idx, b), nil
+	case ScriptTypeP2wpkh:
+		_, program, _ := extractWitnessProgram(b)
+
+		scriptCode, err := NewP2pkhScript(Pkh(program))
+		if err != nil {
+			return nil, nil, err
+		}
+		sb, err := scriptCode.Bytes()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return sb, signer.witnessV0Signer(idx, sb), nil
+	case ScriptTypeP2wsh:
+		_, program, _ := extractWitnessProgram(b)
+
+		wb, err := signer.witnessScript(prevOut, program)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return wb, signer.witnessV0Signer(idx, wb), nil
+	default:
+		return nil, nil, ErrUnsupportedScript
+	}
+}
+
+// Combine merges the key-value pairs of others, which must have the same unsigned tx (Combiner).
+func (psbt *Psbt) Combine(others ...*Psbt) error {
+	tx, err := psbt.unsignedTx()
+	if err != nil {
+		return err
+	}
+	txid, err := tx.Txid()
+	if err != nil {
+		return err
+	}
+
+	for _, other := range others {
+		otherTx, err := other.unsignedTx()
+		if err != nil {
+			return err
+		}
+		otherTxid, err := otherTx.Txid()
+		if err != nil {
+			return err
+		}
+		if otherTxid != txid {
+			return ErrPsbtTxMismatch
+		}
+	}
+
+	for _, other := range others {
+		for _, otherXpub := range other.Xpubs {
+			found := false
+			for _, xpub := range psbt.Xpubs {
+				if bytes.Equal(xpub.Xpub, otherXpub.Xpub) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				psbt.Xpubs = append(psbt.Xpubs, otherXpub)
+			}
+		}
+		for i, in := range psbt.Inputs {
+			in.merge(other.Inputs[i])
+		}
+		for i, out := range psbt.Outputs {
+			out.merge(other.Outputs[i])
+		}
+		psbt.Unknowns = mergePsbtUnknowns(psbt.Unknowns, other.Unknowns)
+	}
+
+	return nil
+}
+
+// Finalize builds the final scriptSig and witness of every input from its partial signatures (Finalizer).
+func (psbt *Psbt) Finalize() error {
+	for i := range psbt.Inputs {
+		if err := psbt.FinalizeInput(i); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FinalizeInput builds the final scriptSig and witness of the input at idx
+// and clears the data other than the UTXOs, the required lock times and unknowns, which are no longer needed.
+func (psbt *Psbt) FinalizeInput(idx int) error {
+	in, err := psbt.input(idx)
+	if err != nil {
+		return err
+	}
+	if in.IsFinalized() {
+		return nil
+	}
+
+	signer, err := psbt.signer(idx, nil)
+	if err != nil {
+		return err
+	}
+	prevOut := signer.prevOuts[idx]
+
+	b, err := prevOut.Script.Bytes()
+	if err != nil {
+		return err
+	}
+
+	var rb []byte
+	if classifyScript(b) == ScriptTypeP2sh {
+		if rb, err = signer.redeemScript(prevOut, b); err != nil {
+			return err
+		}
+		b = rb
+	}
+
+	var scriptSigItems, witnessItems [][]byte
+
+	switch classifyScript(b) {
+	case ScriptTypeP2pk, ScriptTypeP2pkh, ScriptTypeMultisig:
+		if scriptSigItems, err = in.solve(b); err != nil {
+			return err
+		}
+	case ScriptTypeP2wpkh:
+		_, program, _ := extractWitnessProgram(b)
+
+		scriptCode, err := NewP2pkhScript(Pkh(program))
+		if err != nil {
+			return err
+		}
+		sb, err := scriptCode.Bytes()
+		if err != nil {
+			return err
+		}
+
+		if witnessItems, err = in.solve(sb); err != nil {
+			return err
+		}
+	case ScriptTypeP2wsh:
+		_, program, _ := extractWitnessProgram(b)
+
+		wb, err := signer.witnessScript(prevOut, program)
+		if err != nil {
+			return err
+		}
+
+		items, err := in.solve(wb)
+		if err != nil {
+			return err
+		}
+		witnessItems = append(items, wb)
+	case ScriptTypeP2tr:
+		if rb != nil {
+			return ErrUnsupportedScript
+		}
+		if in.TapKeySig == nil {
+			return ErrNotEnoughSignatures
+		}
+		witnessItems = [][]byte{in.TapKeySig}
+	default:
+		return ErrUnsupportedScript
+	}
+
+	if rb != nil {
+		scriptSigItems = append(scriptSigItems, rb)
+	}
+
+	if len(scriptSigItems) > 0 {
+		scriptSig, err := newPushOnlyScript(scriptSigItems)
+		if err != nil {
+			return err
+		}
+		in.FinalScriptSig = scriptSig
+	}
+	if len(witnessItems) > 0 {
+		witness := make([]string, len(witnessItems))
+		for i, item := range witnessItems {
+			witness[i] = hex.EncodeToString(item)
+		}
+		in.FinalScriptWitness = witness
+	}
+
+	in.PartialSigs = nil
+	in.SigHashType = SigHashDefault
+	in.RedeemScript = nil
+	in.WitnessScript = nil
+	in.Bip32Derivations = nil
+	in.TapKeySig = nil
+	in.TapScriptSigs = nil
+	in.TapLeafScripts = nil
+	in.TapBip32Derivations = nil
+	in.TapInternalKey = nil
+	in.TapMerkleRoot = nil
+
+	return nil
+}
+
+func (psbt *Psbt) IsFinalized() bool {
+	for _, in := range psbt.Inputs {
+		if !in.IsFinalized() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Extract returns the tx with the final scriptSigs and witnesses (Extractor).
+// The result is not verified; Signer.Verify does it given the spent outputs.
+func (psbt *Psbt) Extract() (*Tx, error) {
+	if !psbt.IsFinalized() {
+		return nil, ErrPsbtNotFinalized
+	}
+
+	unsignedTx, err := psbt.unsignedTx()
+	if err != nil {
+		return nil, err
+	}
+
+	tx := &Tx{
+		Version:  unsignedTx.Version,
+		TxIns:    make([]*TxIn, len(unsignedTx.TxIns)),
+		TxOuts:   unsignedTx.TxOuts,
+		LockTime: unsignedTx.LockTime,
+	}
+	for i, txIn := range unsignedTx.TxIns {
+		in := psbt.Inputs[i]
+
+		script := in.FinalScriptSig
+		if script == nil {
+			script = &Script{}
+		}
+
+		tx.TxIns[i] = &TxIn{
+			Txid:     txIn.Txid,
+			Index:    txIn.Index,
+			Script:   script,
+			Sequence: txIn.Sequence,
+			Witness:  in.FinalScriptWitness,
+		}
+	}
+
+	return tx, nil
+}
@@ -26,6 +26,11 @@ func (r *reader) readData(size uint, data interface{}) error {
 }
 
 func (r *reader) readBytes(size uint) ([]byte, error) {
+	// fails as reading byte by byte does, without allocating for the size
+	if size > uint(r.Len()) {
+		return nil, io.EOF
+	}
+
 	data := make([]byte, size)
 
 	var i uint
@@ -42,6 +47,10 @@ func (r *reader) readBytes(size uint) ([]byte, error) {
 }
 
 func (r *reader) readBytesReverse(size uint) ([]byte, error) {
+	if size > uint(r.Len()) {
+		return nil, io.EOF
+	}
+
 	data := make([]byte, size)
 
 	var i uint
@@ -179,6 +188,18 @@ func (r *reader) readVarInt() (uint, error) {
 	}
 }
 
+func (r *reader) readVarBytes() ([]byte, error) {
+	size, err := r.readVarInt()
+	if err != nil {
+		return nil, err
+	}
+	if size > uint(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	return r.readBytes(size)
+}
+
 func (r *reader) readOpCode() (OpCode, error) {
 	b, err := r.ReadByte()
 	if err != nil {
@@ -236,6 +257,42 @@ func (r *reader) readScriptPart() ([]string, error) {
 	return []string{op.Name()}, nil
 }
 
+func (r *reader) readScriptOp() (*scriptOp, error) {
+	op, err := r.readOpCode()
+	if err != nil {
+		return nil, err
+	}
+
+	if op.isPushData() {
+		b, err := r.readPushedData(op)
+		if err != nil {
+			return nil, err
+		}
+
+		return &scriptOp{op, b}, nil
+	}
+
+	return &scriptOp{op, nil}, nil
+}
+
+func (r *reader) readScriptOps() ([]*scriptOp, error) {
+	ops := []*scriptOp{}
+
+	for r.Len() > 0 {
+		op, err := r.readScriptOp()
+		if err != nil {
+			if err == io.EOF {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		ops = append(ops, op)
+	}
+
+	return ops, nil
+}
+
 func (r *reader) readScript() (*Script, error) {
 	b := r.Bytes()
 	asmParts := []string{}
@@ -267,6 +324,10 @@ func (r *reader) readTxIns() ([]*TxIn, error) {
 	if err != nil {
 		return nil, err
 	}
+	// every count comes from the data, which has at least one byte per item
+	if txInCnt > uint(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
 
 	txIns := make([]*TxIn, txInCnt)
 	for i := 0; i < int(txInCnt); i++ {
@@ -330,6 +391,9 @@ func (r *reader) readTxOuts() ([]*TxOut, error) {
 	if err != nil {
 		return nil, err
 	}
+	if txOutCnt > uint(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
 
 	txOuts := make([]*TxOut, txOutCnt)
 	for i := 0; i < int(txOutCnt); i++ {
@@ -369,16 +433,63 @@ func (r *reader) readTxOut() (*TxOut, error) {
 	}, nil
 }
 
+func (r *reader) readWitness() ([]string, error) {
+	cnt, err := r.readVarInt()
+	if err != nil {
+		return nil, err
+	}
+	if cnt > uint(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	witness := make([]string, cnt)
+	for i := uint(0); i < cnt; i++ {
+		size, err := r.readVarInt()
+		if err != nil {
+			return nil, err
+		}
+
+		item, err := r.readHex(size)
+		if err != nil {
+			return nil, err
+		}
+
+		witness[i] = item
+	}
+
+	return witness, nil
+}
+
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0144.mediawiki
+func (r *reader) hasWitnessMarker() bool {
+	b := r.Bytes()
+
+	return len(b) >= 2 && b[0] == TxWitnessMarker && b[1] == TxWitnessFlag
+}
+
 func (r *reader) readLockTime() (uint32, error) {
 	return r.readUint32()
 }
 
 func (r *reader) readTx() (*Tx, error) {
+	return r.readTxWithWitness(true)
+}
+
+// readTxWithWitness reads a tx, which is read as serialized without witnesses unless allowWitness is set.
+// A tx without tx ins can be read unambiguously only in that way.
+func (r *reader) readTxWithWitness(allowWitness bool) (*Tx, error) {
 	version, err := r.readTxVersion()
 	if err != nil {
 		return nil, err
 	}
 
+	hasWitness := allowWitness && r.hasWitnessMarker()
+	if hasWitness {
+		if _, err := r.readBytes(2); err != nil {
+			return nil, err
+		}
+	}
+
 	txIns, err := r.readTxIns()
 	if err != nil {
 		return nil, err
@@ -389,6 +500,17 @@ func (r *reader) readTx() (*Tx, error) {
 		return nil, err
 	}
 
+	if hasWitness {
+		for _, txIn := range txIns {
+			witness, err := r.readWitness()
+			if err != nil {
+				return nil, err
+			}
+
+			txIn.Witness = witness
+		}
+	}
+
 	lockTime, err := r.readLockTime()
 	if err != nil {
 		return nil, err
@@ -407,6 +529,9 @@ func (r *reader) readTxes() ([]*Tx, error) {
 	if err != nil {
 		return nil, err
 	}
+	if cnt > uint(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
 
 	txes := make([]*Tx, cnt)
 	for i := uint(0); i < cnt; i++ {
@@ -502,3 +627,35 @@ func (r *reader) readBlockHeader() (*BlockHeader, error) {
 		Nonce:         nonce,
 	}, nil
 }
+
+// readPsbtMap reads key-value pairs up to the separator, rejecting duplicate keys.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0174.mediawiki#specification
+func (r *reader) readPsbtMap() ([]*psbtKeyValue, error) {
+	kvs := []*psbtKeyValue{}
+	seen := map[string]bool{}
+
+	for {
+		key, err := r.readVarBytes()
+		if err != nil {
+			return nil, err
+		}
+		if len(key) == 0 {
+			return kvs, nil
+		}
+		if seen[string(key)] {
+			return nil, ErrDuplicatePsbtKey
+		}
+		seen[string(key)] = true
+
+		value, err := r.readVarBytes()
+		if err != nil {
+			return nil, err
+		}
+
+		kvs = append(kvs, &psbtKeyValue{
+			keyType: key[0],
+			keyData: key[1:],
+			value:   value,
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package btc
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math/big"
+)
+
+const (
+	XOnlyPubKeyLength = 32 // 0x20
+	SchnorrSigLength  = 64 // 0x40
+
+	TagBip340Aux       = "BIP0340/aux"
+	TagBip340Nonce     = "BIP0340/nonce"
+	TagBip340Challenge = "BIP0340/challenge"
+)
+
+var (
+	ErrInvalidXOnlyPubKey      = errors.New("invalid x-only public key")
+	ErrInvalidSchnorrSig       = errors.New("invalid schnorr signature")
+	ErrInvalidAuxRand          = errors.New("invalid aux rand")
+	ErrSchnorrSignFailed       = errors.New("schnorr signing failed")
+	ErrSchnorrBatchSizeUnequal = errors.New("schnorr batch size unequal")
+)
+
+// XOnlyPublicKey is a public key with an implicitly even y coordinate.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0340.mediawiki#public-key-generation
+type XOnlyPublicKey struct {
+	x *big.Int
+	y *big.Int
+}
+
+func NewXOnlyPublicKeyFromHex(s string) (*XOnlyPublicKey, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewXOnlyPublicKeyFromBytes(b)
+}
+
+func NewXOnlyPublicKeyFromBytes(b []byte) (*XOnlyPublicKey, error) {
+	if len(b) != XOnlyPubKeyLength {
+		return nil, ErrInvalidXOnlyPubKey
+	}
+
+	x := new(big.Int).SetBytes(b)
+	y := secp256k1.DecompressY(x, false)
+	if y == nil {
+		return nil, ErrInvalidXOnlyPubKey
+	}
+
+	return &XOnlyPublicKey{x, y}, nil
+}
+
+func (pubKey *XOnlyPublicKey) Bytes() []byte {
+	return paddedBytes(pubKey.x, XOnlyPubKeyLength)
+}
+
+func (pubKey *XOnlyPublicKey) Hex() string {
+	return hex.EncodeToString(pubKey.Bytes())
+}
+
+func (pubKey *XOnlyPublicKey) PublicKey() *PublicKey {
+	return &PublicKey{
+		x:            pubKey.x,
+		y:            pubKey.y,
+		uncompressed: false,
+	}
+}
+
+func (pubKey *XOnlyPublicKey) IsEqual(other *XOnlyPublicKey) bool {
+	return pubKey.x.Cmp(other.x) == 0
+}
+
+func (pubKey *PublicKey) XOnly() *XOnlyPublicKey {
+	if pubKey.hasEvenY() {
+		return &XOnlyPublicKey{pubKey.x, pubKey.y}
+	}
+
+	x, y := secp256k1.Negate(pubKey.x, pubKey.y)
+
+	return &XOnlyPublicKey{x, y}
+}
+
+func (pubKey *PublicKey) hasEvenY() bool {
+	return pubKey.y.Bit(0) == 0
+}
+
+type SchnorrSignature struct {
+	r *big.Int
+	s *big.Int
+}
+
+func NewSchnorrSignatureFromHex(s string) (*SchnorrSignature, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSchnorrSignatureFromBytes(b)
+}
+
+func NewSchnorrSignatureFromBytes(b []byte) (*SchnorrSignature, error) {
+	if len(b) != SchnorrSigLength {
+		return nil, ErrInvalidSchnorrSig
+	}
+
+	r := new(big.Int).SetBytes(b[:32])
+	if r.Cmp(secp256k1.P) >= 0 {
+		return nil, ErrInvalidSchnorrSig
+	}
+
+	s := new(big.Int).SetBytes(b[32:])
+	if s.Cmp(secp256k1.N) >= 0 {
+		return nil, ErrInvalidSchnorrSig
+	}
+
+	return &SchnorrSignature{r, s}, nil
+}
+
+func (sig *SchnorrSignature) Bytes() []byte {
+	b := make([]byte, 0, SchnorrSigLength)
+	b = append(b, paddedBytes(sig.r, 32)...)
+
+	return append(b, paddedBytes(sig.s, 32)...)
+}
+
+func (sig *SchnorrSignature) Hex() string {
+	return hex.EncodeToString(sig.Bytes())
+}
+
+// SignSchnorr returns a BIP340 signature of msg.
+// If auxRand is nil, 32 bytes of fresh randomness are used.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0340.mediawiki#default-signing
+func (privKey *PrivateKey) SignSchnorr(msg []byte, auxRand []byte) (*SchnorrSignature, error) {
+	if auxRand == nil {
+		auxRand = make([]byte, 32)
+		if _, err := rand.Read(auxRand); err != nil {
+			return nil, err
+		}
+	}
+	if len(auxRand) != 32 {
+		return nil, ErrInvalidAuxRand
+	}
+
+	n := secp256k1.N
+
+	pubKey := privKey.PublicKey()
+	d := new(big.Int).Set(privKey.d)
+	if !pubKey.hasEvenY() {
+		d.Sub(n, d)
+	}
+	pb := pubKey.XOnly().Bytes()
+
+	auxHash, err := TaggedHash(TagBip340Aux, auxRand)
+	if err != nil {
+		return nil, err
+	}
+	t := paddedBytes(d, 32)
+	for i := range t {
+		t[i] ^= auxHash[i]
+	}
+
+	nonce, err := TaggedHash(TagBip340Nonce, concatBytes(t, pb, msg))
+	if err != nil {
+		return nil, err
+	}
+	k := new(big.Int).SetBytes(nonce)
+	k.Mod(k, n)
+	if k.Sign() == 0 {
+		return nil, ErrSchnorrSignFailed
+	}
+
+	rx, ry := secp256k1.ScalarBaseMult(k)
+	if ry.Bit(0) == 1 {
+		k.Sub(n, k)
+	}
+
+	e, err := schnorrChallenge(rx, pb, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	s := new(big.Int).Mul(e, d)
+	s.Add(s, k)
+	s.Mod(s, n)
+
+	sig := &SchnorrSignature{rx, s}
+	if !pubKey.XOnly().VerifySchnorr(msg, sig) {
+		return nil, ErrSchnorrSignFailed
+	}
+
+	return sig, nil
+}
+
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0340.mediawiki#verification
+func (pubKey *XOnlyPublicKey) VerifySchnorr(msg []byte, sig *SchnorrSignature) bool {
+	if sig.r.Cmp(secp256k1.P) >= 0 || sig.s.Cmp(secp256k1.N) >= 0 {
+		return false
+	}
+
+	e, err := schnorrChallenge(sig.r, pubKey.Bytes(), msg)
+	if err != nil {
+		return false
+	}
+
+	// R = s*G - e*P
+	negE := new(big.Int).Sub(secp256k1.N, e)
+	x1, y1 := secp256k1.ScalarBaseMult(sig.s)
+	x2, y2 := secp256k1.ScalarMult(pubKey.x, pubKey.y, negE)
+	rx, ry := secp256k1.Add(x1, y1, x2, y2)
+	if rx == nil || ry.Bit(0) == 1 {
+		return false
+	}
+
+	return rx.Cmp(sig.r) == 0
+}
+
+// VerifySchnorrBatch verifies all signatures at once. It returns true only if
+// every signature is valid for its public key and message.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0340.mediawiki#batch-verification
+func VerifySchnorrBatch(pubKeys []*XOnlyPublicKey, msgs [][]byte, sigs []*SchnorrSignature) (bool, error) {
+	if len(pubKeys) != len(msgs) || len(pubKeys) != len(sigs) {
+		return false, ErrSchnorrBatchSizeUnequal
+	}
+
+	n := secp256k1.N
+
+	// (s1 + a2*s2 + ... + au*su)*G = R1 + a2*R2 + ... + au*Ru + e1*P1 + (a2*e2)*P2 + ... + (au*eu)*Pu
+	lhs := new(big.Int)
+	var rhsX, rhsY *big.Int
+
+	for i, sig := range sigs {
+		if sig.r.Cmp(secp256k1.P) >= 0 || sig.s.Cmp(secp256k1.N) >= 0 {
+			return false, nil
+		}
+
+		a := big.NewInt(1)
+		if i > 0 {
+			var err error
+			a, err = randomScalar()
+			if err != nil {
+				return false, err
+			}
+		}
+
+		e, err := schnorrChallenge(sig.r, pubKeys[i].Bytes(), msgs[i])
+		if err != nil {
+			return false, err
+		}
+
+		ry := secp256k1.DecompressY(sig.r, false)
+		if ry == nil {
+			return false, nil
+		}
+
+		lhs.Add(lhs, new(big.Int).Mul(a, sig.s))
+		lhs.Mod(lhs, n)
+
+		ae := new(big.Int).Mul(a, e)
+		ae.Mod(ae, n)
+
+		x, y := secp256k1.ScalarMult(sig.r, ry, a)
+		rhsX, rhsY = secp256k1.Add(rhsX, rhsY, x, y)
+		x, y = secp256k1.ScalarMult(pubKeys[i].x, pubKeys[i].y, ae)
+		rhsX, rhsY = secp256k1.Add(rhsX, rhsY, x, y)
+	}
+
+	lhsX, lhsY := secp256k1.ScalarBaseMult(lhs)
+	if lhsX == nil || rhsX == nil {
+		return lhsX == nil && rhsX == nil, nil
+	}
+
+	return lhsX.Cmp(rhsX) == 0 && lhsY.Cmp(rhsY) == 0, nil
+}
+
+func schnorrChallenge(rx *big.Int, pb []byte, msg []byte) (*big.Int, error) {
+	h, err := TaggedHash(TagBip340Challenge, concatBytes(paddedBytes(rx, 32), pb, msg))
+	if err != nil {
+		return nil, err
+	}
+
+	e := new(big.Int).SetBytes(h)
+
+	return e.Mod(e, secp256k1.N), nil
+}
+
+func randomScalar() (*big.Int, error) {
+	for {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+
+		a := new(big.Int).SetBytes(b)
+		if a.Sign() > 0 && a.Cmp(secp256k1.N) < 0 {
+			return a, nil
+		}
+	}
+}
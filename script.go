@@ -1,6 +1,69 @@
 package btc
 
-import "encoding/hex"
+import (
+	"encoding/hex"
+	"errors"
+)
+
+const (
+	ScriptHashLength        = 20 // 0x14
+	WitnessScriptHashLength = 32 // 0x20
+
+	// the most public keys CHECKMULTISIG accepts
+	MaxMultisigPubKeys = 20
+	MaxScriptSize      = 10000
+	MaxOpsPerScript    = 201
+
+	// the most public keys of bare multisig outputs Bitcoin Core relays
+	maxStandardBareMultisigPubKeys = 3
+
+	WitnessVersionMin = 0
+	WitnessVersionMax = 16
+
+	witnessProgramLengthMin = 2
+	witnessProgramLengthMax = 40
+)
+
+var (
+	ErrInvalidScriptHashLength = errors.New("invalid script hash length")
+	ErrInvalidWitnessVersion   = errors.New("invalid witness version")
+	ErrInvalidWitnessProgram   = errors.New("invalid witness program")
+	ErrInvalidMultisig         = errors.New("invalid multisig")
+	ErrNonPushOnlyScript       = errors.New("script is not push only")
+)
+
+// ref. https://github.com/bitcoin/bitcoin/blob/master/src/script/solver.h
+type ScriptType int
+
+const (
+	ScriptTypeNonStandard ScriptType = iota
+	ScriptTypeP2pk
+	ScriptTypeP2pkh
+	ScriptTypeP2sh
+	ScriptTypeMultisig
+	ScriptTypeNullData
+	ScriptTypeP2wpkh
+	ScriptTypeP2wsh
+	ScriptTypeP2tr
+	ScriptTypeWitnessUnknown
+)
+
+var scriptTypeNameMap = map[ScriptType]string{
+	ScriptTypeNonStandard:    "nonstandard",
+	ScriptTypeP2pk:           "pubkey",
+	ScriptTypeP2pkh:          "pubkeyhash",
+	ScriptTypeP2sh:           "scripthash",
+	ScriptTypeMultisig:       "multisig",
+	ScriptTypeNullData:       "nulldata",
+	ScriptTypeP2wpkh:         "witness_v0_keyhash",
+	ScriptTypeP2wsh:          "witness_v0_scripthash",
+	ScriptTypeP2tr:           "witness_v1_taproot",
+	ScriptTypeWitnessUnknown: "witness_unknown",
+}
+
+func (typ ScriptType) String() string {
+	return scriptTypeNameMap[typ]
+}
 
 type Script struct {
 	Hex string `json:"hex"`
@@ -20,6 +83,425 @@ func NewScriptFromBytes(b []byte) (*Script, error) {
 	return newReader(b).readScript()
 }
 
+func NewP2pkScript(pubKey *PublicKey) (*Script, error) {
+	w := newWriter()
+	if err := w.writePushedData(pubKey.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := w.writeOpCode(OpCheckSig); err != nil {
+		return nil, err
+	}
+
+	return NewScriptFromBytes(w.Bytes())
+}
+
+func NewP2pkhScript(pkh Pkh) (*Script, error) {
+	if len(pkh) != PkhLength {
+		return nil, ErrInvalidPkhLength
+	}
+
+	w := newWriter()
+	if err := w.writeOpCode(OpDup); err != nil {
+		return nil, err
+	}
+	if err := w.writeOpCode(OpHash160); err != nil {
+		return nil, err
+	}
+	if err := w.writePushedData(pkh); err != nil {
+		return nil, err
+	}
+	if err := w.writeOpCode(OpEqualVerify); err != nil {
+		return nil, err
+	}
+	if err := w.writeOpCode(OpCheckSig); err != nil {
+		return nil, err
+	}
+
+	return NewScriptFromBytes(w.Bytes())
+}
+
+func NewP2shScript(scriptHash []byte) (*Script, error) {
+	if len(scriptHash) != ScriptHashLength {
+		return nil, ErrInvalidScriptHashLength
+	}
+
+	w := newWriter()
+	if err := w.writeOpCode(OpHash160); err != nil {
+		return nil, err
+	}
+	if err := w.writePushedData(scriptHash); err != nil {
+		return nil, err
+	}
+	if err := w.writeOpCode(OpEqual); err != nil {
+		return nil, err
+	}
+
+	return NewScriptFromBytes(w.Bytes())
+}
+
+// NewMultisigScript returns a bare m-of-n multisig script.
+// m and n above 16 are pushed as script numbers, which only scripts other than bare ones can have in practice.
+func NewMultisigScript(m int, pubKeys []*PublicKey) (*Script, error) {
+	n := len(pubKeys)
+	if m < 1 || m > n || n > MaxMultisigPubKeys {
+		return nil, ErrInvalidMultisig
+	}
+
+	w := newWriter()
+	if err := w.writeScriptNum(int64(m)); err != nil {
+		return nil, err
+	}
+	for _, pubKey := range pubKeys {
+		if err := w.writePushedData(pubKey.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.writeScriptNum(int64(n)); err != nil {
+		return nil, err
+	}
+	if err := w.writeOpCode(OpCheckMultiSig); err != nil {
+		return nil, err
+	}
+
+	return NewScriptFromBytes(w.Bytes())
+}
+
+func NewNullDataScript(data []byte) (*Script, error) {
+	w := newWriter()
+	if err := w.writeOpCode(OpReturn); err != nil {
+		return nil, err
+	}
+	if err := w.writePushedData(data); err != nil {
+		return nil, err
+	}
+
+	return NewScriptFromBytes(w.Bytes())
+}
+
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0141.mediawiki#witness-program
+func NewWitnessProgramScript(version int, program []byte) (*Script, error) {
+	if version < WitnessVersionMin || version > WitnessVersionMax {
+		return nil, ErrInvalidWitnessVersion
+	}
+	if len(program) < witnessProgramLengthMin || len(program) > witnessProgramLengthMax {
+		return nil, ErrInvalidWitnessProgram
+	}
+
+	w := newWriter()
+	if err := w.writeOpCode(smallIntOpCode(version)); err != nil {
+		return nil, err
+	}
+	if err := w.writePushedData(program); err != nil {
+		return nil, err
+	}
+
+	return NewScriptFromBytes(w.Bytes())
+}
+
+func NewP2wpkhScript(pkh Pkh) (*Script, error) {
+	if len(pkh) != PkhLength {
+		return nil, ErrInvalidPkhLength
+	}
+
+	return NewWitnessProgramScript(0, pkh)
+}
+
+func NewP2wshScript(scriptHash []byte) (*Script, error) {
+	if len(scriptHash) != WitnessScriptHashLength {
+		return nil, ErrInvalidScriptHashLength
+	}
+
+	return NewWitnessProgramScript(0, scriptHash)
+}
+
+func NewP2trScript(pubKey *XOnlyPublicKey) (*Script, error) {
+	return NewWitnessProgramScript(1, pubKey.Bytes())
+}
+
+// NewP2shP2wpkhRedeemScript returns the P2WPKH program of the compressed pubKey
+// to be used as a P2SH redeem script.
+func NewP2shP2wpkhRedeemScript(pubKey *PublicKey) (*Script, error) {
+	pkh, err := pubKey.Compressed().Pkh()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewP2wpkhScript(pkh)
+}
+
+// NewP2shP2wshRedeemScript returns the P2WSH program of witnessScript
+// to be used as a P2SH redeem script.
+func NewP2shP2wshRedeemScript(witnessScript *Script) (*Script, error) {
+	h, err := witnessScript.WitnessScriptHash()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewP2wshScript(h)
+}
+
+func newPushOnlyScript(items [][]byte) (*Script, error) {
+	w := newWriter()
+	for _, item := range items {
+		if err := w.writePushedData(item); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewScriptFromBytes(w.Bytes())
+}
+
 func (script *Script) Bytes() ([]byte, error) {
 	return hex.DecodeString(script.Hex)
 }
+
+// ScriptHash returns the HASH160 of the script committed to by P2SH.
+func (script *Script) ScriptHash() (ScriptHash, error) {
+	b, err := script.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := Hash160(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return ScriptHash(h), nil
+}
+
+// WitnessScriptHash returns the SHA256 of the script committed to by P2WSH.
+func (script *Script) WitnessScriptHash() ([]byte, error) {
+	b, err := script.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return Sha256(b)
+}
+
+func (script *Script) Type() (ScriptType, error) {
+	b, err := script.Bytes()
+	if err != nil {
+		return ScriptTypeNonStandard, err
+	}
+
+	return classifyScript(b), nil
+}
+
+type scriptOp struct {
+	op   OpCode
+	data []byte
+}
+
+func parseScript(b []byte) ([]*scriptOp, error) {
+	return newReader(b).readScriptOps()
+}
+
+// parsePushOnlyScript returns the data pushed by a script consisting only of push operations.
+func parsePushOnlyScript(b []byte) ([][]byte, error) {
+	ops, err := parseScript(b)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([][]byte, len(ops))
+	for i, op := range ops {
+		switch {
+		case op.op == Op0 || op.op.isPushData():
+			items[i] = op.data
+		case op.op == Op1Negate:
+			items[i] = []byte{0x81}
+		case op.op.isSmallInt():
+			items[i] = []byte{byte(op.op.smallInt())}
+		default:
+			return nil, ErrNonPushOnlyScript
+		}
+	}
+
+	return items, nil
+}
+
+// ref. https://github.com/bitcoin/bitcoin/blob/master/src/script/solver.cpp
+func classifyScript(b []byte) ScriptType {
+	if isP2shScript(b) {
+		return ScriptTypeP2sh
+	}
+
+	if version, program, ok := extractWitnessProgram(b); ok {
+		switch {
+		case version == 0 && len(program) == PkhLength:
+			return ScriptTypeP2wpkh
+		case version == 0 && len(program) == WitnessScriptHashLength:
+			return ScriptTypeP2wsh
+		case version == 1 && len(program) == XOnlyPubKeyLength:
+			return ScriptTypeP2tr
+		case version != 0:
+			return ScriptTypeWitnessUnknown
+		default:
+			return ScriptTypeNonStandard
+		}
+	}
+
+	ops, err := parseScript(b)
+	if err != nil {
+		return ScriptTypeNonStandard
+	}
+
+	switch {
+	case isNullDataScript(ops):
+		return ScriptTypeNullData
+	case extractP2pkPubKey(ops) != nil:
+		return ScriptTypeP2pk
+	case extractP2pkhPkh(ops) != nil:
+		return ScriptTypeP2pkh
+	}
+
+	// Bitcoin Core only regards the ones with OP_1 to OP_16 as multisig outputs
+	if _, _, ok := extractMultisig(ops); ok && ops[len(ops)-2].op.isSmallInt() {
+		return ScriptTypeMultisig
+	}
+
+	return ScriptTypeNonStandard
+}
+
+func isP2shScript(b []byte) bool {
+	return len(b) == ScriptHashLength+3 &&
+		b[0] == OpHash160.Byte() &&
+		b[1] == ScriptHashLength &&
+		b[len(b)-1] == OpEqual.Byte()
+}
+
+func extractWitnessProgram(b []byte) (int, []byte, bool) {
+	if len(b) < witnessProgramLengthMin+2 || len(b) > witnessProgramLengthMax+2 {
+		return 0, nil, false
+	}
+
+	op := OpCode(b[0])
+	if !op.isSmallInt() {
+		return 0, nil, false
+	}
+	if int(b[1])+2 != len(b) {
+		return 0, nil, false
+	}
+
+	return op.smallInt(), b[2:], true
+}
+
+func isNullDataScript(ops []*scriptOp) bool {
+	if len(ops) == 0 || ops[0].op != OpReturn {
+		return false
+	}
+
+	for _, op := range ops[1:] {
+		if op.op > Op16 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isValidPubKeySize(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+
+	switch b[0] {
+	case pubKeyFormatCompressedEven, pubKeyFormatCompressedOdd:
+		return len(b) == CompressedPubKeyLength
+	case pubKeyFormatUncompressed, 0x06, 0x07:
+		return len(b) == PubKeyLength
+	default:
+		return false
+	}
+}
+
+func extractP2pkPubKey(ops []*scriptOp) []byte {
+	if len(ops) != 2 || ops[1].op != OpCheckSig {
+		return nil
+	}
+	if !ops[0].op.isDataLen() || !isValidPubKeySize(ops[0].data) {
+		return nil
+	}
+
+	return ops[0].data
+}
+
+func extractP2pkhPkh(ops []*scriptOp) Pkh {
+	if len(ops) != 5 {
+		return nil
+	}
+	if ops[0].op != OpDup ||
+		ops[1].op != OpHash160 ||
+		len(ops[2].data) != PkhLength || ops[2].op != OpCode(PkhLength) ||
+		ops[3].op != OpEqualVerify ||
+		ops[4].op != OpCheckSig {
+		return nil
+	}
+
+	return Pkh(ops[2].data)
+}
+
+func extractMultisig(ops []*scriptOp) (int, [][]byte, bool) {
+	l := len(ops)
+	if l < 4 || ops[l-1].op != OpCheckMultiSig {
+		return 0, nil, false
+	}
+
+	m, ok := multisigNum(ops[0])
+	if !ok {
+		return 0, nil, false
+	}
+	n, ok := multisigNum(ops[l-2])
+	if !ok || n != l-3 || m > n {
+		return 0, nil, false
+	}
+
+	pubKeys := make([][]byte, n)
+	for i, op := range ops[1 : l-2] {
+		if !op.op.isDataLen() || !isValidPubKeySize(op.data) {
+			return 0, nil, false
+		}
+
+		pubKeys[i] = op.data
+	}
+
+	return m, pubKeys, true
+}
+
+// multisigNum returns the number of signatures or public keys of multisig scripts written as NewMultisigScript does.
+func multisigNum(op *scriptOp) (int, bool) {
+	switch {
+	case op.op != Op0 && op.op.isSmallInt():
+		return op.op.smallInt(), true
+	case op.op.isDataLen() && len(op.data) == 1 && op.data[0] > 16 && op.data[0] <= MaxMultisigPubKeys:
+		return int(op.data[0]), true
+	default:
+		return 0, false
+	}
+}
+
+// removeOpCode returns the script without any occurrence of op.
+func removeOpCode(b []byte, op OpCode) ([]byte, error) {
+	r := newReader(b)
+	w := newWriter()
+
+	for r.Len() > 0 {
+		start := len(b) - r.Len()
+
+		o, err := r.readScriptOp()
+		if err != nil {
+			return nil, err
+		}
+		if o.op == op {
+			continue
+		}
+
+		if _, err := w.Write(b[start : len(b)-r.Len()]); err != nil {
+			return nil, err
+		}
+	}
+
+	return w.Bytes(), nil
+}
@@ -1,6 +1,8 @@
 package btc
 
 import (
+	"encoding/hex"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -8,8 +10,6 @@ import (
 )
 
 func TestScriptMapping(t *testing.T) {
-	UseTestnet()
-
 	testCases := []struct {
 		hex string
 		asm string
@@ -33,3 +33,158 @@ func TestScriptMapping(t *testing.T) {
 		})
 	}
 }
+
+func TestScriptType(t *testing.T) {
+	testCases := []struct {
+		hex string
+		typ ScriptType
+	}{
+		{
+			"21028985087b1818714f67e494a076ca0284c060fabc5d2ba66885b4ac60f801d3f5ac",
+			ScriptTypeP2pk,
+		},
+		{
+			"76a914cbc222711a230ecdd9a5aa65b61ed39c24db2b3488ac",
+			ScriptTypeP2pkh,
+		},
+		{
+			"a91419130817a355e1a4df9cb1e25052d39374b83be887",
+			ScriptTypeP2sh,
+		},
+		{
+			"5121035ab4689e400a4a160cf01cd44730845a54768df8547dcdf073d964f109f18c3021037962d45b38e8bcf82fa8efa8432a01f20c9a53e24c7d3f11df197cb8e70926da52ae",
+			ScriptTypeMultisig,
+		},
+		{
+			"6a0568656c6c6f",
+			ScriptTypeNullData,
+		},
+		{
+			"0014e1fae3324e28a4ef5ee01f14dd337ac6c85d1d90",
+			ScriptTypeP2wpkh,
+		},
+		{
+			"002081d00bf06d1dd8645f929b6cfdb84d34973d8d25f0f97d2d0d90b22e9d8f1f71",
+			ScriptTypeP2wsh,
+		},
+		{
+			"5120ab18c190681e65cf7a054cbc85ea72aeeaadb4e41f4022a9132977c7d7061a8b",
+			ScriptTypeP2tr,
+		},
+		{
+			"52020001",
+			ScriptTypeWitnessUnknown,
+		},
+		{
+			"0015e1fae3324e28a4ef5ee01f14dd337ac6c85d1d9000",
+			ScriptTypeNonStandard,
+		},
+		{
+			// truncated push
+			"6a05686565",
+			ScriptTypeNonStandard,
+		},
+		{
+			"5221035ab4689e400a4a160cf01cd44730845a54768df8547dcdf073d964f109f18c3051ae",
+			ScriptTypeNonStandard,
+		},
+		{
+			// 1-of-17 multisig, which is only standard in P2SH or P2WSH
+			"51" + strings.Repeat("21035ab4689e400a4a160cf01cd44730845a54768df8547dcdf073d964f109f18c30", 17) + "0111ae",
+			ScriptTypeNonStandard,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.hex, func(t *testing.T) {
+			typ, err := (&Script{Hex: tc.hex}).Type()
+			require.NoError(t, err)
+			assert.Equal(t, typ, tc.typ)
+		})
+	}
+}
+
+func TestScriptBuilders(t *testing.T) {
+	pubKey, err := NewPublicKeyFromHex("035ab4689e400a4a160cf01cd44730845a54768df8547dcdf073d964f109f18c30")
+	require.NoError(t, err)
+	pkh, err := pubKey.Pkh()
+	require.NoError(t, err)
+
+	pubKeys20 := make([]*PublicKey, MaxMultisigPubKeys)
+	for i := range pubKeys20 {
+		pubKeys20[i] = pubKey
+	}
+
+	testCases := []struct {
+		name   string
+		script func() (*Script, error)
+		asm    string
+	}{
+		{
+			"p2pk",
+			func() (*Script, error) { return NewP2pkScript(pubKey) },
+			"035ab4689e400a4a160cf01cd44730845a54768df8547dcdf073d964f109f18c30 OP_CHECKSIG",
+		},
+		{
+			"p2pkh",
+			func() (*Script, error) { return NewP2pkhScript(pkh) },
+			"OP_DUP OP_HASH160 " + hex.EncodeToString(pkh) + " OP_EQUALVERIFY OP_CHECKSIG",
+		},
+		{
+			"p2sh",
+			func() (*Script, error) { return NewP2shScript(pkh) },
+			"OP_HASH160 " + hex.EncodeToString(pkh) + " OP_EQUAL",
+		},
+		{
+			"multisig",
+			func() (*Script, error) { return NewMultisigScript(1, []*PublicKey{pubKey}) },
+			"OP_1 035ab4689e400a4a160cf01cd44730845a54768df8547dcdf073d964f109f18c30 OP_1 OP_CHECKMULTISIG",
+		},
+		{
+			"multisig with 20 keys",
+			func() (*Script, error) { return NewMultisigScript(17, pubKeys20) },
+			"11 " + strings.Repeat("035ab4689e400a4a160cf01cd44730845a54768df8547dcdf073d964f109f18c30 ", 20) + "14 OP_CHECKMULTISIG",
+		},
+		{
+			"null data",
+			func() (*Script, error) { return NewNullDataScript([]byte("hello")) },
+			"OP_RETURN 68656c6c6f",
+		},
+		{
+			"p2wpkh",
+			func() (*Script, error) { return NewP2wpkhScript(pkh) },
+			"OP_0 " + hex.EncodeToString(pkh),
+		},
+		{
+			"p2tr",
+			func() (*Script, error) { return NewP2trScript(pubKey.XOnly()) },
+			"OP_1 5ab4689e400a4a160cf01cd44730845a54768df8547dcdf073d964f109f18c30",
+		},
+		{
+			"witness v16",
+			func() (*Script, error) { return NewWitnessProgramScript(16, []byte{0x00, 0x01}) },
+			"OP_16 0001",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			script, err := tc.script()
+			require.NoError(t, err)
+			assert.Equal(t, script.Asm, tc.asm)
+		})
+	}
+
+	_, err = NewP2pkhScript(pkh[1:])
+	assert.Equal(t, err, ErrInvalidPkhLength)
+	_, err = NewP2wshScript(pkh)
+	assert.Equal(t, err, ErrInvalidScriptHashLength)
+	_, err = NewMultisigScript(2, []*PublicKey{pubKey})
+	assert.Equal(t, err, ErrInvalidMultisig)
+	_, err = NewMultisigScript(1, append(pubKeys20, pubKey))
+	assert.Equal(t, err, ErrInvalidMultisig)
+	_, err = NewWitnessProgramScript(17, pkh)
+	assert.Equal(t, err, ErrInvalidWitnessVersion)
+	_, err = NewWitnessProgramScript(1, []byte{0x00})
+	assert.Equal(t, err, ErrInvalidWitnessProgram)
+}
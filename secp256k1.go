@@ -0,0 +1,485 @@
+package btc
+
+import (
+	"math/big"
+	"math/bits"
+	"sync"
+)
+
+// secp256k1 curve arithmetic over affine (x, y) big.Int pairs.
+// Nil coordinates represent the point at infinity.
+// Points are multiplied by scalars in constant time, so that secret scalars do not leak through timing,
+// while the other operations, which only handle public values, are not constant-time.
+// ref. https://www.secg.org/sec2-v2.pdf
+type curve struct {
+	P  *big.Int
+	N  *big.Int
+	B  *big.Int
+	Gx *big.Int
+	Gy *big.Int
+
+	halfN *big.Int
+	sqrtE *big.Int
+	pc    *big.Int
+	mask  *big.Int
+
+	baseTableOnce sync.Once
+	baseTable     [64][16]jacobianPoint
+}
+
+var secp256k1 = newCurve()
+
+func newCurve() *curve {
+	c := &curve{
+		P:  mustBigIntFromHex("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f"),
+		N:  mustBigIntFromHex("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141"),
+		B:  big.NewInt(7),
+		Gx: mustBigIntFromHex("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"),
+		Gy: mustBigIntFromHex("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8"),
+	}
+
+	c.halfN = new(big.Int).Rsh(c.N, 1)
+
+	c.mask = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	c.pc = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), c.P)
+
+	// p = 3 (mod 4), so sqrt(a) = a^((p+1)/4)
+	c.sqrtE = new(big.Int).Add(c.P, big.NewInt(1))
+	c.sqrtE.Rsh(c.sqrtE, 2)
+
+	return c
+}
+
+func mustBigIntFromHex(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("invalid hex: " + s)
+	}
+
+	return n
+}
+
+// mod reduces n in place using p = 2^256 - pc.
+func (c *curve) mod(n *big.Int) *big.Int {
+	neg := n.Sign() < 0
+	if neg {
+		n.Neg(n)
+	}
+
+	for n.BitLen() > 256 {
+		hi := new(big.Int).Rsh(n, 256)
+		n.And(n, c.mask)
+		n.Add(n, hi.Mul(hi, c.pc))
+	}
+	if n.Cmp(c.P) >= 0 {
+		n.Sub(n, c.P)
+	}
+
+	if neg && n.Sign() != 0 {
+		n.Sub(c.P, n)
+	}
+
+	return n
+}
+
+// fieldPc is 2^256 - p.
+const fieldPc uint64 = 0x1000003d1
+
+// fieldPMinus2 is p - 2, the exponent of the inverses.
+var fieldPMinus2 = [4]uint64{0xfffffffefffffc2d, 0xffffffffffffffff, 0xffffffffffffffff, 0xffffffffffffffff}
+
+// fieldVal is an element of the field of p, whose little-endian 64-bit limbs are always reduced below p.
+// All the operations on it run in constant time.
+type fieldVal [4]uint64
+
+// fieldValFromBig returns n mod p as a field element, which is not constant-time.
+func (c *curve) fieldValFromBig(n *big.Int) fieldVal {
+	return fieldVal(limbsFromBig(c.mod(new(big.Int).Set(n))))
+}
+
+// limbsFromBig returns the little-endian 64-bit limbs of n, which must be in [0, 2^256).
+func limbsFromBig(n *big.Int) [4]uint64 {
+	var limbs [4]uint64
+	for i, w := range n.Bits() {
+		pos := uint(i * bits.UintSize)
+		limbs[pos/64] |= uint64(w) << (pos % 64)
+	}
+
+	return limbs
+}
+
+func (a fieldVal) big() *big.Int {
+	b := make([]byte, 32)
+	for i, limb := range a {
+		for j := 0; j < 8; j++ {
+			b[31-8*i-j] = byte(limb >> uint(8*j))
+		}
+	}
+
+	return new(big.Int).SetBytes(b)
+}
+
+// add returns a + b mod p.
+func (a fieldVal) add(b fieldVal) fieldVal {
+	var s fieldVal
+	var carry uint64
+	s[0], carry = bits.Add64(a[0], b[0], 0)
+	s[1], carry = bits.Add64(a[1], b[1], carry)
+	s[2], carry = bits.Add64(a[2], b[2], carry)
+	s[3], carry = bits.Add64(a[3], b[3], carry)
+
+	return s.reduce(carry)
+}
+
+// reduce returns carry * 2^256 + a mod p, where the value is less than 2p.
+func (a fieldVal) reduce(carry uint64) fieldVal {
+	// a - p = a + pc - 2^256
+	var t fieldVal
+	var c uint64
+	t[0], c = bits.Add64(a[0], fieldPc, 0)
+	t[1], c = bits.Add64(a[1], 0, c)
+	t[2], c = bits.Add64(a[2], 0, c)
+	t[3], c = bits.Add64(a[3], 0, c)
+
+	return selectFieldVal(-(carry | c), t, a)
+}
+
+// sub returns a - b mod p.
+func (a fieldVal) sub(b fieldVal) fieldVal {
+	var d fieldVal
+	var borrow uint64
+	d[0], borrow = bits.Sub64(a[0], b[0], 0)
+	d[1], borrow = bits.Sub64(a[1], b[1], borrow)
+	d[2], borrow = bits.Sub64(a[2], b[2], borrow)
+	d[3], borrow = bits.Sub64(a[3], b[3], borrow)
+
+	// d + p = d - pc + 2^256
+	var b2 uint64
+	d[0], b2 = bits.Sub64(d[0], fieldPc&-borrow, 0)
+	d[1], b2 = bits.Sub64(d[1], 0, b2)
+	d[2], b2 = bits.Sub64(d[2], 0, b2)
+	d[3], _ = bits.Sub64(d[3], 0, b2)
+
+	return d
+}
+
+// mul returns a * b mod p.
+func (a fieldVal) mul(b fieldVal) fieldVal {
+	var t [8]uint64
+	for i := 0; i < 4; i++ {
+		var carry uint64
+		for j := 0; j < 4; j++ {
+			hi, lo := bits.Mul64(a[i], b[j])
+			var c uint64
+			lo, c = bits.Add64(lo, t[i+j], 0)
+			hi += c
+			lo, c = bits.Add64(lo, carry, 0)
+			hi += c
+			t[i+j] = lo
+			carry = hi
+		}
+		t[i+4] = carry
+	}
+
+	// hi * 2^256 + lo = hi * pc + lo (mod p)
+	var m [5]uint64
+	var carry uint64
+	for i := 0; i < 4; i++ {
+		hi, lo := bits.Mul64(t[4+i], fieldPc)
+		var c uint64
+		lo, c = bits.Add64(lo, carry, 0)
+		m[i] = lo
+		carry = hi + c
+	}
+	m[4] = carry
+
+	var s fieldVal
+	var c uint64
+	s[0], c = bits.Add64(t[0], m[0], 0)
+	s[1], c = bits.Add64(t[1], m[1], c)
+	s[2], c = bits.Add64(t[2], m[2], c)
+	s[3], c = bits.Add64(t[3], m[3], c)
+	top := m[4] + c
+
+	hi, lo := bits.Mul64(top, fieldPc)
+	s[0], c = bits.Add64(s[0], lo, 0)
+	s[1], c = bits.Add64(s[1], hi, c)
+	s[2], c = bits.Add64(s[2], 0, c)
+	s[3], c = bits.Add64(s[3], 0, c)
+
+	// s is small if it overflowed, so adding pc never overflows again
+	s[0], c = bits.Add64(s[0], fieldPc&-c, 0)
+	s[1], c = bits.Add64(s[1], 0, c)
+	s[2], c = bits.Add64(s[2], 0, c)
+	s[3], _ = bits.Add64(s[3], 0, c)
+
+	return s.reduce(0)
+}
+
+func (a fieldVal) square() fieldVal {
+	return a.mul(a)
+}
+
+// inverse returns a^(p-2) mod p, which is the inverse of a unless a is 0.
+func (a fieldVal) inverse() fieldVal {
+	r := fieldVal{1}
+	for i := 255; i >= 0; i-- {
+		r = r.square()
+		if (fieldPMinus2[i/64]>>uint(i%64))&1 == 1 {
+			r = r.mul(a)
+		}
+	}
+
+	return r
+}
+
+// isZero returns all ones if a is 0, or 0 otherwise.
+func (a fieldVal) isZero() uint64 {
+	return isZeroMask(a[0] | a[1] | a[2] | a[3])
+}
+
+// isZeroMask returns all ones if v is 0, or 0 otherwise, in constant time.
+func isZeroMask(v uint64) uint64 {
+	return ((v | -v) >> 63) - 1
+}
+
+// selectFieldVal returns a if mask is all ones, or b if mask is 0.
+func selectFieldVal(mask uint64, a, b fieldVal) fieldVal {
+	var r fieldVal
+	for i := range r {
+		r[i] = a[i]&mask | b[i]&^mask
+	}
+
+	return r
+}
+
+// jacobianPoint is a point in the jacobian coordinates, which is the point at infinity if z is 0.
+type jacobianPoint struct {
+	x, y, z fieldVal
+}
+
+func (c *curve) newJacobianPoint(x, y *big.Int) jacobianPoint {
+	if x == nil || y == nil {
+		return jacobianPoint{}
+	}
+
+	return jacobianPoint{c.fieldValFromBig(x), c.fieldValFromBig(y), fieldVal{1}}
+}
+
+// selectJacobianPoint returns p if mask is all ones, or q if mask is 0.
+func selectJacobianPoint(mask uint64, p, q jacobianPoint) jacobianPoint {
+	return jacobianPoint{
+		selectFieldVal(mask, p.x, q.x),
+		selectFieldVal(mask, p.y, q.y),
+		selectFieldVal(mask, p.z, q.z),
+	}
+}
+
+// double returns 2p, which is the point at infinity if p is, as no point of secp256k1 has y = 0.
+// ref. https://hyperelliptic.org/EFD/g1p/auto-shortw-jacobian-0.html#doubling-dbl-2009-l
+func (c *curve) double(p jacobianPoint) jacobianPoint {
+	a := p.x.square()
+	b := p.y.square()
+	cc := b.square()
+
+	d := p.x.add(b).square().sub(a).sub(cc)
+	d = d.add(d)
+
+	e := a.add(a).add(a)
+	f := e.square()
+
+	x3 := f.sub(d.add(d))
+
+	cc8 := cc.add(cc)
+	cc8 = cc8.add(cc8)
+	cc8 = cc8.add(cc8)
+	y3 := e.mul(d.sub(x3)).sub(cc8)
+
+	z3 := p.y.mul(p.z)
+	z3 = z3.add(z3)
+
+	return jacobianPoint{x3, y3, z3}
+}
+
+// add returns p + q for any points, including the same ones and the point at infinity, in constant time.
+// ref. https://hyperelliptic.org/EFD/g1p/auto-shortw-jacobian-0.html#addition-add-2007-bl
+func (c *curve) add(p, q jacobianPoint) jacobianPoint {
+	z1z1 := p.z.square()
+	z2z2 := q.z.square()
+
+	u1 := p.x.mul(z2z2)
+	u2 := q.x.mul(z1z1)
+
+	s1 := p.y.mul(q.z).mul(z2z2)
+	s2 := q.y.mul(p.z).mul(z1z1)
+
+	h := u2.sub(u1)
+	r := s2.sub(s1)
+
+	hIsZero := h.isZero()
+	rIsZero := r.isZero()
+	r = r.add(r)
+
+	i := h.add(h).square()
+	j := h.mul(i)
+	v := u1.mul(i)
+
+	x3 := r.square().sub(j).sub(v.add(v))
+
+	s1j := s1.mul(j)
+	y3 := r.mul(v.sub(x3)).sub(s1j.add(s1j))
+
+	// z3 is 0 if h is 0, so the sum is the point at infinity if p = -q
+	z3 := p.z.add(q.z).square().sub(z1z1).sub(z2z2).mul(h)
+
+	sum := jacobianPoint{x3, y3, z3}
+	sum = selectJacobianPoint(hIsZero&rIsZero, c.double(p), sum)
+	sum = selectJacobianPoint(p.z.isZero(), q, sum)
+	sum = selectJacobianPoint(q.z.isZero(), p, sum)
+
+	return sum
+}
+
+func (c *curve) toAffine(p jacobianPoint) (*big.Int, *big.Int) {
+	if p.z.isZero() != 0 {
+		return nil, nil
+	}
+
+	zInv := p.z.inverse()
+	zInv2 := zInv.square()
+
+	x := p.x.mul(zInv2)
+	y := p.y.mul(zInv2).mul(zInv)
+
+	return x.big(), y.big()
+}
+
+func (c *curve) IsOnCurve(x, y *big.Int) bool {
+	if x == nil || y == nil {
+		return false
+	}
+	if x.Sign() < 0 || x.Cmp(c.P) >= 0 || y.Sign() < 0 || y.Cmp(c.P) >= 0 {
+		return false
+	}
+
+	// y^2 = x^3 + 7
+	lhs := c.mod(new(big.Int).Mul(y, y))
+
+	return lhs.Cmp(c.polynomial(x)) == 0
+}
+
+func (c *curve) polynomial(x *big.Int) *big.Int {
+	rhs := new(big.Int).Mul(x, x)
+	rhs.Mul(rhs, x)
+	rhs.Add(rhs, c.B)
+
+	return c.mod(rhs)
+}
+
+// DecompressY returns the y coordinate of the point with the given x coordinate
+// and parity, or nil if x is not on the curve.
+func (c *curve) DecompressY(x *big.Int, odd bool) *big.Int {
+	if x.Sign() < 0 || x.Cmp(c.P) >= 0 {
+		return nil
+	}
+
+	ySq := c.polynomial(x)
+	y := new(big.Int).Exp(ySq, c.sqrtE, c.P)
+	if c.mod(new(big.Int).Mul(y, y)).Cmp(ySq) != 0 {
+		return nil
+	}
+
+	if y.Bit(0) == 1 != odd {
+		y.Sub(c.P, y)
+	}
+
+	return y
+}
+
+func (c *curve) Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	return c.toAffine(c.add(c.newJacobianPoint(x1, y1), c.newJacobianPoint(x2, y2)))
+}
+
+func (c *curve) Negate(x, y *big.Int) (*big.Int, *big.Int) {
+	if x == nil || y == nil {
+		return nil, nil
+	}
+
+	return new(big.Int).Set(x), c.mod(new(big.Int).Sub(c.P, y))
+}
+
+// ScalarMult returns k * (x, y) with a 4-bit fixed window, whose time does not depend on k.
+func (c *curve) ScalarMult(x, y *big.Int, k *big.Int) (*big.Int, *big.Int) {
+	var table [16]jacobianPoint
+	table[1] = c.newJacobianPoint(x, y)
+	for i := 2; i < 16; i++ {
+		table[i] = c.add(table[i-1], table[1])
+	}
+
+	nibbles := c.scalarNibbles(k)
+
+	var r jacobianPoint
+	for i := len(nibbles) - 1; i >= 0; i-- {
+		for j := 0; j < 4; j++ {
+			r = c.double(r)
+		}
+		r = c.add(r, lookupJacobianPoint(&table, nibbles[i]))
+	}
+
+	return c.toAffine(r)
+}
+
+// ScalarBaseMult returns k * G with the precomputed multiples of G, whose time does not depend on k.
+func (c *curve) ScalarBaseMult(k *big.Int) (*big.Int, *big.Int) {
+	c.baseTableOnce.Do(c.initBaseTable)
+
+	nibbles := c.scalarNibbles(k)
+
+	var r jacobianPoint
+	for i := range nibbles {
+		r = c.add(r, lookupJacobianPoint(&c.baseTable[i], nibbles[i]))
+	}
+
+	return c.toAffine(r)
+}
+
+// baseTable[i][j] = j * 16^i * G
+func (c *curve) initBaseTable() {
+	p := c.newJacobianPoint(c.Gx, c.Gy)
+	for i := range c.baseTable {
+		row := &c.baseTable[i]
+		row[1] = p
+		for j := 2; j < 16; j++ {
+			row[j] = c.add(row[j-1], p)
+		}
+
+		p = c.add(row[15], p)
+	}
+}
+
+// scalarNibbles returns the 4-bit digits of k mod n from the least significant one.
+// k is only reduced if it is out of the range, which secret scalars never are.
+func (c *curve) scalarNibbles(k *big.Int) [64]uint64 {
+	if k.Sign() < 0 || k.Cmp(c.N) >= 0 {
+		k = new(big.Int).Mod(k, c.N)
+	}
+
+	limbs := limbsFromBig(k)
+
+	var nibbles [64]uint64
+	for i := range nibbles {
+		nibbles[i] = (limbs[i/16] >> uint(4*(i%16))) & 0x0f
+	}
+
+	return nibbles
+}
+
+// lookupJacobianPoint returns table[idx], reading all the entries so that idx does not leak through timing.
+func lookupJacobianPoint(table *[16]jacobianPoint, idx uint64) jacobianPoint {
+	var p jacobianPoint
+	for i := range table {
+		p = selectJacobianPoint(isZeroMask(uint64(i)^idx), table[i], p)
+	}
+
+	return p
+}
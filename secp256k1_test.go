@@ -0,0 +1,133 @@
+package btc
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// naiveScalarMult multiplies the point by double-and-add in the affine coordinates.
+func naiveScalarMult(x, y, k *big.Int) (*big.Int, *big.Int) {
+	c := secp256k1
+	p := c.P
+
+	add := func(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+		if x1 == nil {
+			return x2, y2
+		}
+		if x2 == nil {
+			return x1, y1
+		}
+
+		var l *big.Int
+		if x1.Cmp(x2) == 0 {
+			if new(big.Int).Add(y1, y2).Mod(new(big.Int).Add(y1, y2), p).Sign() == 0 {
+				return nil, nil
+			}
+			// (3x^2) / (2y)
+			l = new(big.Int).Mul(x1, x1)
+			l.Mul(l, big.NewInt(3))
+			l.Mul(l, new(big.Int).ModInverse(new(big.Int).Lsh(y1, 1), p))
+		} else {
+			// (y2 - y1) / (x2 - x1)
+			l = new(big.Int).Sub(y2, y1)
+			l.Mul(l, new(big.Int).ModInverse(new(big.Int).Mod(new(big.Int).Sub(x2, x1), p), p))
+		}
+		l.Mod(l, p)
+
+		x3 := new(big.Int).Mul(l, l)
+		x3.Sub(x3, x1)
+		x3.Sub(x3, x2)
+		x3.Mod(x3, p)
+
+		y3 := new(big.Int).Sub(x1, x3)
+		y3.Mul(y3, l)
+		y3.Sub(y3, y1)
+		y3.Mod(y3, p)
+
+		return x3, y3
+	}
+
+	kk := new(big.Int).Mod(k, c.N)
+
+	var rx, ry *big.Int
+	for i := kk.BitLen() - 1; i >= 0; i-- {
+		rx, ry = add(rx, ry, rx, ry)
+		if kk.Bit(i) == 1 {
+			rx, ry = add(rx, ry, x, y)
+		}
+	}
+
+	return rx, ry
+}
+
+func TestScalarMult(t *testing.T) {
+	c := secp256k1
+
+	scalars := []*big.Int{
+		big.NewInt(0),
+		big.NewInt(1),
+		big.NewInt(2),
+		big.NewInt(15),
+		big.NewInt(16),
+		new(big.Int).Sub(c.N, big.NewInt(1)),
+		new(big.Int).Set(c.N),
+		new(big.Int).Add(c.N, big.NewInt(1)),
+		new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1)),
+		big.NewInt(-1),
+	}
+
+	r := rand.New(rand.NewSource(0))
+	for i := 0; i < 16; i++ {
+		scalars = append(scalars, new(big.Int).Rand(r, c.N))
+	}
+
+	px, py := naiveScalarMult(c.Gx, c.Gy, big.NewInt(0xdeadbeef))
+
+	for _, k := range scalars {
+		t.Run(k.Text(16), func(t *testing.T) {
+			x, y := c.ScalarBaseMult(k)
+			expectedX, expectedY := naiveScalarMult(c.Gx, c.Gy, k)
+			assert.Equal(t, x, expectedX)
+			assert.Equal(t, y, expectedY)
+
+			x, y = c.ScalarMult(px, py, k)
+			expectedX, expectedY = naiveScalarMult(px, py, k)
+			assert.Equal(t, x, expectedX)
+			assert.Equal(t, y, expectedY)
+		})
+	}
+}
+
+func TestCurveAdd(t *testing.T) {
+	c := secp256k1
+
+	x2, y2 := naiveScalarMult(c.Gx, c.Gy, big.NewInt(2))
+	x3, y3 := naiveScalarMult(c.Gx, c.Gy, big.NewInt(3))
+	negX, negY := c.Negate(c.Gx, c.Gy)
+
+	testCases := []struct {
+		name      string
+		x1, y1    *big.Int
+		x2, y2    *big.Int
+		expectedX *big.Int
+		expectedY *big.Int
+	}{
+		{"G + 2G", c.Gx, c.Gy, x2, y2, x3, y3},
+		{"G + G", c.Gx, c.Gy, c.Gx, c.Gy, x2, y2},
+		{"G - G", c.Gx, c.Gy, negX, negY, nil, nil},
+		{"G + O", c.Gx, c.Gy, nil, nil, c.Gx, c.Gy},
+		{"O + G", nil, nil, c.Gx, c.Gy, c.Gx, c.Gy},
+		{"O + O", nil, nil, nil, nil, nil, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			x, y := c.Add(tc.x1, tc.y1, tc.x2, tc.y2)
+			assert.Equal(t, x, tc.expectedX)
+			assert.Equal(t, y, tc.expectedY)
+		})
+	}
+}
@@ -0,0 +1,437 @@
+package btc
+
+import (
+	"encoding/hex"
+	"errors"
+)
+
+const (
+	SigHashDefault      SigHashType = 0x00
+	SigHashAll          SigHashType = 0x01
+	SigHashNone         SigHashType = 0x02
+	SigHashSingle       SigHashType = 0x03
+	SigHashAnyoneCanPay SigHashType = 0x80
+
+	sigHashOutputMask SigHashType = 0x03
+	sigHashLegacyMask SigHashType = 0x1f
+
+	TaprootAnnexTag         byte   = 0x50
+	TapscriptKeyVersion     byte   = 0x00
+	TapscriptCodeSepPosNone uint32 = 0xffffffff
+
+	TagTapSigHash = "TapSighash"
+
+	taprootSigHashEpoch byte = 0x00
+)
+
+var (
+	ErrInvalidSigHashType      = errors.New("invalid sighash type")
+	ErrTxInIndexOutOfRange     = errors.New("tx in index out of range")
+	ErrPrevOutsMismatch        = errors.New("prev outs do not match tx ins")
+	ErrNoTxOutForSigHashSingle = errors.New("no corresponding tx out for SIGHASH_SINGLE")
+	ErrInvalidAnnex            = errors.New("invalid annex")
+	ErrInvalidTapLeafHash      = errors.New("invalid tap leaf hash")
+)
+
+type SigHashType uint32
+
+func (hashType SigHashType) Uint32() uint32 {
+	return uint32(hashType)
+}
+
+func (hashType SigHashType) outputType() SigHashType {
+	return hashType & sigHashOutputMask
+}
+
+func (hashType SigHashType) isAnyoneCanPay() bool {
+	return hashType&SigHashAnyoneCanPay == SigHashAnyoneCanPay
+}
+
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0341.mediawiki#common-signature-message
+func (hashType SigHashType) isValidTaproot() bool {
+	switch hashType {
+	case SigHashDefault,
+		SigHashAll, SigHashNone, SigHashSingle,
+		SigHashAll | SigHashAnyoneCanPay,
+		SigHashNone | SigHashAnyoneCanPay,
+		SigHashSingle | SigHashAnyoneCanPay:
+		return true
+	default:
+		return false
+	}
+}
+
+// SigHash returns the legacy signature hash of the idx-th input of tx
+// signed against subScript, the script being executed.
+// ref. https://en.bitcoin.it/wiki/OP_CHECKSIG
+func (tx *Tx) SigHash(idx int, subScript *Script, hashType SigHashType) ([]byte, error) {
+	if idx < 0 || idx >= len(tx.TxIns) {
+		return nil, ErrTxInIndexOutOfRange
+	}
+
+	outputType := hashType & sigHashLegacyMask
+
+	// the well known SIGHASH_SINGLE bug: sign the number one
+	if outputType == SigHashSingle && idx >= len(tx.TxOuts) {
+		hash := make([]byte, SigHashLength)
+		hash[0] = 0x01
+		return hash, nil
+	}
+
+	b, err := subScript.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	b, err = removeOpCode(b, OpCodeSeparator)
+	if err != nil {
+		return nil, err
+	}
+
+	txCopy := &Tx{
+		Version:  tx.Version,
+		TxIns:    []*TxIn{},
+		TxOuts:   []*TxOut{},
+		LockTime: tx.LockTime,
+	}
+
+	for i, txIn := range tx.TxIns {
+		if hashType.isAnyoneCanPay() && i != idx {
+			continue
+		}
+
+		txInCopy := &TxIn{
+			Txid:     txIn.Txid,
+			Index:    txIn.Index,
+			Script:   &Script{},
+			Sequence: txIn.Sequence,
+		}
+		if i == idx {
+			txInCopy.Script = &Script{Hex: hex.EncodeToString(b)}
+		} else if outputType == SigHashNone || outputType == SigHashSingle {
+			txInCopy.Sequence = 0
+		}
+
+		txCopy.AddTxIn(txInCopy)
+	}
+
+	switch outputType {
+	case SigHashNone:
+	case SigHashSingle:
+		for i := 0; i < idx; i++ {
+			txCopy.AddTxOut(&TxOut{
+				Amount: -1,
+				Script: &Script{},
+			})
+		}
+		txCopy.AddTxOut(tx.TxOuts[idx])
+	default:
+		txCopy.TxOuts = tx.TxOuts
+	}
+
+	w := newWriter()
+	if err := w.writeStrippedTx(txCopy); err != nil {
+		return nil, err
+	}
+	if err := w.writeData(hashType.Uint32()); err != nil {
+		return nil, err
+	}
+
+	return Sha256Double(w.Bytes())
+}
+
+// WitnessV0SigHash returns the BIP143 signature hash of the idx-th input of tx,
+// which spends amount and executes scriptCode.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0143.mediawiki
+func (tx *Tx) WitnessV0SigHash(idx int, scriptCode *Script, amount Satoshi, hashType SigHashType) ([]byte, error) {
+	if idx < 0 || idx >= len(tx.TxIns) {
+		return nil, ErrTxInIndexOutOfRange
+	}
+
+	outputType := hashType & sigHashLegacyMask
+
+	hashPrevOuts := make([]byte, 32)
+	hashSequence := make([]byte, 32)
+	hashOutputs := make([]byte, 32)
+
+	var err error
+
+	if !hashType.isAnyoneCanPay() {
+		hashPrevOuts, err = hashOutPoints(tx.TxIns, Sha256Double)
+		if err != nil {
+			return nil, err
+		}
+
+		if outputType != SigHashSingle && outputType != SigHashNone {
+			hashSequence, err = hashSequences(tx.TxIns, Sha256Double)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if outputType != SigHashSingle && outputType != SigHashNone {
+		hashOutputs, err = hashTxOuts(tx.TxOuts, Sha256Double)
+		if err != nil {
+			return nil, err
+		}
+	} else if outputType == SigHashSingle && idx < len(tx.TxOuts) {
+		hashOutputs, err = hashTxOuts(tx.TxOuts[idx:idx+1], Sha256Double)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	txIn := tx.TxIns[idx]
+
+	w := newWriter()
+	if err := w.writeTxVersion(tx.Version); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(hashPrevOuts); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(hashSequence); err != nil {
+		return nil, err
+	}
+	if err := w.writeOutPoint(txIn.Txid, txIn.Index); err != nil {
+		return nil, err
+	}
+	if err := w.writeScript(scriptCode); err != nil {
+		return nil, err
+	}
+	if err := w.writeData(amount); err != nil {
+		return nil, err
+	}
+	if err := w.writeData(txIn.Sequence); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(hashOutputs); err != nil {
+		return nil, err
+	}
+	if err := w.writeLockTime(tx.LockTime); err != nil {
+		return nil, err
+	}
+	if err := w.writeData(hashType.Uint32()); err != nil {
+		return nil, err
+	}
+
+	return Sha256Double(w.Bytes())
+}
+
+type tapscriptSigHashExt struct {
+	leafHash   []byte
+	codeSepPos uint32
+}
+
+// TaprootSigHash returns the BIP341 signature hash for a key path spend.
+// prevOuts must contain the outputs spent by every input of tx, in order.
+func (tx *Tx) TaprootSigHash(prevOuts []*TxOut, idx int, hashType SigHashType, annex []byte) ([]byte, error) {
+	return tx.taprootSigHash(prevOuts, idx, hashType, annex, nil)
+}
+
+// TapscriptSigHash returns the BIP342 signature hash for a script path spend
+// of the leaf identified by leafHash.
+func (tx *Tx) TapscriptSigHash(prevOuts []*TxOut, idx int, hashType SigHashType, annex []byte, leafHash []byte, codeSepPos uint32) ([]byte, error) {
+	if len(leafHash) != 32 {
+		return nil, ErrInvalidTapLeafHash
+	}
+
+	return tx.taprootSigHash(prevOuts, idx, hashType, annex, &tapscriptSigHashExt{
+		leafHash:   leafHash,
+		codeSepPos: codeSepPos,
+	})
+}
+
+func (tx *Tx) taprootSigHash(prevOuts []*TxOut, idx int, hashType SigHashType, annex []byte, ext *tapscriptSigHashExt) ([]byte, error) {
+	msg, err := tx.taprootSigMsg(prevOuts, idx, hashType, annex, ext)
+	if err != nil {
+		return nil, err
+	}
+
+	return TaggedHash(TagTapSigHash, append([]byte{taprootSigHashEpoch}, msg...))
+}
+
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0341.mediawiki#common-signature-message
+func (tx *Tx) taprootSigMsg(prevOuts []*TxOut, idx int, hashType SigHashType, annex []byte, ext *tapscriptSigHashExt) ([]byte, error) {
+	if !hashType.isValidTaproot() {
+		return nil, ErrInvalidSigHashType
+	}
+	if idx < 0 || idx >= len(tx.TxIns) {
+		return nil, ErrTxInIndexOutOfRange
+	}
+	if len(prevOuts) != len(tx.TxIns) {
+		return nil, ErrPrevOutsMismatch
+	}
+	if annex != nil && (len(annex) == 0 || annex[0] != TaprootAnnexTag) {
+		return nil, ErrInvalidAnnex
+	}
+
+	outputType := hashType.outputType()
+	if hashType == SigHashDefault {
+		outputType = SigHashAll
+	}
+	if outputType == SigHashSingle && idx >= len(tx.TxOuts) {
+		return nil, ErrNoTxOutForSigHashSingle
+	}
+
+	w := newWriter()
+
+	if err := w.WriteByte(byte(hashType)); err != nil {
+		return nil, err
+	}
+	if err := w.writeTxVersion(tx.Version); err != nil {
+		return nil, err
+	}
+	if err := w.writeLockTime(tx.LockTime); err != nil {
+		return nil, err
+	}
+
+	if !hashType.isAnyoneCanPay() {
+		hashes := []func() ([]byte, error){
+			func() ([]byte, error) { return hashOutPoints(tx.TxIns, Sha256) },
+			func() ([]byte, error) { return hashAmounts(prevOuts, Sha256) },
+			func() ([]byte, error) { return hashScriptPubKeys(prevOuts, Sha256) },
+			func() ([]byte, error) { return hashSequences(tx.TxIns, Sha256) },
+		}
+		for _, hash := range hashes {
+			b, err := hash()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := w.Write(b); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if outputType != SigHashNone && outputType != SigHashSingle {
+		b, err := hashTxOuts(tx.TxOuts, Sha256)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(b); err != nil {
+			return nil, err
+		}
+	}
+
+	var spendType byte
+	if ext != nil {
+		spendType |= 0x02
+	}
+	if annex != nil {
+		spendType |= 0x01
+	}
+	if err := w.WriteByte(spendType); err != nil {
+		return nil, err
+	}
+
+	if hashType.isAnyoneCanPay() {
+		txIn := tx.TxIns[idx]
+		if err := w.writeOutPoint(txIn.Txid, txIn.Index); err != nil {
+			return nil, err
+		}
+		if err := w.writeTxOut(prevOuts[idx]); err != nil {
+			return nil, err
+		}
+		if err := w.writeData(txIn.Sequence); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := w.writeData(uint32(idx)); err != nil {
+			return nil, err
+		}
+	}
+
+	if annex != nil {
+		aw := newWriter()
+		if err := aw.writeVarBytes(annex); err != nil {
+			return nil, err
+		}
+		b, err := Sha256(aw.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(b); err != nil {
+			return nil, err
+		}
+	}
+
+	if outputType == SigHashSingle {
+		b, err := hashTxOuts(tx.TxOuts[idx:idx+1], Sha256)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(b); err != nil {
+			return nil, err
+		}
+	}
+
+	if ext != nil {
+		if _, err := w.Write(ext.leafHash); err != nil {
+			return nil, err
+		}
+		if err := w.WriteByte(TapscriptKeyVersion); err != nil {
+			return nil, err
+		}
+		if err := w.writeData(ext.codeSepPos); err != nil {
+			return nil, err
+		}
+	}
+
+	return w.Bytes(), nil
+}
+
+func hashOutPoints(txIns []*TxIn, hash func([]byte) ([]byte, error)) ([]byte, error) {
+	w := newWriter()
+	for _, txIn := range txIns {
+		if err := w.writeOutPoint(txIn.Txid, txIn.Index); err != nil {
+			return nil, err
+		}
+	}
+
+	return hash(w.Bytes())
+}
+
+func hashSequences(txIns []*TxIn, hash func([]byte) ([]byte, error)) ([]byte, error) {
+	w := newWriter()
+	for _, txIn := range txIns {
+		if err := w.writeData(txIn.Sequence); err != nil {
+			return nil, err
+		}
+	}
+
+	return hash(w.Bytes())
+}
+
+func hashAmounts(txOuts []*TxOut, hash func([]byte) ([]byte, error)) ([]byte, error) {
+	w := newWriter()
+	for _, txOut := range txOuts {
+		if err := w.writeData(txOut.Amount); err != nil {
+			return nil, err
+		}
+	}
+
+	return hash(w.Bytes())
+}
+
+func hashScriptPubKeys(txOuts []*TxOut, hash func([]byte) ([]byte, error)) ([]byte, error) {
+	w := newWriter()
+	for _, txOut := range txOuts {
+		if err := w.writeScript(txOut.Script); err != nil {
+			return nil, err
+		}
+	}
+
+	return hash(w.Bytes())
+}
+
+func hashTxOuts(txOuts []*TxOut, hash func([]byte) ([]byte, error)) ([]byte, error) {
+	w := newWriter()
+	for _, txOut := range txOuts {
+		if err := w.writeTxOut(txOut); err != nil {
+			return nil, err
+		}
+	}
+
+	return hash(w.Bytes())
+}
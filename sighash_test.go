@@ -0,0 +1,228 @@
+package btc
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0341/wallet-test-vectors.json
+func newBip341KeyPathSpendingTx(t *testing.T) (*Tx, []*TxOut) {
+	tx, err := NewTxFromHex("02000000097de20cbff686da83a54981d2b9bab3586f4ca7e48f57f5b55963115f3b334e9c010000000000000000d7b7cab57b1393ace2d064f4d4a2cb8af6def61273e127517d44759b6dafdd990000000000fffffffff8e1f583384333689228c5d28eac13366be082dc57441760d957275419a418420000000000fffffffff0689180aa63b30cb162a73c6d2a38b7eeda2a83ece74310fda0843ad604853b0100000000feffffffaa5202bdf6d8ccd2ee0f0202afbbb7461d9264a25e5bfd3c5a52ee1239e0ba6c0000000000feffffff956149bdc66faa968eb2be2d2faa29718acbfe3941215893a2a3446d32acd050000000000000000000e664b9773b88c09c32cb70a2a3e4da0ced63b7ba3b22f848531bbb1d5d5f4c94010000000000000000e9aa6b8e6c9de67619e6a3924ae25696bb7b694bb677a632a74ef7eadfd4eabf0000000000ffffffffa778eb6a263dc090464cd125c466b5a99667720b1c110468831d058aa1b82af10100000000ffffffff0200ca9a3b000000001976a91406afd46bcdfd22ef94ac122aa11f241244a37ecc88ac807840cb0000000020ac9a87f5594be208f8532db38cff670c450ed2fea8fcdefcc9a663f78bab962b0065cd1d")
+	require.NoError(t, err)
+
+	prevOuts := []*TxOut{
+		NewTxOut(420000000, &Script{Hex: "512053a1f6e454df1aa2776a2814a721372d6258050de330b3c6d10ee8f4e0dda343"}),
+		NewTxOut(462000000, &Script{Hex: "5120147c9c57132f6e7ecddba9800bb0c4449251c92a1e60371ee77557b6620f3ea3"}),
+		NewTxOut(294000000, &Script{Hex: "76a914751e76e8199196d454941c45d1b3a323f1433bd688ac"}),
+		NewTxOut(504000000, &Script{Hex: "5120e4d810fd50586274face62b8a807eb9719cef49c04177cc6b76a9a4251d5450e"}),
+		NewTxOut(630000000, &Script{Hex: "512091b64d5324723a985170e4dc5a0f84c041804f2cd12660fa5dec09fc21783605"}),
+		NewTxOut(378000000, &Script{Hex: "00147dd65592d0ab2fe0d0257d571abf032cd9db93dc"}),
+		NewTxOut(672000000, &Script{Hex: "512075169f4001aa68f15bbed28b218df1d0a62cbbcf1188c6665110c293c907b831"}),
+		NewTxOut(546000000, &Script{Hex: "5120712447206d7a5238acc7ff53fbe94a3b64539ad291c7cdbc490b7577e4b17df5"}),
+		NewTxOut(588000000, &Script{Hex: "512077e30a5522dd9f894c3f8b8bd4c4b2cf82ca7da8a3ea6a239655c39c050ab220"}),
+	}
+
+	return tx, prevOuts
+}
+
+func TestTaprootSigHash(t *testing.T) {
+	tx, prevOuts := newBip341KeyPathSpendingTx(t)
+
+	testCases := []struct {
+		idx      int
+		hashType SigHashType
+		sigMsg   string
+		sigHash  string
+	}{
+		{
+			0,
+			SigHashSingle,
+			"0003020000000065cd1de3b33bb4ef3a52ad1fffb555c0d82828eb22737036eaeb02a235d82b909c4c3f58a6964a4f5f8f0b642ded0a8a553be7622a719da71d1f5befcefcdee8e0fde623ad0f61ad2bca5ba6a7693f50fce988e17c3780bf2b1e720cfbb38fbdd52e2118959c7221ab5ce9e26c3cd67b22c24f8baa54bac281d8e6b05e400e6c3a957e0000000000d0418f0e9a36245b9a50ec87f8bf5be5bcae434337b87139c3a5b1f56e33cba0",
+			"2514a6272f85cfa0f45eb907fcb0d121b808ed37c6ea160a5a9046ed5526d555",
+		},
+		{
+			1,
+			SigHashSingle | SigHashAnyoneCanPay,
+			"0083020000000065cd1d00d7b7cab57b1393ace2d064f4d4a2cb8af6def61273e127517d44759b6dafdd9900000000808f891b00000000225120147c9c57132f6e7ecddba9800bb0c4449251c92a1e60371ee77557b6620f3ea3ffffffffffcef8fb4ca7efc5433f591ecfc57391811ce1e186a3793024def5c884cba51d",
+			"325a644af47e8a5a2591cda0ab0723978537318f10e6a63d4eed783b96a71a4d",
+		},
+		{
+			3,
+			SigHashAll,
+			"0001020000000065cd1de3b33bb4ef3a52ad1fffb555c0d82828eb22737036eaeb02a235d82b909c4c3f58a6964a4f5f8f0b642ded0a8a553be7622a719da71d1f5befcefcdee8e0fde623ad0f61ad2bca5ba6a7693f50fce988e17c3780bf2b1e720cfbb38fbdd52e2118959c7221ab5ce9e26c3cd67b22c24f8baa54bac281d8e6b05e400e6c3a957ea2e6dab7c1f0dcd297c8d61647fd17d821541ea69c3cc37dcbad7f90d4eb4bc50003000000",
+			"bf013ea93474aa67815b1b6cc441d23b64fa310911d991e713cd34c7f5d46669",
+		},
+		{
+			4,
+			SigHashDefault,
+			"0000020000000065cd1de3b33bb4ef3a52ad1fffb555c0d82828eb22737036eaeb02a235d82b909c4c3f58a6964a4f5f8f0b642ded0a8a553be7622a719da71d1f5befcefcdee8e0fde623ad0f61ad2bca5ba6a7693f50fce988e17c3780bf2b1e720cfbb38fbdd52e2118959c7221ab5ce9e26c3cd67b22c24f8baa54bac281d8e6b05e400e6c3a957ea2e6dab7c1f0dcd297c8d61647fd17d821541ea69c3cc37dcbad7f90d4eb4bc50004000000",
+			"4f900a0bae3f1446fd48490c2958b5a023228f01661cda3496a11da502a7f7ef",
+		},
+		{
+			6,
+			SigHashNone,
+			"0002020000000065cd1de3b33bb4ef3a52ad1fffb555c0d82828eb22737036eaeb02a235d82b909c4c3f58a6964a4f5f8f0b642ded0a8a553be7622a719da71d1f5befcefcdee8e0fde623ad0f61ad2bca5ba6a7693f50fce988e17c3780bf2b1e720cfbb38fbdd52e2118959c7221ab5ce9e26c3cd67b22c24f8baa54bac281d8e6b05e400e6c3a957e0006000000",
+			"15f25c298eb5cdc7eb1d638dd2d45c97c4c59dcaec6679cfc16ad84f30876b85",
+		},
+		{
+			7,
+			SigHashNone | SigHashAnyoneCanPay,
+			"0082020000000065cd1d00e9aa6b8e6c9de67619e6a3924ae25696bb7b694bb677a632a74ef7eadfd4eabf00000000804c8b2000000000225120712447206d7a5238acc7ff53fbe94a3b64539ad291c7cdbc490b7577e4b17df5ffffffff",
+			"cd292de50313804dabe4685e83f923d2969577191a3e1d2882220dca88cbeb10",
+		},
+		{
+			8,
+			SigHashAll | SigHashAnyoneCanPay,
+			"0081020000000065cd1da2e6dab7c1f0dcd297c8d61647fd17d821541ea69c3cc37dcbad7f90d4eb4bc500a778eb6a263dc090464cd125c466b5a99667720b1c110468831d058aa1b82af101000000002b0c230000000022512077e30a5522dd9f894c3f8b8bd4c4b2cf82ca7da8a3ea6a239655c39c050ab220ffffffff",
+			"cccb739eca6c13a8a89e6e5cd317ffe55669bbda23f2fd37b0f18755e008edd2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.sigHash, func(t *testing.T) {
+			// the sigMsg of the test vectors begins with the epoch
+			sigMsg, err := tx.taprootSigMsg(prevOuts, tc.idx, tc.hashType, nil, nil)
+			require.NoError(t, err)
+			assert.Equal(t, hex.EncodeToString(append([]byte{taprootSigHashEpoch}, sigMsg...)), tc.sigMsg)
+
+			sigHash, err := tx.TaprootSigHash(prevOuts, tc.idx, tc.hashType, nil)
+			require.NoError(t, err)
+			assert.Equal(t, hex.EncodeToString(sigHash), tc.sigHash)
+		})
+	}
+}
+
+func TestTapscriptSigHash(t *testing.T) {
+	tx, prevOuts := newBip341KeyPathSpendingTx(t)
+
+	annex, err := hex.DecodeString("50a1b2c3")
+	require.NoError(t, err)
+	leafHash, err := hex.DecodeString("5b75adecf53548f3ec6ad7d78383bf84cc57b55a3127c72b9a2481752dd88b21")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		idx      int
+		hashType SigHashType
+		sigHash  string
+	}{
+		{0, SigHashDefault, "7a64f3bb381b3af3b33e721090b05117bc5164e6daad3376309a149a461d4831"},
+		{0, SigHashAll, "f4de3f3d29e152e266f3ac15a620f2981ab31ac4e19ded09d02b5455eca7d878"},
+		{3, SigHashNone, "21172180be6ad172a3c107dfa5cd339ee4e71890e62f1edfd53bf98b8c7da601"},
+		{1, SigHashSingle, "f197614d21118f71761a629e31399f0371797382155b146f4f0775907aff00e6"},
+		{7, SigHashAll | SigHashAnyoneCanPay, "8fedbd40877eaed568714c711900a5b51cb1e4534c8f7718bad6e48ab5e3a419"},
+		{8, SigHashNone | SigHashAnyoneCanPay, "0d82c854a08262c00c4901f2156c31b563873091e0be759d112a8f9a9f90894a"},
+		{1, SigHashSingle | SigHashAnyoneCanPay, "d190bfc3994bca3e48b96c0e9c39857b5e790c113fe5c6f4f2f697c392496724"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.sigHash, func(t *testing.T) {
+			sigHash, err := tx.TapscriptSigHash(prevOuts, tc.idx, tc.hashType, annex, leafHash, 7)
+			require.NoError(t, err)
+			assert.Equal(t, hex.EncodeToString(sigHash), tc.sigHash)
+		})
+	}
+}
+
+func TestTaprootSigHashErrors(t *testing.T) {
+	tx := NewTx()
+	tx.AddTxIn(NewTxIn("6eb316926b1c5d567cd6f5e6a84fec606fc53d7b474526d1fff3948020c93dfe", 0, &Script{}))
+	prevOuts := []*TxOut{
+		NewTxOut(1000, &Script{Hex: "5120147c9c57132f6e7ecddba9800bb0c4449251c92a1e60371ee77557b6620f3ea3"}),
+	}
+
+	_, err := tx.TaprootSigHash(prevOuts, 0, SigHashType(0x04), nil)
+	assert.Equal(t, err, ErrInvalidSigHashType)
+
+	_, err = tx.TaprootSigHash(prevOuts, 1, SigHashDefault, nil)
+	assert.Equal(t, err, ErrTxInIndexOutOfRange)
+
+	_, err = tx.TaprootSigHash(nil, 0, SigHashDefault, nil)
+	assert.Equal(t, err, ErrPrevOutsMismatch)
+
+	_, err = tx.TaprootSigHash(prevOuts, 0, SigHashSingle, nil)
+	assert.Equal(t, err, ErrNoTxOutForSigHashSingle)
+
+	_, err = tx.TaprootSigHash(prevOuts, 0, SigHashDefault, []byte{0x51})
+	assert.Equal(t, err, ErrInvalidAnnex)
+}
+
+func TestLegacyAndWitnessV0SigHash(t *testing.T) {
+	txHex := "020000000301000000000000000000000000000000000000000000000000000000000000000000000000fdffffff02000000000000000000000000000000000000000000000000000000000000000100000000fcffffff03000000000000000000000000000000000000000000000000000000000000000200000000fbffffff0250c30000000000001976a914fc7250a211deddc70ee5a2738de5f07817351cef88ac60ea000000000000160014e1fae3324e28a4ef5ee01f14dd337ac6c85d1d9000350c00"
+	// contains OP_CODESEPARATOR, which must not be signed
+	subScript := &Script{Hex: "76a914b256082b934fe782adbacaafeadfca64c52a5384ab88ac"}
+	scriptCode := &Script{Hex: "76a914fc7250a211deddc70ee5a2738de5f07817351cef88ac"}
+	amount := Satoshi(140000)
+
+	tx, err := NewTxFromHex(txHex)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		hashType  SigHashType
+		idx       int
+		legacy    string
+		witnessV0 string
+	}{
+		{
+			SigHashAll,
+			0,
+			"bf561862940ada68ac267f38e3d2b46abe634bb0aafe074626edc9083b50f8f2",
+			"7a67dffc4bdd12b09bfff7f120bbf2ee820381791d89c60622e24d8ed50709b4",
+		},
+		{
+			SigHashNone,
+			0,
+			"8ca726e40786ae30d3be4963cc541f96b54fe56c75ba17dc2c81b2306ca8b049",
+			"58b3aa5693af47d3517ac820cd27cdc47e39b89137238ff30742c9ddcc94d649",
+		},
+		{
+			SigHashSingle,
+			1,
+			"c4cccaee811b3b14ccf47b51e956e13933f4afcf351ac9ddde97cccc7eaff797",
+			"f6ce66833a376836b176ce5a708dac90a6494312f0147c4b45288f69b7c92758",
+		},
+		{
+			SigHashAll | SigHashAnyoneCanPay,
+			1,
+			"8ef0b272d66e55c7743e44bc855181c02f93fabc458455f18019b4f7917bc5d7",
+			"a9578d0c76333558a0bba583eddba5c5376a36a4a53532295bbd4674ef3e728e",
+		},
+		{
+			SigHashNone | SigHashAnyoneCanPay,
+			2,
+			"eca48f8d7a3673e69cb7c828d25419af7fd51d9634fde2001ae585c42a6cd3d6",
+			"23d64981eadbcdb1f284e80dab6b7c84f22787641b0dd036c0d8b379d62673a4",
+		},
+		{
+			SigHashSingle | SigHashAnyoneCanPay,
+			0,
+			"42721e1e015c025c7191c4014f6d50a0d1030c33d9cea373d45fcecf1839d3e4",
+			"1e20a6bfea09f7f85b668166ade2a9e5056973b68f5a775a0447fe7153f099a5",
+		},
+		{
+			SigHashSingle,
+			2,
+			"0100000000000000000000000000000000000000000000000000000000000000",
+			"8202665c1bd37b3333daefd716f6e9967c87a6a2982419e3a1a0be1bdd018194",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.legacy, func(t *testing.T) {
+			hash, err := tx.SigHash(tc.idx, subScript, tc.hashType)
+			require.NoError(t, err)
+			assert.Equal(t, hex.EncodeToString(hash), tc.legacy)
+
+			hash, err = tx.WitnessV0SigHash(tc.idx, scriptCode, amount, tc.hashType)
+			require.NoError(t, err)
+			assert.Equal(t, hex.EncodeToString(hash), tc.witnessV0)
+		})
+	}
+
+	_, err = tx.SigHash(3, subScript, SigHashAll)
+	assert.Equal(t, err, ErrTxInIndexOutOfRange)
+	_, err = tx.WitnessV0SigHash(-1, scriptCode, amount, SigHashAll)
+	assert.Equal(t, err, ErrTxInIndexOutOfRange)
+}
@@ -0,0 +1,685 @@
+package btc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+)
+
+var (
+	ErrPrivateKeyNotFound       = errors.New("private key not found")
+	ErrUnsupportedScript        = errors.New("unsupported script")
+	ErrRedeemScriptNotFound     = errors.New("redeem script not found")
+	ErrWitnessScriptNotFound    = errors.New("witness script not found")
+	ErrTapInternalKeyNotFound   = errors.New("tap internal key not found")
+	ErrScriptHashMismatch       = errors.New("script hash mismatch")
+	ErrTaprootOutputKeyMismatch = errors.New("taproot output key mismatch")
+	ErrNotEnoughSignatures      = errors.New("not enough signatures")
+	ErrTxInVerificationFailed   = errors.New("tx in verification failed")
+)
+
+// PrevOut is the output spent by a tx in, together with
+// the scripts and keys needed to satisfy its script.
+type PrevOut struct {
+	*TxOut
+	RedeemScript   *Script
+	WitnessScript  *Script
+	TapInternalKey *XOnlyPublicKey
+	TapMerkleRoot  []byte
+}
+
+func NewPrevOut(txOut *TxOut) *PrevOut {
+	return &PrevOut{
+		TxOut: txOut,
+	}
+}
+
+// KeySource looks up the private keys a Signer signs with.
+// Both methods return ErrPrivateKeyNotFound for unknown keys.
+type KeySource interface {
+	PrivateKeyByPkh(pkh Pkh) (*PrivateKey, error)
+	PrivateKeyByXOnlyPublicKey(pubKey *XOnlyPublicKey) (*PrivateKey, error)
+}
+
+// KeyRing is an in-memory KeySource.
+type KeyRing []*PrivateKey
+
+func NewKeyRing(privKeys ...*PrivateKey) KeyRing {
+	return KeyRing(privKeys)
+}
+
+// PrivateKeyByPkh returns the key whose compressed or uncompressed public key hashes to pkh.
+func (ring KeyRing) PrivateKeyByPkh(pkh Pkh) (*PrivateKey, error) {
+	for _, privKey := range ring {
+		_, err := matchPkh(privKey.PublicKey(), pkh)
+		if err == ErrPrivateKeyNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		return privKey, nil
+	}
+
+	return nil, ErrPrivateKeyNotFound
+}
+
+func (ring KeyRing) PrivateKeyByXOnlyPublicKey(pubKey *XOnlyPublicKey) (*PrivateKey, error) {
+	for _, privKey := range ring {
+		if privKey.PublicKey().XOnly().IsEqual(pubKey) {
+			return privKey, nil
+		}
+	}
+
+	return nil, ErrPrivateKeyNotFound
+}
+
+// matchPkh returns pubKey in the format that hashes to pkh.
+func matchPkh(pubKey *PublicKey, pkh Pkh) (*PublicKey, error) {
+	for _, pk := range []*PublicKey{pubKey.Compressed(), pubKey.Uncompressed()} {
+		h, err := pk.Pkh()
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(h, pkh) {
+			return pk, nil
+		}
+	}
+
+	return nil, ErrPrivateKeyNotFound
+}
+
+// Signer fills the scriptSigs and witnesses of a tx.
+// It supports P2PK, P2PKH, bare and P2SH multisig, P2WPKH, P2WSH,
+// their P2SH-wrapped forms and P2TR key path spends.
+type Signer struct {
+	tx       *Tx
+	prevOuts []*PrevOut
+	keys     KeySource
+	hashType SigHashType
+}
+
+// NewSigner returns a Signer for tx. prevOuts must contain the output
+// spent by every input of tx, in order.
+func NewSigner(tx *Tx, prevOuts []*PrevOut, keys KeySource) (*Signer, error) {
+	if len(prevOuts) != len(tx.TxIns) {
+		return nil, ErrPrevOutsMismatch
+	}
+
+	return &Signer{
+		tx:       tx,
+		prevOuts: prevOuts,
+		keys:     keys,
+		hashType: SigHashDefault,
+	}, nil
+}
+
+// SetHashType sets the sighash type of the signatures to be created.
+// SigHashDefault signs with SIGHASH_ALL on non-taproot inputs.
+func (signer *Signer) SetHashType(hashType SigHashType) {
+	signer.hashType = hashType
+}
+
+func (signer *Signer) ecdsaHashType() SigHashType {
+	if signer.hashType == SigHashDefault {
+		return SigHashAll
+	}
+
+	return signer.hashType
+}
+
+// Sign signs every input of the tx and verifies the result.
+func (signer *Signer) Sign() error {
+	for i := range signer.tx.TxIns {
+		if err := signer.SignTxIn(i); err != nil {
+			return err
+		}
+	}
+
+	return signer.Verify()
+}
+
+func (signer *Signer) SignTxIn(idx int) error {
+	if idx < 0 || idx >= len(signer.tx.TxIns) {
+		return ErrTxInIndexOutOfRange
+	}
+
+	prevOut := signer.prevOuts[idx]
+
+	b, err := prevOut.Script.Bytes()
+	if err != nil {
+		return err
+	}
+
+	var scriptSigItems, witnessItems [][]byte
+
+	switch classifyScript(b) {
+	case ScriptTypeP2pk, ScriptTypeP2pkh, ScriptTypeMultisig:
+		scriptSigItems, err = signer.solve(b, signer.legacySigner(idx, b))
+	case ScriptTypeP2sh:
+		var rb []byte
+		rb, err = signer.redeemScript(prevOut, b)
+		if err != nil {
+			return err
+		}
+
+		switch classifyScript(rb) {
+		case ScriptTypeP2wpkh, ScriptTypeP2wsh:
+			witnessItems, err = signer.signWitnessV0(idx, rb)
+			scriptSigItems = [][]byte{rb}
+		case ScriptTypeP2pk, ScriptTypeP2pkh, ScriptTypeMultisig:
+			scriptSigItems, err = signer.solve(rb, signer.legacySigner(idx, rb))
+			scriptSigItems = append(scriptSigItems, rb)
+		default:
+			return ErrUnsupportedScript
+		}
+	case ScriptTypeP2wpkh, ScriptTypeP2wsh:
+		witnessItems, err = signer.signWitnessV0(idx, b)
+	case ScriptTypeP2tr:
+		witnessItems, err = signer.signTaproot(idx, b)
+	default:
+		return ErrUnsupportedScript
+	}
+	if err != nil {
+		return err
+	}
+
+	scriptSig, err := newPushOnlyScript(scriptSigItems)
+	if err != nil {
+		return err
+	}
+
+	var witness []string
+	for _, item := range witnessItems {
+		witness = append(witness, hex.EncodeToString(item))
+	}
+
+	txIn := signer.tx.TxIns[idx]
+	txIn.Script = scriptSig
+	txIn.Witness = witness
+
+	return nil
+}
+
+func (signer *Signer) redeemScript(prevOut *PrevOut, scriptPubKey []byte) ([]byte, error) {
+	if prevOut.RedeemScript == nil {
+		return nil, ErrRedeemScriptNotFound
+	}
+
+	rb, err := prevOut.RedeemScript.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := Hash160(rb)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(h, scriptPubKey[2:2+ScriptHashLength]) {
+		return nil, ErrScriptHashMismatch
+	}
+
+	return rb, nil
+}
+
+func (signer *Signer) witnessScript(prevOut *PrevOut, program []byte) ([]byte, error) {
+	if prevOut.WitnessScript == nil {
+		return nil, ErrWitnessScriptNotFound
+	}
+
+	wb, err := prevOut.WitnessScript.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := Sha256(wb)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(h, program) {
+		return nil, ErrScriptHashMismatch
+	}
+
+	return wb, nil
+}
+
+func (signer *Signer) signWitnessV0(idx int, scriptPubKey []byte) ([][]byte, error) {
+	prevOut := signer.prevOuts[idx]
+
+	_, program, _ := extractWitnessProgram(scriptPubKey)
+
+	if len(program) == PkhLength {
+		scriptCode, err := NewP2pkhScript(Pkh(program))
+		if err != nil {
+			return nil, err
+		}
+
+		sb, err := scriptCode.Bytes()
+		if err != nil {
+			return nil, err
+		}
+
+		return signer.solve(sb, signer.witnessV0Signer(idx, sb))
+	}
+
+	wb, err := signer.witnessScript(prevOut, program)
+	if err != nil {
+		return nil, err
+	}
+
+	switch classifyScript(wb) {
+	case ScriptTypeP2pk, ScriptTypeP2pkh, ScriptTypeMultisig:
+	default:
+		return nil, ErrUnsupportedScript
+	}
+
+	items, err := signer.solve(wb, signer.witnessV0Signer(idx, wb))
+	if err != nil {
+		return nil, err
+	}
+
+	return append(items, wb), nil
+}
+
+func (signer *Signer) signTaproot(idx int, scriptPubKey []byte) ([][]byte, error) {
+	prevOut := signer.prevOuts[idx]
+
+	if prevOut.TapInternalKey == nil {
+		return nil, ErrTapInternalKeyNotFound
+	}
+
+	outputKey, err := TaprootOutputKey(prevOut.TapInternalKey, prevOut.TapMerkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(outputKey.Bytes(), scriptPubKey[2:]) {
+		return nil, ErrTaprootOutputKeyMismatch
+	}
+
+	privKey, err := signer.keys.PrivateKeyByXOnlyPublicKey(prevOut.TapInternalKey)
+	if err != nil {
+		return nil, err
+	}
+	tweakedPrivKey, err := TaprootTweakPrivateKey(privKey, prevOut.TapMerkleRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := signer.tx.TaprootSigHash(signer.prevTxOuts(), idx, signer.hashType, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := tweakedPrivKey.SignSchnorr(hash, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	b := sig.Bytes()
+	if signer.hashType != SigHashDefault {
+		b = append(b, byte(signer.hashType))
+	}
+
+	return [][]byte{b}, nil
+}
+
+func (signer *Signer) prevTxOuts() []*TxOut {
+	txOuts := make([]*TxOut, len(signer.prevOuts))
+	for i, prevOut := range signer.prevOuts {
+		txOuts[i] = prevOut.TxOut
+	}
+
+	return txOuts
+}
+
+// sigHasher returns the hash an ECDSA signature with hashType commits to.
+type sigHasher func(hashType SigHashType) ([]byte, error)
+
+func (signer *Signer) legacySigner(idx int, subScript []byte) sigHasher {
+	return func(hashType SigHashType) ([]byte, error) {
+		return signer.tx.SigHash(idx, &Script{Hex: hex.EncodeToString(subScript)}, hashType)
+	}
+}
+
+func (signer *Signer) witnessV0Signer(idx int, scriptCode []byte) sigHasher {
+	amount := signer.prevOuts[idx].Amount
+
+	return func(hashType SigHashType) ([]byte, error) {
+		return signer.tx.WitnessV0SigHash(idx, &Script{Hex: hex.EncodeToString(scriptCode)}, amount, hashType)
+	}
+}
+
+func (signer *Signer) ecdsaSign(privKey *PrivateKey, hasher sigHasher) ([]byte, error) {
+	hashType := signer.ecdsaHashType()
+
+	hash, err := hasher(hashType)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := privKey.Sign(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(sig.Bytes(), byte(hashType)), nil
+}
+
+// solve returns the stack items satisfying a P2PK, P2PKH or multisig script.
+func (signer *Signer) solve(script []byte, hasher sigHasher) ([][]byte, error) {
+	ops, err := parseScript(script)
+	if err != nil {
+		return nil, err
+	}
+
+	if pubKey := extractP2pkPubKey(ops); pubKey != nil {
+		pkh, err := Hash160(pubKey)
+		if err != nil {
+			return nil, err
+		}
+
+		privKey, err := signer.keys.PrivateKeyByPkh(pkh)
+		if err != nil {
+			return nil, err
+		}
+
+		sig, err := signer.ecdsaSign(privKey, hasher)
+		if err != nil {
+			return nil, err
+		}
+
+		return [][]byte{sig}, nil
+	}
+
+	if pkh := extractP2pkhPkh(ops); pkh != nil {
+		privKey, err := signer.keys.PrivateKeyByPkh(pkh)
+		if err != nil {
+			return nil, err
+		}
+
+		pubKey, err := matchPkh(privKey.PublicKey(), pkh)
+		if err != nil {
+			return nil, err
+		}
+
+		sig, err := signer.ecdsaSign(privKey, hasher)
+		if err != nil {
+			return nil, err
+		}
+
+		return [][]byte{sig, pubKey.Bytes()}, nil
+	}
+
+	if m, pubKeys, ok := extractMultisig(ops); ok {
+		// CHECKMULTISIG pops one extra item
+		items := [][]byte{{}}
+
+		for _, pubKey := range pubKeys {
+			if len(items) == m+1 {
+				break
+			}
+
+			pkh, err := Hash160(pubKey)
+			if err != nil {
+				return nil, err
+			}
+
+			privKey, err := signer.keys.PrivateKeyByPkh(pkh)
+			if err == ErrPrivateKeyNotFound {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+
+			sig, err := signer.ecdsaSign(privKey, hasher)
+			if err != nil {
+				return nil, err
+			}
+
+			items = append(items, sig)
+		}
+
+		if len(items) < m+1 {
+			return nil, ErrNotEnoughSignatures
+		}
+
+		return items, nil
+	}
+
+	return nil, ErrUnsupportedScript
+}
+
+// Verify checks the scriptSig and witness of every input of the tx
+// against the script of the output it spends.
+func (signer *Signer) Verify() error {
+	for i := range signer.tx.TxIns {
+		if err := signer.VerifyTxIn(i); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (signer *Signer) VerifyTxIn(idx int) error {
+	if idx < 0 || idx >= len(signer.tx.TxIns) {
+		return ErrTxInIndexOutOfRange
+	}
+
+	txIn := signer.tx.TxIns[idx]
+	prevOut := signer.prevOuts[idx]
+
+	b, err := prevOut.Script.Bytes()
+	if err != nil {
+		return err
+	}
+
+	var scriptSig []byte
+	if txIn.Script != nil {
+		scriptSig, err = txIn.Script.Bytes()
+		if err != nil {
+			return err
+		}
+	}
+	scriptSigItems, err := parsePushOnlyScript(scriptSig)
+	if err != nil {
+		return err
+	}
+
+	witnessItems, err := txIn.WitnessBytes()
+	if err != nil {
+		return err
+	}
+
+	var ok bool
+
+	switch typ := classifyScript(b); typ {
+	case ScriptTypeP2pk, ScriptTypeP2pkh, ScriptTypeMultisig:
+		ok = len(witnessItems) == 0 &&
+			signer.verifyStack(b, scriptSigItems, signer.legacySigner(idx, b))
+	case ScriptTypeP2sh:
+		if len(scriptSigItems) == 0 {
+			return ErrTxInVerificationFailed
+		}
+
+		rb := scriptSigItems[len(scriptSigItems)-1]
+		h, err := Hash160(rb)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(h, b[2:2+ScriptHashLength]) {
+			return ErrTxInVerificationFailed
+		}
+
+		switch classifyScript(rb) {
+		case ScriptTypeP2wpkh, ScriptTypeP2wsh:
+			ok = len(scriptSigItems) == 1 &&
+				signer.verifyWitnessV0(idx, rb, witnessItems)
+		default:
+			ok = len(witnessItems) == 0 &&
+				signer.verifyStack(rb, scriptSigItems[:len(scriptSigItems)-1], signer.legacySigner(idx, rb))
+		}
+	case ScriptTypeP2wpkh, ScriptTypeP2wsh:
+		ok = len(scriptSigItems) == 0 &&
+			signer.verifyWitnessV0(idx, b, witnessItems)
+	case ScriptTypeP2tr:
+		ok = len(scriptSigItems) == 0 &&
+			signer.verifyTaproot(idx, b, txIn)
+	default:
+		return ErrUnsupportedScript
+	}
+
+	if !ok {
+		return ErrTxInVerificationFailed
+	}
+
+	return nil
+}
+
+func (signer *Signer) verifyWitnessV0(idx int, scriptPubKey []byte, items [][]byte) bool {
+	_, program, _ := extractWitnessProgram(scriptPubKey)
+
+	if len(program) == PkhLength {
+		scriptCode, err := NewP2pkhScript(Pkh(program))
+		if err != nil {
+			return false
+		}
+		sb, err := scriptCode.Bytes()
+		if err != nil {
+			return false
+		}
+
+		return len(items) == 2 &&
+			signer.verifyStack(sb, items, signer.witnessV0Signer(idx, sb))
+	}
+
+	if len(items) == 0 {
+		return false
+	}
+
+	wb := items[len(items)-1]
+	h, err := Sha256(wb)
+	if err != nil || !bytes.Equal(h, program) {
+		return false
+	}
+
+	return signer.verifyStack(wb, items[:len(items)-1], signer.witnessV0Signer(idx, wb))
+}
+
+func (signer *Signer) verifyTaproot(idx int, scriptPubKey []byte, txIn *TxIn) bool {
+	items, err := txIn.WitnessBytes()
+	if err != nil {
+		return false
+	}
+
+	annex, err := txIn.Annex()
+	if err != nil {
+		return false
+	}
+	if annex != nil {
+		items = items[:len(items)-1]
+	}
+
+	// only key path spends are supported
+	if len(items) != 1 {
+		return false
+	}
+
+	b := items[0]
+	hashType := SigHashDefault
+	switch len(b) {
+	case SchnorrSigLength:
+	case SchnorrSigLength + 1:
+		hashType = SigHashType(b[SchnorrSigLength])
+		if hashType == SigHashDefault {
+			return false
+		}
+		b = b[:SchnorrSigLength]
+	default:
+		return false
+	}
+
+	sig, err := NewSchnorrSignatureFromBytes(b)
+	if err != nil {
+		return false
+	}
+	outputKey, err := NewXOnlyPublicKeyFromBytes(scriptPubKey[2:])
+	if err != nil {
+		return false
+	}
+
+	hash, err := signer.tx.TaprootSigHash(signer.prevTxOuts(), idx, hashType, annex)
+	if err != nil {
+		return false
+	}
+
+	return outputKey.VerifySchnorr(hash, sig)
+}
+
+// verifyStack reports whether items satisfy a P2PK, P2PKH or multisig script.
+func (signer *Signer) verifyStack(script []byte, items [][]byte, hasher sigHasher) bool {
+	ops, err := parseScript(script)
+	if err != nil {
+		return false
+	}
+
+	if pubKey := extractP2pkPubKey(ops); pubKey != nil {
+		return len(items) == 1 && checkEcdsaSig(items[0], pubKey, hasher)
+	}
+
+	if pkh := extractP2pkhPkh(ops); pkh != nil {
+		if len(items) != 2 {
+			return false
+		}
+
+		h, err := Hash160(items[1])
+		if err != nil || !bytes.Equal(h, pkh) {
+			return false
+		}
+
+		return checkEcdsaSig(items[0], items[1], hasher)
+	}
+
+	if m, pubKeys, ok := extractMultisig(ops); ok {
+		if len(items) != m+1 || len(items[0]) != 0 {
+			return false
+		}
+
+		// signatures must appear in the same order as their public keys
+		sigs := items[1:]
+		for _, pubKey := range pubKeys {
+			if len(sigs) == 0 {
+				break
+			}
+			if checkEcdsaSig(sigs[0], pubKey, hasher) {
+				sigs = sigs[1:]
+			}
+		}
+
+		return len(sigs) == 0
+	}
+
+	return false
+}
+
+func checkEcdsaSig(b []byte, pubKeyBytes []byte, hasher sigHasher) bool {
+	if len(b) == 0 {
+		return false
+	}
+
+	sig, err := NewSignatureFromBytes(b[:len(b)-1])
+	if err != nil {
+		return false
+	}
+	pubKey, err := NewPublicKeyFromBytes(pubKeyBytes)
+	if err != nil {
+		return false
+	}
+
+	hash, err := hasher(SigHashType(b[len(b)-1]))
+	if err != nil {
+		return false
+	}
+
+	return pubKey.Verify(hash, sig)
+}
@@ -0,0 +1,322 @@
+package btc
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type signerTestCase struct {
+	name      string
+	prevOut   *PrevOut
+	scriptSig string
+	witness   []string
+}
+
+func signerTestCases(t *testing.T) ([]*signerTestCase, KeyRing) {
+	var keys KeyRing
+	for _, c := range []string{"11", "22", "33", "44", "55", "66", "77", "88", "99"} {
+		privKey, err := NewPrivateKeyFromHex(strings.Repeat(c, 32))
+		require.NoError(t, err)
+		keys = append(keys, privKey)
+	}
+	pubKey := func(i int) *PublicKey {
+		return keys[i-1].PublicKey()
+	}
+
+	mustScript := func(script *Script, err error) *Script {
+		require.NoError(t, err)
+		return script
+	}
+	pkh := func(pubKey *PublicKey) Pkh {
+		h, err := pubKey.Pkh()
+		require.NoError(t, err)
+		return h
+	}
+	hash160 := func(script *Script) []byte {
+		b, err := script.Bytes()
+		require.NoError(t, err)
+		h, err := Hash160(b)
+		require.NoError(t, err)
+		return h
+	}
+	sha256 := func(script *Script) []byte {
+		b, err := script.Bytes()
+		require.NoError(t, err)
+		h, err := Sha256(b)
+		require.NoError(t, err)
+		return h
+	}
+
+	multisig := mustScript(NewMultisigScript(2, []*PublicKey{pubKey(1), pubKey(2), pubKey(3)}))
+	p2wpkh := mustScript(NewP2wpkhScript(pkh(pubKey(4))))
+	witnessMultisig := mustScript(NewMultisigScript(1, []*PublicKey{pubKey(6), pubKey(7)}))
+	witnessP2pk := mustScript(NewP2pkScript(pubKey(6)))
+	p2wsh := mustScript(NewP2wshScript(sha256(witnessP2pk)))
+
+	return []*signerTestCase{
+		{
+			"p2pkh",
+			&PrevOut{
+				TxOut: NewTxOut(100000, mustScript(NewP2pkhScript(pkh(pubKey(1))))),
+			},
+			"483045022100b7be26134709186de7588a7c74d739dda79529992f9e4014b5a79680e4106b4502201cc25bfc11c1f0b960f69f3784415e20d08b9a2c9a2c785b80603166db0604600121034f355bdcb7cc0af728ef3cceb9615d90684bb5b2ca5f859ab0f0b704075871aa",
+			nil,
+		},
+		{
+			"p2pkh uncompressed",
+			&PrevOut{
+				TxOut: NewTxOut(110000, mustScript(NewP2pkhScript(pkh(pubKey(2).Uncompressed())))),
+			},
+			"47304402204c24a8da4020c4c34341fc25b71ee4132d38e1c1a35445627bb0b853c422b284022043f43e5aba5b4a335641cc8caedec07b803525458efd70f48c3e207c00380898014104466d7fcae563e5cb09a0d1870bb580344804617879a14949cf22285f1bae3f276728176c3c6431f8eeda4538dc37c865e2784f3a9e77d044f33e407797e1278a",
+			nil,
+		},
+		{
+			"p2sh multisig",
+			&PrevOut{
+				TxOut:        NewTxOut(120000, mustScript(NewP2shScript(hash160(multisig)))),
+				RedeemScript: multisig,
+			},
+			"00483045022100a42a6486aa7108111bdd0bd022cc41ad2e92222539ad73b8373bfd539ebd47c00220799492e3e1ba8ad1caa8f02aed31645aa2f9f63b0412c5c5456b4cb51c4fc36c014730440220385206218517c96a610cd8fb1f197bfa1a236023b68761149b543f36a117d65d02206a69c5071cfad594086ea0c3c2290cd0bee62e9cec30910b4f897691c6978021014c695221034f355bdcb7cc0af728ef3cceb9615d90684bb5b2ca5f859ab0f0b704075871aa2102466d7fcae563e5cb09a0d1870bb580344804617879a14949cf22285f1bae3f2721023c72addb4fdf09af94f0c94d7fe92a386a7e70cf8a1d85916386bb2535c7b1b153ae",
+			nil,
+		},
+		{
+			"p2sh-p2wpkh",
+			&PrevOut{
+				TxOut:        NewTxOut(130000, mustScript(NewP2shScript(hash160(p2wpkh)))),
+				RedeemScript: p2wpkh,
+			},
+			"160014cc1b07838e387deacd0e5232e1e8b49f4c29e484",
+			[]string{
+				"3045022100f83c5d1cbc401f7b56f109aaae6c6672cf4e8fbab0dde7b185ec7d299818151c022070def9f4166bbbd28850f67b2628cbee6ee521c6ae80b3ae3fa100560ac7660101",
+				"032c0b7cf95324a07d05398b240174dc0c2be444d96b159aa6c7f7b1e668680991",
+			},
+		},
+		{
+			"p2wpkh",
+			&PrevOut{
+				TxOut: NewTxOut(140000, mustScript(NewP2wpkhScript(pkh(pubKey(5))))),
+			},
+			"",
+			[]string{
+				"3045022100e5f2b0172703f28b04bcc24c3c6898a90f573e0f8b56b5c6de2df1f96609bb2202207ff2d82e4f6e824a107a3653190e5a8c0223cdf2ede78cd02988b7c5def462b401",
+				"029ac20335eb38768d2052be1dbbc3c8f6178407458e51e6b4ad22f1d91758895b",
+			},
+		},
+		{
+			"p2wsh multisig",
+			&PrevOut{
+				TxOut:         NewTxOut(150000, mustScript(NewP2wshScript(sha256(witnessMultisig)))),
+				WitnessScript: witnessMultisig,
+			},
+			"",
+			[]string{
+				"",
+				"3045022100dce23c17d29435ed7cab4877b15b3a9965de83ad93bd5040ea3ab6dfecfe494d02206a5d971f1c07d46dd1bf5f09619d0e4064cf84dfba275d3b3f8178582864a9c901",
+				"5121035ab4689e400a4a160cf01cd44730845a54768df8547dcdf073d964f109f18c3021037962d45b38e8bcf82fa8efa8432a01f20c9a53e24c7d3f11df197cb8e70926da52ae",
+			},
+		},
+		{
+			// schnorr signatures use fresh aux randomness, so only the shape is checked
+			"p2tr key path",
+			&PrevOut{
+				TxOut:          NewTxOut(160000, &Script{Hex: "5120ab18c190681e65cf7a054cbc85ea72aeeaadb4e41f4022a9132977c7d7061a8b"}),
+				TapInternalKey: pubKey(8).XOnly(),
+			},
+			"",
+			nil,
+		},
+		{
+			"p2pk",
+			&PrevOut{
+				TxOut: NewTxOut(170000, mustScript(NewP2pkScript(pubKey(9)))),
+			},
+			"483045022100c26c8762cf87aa4a6fd3a87c36db411c67ea72baec16a36e523c70c40d4333640220432b79c792a65081cea3715bca3428fe005084499ccb4cec52a2a124691a264501",
+			nil,
+		},
+		{
+			"p2sh-p2wsh",
+			&PrevOut{
+				TxOut:         NewTxOut(180000, mustScript(NewP2shScript(hash160(p2wsh)))),
+				RedeemScript:  p2wsh,
+				WitnessScript: witnessP2pk,
+			},
+			"22002086831a0200acb672202222fecd593760a5fab3be67a77d9e36349414c743a8d8",
+			[]string{
+				"3044022015d0a565022a77928977fef06c09556bb7578b3c4fe78d9415292168bac5bed402202669eb0f9debf892eaf5ce02f1728cc9d3b4eeac3473f1615f406fc94049da0901",
+				"21035ab4689e400a4a160cf01cd44730845a54768df8547dcdf073d964f109f18c30ac",
+			},
+		},
+	}, keys
+}
+
+func newSignerTestTx(t *testing.T, testCases []*signerTestCase) (*Tx, []*PrevOut) {
+	tx := NewTx()
+	tx.Version = 2
+
+	prevOuts := make([]*PrevOut, len(testCases))
+	for i, tc := range testCases {
+		tx.AddTxIn(NewTxIn(fmt.Sprintf("%064x", i+1), uint32(i), nil))
+		prevOuts[i] = tc.prevOut
+	}
+
+	script, err := NewScriptFromHex("0014e1fae3324e28a4ef5ee01f14dd337ac6c85d1d90")
+	require.NoError(t, err)
+	tx.AddTxOut(NewTxOut(1000000, script))
+
+	return tx, prevOuts
+}
+
+func TestSigner(t *testing.T) {
+	testCases, keys := signerTestCases(t)
+	tx, prevOuts := newSignerTestTx(t, testCases)
+
+	signer, err := NewSigner(tx, prevOuts, keys)
+	require.NoError(t, err)
+	require.NoError(t, signer.Sign())
+
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			txIn := tx.TxIns[i]
+
+			if tc.name == "p2tr key path" {
+				assert.Equal(t, txIn.Script.Hex, "")
+				require.Len(t, txIn.Witness, 1)
+				assert.Len(t, txIn.Witness[0], 2*SchnorrSigLength)
+				return
+			}
+
+			assert.Equal(t, txIn.Script.Hex, tc.scriptSig)
+			assert.Equal(t, txIn.Witness, tc.witness)
+		})
+	}
+
+	// round trip through serialization
+	txHex, err := tx.Hex()
+	require.NoError(t, err)
+	decodedTx, err := NewTxFromHex(txHex)
+	require.NoError(t, err)
+	signer, err = NewSigner(decodedTx, prevOuts, keys)
+	require.NoError(t, err)
+	assert.NoError(t, signer.Verify())
+}
+
+func TestSignerHashType(t *testing.T) {
+	testCases, keys := signerTestCases(t)
+
+	for _, hashType := range []SigHashType{
+		SigHashAll | SigHashAnyoneCanPay,
+		SigHashNone,
+		SigHashNone | SigHashAnyoneCanPay,
+	} {
+		t.Run(fmt.Sprintf("%02x", hashType), func(t *testing.T) {
+			tx, prevOuts := newSignerTestTx(t, testCases)
+
+			signer, err := NewSigner(tx, prevOuts, keys)
+			require.NoError(t, err)
+			signer.SetHashType(hashType)
+			require.NoError(t, signer.Sign())
+
+			b, err := tx.TxIns[0].Script.Bytes()
+			require.NoError(t, err)
+			items, err := parsePushOnlyScript(b)
+			require.NoError(t, err)
+			sig := items[0]
+			assert.Equal(t, SigHashType(sig[len(sig)-1]), hashType)
+
+			witness, err := tx.TxIns[6].WitnessBytes()
+			require.NoError(t, err)
+			sig = witness[0]
+			require.Len(t, sig, SchnorrSigLength+1)
+			assert.Equal(t, SigHashType(sig[SchnorrSigLength]), hashType)
+		})
+	}
+}
+
+func TestSignerErrors(t *testing.T) {
+	testCases, keys := signerTestCases(t)
+
+	t.Run("prev outs mismatch", func(t *testing.T) {
+		tx, prevOuts := newSignerTestTx(t, testCases)
+
+		_, err := NewSigner(tx, prevOuts[1:], keys)
+		assert.Equal(t, err, ErrPrevOutsMismatch)
+	})
+
+	t.Run("private key not found", func(t *testing.T) {
+		tx, prevOuts := newSignerTestTx(t, testCases[:1])
+
+		signer, err := NewSigner(tx, prevOuts, keys[1:])
+		require.NoError(t, err)
+		assert.Equal(t, signer.Sign(), ErrPrivateKeyNotFound)
+	})
+
+	t.Run("not enough signatures", func(t *testing.T) {
+		tx, prevOuts := newSignerTestTx(t, testCases[2:3])
+
+		signer, err := NewSigner(tx, prevOuts, keys[2:])
+		require.NoError(t, err)
+		assert.Equal(t, signer.Sign(), ErrNotEnoughSignatures)
+	})
+
+	t.Run("redeem script not found", func(t *testing.T) {
+		tx, _ := newSignerTestTx(t, testCases[2:3])
+		prevOuts := []*PrevOut{NewPrevOut(testCases[2].prevOut.TxOut)}
+
+		signer, err := NewSigner(tx, prevOuts, keys)
+		require.NoError(t, err)
+		assert.Equal(t, signer.Sign(), ErrRedeemScriptNotFound)
+	})
+
+	t.Run("script hash mismatch", func(t *testing.T) {
+		tx, _ := newSignerTestTx(t, testCases[2:3])
+		prevOuts := []*PrevOut{{
+			TxOut:        testCases[2].prevOut.TxOut,
+			RedeemScript: testCases[3].prevOut.RedeemScript,
+		}}
+
+		signer, err := NewSigner(tx, prevOuts, keys)
+		require.NoError(t, err)
+		assert.Equal(t, signer.Sign(), ErrScriptHashMismatch)
+	})
+
+	t.Run("taproot output key mismatch", func(t *testing.T) {
+		tx, _ := newSignerTestTx(t, testCases[6:7])
+		prevOuts := []*PrevOut{{
+			TxOut:          testCases[6].prevOut.TxOut,
+			TapInternalKey: keys[0].PublicKey().XOnly(),
+		}}
+
+		signer, err := NewSigner(tx, prevOuts, keys)
+		require.NoError(t, err)
+		assert.Equal(t, signer.Sign(), ErrTaprootOutputKeyMismatch)
+	})
+
+	t.Run("unsupported script", func(t *testing.T) {
+		script, err := NewNullDataScript([]byte("hello"))
+		require.NoError(t, err)
+
+		tx, _ := newSignerTestTx(t, testCases[:1])
+		prevOuts := []*PrevOut{NewPrevOut(NewTxOut(0, script))}
+
+		signer, err := NewSigner(tx, prevOuts, keys)
+		require.NoError(t, err)
+		assert.Equal(t, signer.Sign(), ErrUnsupportedScript)
+	})
+
+	t.Run("tampered tx", func(t *testing.T) {
+		tx, prevOuts := newSignerTestTx(t, testCases)
+
+		signer, err := NewSigner(tx, prevOuts, keys)
+		require.NoError(t, err)
+		require.NoError(t, signer.Sign())
+
+		tx.TxOuts[0].Amount++
+		for i := range tx.TxIns {
+			assert.Equal(t, signer.VerifyTxIn(i), ErrTxInVerificationFailed)
+		}
+	})
+}
@@ -0,0 +1,256 @@
+package btc
+
+const (
+	// the limits of witnesses Bitcoin Core relays
+	MaxStandardP2wshScriptSize        = 3600
+	MaxStandardP2wshStackItems        = 100
+	MaxStandardP2wshStackItemSize     = 80
+	MaxStandardTapscriptStackItemSize = 80
+)
+
+const (
+	RejectVersion            RejectReason = "version"
+	RejectTxSize             RejectReason = "tx-size"
+	RejectTxSizeSmall        RejectReason = "tx-size-small"
+	RejectScriptSigSize      RejectReason = "scriptsig-size"
+	RejectScriptSigPushOnly  RejectReason = "scriptsig-not-pushonly"
+	RejectScriptPubKey       RejectReason = "scriptpubkey"
+	RejectBareMultisig       RejectReason = "bare-multisig"
+	RejectDust               RejectReason = "dust"
+	RejectMultiOpReturn      RejectReason = "multi-op-return"
+	RejectWitnessNonStandard RejectReason = "bad-witness-nonstandard"
+)
+
+// RelayPolicy is the policy txs must follow to be relayed by nodes.
+type RelayPolicy struct {
+	MinVersion int32
+	MaxVersion int32
+	MaxWeight  int
+	// the least size of txs without witnesses, which prevents 64-byte txs confused with merkle tree nodes
+	MinNonWitnessSize int
+	MaxScriptSigSize  int
+	// the fee rate dust outputs are determined with
+	DustRelayFeeRate FeeRate
+	// whether a null data output is allowed, and the largest size of its script
+	DataCarrier        bool
+	MaxDataCarrierSize int
+	PermitBareMultisig bool
+}
+
+// DefaultRelayPolicy returns the default policy of Bitcoin Core.
+// ref. https://github.com/bitcoin/bitcoin/blob/master/src/policy/policy.h
+func DefaultRelayPolicy() *RelayPolicy {
+	return &RelayPolicy{
+		MinVersion:         1,
+		MaxVersion:         3,
+		MaxWeight:          400000,
+		MinNonWitnessSize:  65,
+		MaxScriptSigSize:   1650,
+		DustRelayFeeRate:   DustRelayFeeRate,
+		DataCarrier:        true,
+		MaxDataCarrierSize: 83,
+		PermitBareMultisig: true,
+	}
+}
+
+// IsStandardTx checks the tx against policy, the default one if nil, as Bitcoin Core does,
+// returning a RejectError if nodes do not relay the tx.
+// The witnesses of the tx ins are checked against the tx outs they spend, so tx ins with witnesses need PrevTxOut.
+// ref. https://github.com/bitcoin/bitcoin/blob/master/src/policy/policy.cpp
+func IsStandardTx(tx *Tx, policy *RelayPolicy) error {
+	if policy == nil {
+		policy = DefaultRelayPolicy()
+	}
+
+	if tx.Version < policy.MinVersion || tx.Version > policy.MaxVersion {
+		return newRejectError(RejectVersion)
+	}
+
+	weight, err := tx.Weight()
+	if err != nil {
+		return err
+	}
+	if weight > policy.MaxWeight {
+		return newRejectError(RejectTxSize)
+	}
+
+	strippedBytes, err := tx.StrippedBytes()
+	if err != nil {
+		return err
+	}
+	if len(strippedBytes) < policy.MinNonWitnessSize {
+		return newRejectError(RejectTxSizeSmall)
+	}
+
+	for _, txIn := range tx.TxIns {
+		b, err := txIn.Script.Bytes()
+		if err != nil {
+			return err
+		}
+		if len(b) > policy.MaxScriptSigSize {
+			return newRejectError(RejectScriptSigSize)
+		}
+		if !isPushOnlyScript(b) {
+			return newRejectError(RejectScriptSigPushOnly)
+		}
+	}
+
+	nullDataCount := 0
+	for _, txOut := range tx.TxOuts {
+		b, err := txOut.Script.Bytes()
+		if err != nil {
+			return err
+		}
+
+		scriptType, ok := policy.standardScriptType(b)
+		if !ok {
+			return newRejectError(RejectScriptPubKey)
+		}
+
+		switch scriptType {
+		case ScriptTypeNullData:
+			nullDataCount++
+		case ScriptTypeMultisig:
+			if !policy.PermitBareMultisig {
+				return newRejectError(RejectBareMultisig)
+			}
+		}
+
+		isDust, err := txOut.IsDust(policy.DustRelayFeeRate)
+		if err != nil {
+			return err
+		}
+		if isDust {
+			return newRejectError(RejectDust)
+		}
+	}
+
+	if nullDataCount > 1 {
+		return newRejectError(RejectMultiOpReturn)
+	}
+
+	return isWitnessStandard(tx)
+}
+
+// standardScriptType returns the type of the script of a tx out and whether the type is standard.
+func (policy *RelayPolicy) standardScriptType(b []byte) (ScriptType, bool) {
+	scriptType := classifyScript(b)
+
+	switch scriptType {
+	case ScriptTypeNonStandard:
+		return scriptType, false
+	case ScriptTypeMultisig:
+		ops, err := parseScript(b)
+		if err != nil {
+			return scriptType, false
+		}
+
+		m, pubKeys, _ := extractMultisig(ops)
+		return scriptType, m >= 1 && len(pubKeys) <= maxStandardBareMultisigPubKeys
+	case ScriptTypeNullData:
+		return scriptType, policy.DataCarrier && len(b) <= policy.MaxDataCarrierSize
+	default:
+		return scriptType, true
+	}
+}
+
+// isPushOnlyScript reports whether the script consists only of push operations, including OP_RESERVED as Bitcoin Core does.
+func isPushOnlyScript(b []byte) bool {
+	ops, err := parseScript(b)
+	if err != nil {
+		return false
+	}
+
+	for _, op := range ops {
+		if op.op > Op16 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isWitnessStandard checks the sizes of the witness items and the annexes, which consensus rules allow.
+func isWitnessStandard(tx *Tx) error {
+	if tx.IsCoinbase() {
+		return nil
+	}
+
+	for _, txIn := range tx.TxIns {
+		if !txIn.HasWitness() {
+			continue
+		}
+		if txIn.PrevTxOut == nil {
+			return ErrPrevTxOutNotFound
+		}
+
+		b, err := txIn.PrevTxOut.Script.Bytes()
+		if err != nil {
+			return err
+		}
+
+		isP2sh := classifyScript(b) == ScriptTypeP2sh
+		if isP2sh {
+			scriptSig, err := txIn.Script.Bytes()
+			if err != nil {
+				return err
+			}
+
+			items, err := parsePushOnlyScript(scriptSig)
+			if err != nil || len(items) == 0 {
+				return newRejectError(RejectWitnessNonStandard)
+			}
+			b = items[len(items)-1]
+		}
+
+		version, program, ok := extractWitnessProgram(b)
+		if !ok {
+			return newRejectError(RejectWitnessNonStandard)
+		}
+
+		items, err := txIn.WitnessBytes()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case version == 0 && len(program) == WitnessScriptHashLength:
+			if len(items[len(items)-1]) > MaxStandardP2wshScriptSize {
+				return newRejectError(RejectWitnessNonStandard)
+			}
+			if len(items)-1 > MaxStandardP2wshStackItems {
+				return newRejectError(RejectWitnessNonStandard)
+			}
+			for _, item := range items[:len(items)-1] {
+				if len(item) > MaxStandardP2wshStackItemSize {
+					return newRejectError(RejectWitnessNonStandard)
+				}
+			}
+		case version == 1 && len(program) == XOnlyPubKeyLength && !isP2sh:
+			annex, err := txIn.Annex()
+			if err != nil {
+				return err
+			}
+			if annex != nil {
+				return newRejectError(RejectWitnessNonStandard)
+			}
+
+			// script path spends, whose last items are the script and the control block
+			if len(items) >= 2 {
+				controlBlock := items[len(items)-1]
+				if len(controlBlock) == 0 {
+					return newRejectError(RejectWitnessNonStandard)
+				}
+				if controlBlock[0]&tapLeafVersionMask == TapLeafVersionTapscript {
+					for _, item := range items[:len(items)-2] {
+						if len(item) > MaxStandardTapscriptStackItemSize {
+							return newRejectError(RejectWitnessNonStandard)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
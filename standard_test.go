@@ -0,0 +1,206 @@
+package btc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStandardTestTx(t *testing.T) *Tx {
+	testCases, keys := signerTestCases(t)
+	tx, prevOuts := newSignerTestTx(t, testCases)
+
+	signer, err := NewSigner(tx, prevOuts, keys)
+	require.NoError(t, err)
+	require.NoError(t, signer.Sign())
+
+	txOuts := make([]*TxOut, len(prevOuts))
+	for i, prevOut := range prevOuts {
+		txOuts[i] = prevOut.TxOut
+	}
+	require.NoError(t, tx.SetPrevTxOuts(txOuts))
+
+	return tx
+}
+
+func TestIsStandardTx(t *testing.T) {
+	require.NoError(t, IsStandardTx(newStandardTestTx(t), nil))
+
+	multisig := func(m int, n int) *Script {
+		pubKeys := make([]*PublicKey, n)
+		for i := range pubKeys {
+			privKey, err := NewPrivateKeyFromHex(strings.Repeat("11", 32))
+			require.NoError(t, err)
+			pubKeys[i] = privKey.PublicKey()
+		}
+
+		script, err := NewMultisigScript(m, pubKeys)
+		require.NoError(t, err)
+		return script
+	}
+
+	testCases := []struct {
+		name   string
+		modify func(tx *Tx, policy *RelayPolicy)
+		reason RejectReason
+	}{
+		{
+			"version too high",
+			func(tx *Tx, policy *RelayPolicy) { tx.Version = 4 },
+			RejectVersion,
+		},
+		{
+			"version too low",
+			func(tx *Tx, policy *RelayPolicy) { tx.Version = 0 },
+			RejectVersion,
+		},
+		{
+			"too heavy",
+			func(tx *Tx, policy *RelayPolicy) { policy.MaxWeight = 1000 },
+			RejectTxSize,
+		},
+		{
+			"too small",
+			func(tx *Tx, policy *RelayPolicy) {
+				tx.TxIns = tx.TxIns[4:5]
+				tx.TxOuts = []*TxOut{NewTxOut(0, &Script{Hex: "6a"})}
+			},
+			RejectTxSizeSmall,
+		},
+		{
+			"too large scriptSig",
+			func(tx *Tx, policy *RelayPolicy) { policy.MaxScriptSigSize = 100 },
+			RejectScriptSigSize,
+		},
+		{
+			"non push only scriptSig",
+			func(tx *Tx, policy *RelayPolicy) { tx.TxIns[0].Script = &Script{Hex: "0076"} },
+			RejectScriptSigPushOnly,
+		},
+		{
+			"non-standard script",
+			func(tx *Tx, policy *RelayPolicy) { tx.TxOuts[0].Script = &Script{Hex: "51"} },
+			RejectScriptPubKey,
+		},
+		{
+			"multisig of too many public keys",
+			func(tx *Tx, policy *RelayPolicy) { tx.TxOuts[0].Script = multisig(1, 4) },
+			RejectScriptPubKey,
+		},
+		{
+			"bare multisig",
+			func(tx *Tx, policy *RelayPolicy) {
+				tx.TxOuts[0].Script = multisig(1, 3)
+				policy.PermitBareMultisig = false
+			},
+			RejectBareMultisig,
+		},
+		{
+			"dust",
+			func(tx *Tx, policy *RelayPolicy) { tx.TxOuts[0].Amount = 293 },
+			RejectDust,
+		},
+		{
+			"dust at a higher dust relay fee rate",
+			func(tx *Tx, policy *RelayPolicy) {
+				tx.TxOuts[0].Amount = 294
+				policy.DustRelayFeeRate = 3001
+			},
+			RejectDust,
+		},
+		{
+			"too large null data",
+			func(tx *Tx, policy *RelayPolicy) {
+				tx.AddTxOut(NewTxOut(0, &Script{Hex: "6a4c51" + strings.Repeat("00", 81)}))
+			},
+			RejectScriptPubKey,
+		},
+		{
+			"null data disabled",
+			func(tx *Tx, policy *RelayPolicy) {
+				tx.AddTxOut(NewTxOut(0, &Script{Hex: "6a"}))
+				policy.DataCarrier = false
+			},
+			RejectScriptPubKey,
+		},
+		{
+			"multiple null data",
+			func(tx *Tx, policy *RelayPolicy) {
+				tx.AddTxOut(NewTxOut(0, &Script{Hex: "6a"}))
+				tx.AddTxOut(NewTxOut(0, &Script{Hex: "6a"}))
+			},
+			RejectMultiOpReturn,
+		},
+		{
+			"witness spending a non-witness output",
+			func(tx *Tx, policy *RelayPolicy) { tx.TxIns[0].Witness = []string{"00"} },
+			RejectWitnessNonStandard,
+		},
+		{
+			"too large p2wsh stack item",
+			func(tx *Tx, policy *RelayPolicy) {
+				tx.TxIns[5].Witness[0] = strings.Repeat("00", MaxStandardP2wshStackItemSize+1)
+			},
+			RejectWitnessNonStandard,
+		},
+		{
+			"too many p2wsh stack items",
+			func(tx *Tx, policy *RelayPolicy) {
+				items := make([]string, MaxStandardP2wshStackItems)
+				tx.TxIns[5].Witness = append(items, tx.TxIns[5].Witness...)
+			},
+			RejectWitnessNonStandard,
+		},
+		{
+			"too large p2wsh script",
+			func(tx *Tx, policy *RelayPolicy) {
+				tx.TxIns[5].Witness[2] = strings.Repeat("00", MaxStandardP2wshScriptSize+1)
+			},
+			RejectWitnessNonStandard,
+		},
+		{
+			"too large p2sh-p2wsh stack item",
+			func(tx *Tx, policy *RelayPolicy) {
+				tx.TxIns[8].Witness[0] = strings.Repeat("00", MaxStandardP2wshStackItemSize+1)
+			},
+			RejectWitnessNonStandard,
+		},
+		{
+			"taproot annex",
+			func(tx *Tx, policy *RelayPolicy) { tx.TxIns[6].Witness = append(tx.TxIns[6].Witness, "50") },
+			RejectWitnessNonStandard,
+		},
+		{
+			"too large tapscript stack item",
+			func(tx *Tx, policy *RelayPolicy) {
+				tx.TxIns[6].Witness = []string{strings.Repeat("00", MaxStandardTapscriptStackItemSize+1), "51", "c0" + strings.Repeat("11", 32)}
+			},
+			RejectWitnessNonStandard,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tx := newStandardTestTx(t)
+			policy := DefaultRelayPolicy()
+			tc.modify(tx, policy)
+
+			assert.Equal(t, IsStandardTx(tx, policy), &RejectError{tc.reason})
+		})
+	}
+
+	// null data of the largest size
+	tx := newStandardTestTx(t)
+	tx.AddTxOut(NewTxOut(0, &Script{Hex: "6a4c50" + strings.Repeat("00", 80)}))
+	require.NoError(t, IsStandardTx(tx, nil))
+
+	// tapscript stack items of the other leaf versions are not limited
+	tx = newStandardTestTx(t)
+	tx.TxIns[6].Witness = []string{strings.Repeat("00", MaxStandardTapscriptStackItemSize+1), "51", "c2" + strings.Repeat("11", 32)}
+	require.NoError(t, IsStandardTx(tx, nil))
+
+	tx.TxIns[6].PrevTxOut = nil
+	assert.Equal(t, IsStandardTx(tx, nil), ErrPrevTxOutNotFound)
+}
@@ -0,0 +1,373 @@
+package btc
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+)
+
+const (
+	TagTapLeaf   = "TapLeaf"
+	TagTapBranch = "TapBranch"
+	TagTapTweak  = "TapTweak"
+
+	TapLeafVersionTapscript byte = 0xc0
+
+	TapControlBlockBaseSize      = 33
+	TapControlBlockNodeSize      = 32
+	TapControlBlockMaxDepth      = 128
+	tapLeafVersionMask      byte = 0xfe
+	tapOutputKeyParityMask  byte = 0x01
+)
+
+var (
+	ErrInvalidTapMerkleRoot  = errors.New("invalid tap merkle root")
+	ErrInvalidTapLeafVersion = errors.New("invalid tap leaf version")
+	ErrInvalidTapTreeWeights = errors.New("invalid tap tree weights")
+	ErrEmptyTapTree          = errors.New("empty tap tree")
+	ErrTapTreeTooDeep        = errors.New("tap tree too deep")
+	ErrTapLeafNotFound       = errors.New("tap leaf not found")
+	ErrInvalidControlBlock   = errors.New("invalid control block")
+)
+
+// TapLeafHash returns the hash of a script path leaf.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0341.mediawiki#constructing-and-spending-taproot-outputs
+func TapLeafHash(leafVersion byte, script []byte) ([]byte, error) {
+	if leafVersion&tapLeafVersionMask != leafVersion {
+		return nil, ErrInvalidTapLeafVersion
+	}
+
+	w := newWriter()
+	if err := w.WriteByte(leafVersion); err != nil {
+		return nil, err
+	}
+	if err := w.writeVarBytes(script); err != nil {
+		return nil, err
+	}
+
+	return TaggedHash(TagTapLeaf, w.Bytes())
+}
+
+// TapBranchHash returns the hash of a branch, whose children are sorted before hashing.
+func TapBranchHash(a, b []byte) ([]byte, error) {
+	if bytes.Compare(a, b) > 0 {
+		a, b = b, a
+	}
+
+	return TaggedHash(TagTapBranch, concatBytes(a, b))
+}
+
+// TaprootTweak returns the tweak committing internalKey to merkleRoot,
+// which is nil for outputs without script paths.
+func TaprootTweak(internalKey *XOnlyPublicKey, merkleRoot []byte) ([]byte, error) {
+	if merkleRoot != nil && len(merkleRoot) != 32 {
+		return nil, ErrInvalidTapMerkleRoot
+	}
+
+	return TaggedHash(TagTapTweak, concatBytes(internalKey.Bytes(), merkleRoot))
+}
+
+// TaprootOutputKey returns the output key of a P2TR output.
+func TaprootOutputKey(internalKey *XOnlyPublicKey, merkleRoot []byte) (*XOnlyPublicKey, error) {
+	pubKey, err := taprootTweakPublicKey(internalKey, merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	return pubKey.XOnly(), nil
+}
+
+// taprootTweakPublicKey returns the full output key, whose Y parity goes into control blocks.
+func taprootTweakPublicKey(internalKey *XOnlyPublicKey, merkleRoot []byte) (*PublicKey, error) {
+	t, err := TaprootTweak(internalKey, merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	return internalKey.PublicKey().TweakAdd(t)
+}
+
+// TaprootTweakPrivateKey returns the private key of the output key for key path spends.
+func TaprootTweakPrivateKey(privKey *PrivateKey, merkleRoot []byte) (*PrivateKey, error) {
+	pubKey := privKey.PublicKey()
+
+	t, err := TaprootTweak(pubKey.XOnly(), merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	if !pubKey.hasEvenY() {
+		privKey = &PrivateKey{new(big.Int).Sub(secp256k1.N, privKey.d)}
+	}
+
+	return privKey.TweakAdd(t)
+}
+
+// TapLeaf is a script path of a taproot output.
+type TapLeaf struct {
+	Version byte
+	Script  *Script
+}
+
+// NewTapLeaf returns a tapscript leaf.
+func NewTapLeaf(script *Script) *TapLeaf {
+	return &TapLeaf{
+		Version: TapLeafVersionTapscript,
+		Script:  script,
+	}
+}
+
+func (leaf *TapLeaf) Hash() ([]byte, error) {
+	b, err := leaf.Script.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return TapLeafHash(leaf.Version, b)
+}
+
+// TapTree is a binary tree of script paths, either a single leaf or a branch of two subtrees.
+type TapTree struct {
+	leaf        *TapLeaf
+	left, right *TapTree
+	hash        []byte
+	depth       int
+}
+
+func NewTapTreeLeaf(leaf *TapLeaf) (*TapTree, error) {
+	h, err := leaf.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	return &TapTree{
+		leaf: leaf,
+		hash: h,
+	}, nil
+}
+
+func NewTapTreeBranch(left, right *TapTree) (*TapTree, error) {
+	depth := left.depth
+	if right.depth > depth {
+		depth = right.depth
+	}
+	depth++
+	if depth > TapControlBlockMaxDepth {
+		return nil, ErrTapTreeTooDeep
+	}
+
+	h, err := TapBranchHash(left.hash, right.hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TapTree{
+		left:  left,
+		right: right,
+		hash:  h,
+		depth: depth,
+	}, nil
+}
+
+// NewHuffmanTapTree builds a tree in which leaves with higher weights, e.g. the expected
+// spending frequencies, are closer to the root and so have shorter control blocks.
+// The two lightest subtrees are merged first; ties are broken by the order of leaves.
+func NewHuffmanTapTree(leaves []*TapLeaf, weights []uint32) (*TapTree, error) {
+	if len(leaves) == 0 {
+		return nil, ErrEmptyTapTree
+	}
+	if len(leaves) != len(weights) {
+		return nil, ErrInvalidTapTreeWeights
+	}
+
+	type weightedTree struct {
+		tree   *TapTree
+		weight uint64
+	}
+
+	queue := make([]*weightedTree, len(leaves))
+	for i, leaf := range leaves {
+		tree, err := NewTapTreeLeaf(leaf)
+		if err != nil {
+			return nil, err
+		}
+		queue[i] = &weightedTree{tree, uint64(weights[i])}
+	}
+
+	popLightest := func() *weightedTree {
+		min := 0
+		for i, wt := range queue {
+			if wt.weight < queue[min].weight {
+				min = i
+			}
+		}
+		wt := queue[min]
+		queue = append(queue[:min], queue[min+1:]...)
+		return wt
+	}
+
+	for len(queue) > 1 {
+		a := popLightest()
+		b := popLightest()
+
+		tree, err := NewTapTreeBranch(a.tree, b.tree)
+		if err != nil {
+			return nil, err
+		}
+		queue = append(queue, &weightedTree{tree, a.weight + b.weight})
+	}
+
+	return queue[0].tree, nil
+}
+
+// MerkleRoot returns the root hash the output key commits to.
+func (tree *TapTree) MerkleRoot() []byte {
+	return tree.hash
+}
+
+// Leaves returns the leaves from left to right.
+func (tree *TapTree) Leaves() []*TapLeaf {
+	if tree.leaf != nil {
+		return []*TapLeaf{tree.leaf}
+	}
+
+	return append(tree.left.Leaves(), tree.right.Leaves()...)
+}
+
+// merklePath returns the hashes of the siblings on the path from the leaf of leafHash up to the root.
+func (tree *TapTree) merklePath(leafHash []byte) ([][]byte, bool) {
+	if tree.leaf != nil {
+		return nil, bytes.Equal(tree.hash, leafHash)
+	}
+
+	if path, ok := tree.left.merklePath(leafHash); ok {
+		return append(path, tree.right.hash), true
+	}
+	if path, ok := tree.right.merklePath(leafHash); ok {
+		return append(path, tree.left.hash), true
+	}
+
+	return nil, false
+}
+
+// Address returns the P2TR address of internalKey committing to the tree.
+func (tree *TapTree) Address(internalKey *XOnlyPublicKey, params *Params) (Address, error) {
+	return NewP2trAddress(internalKey, tree.MerkleRoot(), params)
+}
+
+// ControlBlock returns the control block for spending leaf of the output of internalKey committing to the tree.
+func (tree *TapTree) ControlBlock(internalKey *XOnlyPublicKey, leaf *TapLeaf) (*ControlBlock, error) {
+	leafHash, err := leaf.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	path, ok := tree.merklePath(leafHash)
+	if !ok {
+		return nil, ErrTapLeafNotFound
+	}
+
+	outputKey, err := taprootTweakPublicKey(internalKey, tree.MerkleRoot())
+	if err != nil {
+		return nil, err
+	}
+
+	return &ControlBlock{
+		LeafVersion:     leaf.Version,
+		OutputKeyYIsOdd: !outputKey.hasEvenY(),
+		InternalKey:     internalKey,
+		MerklePath:      path,
+	}, nil
+}
+
+// ControlBlock is the last witness element of a script path spend.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0341.mediawiki#script-validation-rules
+type ControlBlock struct {
+	LeafVersion     byte
+	OutputKeyYIsOdd bool
+	InternalKey     *XOnlyPublicKey
+	MerklePath      [][]byte
+}
+
+func NewControlBlockFromBytes(b []byte) (*ControlBlock, error) {
+	l := len(b)
+	if l < TapControlBlockBaseSize ||
+		l > TapControlBlockBaseSize+TapControlBlockNodeSize*TapControlBlockMaxDepth ||
+		(l-TapControlBlockBaseSize)%TapControlBlockNodeSize != 0 {
+		return nil, ErrInvalidControlBlock
+	}
+
+	internalKey, err := NewXOnlyPublicKeyFromBytes(b[1:TapControlBlockBaseSize])
+	if err != nil {
+		return nil, ErrInvalidControlBlock
+	}
+
+	path := make([][]byte, 0, (l-TapControlBlockBaseSize)/TapControlBlockNodeSize)
+	for i := TapControlBlockBaseSize; i < l; i += TapControlBlockNodeSize {
+		path = append(path, append([]byte{}, b[i:i+TapControlBlockNodeSize]...))
+	}
+
+	return &ControlBlock{
+		LeafVersion:     b[0] & tapLeafVersionMask,
+		OutputKeyYIsOdd: b[0]&tapOutputKeyParityMask == 1,
+		InternalKey:     internalKey,
+		MerklePath:      path,
+	}, nil
+}
+
+func (cb *ControlBlock) Bytes() []byte {
+	first := cb.LeafVersion
+	if cb.OutputKeyYIsOdd {
+		first |= tapOutputKeyParityMask
+	}
+
+	b := append([]byte{first}, cb.InternalKey.Bytes()...)
+	for _, node := range cb.MerklePath {
+		b = append(b, node...)
+	}
+
+	return b
+}
+
+// MerkleRoot returns the root hash computed from script and the merkle path.
+func (cb *ControlBlock) MerkleRoot(script *Script) ([]byte, error) {
+	leaf := &TapLeaf{
+		Version: cb.LeafVersion,
+		Script:  script,
+	}
+
+	h, err := leaf.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, node := range cb.MerklePath {
+		if len(node) != TapControlBlockNodeSize {
+			return nil, ErrInvalidControlBlock
+		}
+		if h, err = TapBranchHash(h, node); err != nil {
+			return nil, err
+		}
+	}
+
+	return h, nil
+}
+
+// Verify reports whether script is committed to by outputKey through the control block.
+func (cb *ControlBlock) Verify(outputKey *XOnlyPublicKey, script *Script) bool {
+	if len(cb.MerklePath) > TapControlBlockMaxDepth {
+		return false
+	}
+
+	merkleRoot, err := cb.MerkleRoot(script)
+	if err != nil {
+		return false
+	}
+
+	pubKey, err := taprootTweakPublicKey(cb.InternalKey, merkleRoot)
+	if err != nil {
+		return false
+	}
+
+	return pubKey.XOnly().IsEqual(outputKey) && pubKey.hasEvenY() != cb.OutputKeyYIsOdd
+}
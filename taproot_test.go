@@ -0,0 +1,280 @@
+package btc
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testTapLeaves(t *testing.T, scripts ...string) []*TapLeaf {
+	leaves := make([]*TapLeaf, len(scripts))
+	for i, s := range scripts {
+		script, err := NewScriptFromHex(s)
+		require.NoError(t, err)
+
+		leaves[i] = NewTapLeaf(script)
+	}
+
+	return leaves
+}
+
+// testBip341TapTree builds the tree of a scriptTree of the BIP341 wallet test vectors,
+// returning its leaves from left to right.
+func testBip341TapTree(t *testing.T, b []byte) (*TapTree, []*TapLeaf) {
+	var branch []json.RawMessage
+	if err := json.Unmarshal(b, &branch); err == nil {
+		require.Len(t, branch, 2)
+
+		left, leftLeaves := testBip341TapTree(t, branch[0])
+		right, rightLeaves := testBip341TapTree(t, branch[1])
+
+		tree, err := NewTapTreeBranch(left, right)
+		require.NoError(t, err)
+
+		return tree, append(leftLeaves, rightLeaves...)
+	}
+
+	var leaf struct {
+		Script      string `json:"script"`
+		LeafVersion byte   `json:"leafVersion"`
+	}
+	require.NoError(t, json.Unmarshal(b, &leaf))
+
+	script, err := NewScriptFromHex(leaf.Script)
+	require.NoError(t, err)
+
+	tapLeaf := &TapLeaf{
+		Version: leaf.LeafVersion,
+		Script:  script,
+	}
+
+	tree, err := NewTapTreeLeaf(tapLeaf)
+	require.NoError(t, err)
+
+	return tree, []*TapLeaf{tapLeaf}
+}
+
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0341/wallet-test-vectors.json
+func TestTaprootScriptPubKey(t *testing.T) {
+	testCases := []struct {
+		internalKey   string
+		scriptTree    string
+		merkleRoot    string
+		tweakedKey    string
+		scriptPubKey  string
+		address       Address
+		controlBlocks []string
+	}{
+		{
+			"d6889cb081036e0faefa3a35157ad71086b123b2b144b649798b494c300a961d",
+			"",
+			"",
+			"53a1f6e454df1aa2776a2814a721372d6258050de330b3c6d10ee8f4e0dda343",
+			"512053a1f6e454df1aa2776a2814a721372d6258050de330b3c6d10ee8f4e0dda343",
+			"bc1p2wsldez5mud2yam29q22wgfh9439spgduvct83k3pm50fcxa5dps59h4z5",
+			nil,
+		},
+		{
+			"187791b6f712a8ea41c8ecdd0ee77fab3e85263b37e1ec18a3651926b3a6cf27",
+			`{"id":0,"script":"20d85a959b0290bf19bb89ed43c916be835475d013da4b362117393e25a48229b8ac","leafVersion":192}`,
+			"5b75adecf53548f3ec6ad7d78383bf84cc57b55a3127c72b9a2481752dd88b21",
+			"147c9c57132f6e7ecddba9800bb0c4449251c92a1e60371ee77557b6620f3ea3",
+			"5120147c9c57132f6e7ecddba9800bb0c4449251c92a1e60371ee77557b6620f3ea3",
+			"bc1pz37fc4cn9ah8anwm4xqqhvxygjf9rjf2resrw8h8w4tmvcs0863sa2e586",
+			[]string{
+				"c1187791b6f712a8ea41c8ecdd0ee77fab3e85263b37e1ec18a3651926b3a6cf27",
+			},
+		},
+		{
+			"93478e9488f956df2396be2ce6c5cced75f900dfa18e7dabd2428aae78451820",
+			`{"id":0,"script":"20b617298552a72ade070667e86ca63b8f5789a9fe8731ef91202a91c9f3459007ac","leafVersion":192}`,
+			"c525714a7f49c28aedbbba78c005931a81c234b2f6c99a73e4d06082adc8bf2b",
+			"e4d810fd50586274face62b8a807eb9719cef49c04177cc6b76a9a4251d5450e",
+			"5120e4d810fd50586274face62b8a807eb9719cef49c04177cc6b76a9a4251d5450e",
+			"bc1punvppl2stp38f7kwv2u2spltjuvuaayuqsthe34hd2dyy5w4g58qqfuag5",
+			[]string{
+				"c093478e9488f956df2396be2ce6c5cced75f900dfa18e7dabd2428aae78451820",
+			},
+		},
+		{
+			"ee4fe085983462a184015d1f782d6a5f8b9c2b60130aff050ce221ecf3786592",
+			`[{"id":0,"script":"20387671353e273264c495656e27e39ba899ea8fee3bb69fb2a680e22093447d48ac","leafVersion":192},{"id":1,"script":"06424950333431","leafVersion":250}]`,
+			"6c2dc106ab816b73f9d07e3cd1ef2c8c1256f519748e0813e4edd2405d277bef",
+			"712447206d7a5238acc7ff53fbe94a3b64539ad291c7cdbc490b7577e4b17df5",
+			"5120712447206d7a5238acc7ff53fbe94a3b64539ad291c7cdbc490b7577e4b17df5",
+			"bc1pwyjywgrd0ffr3tx8laflh6228dj98xkjj8rum0zfpd6h0e930h6saqxrrm",
+			[]string{
+				"c0ee4fe085983462a184015d1f782d6a5f8b9c2b60130aff050ce221ecf3786592f224a923cd0021ab202ab139cc56802ddb92dcfc172b9212261a539df79a112a",
+				"faee4fe085983462a184015d1f782d6a5f8b9c2b60130aff050ce221ecf37865928ad69ec7cf41c2a4001fd1f738bf1e505ce2277acdcaa63fe4765192497f47a7",
+			},
+		},
+		{
+			"f9f400803e683727b14f463836e1e78e1c64417638aa066919291a225f0e8dd8",
+			`[{"id":0,"script":"2044b178d64c32c4a05cc4f4d1407268f764c940d20ce97abfd44db5c3592b72fdac","leafVersion":192},{"id":1,"script":"07546170726f6f74","leafVersion":192}]`,
+			"ab179431c28d3b68fb798957faf5497d69c883c6fb1e1cd9f81483d87bac90cc",
+			"77e30a5522dd9f894c3f8b8bd4c4b2cf82ca7da8a3ea6a239655c39c050ab220",
+			"512077e30a5522dd9f894c3f8b8bd4c4b2cf82ca7da8a3ea6a239655c39c050ab220",
+			"bc1pwl3s54fzmk0cjnpl3w9af39je7pv5ldg504x5guk2hpecpg2kgsqaqstjq",
+			[]string{
+				"c1f9f400803e683727b14f463836e1e78e1c64417638aa066919291a225f0e8dd82cb2b90daa543b544161530c925f285b06196940d6085ca9474d41dc3822c5cb",
+				"c1f9f400803e683727b14f463836e1e78e1c64417638aa066919291a225f0e8dd864512fecdb5afa04f98839b50e6f0cb7b1e539bf6f205f67934083cdcc3c8d89",
+			},
+		},
+		{
+			"e0dfe2300b0dd746a3f8674dfd4525623639042569d829c7f0eed9602d263e6f",
+			`[{"id":0,"script":"2072ea6adcf1d371dea8fba1035a09f3d24ed5a059799bae114084130ee5898e69ac","leafVersion":192},[{"id":1,"script":"202352d137f2f3ab38d1eaa976758873377fa5ebb817372c71e2c542313d4abda8ac","leafVersion":192},{"id":2,"script":"207337c0dd4253cb86f2c43a2351aadd82cccb12a172cd120452b9bb8324f2186aac","leafVersion":192}]]`,
+			"ccbd66c6f7e8fdab47b3a486f59d28262be857f30d4773f2d5ea47f7761ce0e2",
+			"91b64d5324723a985170e4dc5a0f84c041804f2cd12660fa5dec09fc21783605",
+			"512091b64d5324723a985170e4dc5a0f84c041804f2cd12660fa5dec09fc21783605",
+			"bc1pjxmy65eywgafs5tsunw95ruycpqcqnev6ynxp7jaasylcgtcxczs6n332e",
+			[]string{
+				"c0e0dfe2300b0dd746a3f8674dfd4525623639042569d829c7f0eed9602d263e6fffe578e9ea769027e4f5a3de40732f75a88a6353a09d767ddeb66accef85e553",
+				"c0e0dfe2300b0dd746a3f8674dfd4525623639042569d829c7f0eed9602d263e6f9e31407bffa15fefbf5090b149d53959ecdf3f62b1246780238c24501d5ceaf62645a02e0aac1fe69d69755733a9b7621b694bb5b5cde2bbfc94066ed62b9817",
+				"c0e0dfe2300b0dd746a3f8674dfd4525623639042569d829c7f0eed9602d263e6fba982a91d4fc552163cb1c0da03676102d5b7a014304c01f0c77b2b8e888de1c2645a02e0aac1fe69d69755733a9b7621b694bb5b5cde2bbfc94066ed62b9817",
+			},
+		},
+		{
+			"55adf4e8967fbd2e29f20ac896e60c3b0f1d5b0efa9d34941b5958c7b0a0312d",
+			`[{"id":0,"script":"2071981521ad9fc9036687364118fb6ccd2035b96a423c59c5430e98310a11abe2ac","leafVersion":192},[{"id":1,"script":"20d5094d2dbe9b76e2c245a2b89b6006888952e2faa6a149ae318d69e520617748ac","leafVersion":192},{"id":2,"script":"20c440b462ad48c7a77f94cd4532d8f2119dcebbd7c9764557e62726419b08ad4cac","leafVersion":192}]]`,
+			"2f6b2c5397b6d68ca18e09a3f05161668ffe93a988582d55c6f07bd5b3329def",
+			"75169f4001aa68f15bbed28b218df1d0a62cbbcf1188c6665110c293c907b831",
+			"512075169f4001aa68f15bbed28b218df1d0a62cbbcf1188c6665110c293c907b831",
+			"bc1pw5tf7sqp4f50zka7629jrr036znzew70zxyvvej3zrpf8jg8hqcssyuewe",
+			[]string{
+				"c155adf4e8967fbd2e29f20ac896e60c3b0f1d5b0efa9d34941b5958c7b0a0312d3cd369a528b326bc9d2133cbd2ac21451acb31681a410434672c8e34fe757e91",
+				"c155adf4e8967fbd2e29f20ac896e60c3b0f1d5b0efa9d34941b5958c7b0a0312dd7485025fceb78b9ed667db36ed8b8dc7b1f0b307ac167fa516fe4352b9f4ef7f154e8e8e17c31d3462d7132589ed29353c6fafdb884c5a6e04ea938834f0d9d",
+				"c155adf4e8967fbd2e29f20ac896e60c3b0f1d5b0efa9d34941b5958c7b0a0312d737ed1fe30bc42b8022d717b44f0d93516617af64a64753b7a06bf16b26cd711f154e8e8e17c31d3462d7132589ed29353c6fafdb884c5a6e04ea938834f0d9d",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scriptPubKey, func(t *testing.T) {
+			internalKey, err := NewXOnlyPublicKeyFromHex(tc.internalKey)
+			require.NoError(t, err)
+
+			var tree *TapTree
+			var leaves []*TapLeaf
+			var merkleRoot []byte
+			if tc.scriptTree != "" {
+				tree, leaves = testBip341TapTree(t, []byte(tc.scriptTree))
+				merkleRoot = tree.MerkleRoot()
+			}
+			assert.Equal(t, hex.EncodeToString(merkleRoot), tc.merkleRoot)
+
+			outputKey, err := TaprootOutputKey(internalKey, merkleRoot)
+			require.NoError(t, err)
+			assert.Equal(t, outputKey.Hex(), tc.tweakedKey)
+
+			script, err := NewP2trScript(outputKey)
+			require.NoError(t, err)
+			assert.Equal(t, script.Hex, tc.scriptPubKey)
+
+			address, err := NewP2trAddress(internalKey, merkleRoot, MainNetParams)
+			require.NoError(t, err)
+			assert.Equal(t, address, tc.address)
+
+			require.Len(t, leaves, len(tc.controlBlocks))
+			for i, leaf := range leaves {
+				cb, err := tree.ControlBlock(internalKey, leaf)
+				require.NoError(t, err)
+				assert.Equal(t, hex.EncodeToString(cb.Bytes()), tc.controlBlocks[i])
+				assert.True(t, cb.Verify(outputKey, leaf.Script))
+			}
+		})
+	}
+}
+
+func TestHuffmanTapTree(t *testing.T) {
+	internalKey, err := NewXOnlyPublicKeyFromHex("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+	require.NoError(t, err)
+
+	// weights 1, 1, 2 and 4 give the tree {OP_4,{OP_3,{OP_1,OP_2}}}
+	leaves := testTapLeaves(t, "51", "52", "53", "54")
+
+	tree, err := NewHuffmanTapTree(leaves, []uint32{1, 1, 2, 4})
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(tree.MerkleRoot()), "2686b316e69ddafa83c7eb811ece0b7122ad6d0d02b6c693d06930ff602cea1a")
+
+	outputKey, err := TaprootOutputKey(internalKey, tree.MerkleRoot())
+	require.NoError(t, err)
+	assert.Equal(t, outputKey.Hex(), "7ff7c54fcec98417b9b64b0403edb6f1c0d3f9e2a8d844b5e0bbc170696f9cb2")
+
+	address, err := tree.Address(internalKey, MainNetParams)
+	require.NoError(t, err)
+	assert.Equal(t, address, Address("bc1p0lmu2n7wexzp0wdkfvzq8mdk78qd870z4rvyfd0qh0qhq6t0njeqmeutuu"))
+
+	controlBlocks := []string{
+		"c179be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798c276fef1386890619b80e10a4a328572d97493add269df1a15a7f89f8ae8ec09a8199db85e1f94b911a63ffece012bb8afc92131e59a614341db4ed2312a3c4865479d810e8dac590c7bfc09abfc02d09613c9cdfbb7755df654cf49ae7e5e2b",
+		"c179be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798a85b2107f791b26a84e7586c28cec7cb61202ed3d01944d832500f363782d675a8199db85e1f94b911a63ffece012bb8afc92131e59a614341db4ed2312a3c4865479d810e8dac590c7bfc09abfc02d09613c9cdfbb7755df654cf49ae7e5e2b",
+		"c179be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f817986496f0779f38b871013be71ee7dcce8fcdcc02afc4c688acb159fc5de2fba55e65479d810e8dac590c7bfc09abfc02d09613c9cdfbb7755df654cf49ae7e5e2b",
+		"c179be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798609f09890e4348cc5bcc26e51c432c7830162715d64aab7c306f853c9da06a7b",
+	}
+
+	for i, leaf := range leaves {
+		cb, err := tree.ControlBlock(internalKey, leaf)
+		require.NoError(t, err)
+		assert.Equal(t, hex.EncodeToString(cb.Bytes()), controlBlocks[i])
+		assert.True(t, cb.Verify(outputKey, leaf.Script))
+
+		// the control block of another leaf does not commit to this one
+		assert.False(t, cb.Verify(outputKey, leaves[(i+1)%len(leaves)].Script))
+
+		b, err := hex.DecodeString(controlBlocks[i])
+		require.NoError(t, err)
+
+		decoded, err := NewControlBlockFromBytes(b)
+		require.NoError(t, err)
+		assert.Equal(t, decoded, cb)
+	}
+
+	assert.Equal(t, len(tree.Leaves()), 4)
+}
+
+func TestTapTreeSingleLeaf(t *testing.T) {
+	internalKey, err := NewXOnlyPublicKeyFromHex("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+	require.NoError(t, err)
+
+	leaves := testTapLeaves(t, "51")
+
+	tree, err := NewHuffmanTapTree(leaves, []uint32{1})
+	require.NoError(t, err)
+
+	leafHash, err := leaves[0].Hash()
+	require.NoError(t, err)
+	assert.Equal(t, tree.MerkleRoot(), leafHash)
+
+	cb, err := tree.ControlBlock(internalKey, leaves[0])
+	require.NoError(t, err)
+	assert.Equal(t, len(cb.Bytes()), TapControlBlockBaseSize)
+
+	outputKey, err := TaprootOutputKey(internalKey, tree.MerkleRoot())
+	require.NoError(t, err)
+	assert.True(t, cb.Verify(outputKey, leaves[0].Script))
+}
+
+func TestTapTreeErrors(t *testing.T) {
+	internalKey, err := NewXOnlyPublicKeyFromHex("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+	require.NoError(t, err)
+
+	_, err = NewHuffmanTapTree(nil, nil)
+	assert.Equal(t, err, ErrEmptyTapTree)
+
+	_, err = NewHuffmanTapTree(testTapLeaves(t, "51", "52"), []uint32{1})
+	assert.Equal(t, err, ErrInvalidTapTreeWeights)
+
+	tree, err := NewHuffmanTapTree(testTapLeaves(t, "51", "52"), []uint32{1, 1})
+	require.NoError(t, err)
+
+	_, err = tree.ControlBlock(internalKey, testTapLeaves(t, "53")[0])
+	assert.Equal(t, err, ErrTapLeafNotFound)
+
+	_, err = NewControlBlockFromBytes(make([]byte, TapControlBlockBaseSize+1))
+	assert.Equal(t, err, ErrInvalidControlBlock)
+
+	_, err = TapLeafHash(0xc1, nil)
+	assert.Equal(t, err, ErrInvalidTapLeafVersion)
+}
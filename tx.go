@@ -3,10 +3,14 @@ package btc
 import "encoding/hex"
 
 type TxIn struct {
-	Txid     string  `json:"txid"`
-	Index    uint32  `json:"index"`
-	Script   *Script `json:"script"`
-	Sequence uint32  `json:"sequence"`
+	Txid     string   `json:"txid"`
+	Index    uint32   `json:"index"`
+	Script   *Script  `json:"script"`
+	Sequence uint32   `json:"sequence"`
+	Witness  []string `json:"witness,omitempty"`
+
+	// the tx out spent by the tx in, which is serialized neither in the raw tx nor in JSON
+	PrevTxOut *TxOut `json:"-"`
 }
 
 func NewTxIn(txid string, index uint32, script *Script) *TxIn {
@@ -18,6 +22,43 @@ func NewTxIn(txid string, index uint32, script *Script) *TxIn {
 	}
 }
 
+func (txIn *TxIn) HasWitness() bool {
+	return len(txIn.Witness) > 0
+}
+
+func (txIn *TxIn) WitnessBytes() ([][]byte, error) {
+	items := make([][]byte, len(txIn.Witness))
+	for i, item := range txIn.Witness {
+		b, err := hex.DecodeString(item)
+		if err != nil {
+			return nil, err
+		}
+
+		items[i] = b
+	}
+
+	return items, nil
+}
+
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0341.mediawiki#script-validation-rules
+func (txIn *TxIn) Annex() ([]byte, error) {
+	items, err := txIn.WitnessBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(items) < 2 {
+		return nil, nil
+	}
+
+	last := items[len(items)-1]
+	if len(last) == 0 || last[0] != TaprootAnnexTag {
+		return nil, nil
+	}
+
+	return last, nil
+}
+
 type TxOut struct {
 	Amount Satoshi `json:"amount"`
 	Script *Script `json:"script"`
@@ -30,6 +71,16 @@ func NewTxOut(amount int64, script *Script) *TxOut {
 	}
 }
 
+// Size returns the serialized size of the tx out.
+func (txOut *TxOut) Size() (int, error) {
+	b, err := txOut.Script.Bytes()
+	if err != nil {
+		return 0, err
+	}
+
+	return 8 + compactSizeLength(uint64(len(b))) + len(b), nil
+}
+
 type Tx struct {
 	Version  int32    `json:"version"`
 	TxIns    []*TxIn  `json:"txIns"`
@@ -67,6 +118,16 @@ func (tx *Tx) AddTxOut(txOut *TxOut) {
 	tx.TxOuts = append(tx.TxOuts, txOut)
 }
 
+func (tx *Tx) HasWitness() bool {
+	for _, txIn := range tx.TxIns {
+		if txIn.HasWitness() {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (tx *Tx) Bytes() ([]byte, error) {
 	w := newWriter()
 	if err := w.writeTx(tx); err != nil {
@@ -85,7 +146,30 @@ func (tx *Tx) Hex() (string, error) {
 	return hex.EncodeToString(b), nil
 }
 
+func (tx *Tx) StrippedBytes() ([]byte, error) {
+	w := newWriter()
+	if err := w.writeStrippedTx(tx); err != nil {
+		return nil, err
+	}
+
+	return w.Bytes(), nil
+}
+
 func (tx *Tx) Txid() (string, error) {
+	txBytes, err := tx.StrippedBytes()
+	if err != nil {
+		return "", err
+	}
+
+	hashBytes, err := Sha256Double(txBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(reverseBytes(hashBytes)), nil
+}
+
+func (tx *Tx) Wtxid() (string, error) {
 	txBytes, err := tx.Bytes()
 	if err != nil {
 		return "", err
@@ -98,3 +182,33 @@ func (tx *Tx) Txid() (string, error) {
 
 	return hex.EncodeToString(reverseBytes(hashBytes)), nil
 }
+
+// Weight returns the weight of the tx, in which witness bytes count a quarter of the others.
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0141.mediawiki#transaction-size-calculations
+func (tx *Tx) Weight() (int, error) {
+	strippedBytes, err := tx.StrippedBytes()
+	if err != nil {
+		return 0, err
+	}
+
+	txBytes, err := tx.Bytes()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(strippedBytes)*(WitnessScaleFactor-1) + len(txBytes), nil
+}
+
+// Vsize returns the virtual size of the tx, which fee rates are based on.
+func (tx *Tx) Vsize() (int, error) {
+	weight, err := tx.Weight()
+	if err != nil {
+		return 0, err
+	}
+
+	return weightToVsize(weight), nil
+}
+
+func weightToVsize(weight int) int {
+	return (weight + WitnessScaleFactor - 1) / WitnessScaleFactor
+}
@@ -1,6 +1,8 @@
 package btc
 
 import (
+	"io"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -8,8 +10,6 @@ import (
 )
 
 func TestTxMapping(t *testing.T) {
-	UseTestnet()
-
 	testCases := []struct {
 		txid     string
 		hex      string
@@ -105,3 +105,101 @@ func TestTxMapping(t *testing.T) {
 		})
 	}
 }
+
+func TestTxWitnessMapping(t *testing.T) {
+	testCases := []struct {
+		txid      string
+		wtxid     string
+		hex       string
+		witnesses [][]string
+		weight    int
+		vsize     int
+	}{
+		{
+			"c5cfbb18ce66fa0c37e048b80e24e47c3be09582c9cb66e0a288c4c39fd881d4",
+			"ce14003d603a758fa6bfe6dd20b1a5ae086a499b9fde5d03819424e8aa029a75",
+			"020000000001036910051cf3ce36257a1d844e28959f368a35adc9520fb9679175f6cdf8c1f1d10000000000fffffffff8e9d0c1b2a3f4e5d6c7b8a9f0e1d2c3b4a5768695a4b3c2e1a9d8b7e4b3d8e50300000000fdffffffbc0a00000000000000000000000000000000000000000000000000000000000006000000000000000002a086010000000000160014a9b8c7d6e5f4a3b2c1d0e9f8a9b8c7d6e5f4a3b2a025260000000000225120b8c7d6e5f4a3b2c1d0e9f8a9b8c7d6e5f4a3b2c1d0e9f8a9b8c7d6e5f4a3b2c10140e907831f80848d1069a5371b402410364bdf1c5f8307b0084c55f1cbe2dca821525f66a4a85ea8b71e482a74f382d2ce5ebeee8fdb2172f477df4900d310536c0002473044022065fe1ea4e94a9b44fb62c2b874b63a947504273a60b99b8f7bbf77b4db9331b002205559d8ee93cf341d75866f9eb912af05904fb6eed7372a837308c4e37f3ab58f012103bae5f04799c40862358560e42e441c3080b997a3dec161dd40395e992362bfc920a10700",
+			[][]string{
+				[]string{
+					"e907831f80848d1069a5371b402410364bdf1c5f8307b0084c55f1cbe2dca821525f66a4a85ea8b71e482a74f382d2ce5ebeee8fdb2172f477df4900d310536c",
+				},
+				[]string{},
+				[]string{
+					"3044022065fe1ea4e94a9b44fb62c2b874b63a947504273a60b99b8f7bbf77b4db9331b002205559d8ee93cf341d75866f9eb912af05904fb6eed7372a837308c4e37f3ab58f01",
+					"03bae5f04799c40862358560e42e441c3080b997a3dec161dd40395e992362bfc9",
+				},
+			},
+			1004,
+			251,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.txid, func(t *testing.T) {
+			tx, err := NewTxFromHex(tc.hex)
+			require.NoError(t, err)
+			assert.True(t, tx.HasWitness())
+			require.Equal(t, len(tx.TxIns), len(tc.witnesses))
+
+			for idx, txIn := range tx.TxIns {
+				assert.Equal(t, txIn.Witness, tc.witnesses[idx])
+			}
+
+			txid, err := tx.Txid()
+			require.NoError(t, err)
+			assert.Equal(t, txid, tc.txid)
+
+			wtxid, err := tx.Wtxid()
+			require.NoError(t, err)
+			assert.Equal(t, wtxid, tc.wtxid)
+
+			weight, err := tx.Weight()
+			require.NoError(t, err)
+			assert.Equal(t, weight, tc.weight)
+
+			vsize, err := tx.Vsize()
+			require.NoError(t, err)
+			assert.Equal(t, vsize, tc.vsize)
+
+			txHex, err := tx.Hex()
+			require.NoError(t, err)
+			assert.Equal(t, txHex, tc.hex)
+		})
+	}
+}
+
+func TestTxErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		hex  string
+		err  error
+	}{
+		{
+			"too many tx ins",
+			"02000000ffffffffffffffffff",
+			io.ErrUnexpectedEOF,
+		},
+		{
+			"too many tx outs",
+			"0200000000ffffffffffffffffff",
+			io.ErrUnexpectedEOF,
+		},
+		{
+			"too long script",
+			"0200000001" + strings.Repeat("11", 32) + "00000000ffffffffffffffffff",
+			io.EOF,
+		},
+		{
+			"too many witness items",
+			"02000000000101" + strings.Repeat("11", 32) + "0000000000ffffffff01000000000000000000ffffffffffffffffff",
+			io.ErrUnexpectedEOF,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewTxFromHex(tc.hex)
+			assert.Equal(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,465 @@
+package btc
+
+import "errors"
+
+const (
+	// the sizes of the placeholders of signatures, which are the largest ones the signer creates
+	estimatedEcdsaSigSize   = 72 // low-S DER signature followed by the sighash type
+	estimatedSchnorrSigSize = SchnorrSigLength
+)
+
+var (
+	ErrNoTxOuts               = errors.New("no tx outs")
+	ErrDustTxOut              = errors.New("tx out amount is dust")
+	ErrInsufficientFunds      = errors.New("insufficient funds")
+	ErrChangeAddressNotFound  = errors.New("change address not found")
+	ErrInvalidRecipientAmount = errors.New("invalid recipient amount")
+)
+
+// Utxo is an unspent tx out with the data needed to spend it.
+// PrevTx is the tx containing the output, which PSBTs need to spend non-witness outputs.
+type Utxo struct {
+	*PrevOut
+	Txid   string
+	Index  uint32
+	PrevTx *Tx
+}
+
+func NewUtxo(txid string, index uint32, prevOut *PrevOut) *Utxo {
+	return &Utxo{
+		PrevOut: prevOut,
+		Txid:    txid,
+		Index:   index,
+	}
+}
+
+// inputWeight returns the weight the input spending the utxo adds to a tx once signed,
+// and whether the input has a witness. Public keys of P2PKH outputs are assumed to be compressed.
+func (utxo *Utxo) inputWeight() (int, bool, error) {
+	b, err := utxo.Script.Bytes()
+	if err != nil {
+		return 0, false, err
+	}
+
+	var rb []byte
+	if classifyScript(b) == ScriptTypeP2sh {
+		if utxo.RedeemScript == nil {
+			return 0, false, ErrRedeemScriptNotFound
+		}
+		if rb, err = utxo.RedeemScript.Bytes(); err != nil {
+			return 0, false, err
+		}
+		b = rb
+	}
+
+	var scriptSigItems, witnessItems [][]byte
+
+	switch classifyScript(b) {
+	case ScriptTypeP2pk, ScriptTypeP2pkh, ScriptTypeMultisig:
+		if scriptSigItems, err = estimatedStackItems(b); err != nil {
+			return 0, false, err
+		}
+	case ScriptTypeP2wpkh:
+		witnessItems = [][]byte{make([]byte, estimatedEcdsaSigSize), make([]byte, CompressedPubKeyLength)}
+	case ScriptTypeP2wsh:
+		if utxo.WitnessScript == nil {
+			return 0, false, ErrWitnessScriptNotFound
+		}
+		wb, err := utxo.WitnessScript.Bytes()
+		if err != nil {
+			return 0, false, err
+		}
+
+		items, err := estimatedStackItems(wb)
+		if err != nil {
+			return 0, false, err
+		}
+		witnessItems = append(items, wb)
+	case ScriptTypeP2tr:
+		if rb != nil {
+			return 0, false, ErrUnsupportedScript
+		}
+		witnessItems = [][]byte{make([]byte, estimatedSchnorrSigSize)}
+	default:
+		return 0, false, ErrUnsupportedScript
+	}
+
+	if rb != nil {
+		scriptSigItems = append(scriptSigItems, rb)
+	}
+
+	scriptSig, err := newPushOnlyScript(scriptSigItems)
+	if err != nil {
+		return 0, false, err
+	}
+	sb, err := scriptSig.Bytes()
+	if err != nil {
+		return 0, false, err
+	}
+
+	// outpoint, scriptSig and sequence
+	weight := (32 + 4 + compactSizeLength(uint64(len(sb))) + len(sb) + 4) * WitnessScaleFactor
+
+	if len(witnessItems) == 0 {
+		return weight, false, nil
+	}
+
+	weight += compactSizeLength(uint64(len(witnessItems)))
+	for _, item := range witnessItems {
+		weight += compactSizeLength(uint64(len(item))) + len(item)
+	}
+
+	return weight, true, nil
+}
+
+// estimatedStackItems returns the stack items satisfying a P2PK, P2PKH or multisig script
+// with placeholders of signatures and a compressed public key.
+func estimatedStackItems(script []byte) ([][]byte, error) {
+	ops, err := parseScript(script)
+	if err != nil {
+		return nil, err
+	}
+
+	if extractP2pkPubKey(ops) != nil {
+		return [][]byte{make([]byte, estimatedEcdsaSigSize)}, nil
+	}
+	if extractP2pkhPkh(ops) != nil {
+		return [][]byte{make([]byte, estimatedEcdsaSigSize), make([]byte, CompressedPubKeyLength)}, nil
+	}
+	if m, _, ok := extractMultisig(ops); ok {
+		// CHECKMULTISIG pops one extra item
+		items := [][]byte{{}}
+		for i := 0; i < m; i++ {
+			items = append(items, make([]byte, estimatedEcdsaSigSize))
+		}
+
+		return items, nil
+	}
+
+	return nil, ErrUnsupportedScript
+}
+
+// estimateTxWeight returns the weight of the tx spending utxos to txOuts once signed.
+func estimateTxWeight(utxos []*Utxo, txOuts []*TxOut) (int, error) {
+	// version, numbers of tx ins and tx outs and lock time
+	weight := (4 + compactSizeLength(uint64(len(utxos))) + compactSizeLength(uint64(len(txOuts))) + 4) * WitnessScaleFactor
+
+	hasWitness := false
+	nonWitnessIns := 0
+	for _, utxo := range utxos {
+		w, ok, err := utxo.inputWeight()
+		if err != nil {
+			return 0, err
+		}
+
+		weight += w
+		if ok {
+			hasWitness = true
+		} else {
+			nonWitnessIns++
+		}
+	}
+
+	for _, txOut := range txOuts {
+		size, err := txOut.Size()
+		if err != nil {
+			return 0, err
+		}
+
+		weight += size * WitnessScaleFactor
+	}
+
+	// the marker, the flag and the empty witnesses of the non-witness inputs
+	if hasWitness {
+		weight += 2 + nonWitnessIns
+	}
+
+	return weight, nil
+}
+
+// TxBuilder builds a tx paying to recipients with UTXOs at a fee rate,
+// estimating the fee from the size of the tx once signed.
+// UTXOs are spent in the order they are added until they cover the amounts and the fee
+// unless coin selectors are set, and the rest goes to the change address unless it is dust
+// or the selection is changeless.
+type TxBuilder struct {
+	params          *Params
+	utxos           []*Utxo
+	txOuts          []*TxOut
+	changeScript    *Script
+	feeRate         FeeRate
+	dustRelayRate   FeeRate
+	longTermFeeRate FeeRate
+	coinSelectors   []CoinSelector
+}
+
+func NewTxBuilder(params *Params) *TxBuilder {
+	return &TxBuilder{
+		params:          params,
+		feeRate:         FeeRateSatPerVByte,
+		dustRelayRate:   DustRelayFeeRate,
+		longTermFeeRate: DefaultLongTermFeeRate,
+	}
+}
+
+func (builder *TxBuilder) addressScript(address Address) (*Script, error) {
+	decoded, err := DecodeAddress(address.String(), builder.params)
+	if err != nil {
+		return nil, err
+	}
+
+	return decoded.Script()
+}
+
+func (builder *TxBuilder) AddUtxos(utxos ...*Utxo) {
+	builder.utxos = append(builder.utxos, utxos...)
+}
+
+func (builder *TxBuilder) AddRecipient(address Address, amount Satoshi) error {
+	if amount <= 0 {
+		return ErrInvalidRecipientAmount
+	}
+
+	script, err := builder.addressScript(address)
+	if err != nil {
+		return err
+	}
+
+	builder.AddTxOut(&TxOut{
+		Amount: amount,
+		Script: script,
+	})
+
+	return nil
+}
+
+// AddTxOut adds a tx out as is, e.g. a null data output.
+func (builder *TxBuilder) AddTxOut(txOut *TxOut) {
+	builder.txOuts = append(builder.txOuts, txOut)
+}
+
+func (builder *TxBuilder) SetChangeAddress(address Address) error {
+	script, err := builder.addressScript(address)
+	if err != nil {
+		return err
+	}
+
+	builder.changeScript = script
+
+	return nil
+}
+
+// SetFeeRate sets the fee rate of the tx, which is 1 sat/vB by default.
+func (builder *TxBuilder) SetFeeRate(rate FeeRate) {
+	builder.feeRate = rate
+}
+
+// SetDustRelayFeeRate sets the fee rate dust outputs are determined with, which is DustRelayFeeRate by default.
+func (builder *TxBuilder) SetDustRelayFeeRate(rate FeeRate) {
+	builder.dustRelayRate = rate
+}
+
+// SetCoinSelectors makes the builder spend the UTXOs selected by SelectCoins with selectors
+// instead of spending them in order.
+func (builder *TxBuilder) SetCoinSelectors(selectors ...CoinSelector) {
+	builder.coinSelectors = selectors
+}
+
+// SetLongTermFeeRate sets the long term fee rate coin selectors work with, which is DefaultLongTermFeeRate by default.
+func (builder *TxBuilder) SetLongTermFeeRate(rate FeeRate) {
+	builder.longTermFeeRate = rate
+}
+
+// Build returns the unsigned tx and the outputs spent by its inputs, with which the tx can be signed by Signer.
+func (builder *TxBuilder) Build() (*Tx, []*PrevOut, error) {
+	tx, utxos, err := builder.build()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	prevOuts := make([]*PrevOut, len(utxos))
+	for i, utxo := range utxos {
+		prevOuts[i] = utxo.PrevOut
+	}
+
+	return tx, prevOuts, nil
+}
+
+// build returns the unsigned tx and the UTXOs spent by its inputs.
+func (builder *TxBuilder) build() (*Tx, []*Utxo, error) {
+	if len(builder.txOuts) == 0 {
+		return nil, nil, ErrNoTxOuts
+	}
+	if builder.changeScript == nil {
+		return nil, nil, ErrChangeAddressNotFound
+	}
+
+	var target Satoshi
+	for _, txOut := range builder.txOuts {
+		isDust, err := txOut.IsDust(builder.dustRelayRate)
+		if err != nil {
+			return nil, nil, err
+		}
+		if isDust {
+			return nil, nil, ErrDustTxOut
+		}
+
+		target += txOut.Amount
+	}
+
+	if len(builder.coinSelectors) > 0 {
+		selection, err := builder.selectCoins(target)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// the excess of changeless selections goes to the fee as the selectors decided
+		utxos := selection.Utxos
+		if selection.Change == 0 {
+			return newUnsignedTx(utxos, builder.txOuts), utxos, nil
+		}
+
+		var total Satoshi
+		for _, utxo := range utxos {
+			total += utxo.Amount
+		}
+
+		txOuts, err := builder.txOutsWithChange(utxos, total-target)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return newUnsignedTx(utxos, txOuts), utxos, nil
+	}
+
+	var total Satoshi
+	for i, utxo := range builder.utxos {
+		total += utxo.Amount
+
+		utxos := builder.utxos[:i+1]
+		txOuts, err := builder.txOutsWithChange(utxos, total-target)
+		if err == ErrInsufficientFunds {
+			continue
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		return newUnsignedTx(utxos, txOuts), utxos, nil
+	}
+
+	return nil, nil, ErrInsufficientFunds
+}
+
+// selectCoins returns the selection of the coin selectors to pay amount.
+func (builder *TxBuilder) selectCoins(amount Satoshi) (*CoinSelection, error) {
+	weight, err := estimateTxWeight(nil, builder.txOuts)
+	if err != nil {
+		return nil, err
+	}
+
+	change := &TxOut{
+		Script: builder.changeScript,
+	}
+	changeOutputSize, err := change.Size()
+	if err != nil {
+		return nil, err
+	}
+	changeSpendSize, err := change.spendSize()
+	if err != nil {
+		return nil, err
+	}
+	minViableChange, err := change.DustThreshold(builder.dustRelayRate)
+	if err != nil {
+		return nil, err
+	}
+	// change costing more to spend later than it is worth is not viable either, as Bitcoin Core does
+	if spendFee := builder.longTermFeeRate.Fee(changeSpendSize) + 1; spendFee > minViableChange {
+		minViableChange = spendFee
+	}
+
+	params := &CoinSelectionParams{
+		FeeRate:          builder.feeRate,
+		LongTermFeeRate:  builder.longTermFeeRate,
+		ChangeOutputSize: changeOutputSize,
+		ChangeSpendSize:  changeSpendSize,
+		MinViableChange:  minViableChange,
+		MinChange:        CoinSelectionChangeLower,
+	}
+
+	return SelectCoins(builder.utxos, amount+builder.feeRate.Fee(weightToVsize(weight)), params, builder.coinSelectors...)
+}
+
+func newUnsignedTx(utxos []*Utxo, txOuts []*TxOut) *Tx {
+	tx := NewTx()
+	tx.Version = 2
+	for _, utxo := range utxos {
+		txIn := NewTxIn(utxo.Txid, utxo.Index, &Script{})
+		txIn.PrevTxOut = utxo.TxOut
+		tx.AddTxIn(txIn)
+	}
+	for _, txOut := range txOuts {
+		tx.AddTxOut(txOut)
+	}
+
+	return tx
+}
+
+// txOutsWithChange returns the tx outs of the tx spending utxos, which provide excess over the amounts paid,
+// with the change output if the excess after the fee is not dust.
+func (builder *TxBuilder) txOutsWithChange(utxos []*Utxo, excess Satoshi) ([]*TxOut, error) {
+	weight, err := estimateTxWeight(utxos, builder.txOuts)
+	if err != nil {
+		return nil, err
+	}
+
+	fee := builder.feeRate.Fee(weightToVsize(weight))
+	if excess < fee {
+		return nil, ErrInsufficientFunds
+	}
+	change := &TxOut{
+		Script: builder.changeScript,
+	}
+	txOuts := append(append([]*TxOut{}, builder.txOuts...), change)
+
+	if weight, err = estimateTxWeight(utxos, txOuts); err != nil {
+		return nil, err
+	}
+	change.Amount = excess - builder.feeRate.Fee(weightToVsize(weight))
+
+	isDust, err := change.IsDust(builder.dustRelayRate)
+	if err != nil {
+		return nil, err
+	}
+	if isDust {
+		return builder.txOuts, nil
+	}
+
+	return txOuts, nil
+}
+
+// BuildPsbt returns the PSBT of the unsigned tx with the spent outputs and their scripts.
+// The previous txs of non-witness outputs are added if the UTXOs have them.
+func (builder *TxBuilder) BuildPsbt() (*Psbt, error) {
+	tx, utxos, err := builder.build()
+	if err != nil {
+		return nil, err
+	}
+
+	psbt, err := NewPsbt(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, utxo := range utxos {
+		if err := psbt.UpdateInput(i, utxo.PrevOut); err != nil {
+			return nil, err
+		}
+		if prevTx := utxo.PrevTx; prevTx != nil {
+			if err := psbt.SetNonWitnessUtxo(i, prevTx); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return psbt, nil
+}
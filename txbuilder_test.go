@@ -0,0 +1,228 @@
+package btc
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func txBuilderTestAddresses(t *testing.T, keys KeyRing) (Address, Address) {
+	recipient, err := NewWitnessAddress(0, []byte(strings.Repeat("\x11", PkhLength)), MainNetParams)
+	require.NoError(t, err)
+
+	change, err := NewP2trAddress(keys[0].PublicKey().XOnly(), nil, MainNetParams)
+	require.NoError(t, err)
+
+	return recipient, change
+}
+
+func TestTxBuilder(t *testing.T) {
+	testCases, keys := signerTestCases(t)
+	recipient, change := txBuilderTestAddresses(t, keys)
+
+	var utxos []*Utxo
+	for i, tc := range testCases {
+		// the estimation assumes compressed public keys
+		if tc.name == "p2pkh uncompressed" {
+			continue
+		}
+		utxos = append(utxos, NewUtxo(fmt.Sprintf("%064x", i+1), uint32(i), tc.prevOut))
+	}
+
+	rate := FeeRate(2500)
+
+	builder := NewTxBuilder(MainNetParams)
+	builder.AddUtxos(utxos...)
+	builder.SetFeeRate(rate)
+	require.NoError(t, builder.AddRecipient(recipient, 1000000))
+	require.NoError(t, builder.SetChangeAddress(change))
+
+	tx, prevOuts, err := builder.Build()
+	require.NoError(t, err)
+	require.Equal(t, len(tx.TxIns), len(utxos))
+	require.Equal(t, len(tx.TxOuts), 2)
+
+	estimatedWeight, err := estimateTxWeight(utxos, tx.TxOuts)
+	require.NoError(t, err)
+
+	signer, err := NewSigner(tx, prevOuts, keys)
+	require.NoError(t, err)
+	require.NoError(t, signer.Sign())
+	require.NoError(t, signer.Verify())
+
+	weight, err := tx.Weight()
+	require.NoError(t, err)
+	assert.True(t, estimatedWeight >= weight)
+	// each ECDSA signature is at most 1 byte shorter than the placeholder
+	assert.True(t, estimatedWeight-weight <= 8*WitnessScaleFactor)
+
+	assert.Equal(t, tx.TxOuts[0].Amount, Satoshi(1000000))
+
+	fee, err := tx.Fee()
+	require.NoError(t, err)
+	assert.Equal(t, fee, rate.Fee(weightToVsize(estimatedWeight)))
+
+	feeRate, err := tx.FeeRate()
+	require.NoError(t, err)
+	assert.True(t, feeRate >= rate)
+}
+
+func TestTxBuilderDustChange(t *testing.T) {
+	testCases, keys := signerTestCases(t)
+	recipient, change := txBuilderTestAddresses(t, keys)
+
+	// p2wpkh
+	utxo := NewUtxo(fmt.Sprintf("%064x", 1), 0, testCases[4].prevOut)
+
+	builder := NewTxBuilder(MainNetParams)
+	builder.AddUtxos(utxo)
+	require.NoError(t, builder.AddRecipient(recipient, 139700))
+	require.NoError(t, builder.SetChangeAddress(change))
+
+	tx, _, err := builder.Build()
+	require.NoError(t, err)
+	require.Equal(t, len(tx.TxOuts), 1)
+	assert.Equal(t, tx.TxOuts[0].Amount, Satoshi(139700))
+
+	// the change is not dust any more
+	builder.SetDustRelayFeeRate(FeeRateSatPerVByte)
+
+	tx, _, err = builder.Build()
+	require.NoError(t, err)
+	require.Equal(t, len(tx.TxOuts), 2)
+}
+
+func TestTxBuilderPsbt(t *testing.T) {
+	testCases, keys := signerTestCases(t)
+	recipient, change := txBuilderTestAddresses(t, keys)
+
+	// p2pkh
+	prevTx := NewTx()
+	prevTx.AddTxIn(NewTxIn(fmt.Sprintf("%064x", 1), 0, &Script{}))
+	prevTx.AddTxOut(testCases[0].prevOut.TxOut)
+
+	txid, err := prevTx.Txid()
+	require.NoError(t, err)
+
+	legacyUtxo := NewUtxo(txid, 0, testCases[0].prevOut)
+	legacyUtxo.PrevTx = prevTx
+
+	// p2sh-p2wpkh
+	nestedUtxo := NewUtxo(fmt.Sprintf("%064x", 2), 1, testCases[3].prevOut)
+
+	builder := NewTxBuilder(MainNetParams)
+	builder.AddUtxos(legacyUtxo, nestedUtxo)
+	require.NoError(t, builder.AddRecipient(recipient, 200000))
+	require.NoError(t, builder.SetChangeAddress(change))
+
+	psbt, err := builder.BuildPsbt()
+	require.NoError(t, err)
+	require.Equal(t, len(psbt.Inputs), 2)
+
+	assert.Equal(t, psbt.Inputs[0].NonWitnessUtxo, prevTx)
+	assert.Nil(t, psbt.Inputs[0].WitnessUtxo)
+	assert.Equal(t, psbt.Inputs[1].WitnessUtxo, testCases[3].prevOut.TxOut)
+	assert.Equal(t, psbt.Inputs[1].RedeemScript, testCases[3].prevOut.RedeemScript)
+
+	require.NoError(t, psbt.Sign(keys))
+	require.NoError(t, psbt.Finalize())
+
+	tx, err := psbt.Extract()
+	require.NoError(t, err)
+
+	signer, err := NewSigner(tx, []*PrevOut{legacyUtxo.PrevOut, nestedUtxo.PrevOut}, keys)
+	require.NoError(t, err)
+	require.NoError(t, signer.Verify())
+}
+
+func TestTxBuilderErrors(t *testing.T) {
+	testCases, keys := signerTestCases(t)
+	recipient, change := txBuilderTestAddresses(t, keys)
+
+	utxo := NewUtxo(fmt.Sprintf("%064x", 1), 0, testCases[4].prevOut)
+
+	builder := NewTxBuilder(MainNetParams)
+	builder.AddUtxos(utxo)
+
+	_, _, err := builder.Build()
+	assert.Equal(t, err, ErrNoTxOuts)
+
+	assert.Equal(t, builder.AddRecipient(recipient, 0), ErrInvalidRecipientAmount)
+	require.NoError(t, builder.AddRecipient(recipient, 140000))
+
+	_, _, err = builder.Build()
+	assert.Equal(t, err, ErrChangeAddressNotFound)
+
+	require.NoError(t, builder.SetChangeAddress(change))
+
+	_, _, err = builder.Build()
+	assert.Equal(t, err, ErrInsufficientFunds)
+
+	builder = NewTxBuilder(MainNetParams)
+	builder.AddUtxos(utxo)
+	require.NoError(t, builder.AddRecipient(recipient, 293))
+	require.NoError(t, builder.SetChangeAddress(change))
+
+	_, _, err = builder.Build()
+	assert.Equal(t, err, ErrDustTxOut)
+
+	builder = NewTxBuilder(MainNetParams)
+	builder.AddUtxos(NewUtxo(fmt.Sprintf("%064x", 1), 0, &PrevOut{TxOut: testCases[2].prevOut.TxOut}))
+	require.NoError(t, builder.AddRecipient(recipient, 10000))
+	require.NoError(t, builder.SetChangeAddress(change))
+
+	_, _, err = builder.Build()
+	assert.Equal(t, err, ErrRedeemScriptNotFound)
+}
+
+func TestTxBuilderCoinSelectors(t *testing.T) {
+	_, keys := signerTestCases(t)
+	recipient, change := txBuilderTestAddresses(t, keys)
+
+	utxos := coinSelectionTestUtxos(t, 100000, 200000, 300000, 500000)
+
+	builder := NewTxBuilder(MainNetParams)
+	builder.AddUtxos(utxos...)
+	builder.SetCoinSelectors(&BnbSelector{}, &LargestFirstSelector{})
+	require.NoError(t, builder.AddRecipient(recipient, 590000))
+	require.NoError(t, builder.SetChangeAddress(change))
+
+	tx, prevOuts, err := builder.Build()
+	require.NoError(t, err)
+
+	// the largest UTXOs are selected instead of the first ones
+	require.Equal(t, len(tx.TxIns), 2)
+	assert.Equal(t, tx.TxIns[0].Txid, utxos[3].Txid)
+	assert.Equal(t, tx.TxIns[1].Txid, utxos[2].Txid)
+	assert.Equal(t, prevOuts, []*PrevOut{utxos[3].PrevOut, utxos[2].PrevOut})
+	require.Equal(t, len(tx.TxOuts), 2)
+	assert.Equal(t, tx.TxOuts[0].Amount, Satoshi(590000))
+}
+
+func TestTxBuilderChangelessSelection(t *testing.T) {
+	_, keys := signerTestCases(t)
+	recipient, change := txBuilderTestAddresses(t, keys)
+
+	// the effective value exceeds the amount and the fee of the tx without inputs, 41 satoshis, by 500 satoshis,
+	// which is less than the cost of change
+	utxos := coinSelectionTestUtxos(t, 590541)
+
+	builder := NewTxBuilder(MainNetParams)
+	builder.AddUtxos(utxos...)
+	builder.SetCoinSelectors(&BnbSelector{})
+	require.NoError(t, builder.AddRecipient(recipient, 590000))
+	require.NoError(t, builder.SetChangeAddress(change))
+
+	tx, _, err := builder.Build()
+	require.NoError(t, err)
+	require.Equal(t, len(tx.TxIns), 1)
+	require.Equal(t, len(tx.TxOuts), 1)
+	assert.Equal(t, tx.TxOuts[0].Amount, Satoshi(590000))
+
+	fee, err := tx.Fee()
+	require.NoError(t, err)
+	assert.Equal(t, fee, Satoshi(609))
+}
@@ -0,0 +1,87 @@
+package btc
+
+import (
+	"errors"
+
+	"github.com/m0t0k1ch1/base58"
+)
+
+const (
+	wifCompressedFlag byte = 0x01
+)
+
+var (
+	ErrInvalidWif             = errors.New("invalid wif")
+	ErrInvalidWifChecksum     = errors.New("invalid wif checksum")
+	ErrInvalidWifVersion      = errors.New("invalid wif version")
+	ErrWifNetworkMismatch     = errors.New("wif network mismatch")
+	ErrInvalidWifCompressFlag = errors.New("invalid wif compress flag")
+)
+
+// Wif is a private key in the wallet import format.
+// ref. https://en.bitcoin.it/wiki/Wallet_import_format
+type Wif string
+
+func (wif Wif) String() string {
+	return string(wif)
+}
+
+// Decode returns the private key of wif for params and
+// whether its public key is to be serialized in the compressed format.
+func (wif Wif) Decode(params *Params) (*PrivateKey, bool, error) {
+	b, err := base58.NewBitcoinBase58().DecodeString(wif.String())
+	if err != nil {
+		return nil, false, ErrInvalidWif
+	}
+
+	compressed := false
+	switch len(b) {
+	case 1 + PrivateKeyLength + base58ChecksumLength:
+	case 1 + PrivateKeyLength + 1 + base58ChecksumLength:
+		compressed = true
+	default:
+		return nil, false, ErrInvalidWif
+	}
+
+	ok, err := verifyBase58Checksum(b)
+	if err != nil {
+		return nil, false, err
+	}
+	if !ok {
+		return nil, false, ErrInvalidWifChecksum
+	}
+
+	if b[0] != params.PrivateKeyVersion {
+		for _, p := range networkParams {
+			if b[0] == p.PrivateKeyVersion {
+				return nil, false, ErrWifNetworkMismatch
+			}
+		}
+		return nil, false, ErrInvalidWifVersion
+	}
+	if compressed && b[1+PrivateKeyLength] != wifCompressedFlag {
+		return nil, false, ErrInvalidWifCompressFlag
+	}
+
+	privKey, err := NewPrivateKeyFromBytes(b[1 : 1+PrivateKeyLength])
+	if err != nil {
+		return nil, false, err
+	}
+
+	return privKey, compressed, nil
+}
+
+// Wif returns privKey in the wallet import format for params.
+func (privKey *PrivateKey) Wif(compressed bool, params *Params) (Wif, error) {
+	b := append([]byte{params.PrivateKeyVersion}, privKey.Bytes()...)
+	if compressed {
+		b = append(b, wifCompressedFlag)
+	}
+
+	s, err := encodeBase58Check(b)
+	if err != nil {
+		return "", err
+	}
+
+	return Wif(s), nil
+}
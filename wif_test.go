@@ -0,0 +1,96 @@
+package btc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWif(t *testing.T) {
+	testCases := []struct {
+		privKey    string
+		compressed bool
+		params     *Params
+		wif        Wif
+	}{
+		{
+			// ref. https://en.bitcoin.it/wiki/Wallet_import_format
+			"0c28fca386c7a227600b2fe50b7cae11ec86d3bf1fbe471be89827e19d72aa1d",
+			false,
+			MainNetParams,
+			"5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ",
+		},
+		{
+			"0000000000000000000000000000000000000000000000000000000000000001",
+			true,
+			MainNetParams,
+			"KwDiBf89QgGbjEhKnhXJuH7LrciVrZi3qYjgd9M7rFU73sVHnoWn",
+		},
+		{
+			"dda35a1488fb97b6eb3fe6e9ef2a25814e396fb5dc295fe994b96789b21a0398",
+			true,
+			TestNet3Params,
+			"cV1Y7ARUr9Yx7BR55nTdnR7ZXNJphZtCCMBTEZBJe1hXt2kB684q",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.wif.String(), func(t *testing.T) {
+			privKey, err := NewPrivateKeyFromHex(tc.privKey)
+			require.NoError(t, err)
+
+			// private key -> wif
+			wif, err := privKey.Wif(tc.compressed, tc.params)
+			require.NoError(t, err)
+			assert.Equal(t, wif, tc.wif)
+
+			// wif -> private key
+			decoded, compressed, err := wif.Decode(tc.params)
+			require.NoError(t, err)
+			assert.Equal(t, decoded.Hex(), tc.privKey)
+			assert.Equal(t, compressed, tc.compressed)
+		})
+	}
+}
+
+func TestWifDecodeErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		wif    Wif
+		params *Params
+		err    error
+	}{
+		{
+			"invalid length",
+			"deadbeef",
+			MainNetParams,
+			ErrInvalidWif,
+		},
+		{
+			"invalid compress flag",
+			"KwDiBf89QgGbjEhKnhXJuH7LrciVrZi3qYjgd9M7rFU73sfZr2ym",
+			MainNetParams,
+			ErrInvalidWifCompressFlag,
+		},
+		{
+			"invalid checksum",
+			"5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTj",
+			MainNetParams,
+			ErrInvalidWifChecksum,
+		},
+		{
+			"network mismatch",
+			"cV1Y7ARUr9Yx7BR55nTdnR7ZXNJphZtCCMBTEZBJe1hXt2kB684q",
+			MainNetParams,
+			ErrWifNetworkMismatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := tc.wif.Decode(tc.params)
+			assert.Equal(t, err, tc.err)
+		})
+	}
+}
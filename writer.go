@@ -85,12 +85,16 @@ func (w *writer) writeTxIns(txIns []*TxIn) error {
 	return nil
 }
 
-func (w *writer) writeTxIn(txIn *TxIn) error {
-	if err := w.writeHexReverse(txIn.Txid); err != nil {
+func (w *writer) writeOutPoint(txid string, index uint32) error {
+	if err := w.writeHexReverse(txid); err != nil {
 		return err
 	}
 
-	if err := w.writeData(txIn.Index); err != nil {
+	return w.writeData(index)
+}
+
+func (w *writer) writeTxIn(txIn *TxIn) error {
+	if err := w.writeOutPoint(txIn.Txid, txIn.Index); err != nil {
 		return err
 	}
 
@@ -135,7 +139,94 @@ func (w *writer) writeLockTime(lockTime uint32) error {
 	return w.writeData(lockTime)
 }
 
+func (w *writer) writeOpCode(op OpCode) error {
+	return w.WriteByte(op.Byte())
+}
+
+// encodeScriptNum returns the minimal little-endian sign-magnitude encoding of n.
+func encodeScriptNum(n int64) []byte {
+	if n == 0 {
+		return nil
+	}
+
+	neg := n < 0
+	abs := uint64(n)
+	if neg {
+		abs = uint64(-n)
+	}
+
+	var b []byte
+	for abs > 0 {
+		b = append(b, byte(abs&0xff))
+		abs >>= 8
+	}
+
+	if b[len(b)-1]&0x80 != 0 {
+		if neg {
+			b = append(b, 0x80)
+		} else {
+			b = append(b, 0x00)
+		}
+	} else if neg {
+		b[len(b)-1] |= 0x80
+	}
+
+	return b
+}
+
+// writeScriptNum writes n with OP_0 to OP_16 if possible, or pushes it as a script number otherwise.
+func (w *writer) writeScriptNum(n int64) error {
+	if 0 <= n && n <= 16 {
+		return w.writeOpCode(smallIntOpCode(int(n)))
+	}
+
+	return w.writePushedData(encodeScriptNum(n))
+}
+
+// writePushedData writes b with the smallest push operation for its size.
+func (w *writer) writePushedData(b []byte) error {
+	l := len(b)
+
+	switch {
+	case l <= int(OpDataLenMax):
+		if err := w.WriteByte(byte(l)); err != nil {
+			return err
+		}
+	case l <= 0xff:
+		if err := w.writeOpCode(OpPushdata1); err != nil {
+			return err
+		}
+		if err := w.WriteByte(byte(l)); err != nil {
+			return err
+		}
+	case l <= 0xffff:
+		if err := w.writeOpCode(OpPushdata2); err != nil {
+			return err
+		}
+		if err := w.writeData(uint16(l)); err != nil {
+			return err
+		}
+	default:
+		if err := w.writeOpCode(OpPushdata4); err != nil {
+			return err
+		}
+		if err := w.writeData(uint32(l)); err != nil {
+			return err
+		}
+	}
+
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (w *writer) writeScript(script *Script) error {
+	if script == nil {
+		return w.writeVarInt(0)
+	}
+
 	b, err := script.Bytes()
 	if err != nil {
 		return err
@@ -152,11 +243,60 @@ func (w *writer) writeScript(script *Script) error {
 	return nil
 }
 
+func (w *writer) writeWitness(witness []string) error {
+	if err := w.writeVarInt(uint(len(witness))); err != nil {
+		return err
+	}
+
+	for _, item := range witness {
+		b, err := hex.DecodeString(item)
+		if err != nil {
+			return err
+		}
+
+		if err := w.writeVarBytes(b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *writer) writeVarBytes(b []byte) error {
+	if err := w.writeVarInt(uint(len(b))); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (w *writer) writeTx(tx *Tx) error {
+	return w.writeTxWithWitness(tx, tx.HasWitness())
+}
+
+func (w *writer) writeStrippedTx(tx *Tx) error {
+	return w.writeTxWithWitness(tx, false)
+}
+
+// ref. https://github.com/bitcoin/bips/blob/master/bip-0144.mediawiki
+func (w *writer) writeTxWithWitness(tx *Tx, withWitness bool) error {
 	if err := w.writeTxVersion(tx.Version); err != nil {
 		return err
 	}
 
+	if withWitness {
+		if err := w.WriteByte(TxWitnessMarker); err != nil {
+			return err
+		}
+		if err := w.WriteByte(TxWitnessFlag); err != nil {
+			return err
+		}
+	}
+
 	if err := w.writeTxIns(tx.TxIns); err != nil {
 		return err
 	}
@@ -165,6 +305,14 @@ func (w *writer) writeTx(tx *Tx) error {
 		return err
 	}
 
+	if withWitness {
+		for _, txIn := range tx.TxIns {
+			if err := w.writeWitness(txIn.Witness); err != nil {
+				return err
+			}
+		}
+	}
+
 	if err := w.writeLockTime(tx.LockTime); err != nil {
 		return err
 	}
@@ -249,3 +397,17 @@ func (w *writer) writeBlock(block *Block) error {
 
 	return nil
 }
+
+// writePsbtMap writes key-value pairs followed by the separator.
+func (w *writer) writePsbtMap(kvs []*psbtKeyValue) error {
+	for _, kv := range kvs {
+		if err := w.writeVarBytes(append([]byte{kv.keyType}, kv.keyData...)); err != nil {
+			return err
+		}
+		if err := w.writeVarBytes(kv.value); err != nil {
+			return err
+		}
+	}
+
+	return w.WriteByte(psbtSeparator)
+}